@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/tsukinoko-kun/pogo/db"
+	"github.com/tsukinoko-kun/pogo/fsck"
+	"github.com/tsukinoko-kun/pogo/repos"
+)
+
+// fsckMain verifies the integrity of the database and the blob store.
+// It exits with status 1 if problems remain.
+func fsckMain(args []string) {
+	flags := flag.NewFlagSet("fsck", flag.ExitOnError)
+	repair := flags.Bool("repair", false, "repair what can be repaired safely (change depths and file sizes)")
+	_ = flags.Parse(args)
+
+	db.Connect()
+	repos.SetBlobStore(blobStore)
+
+	report, err := fsck.Run(context.Background(), fsck.Options{
+		Repair: *repair,
+		Problem: func(p fsck.Problem) {
+			fmt.Println(p.String())
+		},
+	})
+	db.Disconnect()
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+
+	if len(report.Problems) == 0 {
+		fmt.Println("no problems found")
+		return
+	}
+	unrepaired := report.Unrepaired()
+	fmt.Printf("%d problems found, %d repaired\n", len(report.Problems), len(report.Problems)-unrepaired)
+	if unrepaired > 0 {
+		os.Exit(1)
+	}
+}
@@ -1,18 +1,30 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"github.com/tsukinoko-kun/pogo/blobstore"
 	"github.com/tsukinoko-kun/pogo/db"
+	"github.com/tsukinoko-kun/pogo/gc"
+	"github.com/tsukinoko-kun/pogo/repos"
 	"github.com/tsukinoko-kun/pogo/serve"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 )
 
 var (
-	host string
+	host               string
+	registrationMode   serve.RegistrationMode
+	nonceStore         string
+	blobStore          blobstore.BlobStore
+	gcGracePeriod      = gc.DefaultGracePeriod
+	operationRetention = gc.DefaultOperationRetention
+	evologRetention    time.Duration
+	gcInterval         time.Duration
 )
 
 func init() {
@@ -23,11 +35,89 @@ func init() {
 			host = ":" + portEnv
 		}
 	}
+	var err error
+	registrationMode, err = serve.ParseRegistrationMode(os.Getenv("REGISTRATION"))
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	nonceStore = os.Getenv("NONCE_STORE")
+	switch nonceStore {
+	case "", "memory", "postgres":
+	default:
+		_, _ = fmt.Fprintf(os.Stderr, "unknown nonce store '%s', expected 'memory' or 'postgres'\n", nonceStore)
+		os.Exit(1)
+	}
+	switch blobStoreEnv := os.Getenv("BLOB_STORE"); blobStoreEnv {
+	case "", "fs":
+		root := "content"
+		if rootEnv, ok := os.LookupEnv("BLOB_ROOT"); ok {
+			root = rootEnv
+		}
+		blobStore = blobstore.NewFileSystem(root)
+	case "s3":
+		blobStore, err = blobstore.NewS3(blobstore.S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          os.Getenv("S3_REGION"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			Prefix:          os.Getenv("S3_PREFIX"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+		})
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+	default:
+		_, _ = fmt.Fprintf(os.Stderr, "unknown blob store '%s', expected 'fs' or 's3'\n", blobStoreEnv)
+		os.Exit(1)
+	}
+	if gracePeriodEnv, ok := os.LookupEnv("GC_GRACE_PERIOD"); ok {
+		gcGracePeriod, err = time.ParseDuration(gracePeriodEnv)
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, "invalid GC_GRACE_PERIOD:", err.Error())
+			os.Exit(1)
+		}
+	}
+	if retentionEnv, ok := os.LookupEnv("OPERATION_RETENTION"); ok {
+		operationRetention, err = time.ParseDuration(retentionEnv)
+		if err != nil || operationRetention < 0 {
+			_, _ = fmt.Fprintln(os.Stderr, "invalid OPERATION_RETENTION:", retentionEnv)
+			os.Exit(1)
+		}
+	}
+	if retentionEnv, ok := os.LookupEnv("EVOLOG_RETENTION"); ok {
+		evologRetention, err = time.ParseDuration(retentionEnv)
+		if err != nil || evologRetention < 0 {
+			_, _ = fmt.Fprintln(os.Stderr, "invalid EVOLOG_RETENTION:", retentionEnv)
+			os.Exit(1)
+		}
+	}
+	if intervalEnv, ok := os.LookupEnv("GC_INTERVAL"); ok {
+		gcInterval, err = time.ParseDuration(intervalEnv)
+		if err != nil || gcInterval <= 0 {
+			_, _ = fmt.Fprintln(os.Stderr, "invalid GC_INTERVAL:", intervalEnv)
+			os.Exit(1)
+		}
+	}
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "fsck" {
+		fsckMain(os.Args[2:])
+		return
+	}
+
 	db.Connect()
+	repos.SetBlobStore(blobStore)
 	app := serve.NewApp()
+	app.SetRegistrationMode(registrationMode)
+	app.SetGCGracePeriod(gcGracePeriod)
+	app.SetOperationRetention(operationRetention)
+	app.SetEvologRetention(evologRetention)
+	if nonceStore == "postgres" {
+		app.SetNonceStore(db.NewNonceStore())
+	}
 	app.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		log.Println("request to unregistered path", r.URL.Path)
 		w.WriteHeader(http.StatusNotFound)
@@ -37,9 +127,19 @@ func main() {
 		os.Exit(1)
 		return
 	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if gcInterval > 0 {
+		go gc.RunPeriodically(ctx, gcInterval, gc.Options{
+			GracePeriod:        gcGracePeriod,
+			OperationRetention: operationRetention,
+			EvologRetention:    evologRetention,
+		})
+	}
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT, syscall.SIGABRT)
 	<-sig
+	cancel()
 	log.Println("shutting down server")
 	if err := app.Stop(); err != nil {
 		_, _ = fmt.Fprintln(os.Stderr, err.Error())
@@ -0,0 +1,55 @@
+// Package blobstore stores the compressed file contents of all repositories, addressed by their content hash.
+package blobstore
+
+import (
+	"encoding/base64"
+	"io"
+	"path"
+	"time"
+)
+
+// BlobStore is a content-addressed store for blobs.
+// Missing blobs are reported with an error that matches fs.ErrNotExist.
+type BlobStore interface {
+	// Exists reports whether a blob for contentHash is stored.
+	Exists(contentHash []byte) (bool, error)
+	// Stat returns information about the stored blob for contentHash.
+	Stat(contentHash []byte) (BlobInfo, error)
+	// Open opens the stored blob for contentHash for reading.
+	Open(contentHash []byte) (io.ReadCloser, error)
+	// Put stores the blob for contentHash.
+	// The blob only becomes visible if content could be read to the end without an error,
+	// so a failing reader never leaves a partial blob behind.
+	Put(contentHash []byte, content io.Reader) error
+	// Delete removes the blob for contentHash.
+	Delete(contentHash []byte) error
+	// List calls fn for every stored blob.
+	// If fn returns an error, listing stops and the error is returned.
+	List(fn func(contentHash []byte, info BlobInfo) error) error
+}
+
+// BlobInfo describes a stored blob.
+type BlobInfo struct {
+	// Size is the size of the stored (compressed) blob in bytes.
+	Size    int64
+	ModTime time.Time
+}
+
+// key returns the location of a blob relative to the root of a store: xx/yyyy
+func key(contentHash []byte) string {
+	str := base64.RawURLEncoding.EncodeToString(contentHash)
+	return path.Join(str[:2], str[2:])
+}
+
+// contentHashFromKey is the inverse of key.
+func contentHashFromKey(k string) ([]byte, bool) {
+	dir, name := path.Split(k)
+	if len(dir) != 3 || dir[2] != '/' || len(name) == 0 {
+		return nil, false
+	}
+	contentHash, err := base64.RawURLEncoding.DecodeString(dir[:2] + name)
+	if err != nil {
+		return nil, false
+	}
+	return contentHash, true
+}
@@ -0,0 +1,143 @@
+package blobstore
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"io/fs"
+	"slices"
+	"testing"
+)
+
+func testHash(content string) []byte {
+	h := sha256.Sum256([]byte(content))
+	return h[:]
+}
+
+// failingReader returns err after its content was read.
+type failingReader struct {
+	r   io.Reader
+	err error
+}
+
+func (f *failingReader) Read(p []byte) (int, error) {
+	n, err := f.r.Read(p)
+	if err == io.EOF {
+		return n, f.err
+	}
+	return n, err
+}
+
+// testBlobStore runs the behavior every BlobStore must have against an empty store.
+func testBlobStore(t *testing.T, store BlobStore) {
+	a, b := testHash("a"), testHash("b")
+
+	if exists, err := store.Exists(a); err != nil {
+		t.Fatal(err)
+	} else if exists {
+		t.Fatal("blob should not exist in an empty store")
+	}
+	if _, err := store.Stat(a); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("stat of a missing blob should fail with fs.ErrNotExist, got %v", err)
+	}
+	if _, err := store.Open(a); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("open of a missing blob should fail with fs.ErrNotExist, got %v", err)
+	}
+
+	if err := store.Put(a, bytes.NewReader([]byte("content a"))); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(b, bytes.NewReader([]byte("content bb"))); err != nil {
+		t.Fatal(err)
+	}
+
+	if exists, err := store.Exists(a); err != nil {
+		t.Fatal(err)
+	} else if !exists {
+		t.Fatal("stored blob should exist")
+	}
+	if info, err := store.Stat(b); err != nil {
+		t.Fatal(err)
+	} else if info.Size != int64(len("content bb")) {
+		t.Fatalf("size should be %d, got %d", len("content bb"), info.Size)
+	}
+	f, err := store.Open(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := io.ReadAll(f)
+	_ = f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "content a" {
+		t.Fatalf("content should be %q, got %q", "content a", content)
+	}
+
+	// a failing upload must not leave a partial blob behind
+	failed := testHash("failed")
+	if err := store.Put(failed, &failingReader{r: bytes.NewReader([]byte("partial")), err: io.ErrUnexpectedEOF}); err == nil {
+		t.Fatal("put from a failing reader should fail")
+	}
+	if exists, err := store.Exists(failed); err != nil {
+		t.Fatal(err)
+	} else if exists {
+		t.Fatal("failed put should not leave a blob")
+	}
+
+	var listed [][]byte
+	if err := store.List(func(contentHash []byte, info BlobInfo) error {
+		listed = append(listed, contentHash)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	slices.SortFunc(listed, bytes.Compare)
+	want := [][]byte{a, b}
+	slices.SortFunc(want, bytes.Compare)
+	if !slices.EqualFunc(listed, want, bytes.Equal) {
+		t.Fatalf("list should return %x, got %x", want, listed)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	if err := store.List(func([]byte, BlobInfo) error {
+		calls++
+		return stop
+	}); !errors.Is(err, stop) || calls != 1 {
+		t.Fatalf("list should stop at the first error, got %v after %d calls", err, calls)
+	}
+
+	if err := store.Delete(a); err != nil {
+		t.Fatal(err)
+	}
+	if exists, err := store.Exists(a); err != nil {
+		t.Fatal(err)
+	} else if exists {
+		t.Fatal("deleted blob should not exist")
+	}
+	if exists, err := store.Exists(b); err != nil {
+		t.Fatal(err)
+	} else if !exists {
+		t.Fatal("other blobs should survive a delete")
+	}
+}
+
+func TestKey(t *testing.T) {
+	contentHash := testHash("a")
+	k := key(contentHash)
+	if len(k) < 4 || k[2] != '/' {
+		t.Fatalf("key should be xx/yyyy, got %s", k)
+	}
+	got, ok := contentHashFromKey(k)
+	if !ok || !bytes.Equal(got, contentHash) {
+		t.Fatalf("contentHashFromKey(%s) should return %x, got %x (%v)", k, contentHash, got, ok)
+	}
+
+	for _, k := range []string{"tmp/blob-123", "ab", "abc/def", "/abcd", "ab/!!"} {
+		if _, ok := contentHashFromKey(k); ok {
+			t.Errorf("%s should not be a blob key", k)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package blobstore
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// FileSystem stores blobs as files below a root directory.
+type FileSystem struct {
+	root string
+}
+
+var _ BlobStore = (*FileSystem)(nil)
+
+func NewFileSystem(root string) *FileSystem {
+	return &FileSystem{root: root}
+}
+
+func (s *FileSystem) path(contentHash []byte) string {
+	return filepath.Join(s.root, filepath.FromSlash(key(contentHash)))
+}
+
+func (s *FileSystem) Exists(contentHash []byte) (bool, error) {
+	_, err := os.Stat(s.path(contentHash))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *FileSystem) Stat(contentHash []byte) (BlobInfo, error) {
+	fi, err := os.Stat(s.path(contentHash))
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	return BlobInfo{Size: fi.Size(), ModTime: fi.ModTime()}, nil
+}
+
+func (s *FileSystem) Open(contentHash []byte) (io.ReadCloser, error) {
+	return os.Open(s.path(contentHash))
+}
+
+// Put writes content to a temporary file first, which is renamed to its final name once it is complete.
+func (s *FileSystem) Put(contentHash []byte, content io.Reader) error {
+	tmpDir := filepath.Join(s.root, "tmp")
+	_ = os.MkdirAll(tmpDir, 0755)
+	f, err := os.CreateTemp(tmpDir, "blob-*")
+	if err != nil {
+		return err
+	}
+	tmpName := f.Name()
+	defer os.Remove(tmpName)
+
+	_, err = io.Copy(f, content)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	name := s.path(contentHash)
+	_ = os.MkdirAll(filepath.Dir(name), 0755)
+	return os.Rename(tmpName, name)
+}
+
+func (s *FileSystem) Delete(contentHash []byte) error {
+	return os.Remove(s.path(contentHash))
+}
+
+func (s *FileSystem) List(fn func(contentHash []byte, info BlobInfo) error) error {
+	err := filepath.WalkDir(s.root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(s.root, p)
+		if err != nil {
+			return err
+		}
+		contentHash, ok := contentHashFromKey(filepath.ToSlash(rel))
+		if !ok {
+			// temporary files and everything else that is not a blob
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return fn(contentHash, BlobInfo{Size: fi.Size(), ModTime: fi.ModTime()})
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		// nothing stored yet
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,20 @@
+package blobstore
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestFileSystem(t *testing.T) {
+	testBlobStore(t, NewFileSystem(t.TempDir()))
+}
+
+func TestFileSystemMissingRoot(t *testing.T) {
+	store := NewFileSystem(filepath.Join(t.TempDir(), "missing"))
+	if err := store.List(func([]byte, BlobInfo) error {
+		t.Fatal("an empty store should not list blobs")
+		return nil
+	}); err != nil {
+		t.Fatalf("listing a store without root directory should succeed, got %v", err)
+	}
+}
@@ -0,0 +1,338 @@
+package blobstore
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Config configures an S3 compatible blob store (AWS S3, MinIO, …).
+type S3Config struct {
+	// Endpoint is the base URL of the S3 API, like https://s3.eu-central-1.amazonaws.com or http://localhost:9000.
+	Endpoint string
+	Region   string
+	Bucket   string
+	// Prefix is prepended to the key of every blob.
+	Prefix          string
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// S3 stores blobs as objects in an S3 compatible bucket.
+// Objects are addressed path-style (endpoint/bucket/key), which MinIO and AWS support.
+type S3 struct {
+	config   S3Config
+	endpoint *url.URL
+	client   *http.Client
+}
+
+var _ BlobStore = (*S3)(nil)
+
+func NewS3(config S3Config) (*S3, error) {
+	if config.Endpoint == "" {
+		return nil, errors.New("s3 endpoint not set")
+	}
+	if config.Bucket == "" {
+		return nil, errors.New("s3 bucket not set")
+	}
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil {
+		return nil, errors.Join(errors.New("parse s3 endpoint"), err)
+	}
+	return &S3{
+		config:   config,
+		endpoint: endpoint,
+		client:   &http.Client{},
+	}, nil
+}
+
+func (s *S3) objectURL(contentHash []byte) *url.URL {
+	u := *s.endpoint
+	u.Path = path.Join("/", u.Path, s.config.Bucket, s.config.Prefix, key(contentHash))
+	return &u
+}
+
+func (s *S3) Exists(contentHash []byte) (bool, error) {
+	_, err := s.Stat(contentHash)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *S3) Stat(contentHash []byte) (BlobInfo, error) {
+	resp, err := s.do(http.MethodHead, contentHash, nil, 0, emptyPayloadHash)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	_ = resp.Body.Close()
+	if err := s3Error(resp, contentHash); err != nil {
+		return BlobInfo{}, err
+	}
+	modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	return BlobInfo{Size: resp.ContentLength, ModTime: modTime}, nil
+}
+
+func (s *S3) Open(contentHash []byte) (io.ReadCloser, error) {
+	resp, err := s.do(http.MethodGet, contentHash, nil, 0, emptyPayloadHash)
+	if err != nil {
+		return nil, err
+	}
+	if err := s3Error(resp, contentHash); err != nil {
+		_ = resp.Body.Close()
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Put spools content to a temporary file first.
+// S3 needs the size and the hash of the payload before the upload starts.
+func (s *S3) Put(contentHash []byte, content io.Reader) error {
+	f, err := os.CreateTemp("", "pogo-blob-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(f, hasher), content)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	resp, err := s.do(http.MethodPut, contentHash, f, size, hex.EncodeToString(hasher.Sum(nil)))
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	return s3Error(resp, contentHash)
+}
+
+func (s *S3) Delete(contentHash []byte) error {
+	resp, err := s.do(http.MethodDelete, contentHash, nil, 0, emptyPayloadHash)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	return s3Error(resp, contentHash)
+}
+
+func (s *S3) List(fn func(contentHash []byte, info BlobInfo) error) error {
+	u := *s.endpoint
+	u.Path = path.Join("/", u.Path, s.config.Bucket)
+	prefix := ""
+	if p := strings.Trim(s.config.Prefix, "/"); p != "" {
+		prefix = p + "/"
+	}
+
+	continuationToken := ""
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", prefix)
+		if continuationToken != "" {
+			query.Set("continuation-token", continuationToken)
+		}
+		u.RawQuery = canonicalQuery(query)
+
+		resp, err := s.doURL(http.MethodGet, &u, nil, 0, emptyPayloadHash)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode >= http.StatusBadRequest {
+			msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+			_ = resp.Body.Close()
+			return fmt.Errorf("s3 %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+		}
+		var result listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		_ = resp.Body.Close()
+		if err != nil {
+			return errors.Join(errors.New("decode s3 list result"), err)
+		}
+
+		for _, object := range result.Contents {
+			contentHash, ok := contentHashFromKey(strings.TrimPrefix(object.Key, prefix))
+			if !ok {
+				continue
+			}
+			if err := fn(contentHash, BlobInfo{Size: object.Size, ModTime: object.LastModified}); err != nil {
+				return err
+			}
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return nil
+		}
+		continuationToken = result.NextContinuationToken
+	}
+}
+
+// listBucketResult is the response of ListObjectsV2.
+type listBucketResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		LastModified time.Time `xml:"LastModified"`
+		Size         int64     `xml:"Size"`
+	} `xml:"Contents"`
+}
+
+func (s *S3) do(method string, contentHash []byte, body io.Reader, size int64, payloadHash string) (*http.Response, error) {
+	return s.doURL(method, s.objectURL(contentHash), body, size, payloadHash)
+}
+
+func (s *S3) doURL(method string, u *url.URL, body io.Reader, size int64, payloadHash string) (*http.Response, error) {
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	s.sign(req, payloadHash, time.Now().UTC())
+	return s.client.Do(req)
+}
+
+func s3Error(resp *http.Response, contentHash []byte) error {
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("blob %s: %w", key(contentHash), fs.ErrNotExist)
+	case resp.StatusCode >= http.StatusBadRequest:
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("s3 %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	default:
+		return nil
+	}
+}
+
+// emptyPayloadHash is the hex encoded SHA-256 of an empty payload.
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// sign adds an AWS Signature Version 4 to req.
+// https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html
+func (s *S3) sign(req *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	scope := date + "/" + s.config.Region + "/s3/aws4_request"
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if strings.HasPrefix(name, "x-amz-") {
+			headers[name] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	headerNames := make([]string, 0, len(headers))
+	for name := range headers {
+		headerNames = append(headerNames, name)
+	}
+	sort.Strings(headerNames)
+	var canonicalHeaders strings.Builder
+	for _, name := range headerNames {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(headerNames, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		uriEncodePath(req.URL.Path),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	canonicalRequestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(canonicalRequestHash[:]),
+	}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+s.config.SecretAccessKey), date)
+	signingKey = hmacSHA256(signingKey, s.config.Region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.config.AccessKeyID, scope, signedHeaders, signature,
+	))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		values := query[k]
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func uriEncodePath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	return uriEncode(p, false)
+}
+
+// uriEncode percent-encodes everything except the unreserved characters of RFC 3986.
+// Slashes are only encoded if encodeSlash is set.
+func uriEncode(s string, encodeSlash bool) string {
+	var sb strings.Builder
+	for _, b := range []byte(s) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9',
+			b == '-', b == '_', b == '.', b == '~':
+			sb.WriteByte(b)
+		case b == '/' && !encodeSlash:
+			sb.WriteByte(b)
+		default:
+			fmt.Fprintf(&sb, "%%%02X", b)
+		}
+	}
+	return sb.String()
+}
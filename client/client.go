@@ -12,10 +12,10 @@ import (
 	"path/filepath"
 	"slices"
 	"strings"
-	"time"
 
 	"github.com/tsukinoko-kun/pogo/colors"
 	"github.com/tsukinoko-kun/pogo/config"
+	"github.com/tsukinoko-kun/pogo/loggraph"
 	"github.com/tsukinoko-kun/pogo/protos"
 	"github.com/tsukinoko-kun/pogo/serve/serveerrors"
 	"github.com/tsukinoko-kun/pogo/signedhttp"
@@ -62,31 +62,105 @@ func Init(host string, repoName string, userName string, machineId string) (*Cli
 	if err != nil {
 		return nil, errors.Join(fmt.Errorf("init"), err)
 	}
-	config := RepoConfig{
+	if err = writeRepoConfig(absPath, RepoConfig{
 		Host:   host,
 		RepoID: repoId,
+	}); err != nil {
+		return nil, err
 	}
-	f, err := os.Create(filepath.Join(absPath, ".pogo"))
+
+	urlStr, err = url.JoinPath(host, "rpc", fmt.Sprintf("%d", repoId))
 	if err != nil {
-		return nil, errors.Join(fmt.Errorf("create config file"), err)
+		return nil, errors.Join(fmt.Errorf("join path %s %d (%s)", host, repoId, repoName), err)
 	}
-	defer f.Close()
-	yamlEnc := yaml.NewEncoder(f)
-	yamlEnc.SetIndent(4)
-	err = yamlEnc.Encode(config)
+	c.url = urlStr
+
+	return c, nil
+}
+
+// Clone attaches the directory dir to the existing repository repo (name or ID) on host.
+// The head of this machine is set to the change bookmark points to and the change is checked out into dir.
+// If bookmark is empty, "main" is used.
+func Clone(host string, repo string, bookmark string, dir string, userName string, machineId string) (*Client, error) {
+	absPath, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("get absolute path %s", dir), err)
+	}
+	if err := os.MkdirAll(absPath, 0755); err != nil {
+		return nil, errors.Join(fmt.Errorf("create directory %s", absPath), err)
+	}
+	if entries, err := os.ReadDir(absPath); err != nil {
+		return nil, errors.Join(fmt.Errorf("read directory %s", absPath), err)
+	} else if len(entries) != 0 {
+		return nil, fmt.Errorf("directory %s is not empty", absPath)
+	}
+	urlStr, err := url.JoinPath(host, "rpc")
 	if err != nil {
-		return nil, errors.Join(fmt.Errorf("encode config file"), err)
+		return nil, errors.Join(fmt.Errorf("join path %s", host), err)
+	}
+	c := &Client{
+		url:     urlStr,
+		rootDir: absPath,
+		stdout:  os.Stdout,
+	}
+	if err = c.Login(userName, machineId); err != nil {
+		return nil, errors.Join(fmt.Errorf("login"), err)
+	}
+	cloneResp, err := c.Clone(repo, bookmark)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("clone"), err)
 	}
 
-	urlStr, err = url.JoinPath(host, "rpc", fmt.Sprintf("%d", repoId))
+	urlStr, err = url.JoinPath(host, "rpc", fmt.Sprintf("%d", cloneResp.RepoID))
 	if err != nil {
-		return nil, errors.Join(fmt.Errorf("join path %s %d (%s)", host, repoId, repoName), err)
+		return nil, errors.Join(fmt.Errorf("join path %s %d (%s)", host, cloneResp.RepoID, repo), err)
 	}
 	c.url = urlStr
 
+	if err = c.Edit(cloneResp.ChangeId); err != nil {
+		// the directory was empty, don't leave a partial checkout behind
+		return nil, errors.Join(fmt.Errorf("checkout change %d", cloneResp.ChangeId), err, clearDir(absPath))
+	}
+
+	// the config is written last, a directory with a config is a complete clone
+	if err = writeRepoConfig(absPath, RepoConfig{
+		Host:   host,
+		RepoID: cloneResp.RepoID,
+	}); err != nil {
+		return nil, errors.Join(err, clearDir(absPath))
+	}
+
 	return c, nil
 }
 
+// clearDir removes everything inside dir.
+func clearDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return errors.Join(fmt.Errorf("read directory %s", dir), err)
+	}
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+			return errors.Join(fmt.Errorf("remove %s", entry.Name()), err)
+		}
+	}
+	return nil
+}
+
+func writeRepoConfig(dir string, config RepoConfig) error {
+	f, err := os.Create(filepath.Join(dir, ".pogo"))
+	if err != nil {
+		return errors.Join(fmt.Errorf("create config file"), err)
+	}
+	defer f.Close()
+	yamlEnc := yaml.NewEncoder(f)
+	yamlEnc.SetIndent(4)
+	if err = yamlEnc.Encode(config); err != nil {
+		return errors.Join(fmt.Errorf("encode config file"), err)
+	}
+	return nil
+}
+
 func (c *Client) Login(userName string, machineId string) error {
 	c.headName = fmt.Sprintf("__head-%s-%s", userName, machineId)
 	c.userName = userName
@@ -122,17 +196,9 @@ func Open(fileName string) (*Client, error) {
 		return nil, errors.Join(fmt.Errorf("get absolute path %s", fileName), err)
 	}
 
-	f, err := os.Open(fileName)
+	repoConfig, err := LoadRepoConfig(fileName)
 	if err != nil {
-		return nil, errors.Join(fmt.Errorf("open %s", fileName), err)
-	}
-	defer f.Close()
-
-	repoConfig := RepoConfig{}
-	yamlDec := yaml.NewDecoder(f)
-	err = yamlDec.Decode(&repoConfig)
-	if err != nil {
-		return nil, errors.Join(fmt.Errorf("decode config file"), err)
+		return nil, err
 	}
 
 	urlStr, err := url.JoinPath(repoConfig.Host, "rpc", fmt.Sprintf("%d", repoConfig.RepoID))
@@ -159,6 +225,38 @@ func Open(fileName string) (*Client, error) {
 	return c, nil
 }
 
+// LoadRepoConfig reads the repository configuration from a .pogo file.
+func LoadRepoConfig(fileName string) (RepoConfig, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return RepoConfig{}, errors.Join(fmt.Errorf("open %s", fileName), err)
+	}
+	defer f.Close()
+
+	repoConfig := RepoConfig{}
+	yamlDec := yaml.NewDecoder(f)
+	if err = yamlDec.Decode(&repoConfig); err != nil {
+		return RepoConfig{}, errors.Join(fmt.Errorf("decode config file"), err)
+	}
+	return repoConfig, nil
+}
+
+// Connect creates a client for server-wide RPCs that are not bound to a repository.
+func Connect(host string, userName string, machineId string) (*Client, error) {
+	urlStr, err := url.JoinPath(host, "rpc")
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("join path %s", host), err)
+	}
+	c := &Client{
+		url:    urlStr,
+		stdout: os.Stdout,
+	}
+	if err = c.Login(userName, machineId); err != nil {
+		return nil, errors.Join(fmt.Errorf("login"), err)
+	}
+	return c, nil
+}
+
 func (c *Client) Init(repoName string) (int32, error) {
 	headName, err := c.Head()
 	if err != nil {
@@ -176,6 +274,18 @@ func (c *Client) Init(repoName string) (int32, error) {
 	return resp.RepoID, nil
 }
 
+func (c *Client) Clone(repo string, bookmark string) (*protos.CloneResponse, error) {
+	req := &protos.CloneRequest{
+		Repo:     repo,
+		Bookmark: bookmark,
+	}
+	resp := new(protos.CloneResponse)
+	if err := c.execute("clone", req, resp); err != nil {
+		return nil, errors.Join(fmt.Errorf("clone"), err)
+	}
+	return resp, nil
+}
+
 func (c *Client) Push() error {
 	headName, err := c.Head()
 	if err != nil {
@@ -218,11 +328,10 @@ func (c *Client) Push() error {
 	// now, push push all local files
 	// if the file does exist on the server, exclude the content
 
+	// the tar is streamed to the server while it is written, nothing is buffered
 	pipeReader, pipeWriter := io.Pipe()
 	go func() {
-		defer pipeWriter.Close()
 		tarWriter := tar.NewWriter(pipeWriter)
-		defer tarWriter.Close()
 		for _, localFile := range localFiles {
 			pfi := &protos.PushFileInfo{
 				Name:            localFile.name,
@@ -241,18 +350,19 @@ func (c *Client) Push() error {
 				header.Size, _ = utils.GetFileSize(localFile.absPath)
 			}
 			if err := tarWriter.WriteHeader(header); err != nil {
-				panic(err)
+				_ = pipeWriter.CloseWithError(errors.Join(fmt.Errorf("write tar header for %s", localFile.name), err))
+				return
 			}
 			if !localFile.existsOnServer {
-				f, _ := os.Open(localFile.absPath)
-				defer f.Close()
 				// send uncompressed
 				// compression is done on the server to make the client logic simpler
-				if _, err := io.Copy(tarWriter, f); err != nil {
-					panic(err)
+				if err := copyFile(tarWriter, localFile.absPath); err != nil {
+					_ = pipeWriter.CloseWithError(errors.Join(fmt.Errorf("write %s", localFile.name), err))
+					return
 				}
 			}
 		}
+		_ = pipeWriter.CloseWithError(tarWriter.Close())
 	}()
 
 	rc, err := c.executeStream(
@@ -271,6 +381,10 @@ func (c *Client) Push() error {
 			fmt.Println(colors.BrightBlack + "(" + serveerrors.ErrPushToChangeNotOwned.Error() + ")" + colors.Reset)
 			return nil
 		}
+		if errors.Is(err, ErrForbidden) {
+			fmt.Println(colors.BrightBlack + "(no write access, local changes are not pushed)" + colors.Reset)
+			return nil
+		}
 		return errors.Join(fmt.Errorf("execute push"), err)
 	}
 	defer rc.Close()
@@ -278,6 +392,16 @@ func (c *Client) Push() error {
 	return nil
 }
 
+func copyFile(w io.Writer, name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
 func (c *Client) NewChange(parents []string, description *string, setBookmarks []string) (*protos.NewChangeResponse, error) {
 	resp := new(protos.NewChangeResponse)
 	err := c.execute("new_change", &protos.NewChangeRequest{
@@ -355,69 +479,234 @@ func (c *Client) Edit(changeId int64) error {
 		}
 	}
 
-	headName, err := c.Head()
+	if err = c.execute("set_head", &protos.SetHeadRequest{
+		ChangeId: changeId,
+	}, nil); err != nil {
+		return errors.Join(fmt.Errorf("set head to change %d", changeId), err)
+	}
+
+	return nil
+}
+
+// LogGraph returns the changes (and their edges) selected by the revset, newest first.
+// If revset is empty, the ancestry of the head is returned.
+// At most limit changes are returned, a limit of 0 or less uses the server default.
+func (c *Client) LogGraph(revset string, limit int32) (*protos.LogResponse, error) {
+	res := new(protos.LogResponse)
+	if err := c.execute("log", &protos.LogRequest{
+		Head:   "@",
+		Limit:  limit,
+		Revset: revset,
+	}, res); err != nil {
+		return nil, errors.Join(fmt.Errorf("log request"), err)
+	}
+
+	return res, nil
+}
+
+// LogRevset renders the changes selected by the revset to stdout.
+func (c *Client) LogRevset(revset string, limit int32) error {
+	res, err := c.LogGraph(revset, limit)
 	if err != nil {
-		return errors.Join(errors.New("get head"), err)
+		return err
 	}
 
-	if err = c.execute("set_bookmark", &protos.SetBookmarkRequest{
-		Bookmark: headName,
-		ChangeId: changeId,
-	}, nil); err != nil {
-		return errors.Join(fmt.Errorf("set bookmark '%s' to change %d", headName, changeId), err)
+	if err := loggraph.Render(os.Stdout, res, loggraph.TerminalOptions(os.Stdout)); err != nil {
+		return errors.Join(fmt.Errorf("render log"), err)
 	}
 
 	return nil
 }
 
 func (c *Client) LogLimit(limit int32) error {
-	head, err := c.Head()
+	return c.LogRevset("", limit)
+}
+
+func (c *Client) Log() error {
+	return c.LogLimit(0)
+}
+
+// Conflicts lists the conflicted files of a change with the versions they were merged from.
+func (c *Client) Conflicts(change string) ([]*protos.FileConflict, error) {
+	res := new(protos.ConflictsResponse)
+	err := c.execute("conflicts", &protos.ConflictsRequest{
+		Change: change,
+	}, res)
 	if err != nil {
-		return errors.Join(fmt.Errorf("get head"), err)
+		return nil, err
 	}
+	return res.Files, nil
+}
 
-	res := new(protos.LogResponse)
-	if err = c.execute("log", &protos.LogRequest{
-		Head:     head,
-		Limit:    limit,
-		TimeZone: time.Local.String(),
+// Diff compares the files of two changes, see protos.DiffRequest.
+func (c *Client) Diff(from string, fromSnapshot *int64, to string, paths []string, stat bool) (*protos.DiffResponse, error) {
+	res := new(protos.DiffResponse)
+	if err := c.execute("diff", &protos.DiffRequest{
+		From:         from,
+		FromSnapshot: fromSnapshot,
+		To:           to,
+		Paths:        paths,
+		Stat:         stat,
 	}, res); err != nil {
-		return errors.Join(fmt.Errorf("log request"), err)
+		return nil, err
 	}
+	return res, nil
+}
 
-	fmt.Println(res.GetLog())
+// Files lists the files of the change selected by the revset.
+func (c *Client) Files(change string) (*protos.FilesResponse, error) {
+	res := new(protos.FilesResponse)
+	if err := c.execute("files", &protos.FilesRequest{Change: change}, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
 
-	return nil
+// Cat returns the content of the file at path (relative to the repository root) in the change selected by the revset.
+// The caller must close the returned reader.
+func (c *Client) Cat(change string, path string) (io.ReadCloser, error) {
+	return c.executeStream("cat", protos.Marshal(&protos.CatRequest{Change: change, Path: path}), nil)
 }
 
-func (c *Client) Log() error {
-	head, err := c.Head()
+// RepoPath converts a path relative to the working directory into a slash separated path relative to the repository root.
+func (c *Client) RepoPath(p string) (string, error) {
+	abs, err := filepath.Abs(p)
 	if err != nil {
-		return errors.Join(fmt.Errorf("get head"), err)
+		return "", errors.Join(fmt.Errorf("get absolute path %s", p), err)
+	}
+	rel, err := filepath.Rel(c.rootDir, abs)
+	if err != nil {
+		return "", errors.Join(fmt.Errorf("get path %s relative to repository root", p), err)
 	}
+	rel = filepath.ToSlash(rel)
+	if rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", fmt.Errorf("path %s is outside of the repository", p)
+	}
+	return rel, nil
+}
 
-	res := new(protos.LogResponse)
-	if err = c.execute("log", &protos.LogRequest{
-		Head:     head,
-		TimeZone: time.Local.String(),
+// Abandon hides the change selected by the revset, see protos.AbandonResponse.
+// If the working copy was abandoned, a new change on top of the parents of the abandoned change is checked out.
+func (c *Client) Abandon(change string) (*protos.AbandonResponse, error) {
+	res := new(protos.AbandonResponse)
+	if err := c.execute("abandon", &protos.AbandonRequest{Change: change}, res); err != nil {
+		return nil, err
+	}
+	if res.HeadAbandoned {
+		newChangeResp, err := c.NewChange(res.Parents, nil, nil)
+		if err != nil {
+			return nil, errors.Join(errors.New("create new change"), err)
+		}
+		if err := c.Edit(newChangeResp.GetChangeId()); err != nil {
+			return nil, errors.Join(errors.New("edit new change"), err)
+		}
+	}
+	return res, nil
+}
+
+// Squash moves the diff of source against its parent, or only the given paths of it, into another change.
+// An empty into squashes into the parent. The working copy is updated because the squash can rewrite it.
+func (c *Client) Squash(source string, into string, paths []string, description *string) (*protos.SquashResponse, error) {
+	res := new(protos.SquashResponse)
+	if err := c.execute("squash", &protos.SquashRequest{
+		Source:      source,
+		Into:        into,
+		Paths:       paths,
+		Description: description,
 	}, res); err != nil {
-		return errors.Join(fmt.Errorf("log request"), err)
+		return nil, err
 	}
+	if res.Abandoned != nil && res.Abandoned.HeadAbandoned {
+		newChangeResp, err := c.NewChange(res.Abandoned.Parents, nil, nil)
+		if err != nil {
+			return nil, errors.Join(errors.New("create new change"), err)
+		}
+		if err := c.Edit(newChangeResp.GetChangeId()); err != nil {
+			return nil, errors.Join(errors.New("edit new change"), err)
+		}
+		return res, nil
+	}
+	if err := c.EditName("@"); err != nil {
+		return nil, errors.Join(errors.New("update working copy"), err)
+	}
+	return res, nil
+}
 
-	fmt.Println(res.GetLog())
+// Rebase moves source and its descendants onto destination.
+// The working copy is updated if its change was rebased.
+func (c *Client) Rebase(source string, destination string) (*protos.RebaseResponse, error) {
+	res := new(protos.RebaseResponse)
+	if err := c.execute("rebase", &protos.RebaseRequest{
+		Source:      source,
+		Destination: destination,
+	}, res); err != nil {
+		return nil, err
+	}
+	if res.HeadRebased {
+		if err := c.EditName("@"); err != nil {
+			return nil, errors.Join(errors.New("update working copy"), err)
+		}
+	}
+	return res, nil
+}
 
-	return nil
+// OperationLog lists the latest operations of the repository, newest first.
+func (c *Client) OperationLog(limit int32) ([]*protos.Operation, error) {
+	res := new(protos.OperationLogResponse)
+	if err := c.execute("op_log", &protos.OperationLogRequest{Limit: limit}, res); err != nil {
+		return nil, err
+	}
+	return res.Operations, nil
 }
 
-func (c *Client) Conflicts(change string) ([]string, error) {
-	res := new(protos.ConflictsResponse)
-	err := c.execute("conflicts", &protos.ConflictsRequest{
-		Change: change,
-	}, res)
-	if err != nil {
+// Undo reverts an operation, nil undoes the latest operation of this user and machine.
+// The working copy is updated because the undo can rewrite it.
+func (c *Client) Undo(operationId *int64) (*protos.Operation, error) {
+	res := new(protos.UndoResponse)
+	if err := c.execute("undo", &protos.UndoRequest{OperationId: operationId}, res); err != nil {
+		return nil, err
+	}
+	if err := c.EditName("@"); err != nil {
+		return nil, errors.Join(errors.New("update working copy"), err)
+	}
+	return res.Undone, nil
+}
+
+// RestoreOperation reverts all operations after the given one and returns them, newest first.
+// The working copy is updated because the restore can rewrite it.
+func (c *Client) RestoreOperation(operationId int64) ([]*protos.Operation, error) {
+	res := new(protos.RestoreOperationResponse)
+	if err := c.execute("restore_operation", &protos.RestoreOperationRequest{OperationId: operationId}, res); err != nil {
+		return nil, err
+	}
+	if err := c.EditName("@"); err != nil {
+		return nil, errors.Join(errors.New("update working copy"), err)
+	}
+	return res.Reverted, nil
+}
+
+// Evolog lists the snapshots of the change selected by the revset, newest first.
+func (c *Client) Evolog(change string) (*protos.EvologResponse, error) {
+	res := new(protos.EvologResponse)
+	if err := c.execute("evolog", &protos.EvologRequest{Change: change}, res); err != nil {
 		return nil, err
 	}
-	return res.Conflicts, nil
+	return res, nil
+}
+
+// RestoreSnapshot resets the files and the description of a change to one of its snapshots.
+// The working copy is updated if the restore rewrote it.
+func (c *Client) RestoreSnapshot(snapshotId int64) (*protos.RestoreSnapshotResponse, error) {
+	res := new(protos.RestoreSnapshotResponse)
+	if err := c.execute("restore_snapshot", &protos.RestoreSnapshotRequest{SnapshotId: snapshotId}, res); err != nil {
+		return nil, err
+	}
+	if res.HeadChanged {
+		if err := c.EditName("@"); err != nil {
+			return nil, errors.Join(errors.New("update working copy"), err)
+		}
+	}
+	return res, nil
 }
 
 func (c *Client) Describe(change string, description string) error {
@@ -436,6 +725,112 @@ func (c *Client) ListBookmarks() ([]*protos.Bookmark, error) {
 	return res.Bookmarks, nil
 }
 
+// SetBookmark creates the bookmark or moves it to the change selected by the revset.
+// Moving it to a change that is not a descendant of its current target requires allowBackwards.
+func (c *Client) SetBookmark(bookmark string, change string, allowBackwards bool) error {
+	return c.execute("set_bookmark", &protos.SetBookmarkRequest{
+		Bookmark:       bookmark,
+		Change:         change,
+		AllowBackwards: allowBackwards,
+	}, nil)
+}
+
+// MoveBookmark is like SetBookmark but fails if the bookmark doesn't exist.
+func (c *Client) MoveBookmark(bookmark string, change string, allowBackwards bool) error {
+	return c.execute("set_bookmark", &protos.SetBookmarkRequest{
+		Bookmark:       bookmark,
+		Change:         change,
+		AllowBackwards: allowBackwards,
+		MustExist:      true,
+	}, nil)
+}
+
+func (c *Client) DeleteBookmark(bookmark string) error {
+	return c.execute("delete_bookmark", &protos.DeleteBookmarkRequest{Bookmark: bookmark}, nil)
+}
+
+func (c *Client) RenameBookmark(bookmark string, newName string) error {
+	return c.execute("rename_bookmark", &protos.RenameBookmarkRequest{
+		Bookmark: bookmark,
+		NewName:  newName,
+	}, nil)
+}
+
+func (c *Client) ListBookmarkRules() ([]*protos.BookmarkRule, error) {
+	res := new(protos.ListBookmarkRulesResponse)
+	if err := c.execute("list_bookmark_rules", nil, res); err != nil {
+		return nil, err
+	}
+	return res.Rules, nil
+}
+
+// SetBookmarkRule protects a bookmark. An existing rule of the bookmark is replaced.
+func (c *Client) SetBookmarkRule(rule *protos.BookmarkRule) error {
+	return c.execute("set_bookmark_rule", rule, nil)
+}
+
+func (c *Client) RemoveBookmarkRule(bookmark string) error {
+	return c.execute("remove_bookmark_rule", &protos.RemoveBookmarkRuleRequest{Bookmark: bookmark}, nil)
+}
+
+func (c *Client) ListMembers() ([]*protos.Member, error) {
+	res := new(protos.ListMembersResponse)
+	if err := c.execute("list_members", nil, res); err != nil {
+		return nil, err
+	}
+	return res.Members, nil
+}
+
+// SetMember adds a user to the repository or changes their role. Requires admin access.
+func (c *Client) SetMember(username string, role string) error {
+	return c.execute("set_member", &protos.SetMemberRequest{
+		Username: username,
+		Role:     role,
+	}, nil)
+}
+
+// RemoveMember removes a user from the repository. Requires admin access.
+func (c *Client) RemoveMember(username string) error {
+	return c.execute("remove_member", &protos.RemoveMemberRequest{
+		Username: username,
+	}, nil)
+}
+
+// Register registers the public key of the client for its username on the server.
+func (c *Client) Register() (*protos.RegisterResponse, error) {
+	res := new(protos.RegisterResponse)
+	if err := c.execute("register", nil, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// PendingKeys lists all keys that wait for approval. Requires admin rights.
+func (c *Client) PendingKeys() ([]*protos.PendingKey, error) {
+	res := new(protos.ListPendingKeysResponse)
+	if err := c.execute("pending_keys", nil, res); err != nil {
+		return nil, err
+	}
+	return res.Keys, nil
+}
+
+// ApproveKey approves the pending key with the given fingerprint for a user. Requires admin rights.
+func (c *Client) ApproveKey(username string, fingerprint string) error {
+	return c.execute("approve_key", &protos.ApproveKeyRequest{
+		Username:    username,
+		Fingerprint: fingerprint,
+	}, nil)
+}
+
+// GC runs the garbage collection on the server. Requires admin rights.
+func (c *Client) GC(dryRun bool) (*protos.GCResponse, error) {
+	res := new(protos.GCResponse)
+	if err := c.execute("gc", &protos.GCRequest{DryRun: dryRun}, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
 func (c *Client) executeStream(f string, reqBody io.Reader, headers map[string]string) (io.ReadCloser, error) {
 	urlStr, err := url.JoinPath(c.url, f)
 	if err != nil {
@@ -468,13 +863,23 @@ func (c *Client) execute(f string, reqBody proto.Message, respBody proto.Message
 	return nil
 }
 
+// ErrForbidden is returned when the server denies access to a repository or an action.
+var ErrForbidden = errors.New("access denied")
+
 func errFromResp(resp *http.Response) error {
 	if resp.StatusCode < http.StatusBadRequest {
 		return nil
 	}
 	rb, _ := io.ReadAll(resp.Body)
-	if len(rb) == 0 {
+	msg := strings.TrimSpace(string(rb))
+	if resp.StatusCode == http.StatusForbidden {
+		if len(msg) == 0 {
+			return ErrForbidden
+		}
+		return fmt.Errorf("%w: %s", ErrForbidden, msg)
+	}
+	if len(msg) == 0 {
 		return errors.New(resp.Status)
 	}
-	return fmt.Errorf("%s: %s", resp.Status, string(rb))
+	return fmt.Errorf("%s: %s", resp.Status, msg)
 }
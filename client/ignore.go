@@ -143,3 +143,42 @@ func getLocalFiles(rootDir string) func(yield func(absPath, name string) bool) {
 		})
 	}
 }
+
+// getIgnoredFiles yields the files that are excluded by the ignore patterns.
+// An ignored directory is yielded once with a trailing slash instead of its content.
+// The .pogo file and the .git directory are not yielded.
+func getIgnoredFiles(rootDir string) func(yield func(name string) bool) {
+	return func(yield func(name string) bool) {
+		matcher := getLocalIgnoreMatcher(rootDir)
+		_ = filepath.WalkDir(rootDir, func(absPath string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			isDir := d.IsDir()
+			relPath, err := filepath.Rel(rootDir, absPath)
+			if err != nil {
+				return err
+			}
+			if relPath == "." {
+				return nil
+			}
+			gitPath := strings.Split(relPath, string(os.PathSeparator))
+			if !matcher.Match(gitPath, isDir) {
+				return nil
+			}
+
+			name := filepath.ToSlash(relPath)
+			if isDir {
+				name += "/"
+			}
+			if name != ".pogo" && name != ".git/" && !yield(name) {
+				return filepath.SkipAll
+			}
+			if isDir {
+				return filepath.SkipDir
+			}
+			return nil
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/tsukinoko-kun/pogo/protos"
+	"github.com/tsukinoko-kun/pogo/utils"
+)
+
+// Status compares the working copy with the change the head points at.
+// Paths are slash separated and relative to the repository root.
+type Status struct {
+	// Change is the change the head points at.
+	Change *protos.StatusResponse
+
+	Added    []string
+	Modified []string
+	Deleted  []string
+	Ignored  []string
+
+	// NotOwned reports that the change belongs to another user or machine, so a push is refused.
+	NotOwned bool
+	// HasChildren reports that the change already has children, so a push is refused.
+	HasChildren bool
+}
+
+// Clean reports whether the working copy matches the change.
+func (s *Status) Clean() bool {
+	return len(s.Added) == 0 && len(s.Modified) == 0 && len(s.Deleted) == 0
+}
+
+// Status compares the working copy with the change the head points at without pushing anything.
+func (c *Client) Status() (*Status, error) {
+	res := new(protos.StatusResponse)
+	if err := c.execute("status", nil, res); err != nil {
+		return nil, errors.Join(fmt.Errorf("status request"), err)
+	}
+
+	status := &Status{
+		Change:      res,
+		NotOwned:    res.Author != c.userName || res.Device != c.machineId,
+		HasChildren: res.HasChildren,
+	}
+
+	remoteFiles := make(map[string]*protos.StatusFile, len(res.Files))
+	for _, f := range res.Files {
+		remoteFiles[f.Name] = f
+	}
+
+	for absPath, name := range getLocalFiles(c.rootDir) {
+		remote, ok := remoteFiles[name]
+		if !ok {
+			status.Added = append(status.Added, name)
+			continue
+		}
+		delete(remoteFiles, name)
+		if !bytes.Equal(utils.HashFile(absPath), remote.ContentHash) {
+			status.Modified = append(status.Modified, name)
+			continue
+		}
+		// nil if the file system has no executable bit
+		if executable := utils.IsExecutable(absPath); executable != nil && *executable != remote.Executable {
+			status.Modified = append(status.Modified, name)
+		}
+	}
+
+	for name := range remoteFiles {
+		status.Deleted = append(status.Deleted, name)
+	}
+	slices.Sort(status.Deleted)
+
+	for name := range getIgnoredFiles(c.rootDir) {
+		status.Ignored = append(status.Ignored, name)
+	}
+
+	return status, nil
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/tsukinoko-kun/pogo/client"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var abandonCmd = &cobra.Command{
+	Use:   "abandon [change]",
+	Short: "Abandon a change",
+	Long: "Abandon a change, defaults to the working copy (@).\n" +
+		"Its children are moved onto its parents and its bookmarks are moved to its parent.\n" +
+		"If the working copy is abandoned, a new change on top of the parents is checked out.\n" +
+		"Only changes created by you on this machine can be abandoned.",
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := client.Open(".pogo")
+		if err != nil {
+			return errors.Join(errors.New("open repository"), err)
+		}
+
+		if err := c.Push(); err != nil {
+			return errors.Join(errors.New("push"), err)
+		}
+
+		change := "@"
+		if len(args) == 1 {
+			change = args[0]
+		}
+
+		res, err := c.Abandon(change)
+		if err != nil {
+			return errors.Join(errors.New("abandon"), err)
+		}
+
+		fmt.Println("Abandoned change " + res.ChangeName)
+		if len(res.Bookmarks) > 0 {
+			fmt.Println("Moved bookmarks " + strings.Join(res.Bookmarks, ", ") + " to " + res.Parents[0])
+		}
+
+		if err := c.Log(); err != nil {
+			return errors.Join(errors.New("log"), err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(abandonCmd)
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/tsukinoko-kun/pogo/client"
+	"github.com/tsukinoko-kun/pogo/colors"
+	"github.com/tsukinoko-kun/pogo/config"
+	"github.com/tsukinoko-kun/pogo/sysid"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	authCmd = &cobra.Command{
+		Use:   "auth",
+		Short: "Manage the registration of your public key on a server",
+	}
+
+	authRegisterCmd = &cobra.Command{
+		Use:   "register",
+		Short: "Register your public key for your username",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := connect(cmd)
+			if err != nil {
+				return err
+			}
+
+			res, err := c.Register()
+			if err != nil {
+				return errors.Join(errors.New("register"), err)
+			}
+
+			switch {
+			case res.Admin && res.Approved:
+				fmt.Println("registered as " + config.GetUsername() + " (admin)")
+			case res.Approved:
+				fmt.Println("registered as " + config.GetUsername())
+			default:
+				fmt.Println(colors.BrightBlack + "(registered as " + config.GetUsername() + ", waiting for approval by an admin)" + colors.Reset)
+			}
+
+			return nil
+		},
+	}
+
+	authPendingCmd = &cobra.Command{
+		Use:   "pending",
+		Short: "List keys that wait for approval (admin only)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := connect(cmd)
+			if err != nil {
+				return err
+			}
+
+			keys, err := c.PendingKeys()
+			if err != nil {
+				return errors.Join(errors.New("list pending keys"), err)
+			}
+
+			if len(keys) == 0 {
+				fmt.Println(colors.BrightBlack + "(no pending keys)" + colors.Reset)
+				return nil
+			}
+
+			for _, key := range keys {
+				fmt.Println(
+					key.Username + " " +
+						colors.Magenta + key.Fingerprint + colors.Reset + " " +
+						colors.BrightBlack + key.CreatedAt.AsTime().In(time.Local).Format(time.DateTime) + colors.Reset,
+				)
+			}
+
+			return nil
+		},
+	}
+
+	authApproveCmd = &cobra.Command{
+		Use:   "approve <username> <fingerprint>",
+		Short: "Approve a pending key (admin only)",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := connect(cmd)
+			if err != nil {
+				return err
+			}
+
+			if err := c.ApproveKey(args[0], args[1]); err != nil {
+				return errors.Join(errors.New("approve key"), err)
+			}
+
+			fmt.Println("approved " + args[1] + " for " + args[0])
+			return nil
+		},
+	}
+)
+
+// connect creates a client for the host given by the --host flag.
+// If the flag is not set, the host of the repository in the current directory is used.
+func connect(cmd *cobra.Command) (*client.Client, error) {
+	host, _ := cmd.Flags().GetString("host")
+	if host == "" {
+		repoConfig, err := client.LoadRepoConfig(localRepoFileName)
+		if err != nil {
+			return nil, errors.Join(errors.New("host must be specified or a repository must be opened"), err)
+		}
+		host = repoConfig.Host
+	}
+
+	machine, err := sysid.GetMachineID()
+	if err != nil {
+		return nil, errors.Join(errors.New("get machine id"), err)
+	}
+
+	c, err := client.Connect(host, config.GetUsername(), machine)
+	if err != nil {
+		return nil, errors.Join(errors.New("connect"), err)
+	}
+	return c, nil
+}
+
+func init() {
+	authCmd.PersistentFlags().String("host", "", "Remote host (defaults to the host of the repository in the current directory)")
+	authCmd.AddCommand(authRegisterCmd)
+	authCmd.AddCommand(authPendingCmd)
+	authCmd.AddCommand(authApproveCmd)
+	RootCmd.AddCommand(authCmd)
+}
@@ -54,9 +54,101 @@ var (
 			return nil
 		},
 	}
+
+	bookmarkSetCmd = &cobra.Command{
+		Use:     "set <name>",
+		Aliases: []string{"s"},
+		Short:   "Create a bookmark or move it to a change",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := client.Open(".pogo")
+			if err != nil {
+				return fmt.Errorf("open repository: %w", err)
+			}
+
+			if err := c.Push(); err != nil {
+				return errors.Join(errors.New("push"), err)
+			}
+
+			change, _ := cmd.Flags().GetString("revision")
+			allowBackwards, _ := cmd.Flags().GetBool("allow-backwards")
+			if err := c.SetBookmark(args[0], change, allowBackwards); err != nil {
+				return fmt.Errorf("set bookmark: %w", err)
+			}
+			return nil
+		},
+	}
+
+	bookmarkMoveCmd = &cobra.Command{
+		Use:     "move <name>",
+		Aliases: []string{"m"},
+		Short:   "Move an existing bookmark to a change",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := client.Open(".pogo")
+			if err != nil {
+				return fmt.Errorf("open repository: %w", err)
+			}
+
+			if err := c.Push(); err != nil {
+				return errors.Join(errors.New("push"), err)
+			}
+
+			change, _ := cmd.Flags().GetString("revision")
+			allowBackwards, _ := cmd.Flags().GetBool("allow-backwards")
+			if err := c.MoveBookmark(args[0], change, allowBackwards); err != nil {
+				return fmt.Errorf("move bookmark: %w", err)
+			}
+			return nil
+		},
+	}
+
+	bookmarkDeleteCmd = &cobra.Command{
+		Use:     "delete <name>",
+		Aliases: []string{"d"},
+		Short:   "Delete a bookmark, the change it points to is kept",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := client.Open(".pogo")
+			if err != nil {
+				return fmt.Errorf("open repository: %w", err)
+			}
+
+			if err := c.DeleteBookmark(args[0]); err != nil {
+				return fmt.Errorf("delete bookmark: %w", err)
+			}
+			return nil
+		},
+	}
+
+	bookmarkRenameCmd = &cobra.Command{
+		Use:     "rename <old> <new>",
+		Aliases: []string{"r"},
+		Short:   "Rename a bookmark",
+		Args:    cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := client.Open(".pogo")
+			if err != nil {
+				return fmt.Errorf("open repository: %w", err)
+			}
+
+			if err := c.RenameBookmark(args[0], args[1]); err != nil {
+				return fmt.Errorf("rename bookmark: %w", err)
+			}
+			return nil
+		},
+	}
 )
 
 func init() {
+	for _, cmd := range []*cobra.Command{bookmarkSetCmd, bookmarkMoveCmd} {
+		cmd.Flags().StringP("revision", "r", "@", "Revset of the change the bookmark should point to")
+		cmd.Flags().Bool("allow-backwards", false, "Allow moving the bookmark to a change that is not a descendant of its current target")
+	}
 	bookmarkCmd.AddCommand(bookmarkListCmd)
+	bookmarkCmd.AddCommand(bookmarkSetCmd)
+	bookmarkCmd.AddCommand(bookmarkMoveCmd)
+	bookmarkCmd.AddCommand(bookmarkDeleteCmd)
+	bookmarkCmd.AddCommand(bookmarkRenameCmd)
 	RootCmd.AddCommand(bookmarkCmd)
 }
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/tsukinoko-kun/pogo/client"
+	"github.com/tsukinoko-kun/pogo/colors"
+	"github.com/tsukinoko-kun/pogo/protos"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	bookmarkRuleCmd = &cobra.Command{
+		Use:     "rule",
+		Aliases: []string{"rules"},
+		Short:   "Manage the rules of protected bookmarks",
+	}
+
+	bookmarkRuleListCmd = &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"l"},
+		Short:   "List protected bookmarks and their rules",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := client.Open(localRepoFileName)
+			if err != nil {
+				return errors.Join(errors.New("open repository"), err)
+			}
+
+			rules, err := c.ListBookmarkRules()
+			if err != nil {
+				return errors.Join(errors.New("list bookmark rules"), err)
+			}
+
+			longestBookmarkName := 0
+			for _, rule := range rules {
+				if len(rule.Bookmark) > longestBookmarkName {
+					longestBookmarkName = len(rule.Bookmark)
+				}
+			}
+
+			for _, rule := range rules {
+				fmt.Println(
+					strings.Repeat(" ", longestBookmarkName-len(rule.Bookmark)) + rule.Bookmark +
+						" " + colors.Magenta + describeBookmarkRule(rule) + colors.Reset,
+				)
+			}
+
+			return nil
+		},
+	}
+
+	bookmarkRuleSetCmd = &cobra.Command{
+		Use:     "set <bookmark>",
+		Aliases: []string{"add"},
+		Short:   "Protect a bookmark or replace its rule",
+		Long: "Protect a bookmark or replace its rule.\n" +
+			"Every move of a protected bookmark must satisfy all checks of its rule.\n" +
+			"Protected bookmarks can't be deleted or renamed until the rule is removed.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := client.Open(localRepoFileName)
+			if err != nil {
+				return errors.Join(errors.New("open repository"), err)
+			}
+
+			rule := &protos.BookmarkRule{Bookmark: args[0]}
+			rule.DescendantsOnly, _ = cmd.Flags().GetBool("descendants-only")
+			rule.NoConflicts, _ = cmd.Flags().GetBool("no-conflicts")
+			rule.RequireDescription, _ = cmd.Flags().GetBool("require-description")
+			rule.AllowedUsers, _ = cmd.Flags().GetStringSlice("allow-user")
+
+			if err := c.SetBookmarkRule(rule); err != nil {
+				return errors.Join(errors.New("set bookmark rule"), err)
+			}
+
+			fmt.Println(rule.Bookmark + " " + colors.Magenta + describeBookmarkRule(rule) + colors.Reset)
+			return nil
+		},
+	}
+
+	bookmarkRuleRemoveCmd = &cobra.Command{
+		Use:     "remove <bookmark>",
+		Aliases: []string{"rm"},
+		Short:   "Remove the protection of a bookmark",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := client.Open(localRepoFileName)
+			if err != nil {
+				return errors.Join(errors.New("open repository"), err)
+			}
+
+			if err := c.RemoveBookmarkRule(args[0]); err != nil {
+				return errors.Join(errors.New("remove bookmark rule"), err)
+			}
+
+			fmt.Println("unprotected " + args[0])
+			return nil
+		},
+	}
+)
+
+func describeBookmarkRule(rule *protos.BookmarkRule) string {
+	var checks []string
+	if rule.DescendantsOnly {
+		checks = append(checks, "descendants only")
+	}
+	if rule.NoConflicts {
+		checks = append(checks, "no conflicts")
+	}
+	if rule.RequireDescription {
+		checks = append(checks, "require description")
+	}
+	if len(rule.AllowedUsers) > 0 {
+		checks = append(checks, "users: "+strings.Join(rule.AllowedUsers, ", "))
+	}
+	if len(checks) == 0 {
+		return "no delete or rename"
+	}
+	return strings.Join(checks, "; ")
+}
+
+func init() {
+	bookmarkRuleSetCmd.Flags().Bool("descendants-only", false, "Only allow moving the bookmark to descendants of its current change")
+	bookmarkRuleSetCmd.Flags().Bool("no-conflicts", false, "Forbid moving the bookmark to a change with conflicts")
+	bookmarkRuleSetCmd.Flags().Bool("require-description", false, "Forbid moving the bookmark to a change without a description")
+	bookmarkRuleSetCmd.Flags().StringSlice("allow-user", nil, "Only allow these users to move the bookmark (repeatable)")
+	bookmarkRuleCmd.AddCommand(bookmarkRuleListCmd)
+	bookmarkRuleCmd.AddCommand(bookmarkRuleSetCmd)
+	bookmarkRuleCmd.AddCommand(bookmarkRuleRemoveCmd)
+	bookmarkCmd.AddCommand(bookmarkRuleCmd)
+}
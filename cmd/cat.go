@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"errors"
+	"github.com/tsukinoko-kun/pogo/client"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var catCmd = &cobra.Command{
+	Use:   "cat <change> <path>",
+	Short: "Print the content of a file in a change",
+	Long: "Print the content of a file in a change without checking it out.\n" +
+		"The change is a revset that selects one change.",
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := client.Open(".pogo")
+		if err != nil {
+			return errors.Join(errors.New("open repository"), err)
+		}
+
+		if err := c.Push(); err != nil {
+			return errors.Join(errors.New("push"), err)
+		}
+
+		path, err := c.RepoPath(args[1])
+		if err != nil {
+			return err
+		}
+
+		content, err := c.Cat(args[0], path)
+		if err != nil {
+			return errors.Join(errors.New("cat"), err)
+		}
+		defer content.Close()
+
+		if _, err := io.Copy(os.Stdout, content); err != nil {
+			return errors.Join(errors.New("write content"), err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(catCmd)
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/tsukinoko-kun/pogo/client"
+	"github.com/tsukinoko-kun/pogo/config"
+	"github.com/tsukinoko-kun/pogo/sysid"
+
+	"github.com/spf13/cobra"
+)
+
+var cloneCmd = &cobra.Command{
+	Use:   "clone <repo-name|id> [dir]",
+	Short: "Attach a directory to an existing repository",
+	Args:  cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !cmd.Flag("host").Changed {
+			return fmt.Errorf("host must be specified")
+		}
+		host := cmd.Flag("host").Value.String()
+		bookmark := cmd.Flag("bookmark").Value.String()
+
+		repo := args[0]
+		dir := repo
+		if len(args) > 1 {
+			dir = args[1]
+		}
+
+		machine, err := sysid.GetMachineID()
+		if err != nil {
+			return errors.Join(errors.New("get machine id"), err)
+		}
+
+		c, err := client.Clone(host, repo, bookmark, dir, config.GetUsername(), machine)
+		if err != nil {
+			return errors.Join(errors.New("clone"), err)
+		}
+		_, _ = fmt.Fprintln(cmd.OutOrStdout(), "repository cloned into "+dir)
+
+		if err := c.Log(); err != nil {
+			return errors.Join(errors.New("log"), err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	cloneCmd.Flags().String("host", "", "Remote host where the repository is located")
+	cloneCmd.Flags().StringP("bookmark", "b", "main", "Bookmark to check out")
+	RootCmd.AddCommand(cloneCmd)
+}
@@ -1,17 +1,23 @@
 package cmd
 
 import (
+	"encoding/base64"
 	"errors"
 	"fmt"
 
 	"github.com/spf13/cobra"
 	"github.com/tsukinoko-kun/pogo/client"
 	"github.com/tsukinoko-kun/pogo/colors"
+	"github.com/tsukinoko-kun/pogo/protos"
 )
 
 var conflictsCmd = &cobra.Command{
-	Use:   "conflicts",
+	Use:   "conflicts [change]",
 	Short: "List all conflicts in a change",
+	Long: "List the conflicted files of a change with the merge base and the sides they were merged from.\n" +
+		"Conflicted text files contain conflict markers that name the changes of both sides.\n" +
+		"A file is resolved once no marked region of its sides is left, a .binconflict_ file once its content\n" +
+		"is none of the sides anymore. Other files never become conflicts, even if they contain markers.",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		c, err := client.Open(".pogo")
 		if err != nil {
@@ -25,10 +31,7 @@ var conflictsCmd = &cobra.Command{
 		var changeName string
 		switch len(args) {
 		case 0:
-			changeName, err = c.Head()
-			if err != nil {
-				return errors.Join(errors.New("get head"), err)
-			}
+			changeName = "@"
 		case 1:
 			changeName = args[0]
 		default:
@@ -45,7 +48,19 @@ var conflictsCmd = &cobra.Command{
 		} else {
 			fmt.Printf("%s(%d conflicts)%s\n", colors.Red, len(conflicts), colors.Reset)
 			for _, conflict := range conflicts {
-				fmt.Println(conflict)
+				if conflict.Kind == "" {
+					fmt.Println(conflict.Path)
+					continue
+				}
+				fmt.Println(conflict.Path + " " + colors.Red + "(" + conflict.Kind + " conflict)" + colors.Reset)
+				if conflict.Base == nil {
+					fmt.Println("  base " + colors.BrightBlack + "(not in the merge base)" + colors.Reset)
+				} else {
+					fmt.Println("  base " + formatConflictSide(conflict.Base))
+				}
+				for _, side := range conflict.Sides {
+					fmt.Println("  side " + formatConflictSide(side))
+				}
 			}
 		}
 
@@ -56,3 +71,10 @@ var conflictsCmd = &cobra.Command{
 func init() {
 	RootCmd.AddCommand(conflictsCmd)
 }
+
+func formatConflictSide(side *protos.ConflictSide) string {
+	if len(side.ContentHash) == 0 {
+		return colors.Magenta + side.Change + colors.Reset + " deleted"
+	}
+	return colors.Magenta + side.Change + colors.Reset + " " + fileMode(side.Executable) + " " + base64.RawURLEncoding.EncodeToString(side.ContentHash)
+}
@@ -25,10 +25,7 @@ var describeCmd = &cobra.Command{
 		var changeName string
 		switch len(args) {
 		case 0:
-			changeName, err = c.Head()
-			if err != nil {
-				return errors.Join(errors.New("get head"), err)
-			}
+			changeName = "@"
 		case 1:
 			changeName = args[0]
 		default:
@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/tsukinoko-kun/pogo/client"
+	"github.com/tsukinoko-kun/pogo/colors"
+	"github.com/tsukinoko-kun/pogo/protos"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// diffStatWidth is the maximum width of the +/- bar of diff --stat.
+const diffStatWidth = 40
+
+var diffCmd = &cobra.Command{
+	Use:   "diff [paths...]",
+	Short: "Show the changes between two changes",
+	Long: "Show the changes between two changes.\n" +
+		"By default, the working copy (@) is compared to its parent.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := client.Open(".pogo")
+		if err != nil {
+			return errors.Join(errors.New("open repository"), err)
+		}
+
+		if err := c.Push(); err != nil {
+			return errors.Join(errors.New("push"), err)
+		}
+
+		paths := make([]string, len(args))
+		for i, arg := range args {
+			if paths[i], err = c.RepoPath(arg); err != nil {
+				return err
+			}
+		}
+
+		from, _ := cmd.Flags().GetString("from")
+		to, _ := cmd.Flags().GetString("to")
+		stat, _ := cmd.Flags().GetBool("stat")
+		var fromSnapshot *int64
+		if cmd.Flags().Changed("from-snapshot") {
+			if from != "" {
+				return errors.New("--from and --from-snapshot can't be combined")
+			}
+			id, _ := cmd.Flags().GetInt64("from-snapshot")
+			fromSnapshot = &id
+		}
+
+		res, err := c.Diff(from, fromSnapshot, to, paths, stat)
+		if err != nil {
+			return errors.Join(errors.New("diff"), err)
+		}
+
+		color := func(c string) string {
+			if colors.Enabled(os.Stdout) {
+				return c
+			}
+			return ""
+		}
+
+		if len(res.Files) == 0 {
+			fmt.Println(color(colors.BrightBlack) + "(no changes)" + color(colors.Reset))
+			return nil
+		}
+
+		if stat {
+			printDiffStat(res.Files, color)
+			return nil
+		}
+
+		for _, f := range res.Files {
+			fmt.Println(color(colors.Bold) + "diff " + f.Path + color(colors.Reset))
+			switch {
+			case len(f.OldContentHash) == 0:
+				fmt.Printf("%snew file mode %s%s\n", color(colors.Bold), fileMode(f.NewExecutable), color(colors.Reset))
+			case len(f.NewContentHash) == 0:
+				fmt.Printf("%sdeleted file mode %s%s\n", color(colors.Bold), fileMode(f.OldExecutable), color(colors.Reset))
+			case f.OldExecutable != f.NewExecutable:
+				fmt.Printf("%sold mode %s%s\n", color(colors.Bold), fileMode(f.OldExecutable), color(colors.Reset))
+				fmt.Printf("%snew mode %s%s\n", color(colors.Bold), fileMode(f.NewExecutable), color(colors.Reset))
+			}
+			if f.Binary {
+				fmt.Println("Binary files differ")
+				continue
+			}
+			if f.Patch == "" {
+				continue
+			}
+			for _, line := range strings.Split(strings.TrimSuffix(f.Patch, "\n"), "\n") {
+				lineColor := ""
+				switch {
+				case strings.HasPrefix(line, "---"), strings.HasPrefix(line, "+++"):
+					lineColor = colors.Bold
+				case strings.HasPrefix(line, "@@"):
+					lineColor = colors.Cyan
+				case strings.HasPrefix(line, "+"):
+					lineColor = colors.Green
+				case strings.HasPrefix(line, "-"):
+					lineColor = colors.Red
+				default:
+					fmt.Println(line)
+					continue
+				}
+				fmt.Println(color(lineColor) + line + color(colors.Reset))
+			}
+		}
+
+		return nil
+	},
+}
+
+func fileMode(executable bool) string {
+	if executable {
+		return "100755"
+	}
+	return "100644"
+}
+
+func printDiffStat(files []*protos.FileDiff, color func(string) string) {
+	longestPath := 0
+	var maxChanges int32
+	for _, f := range files {
+		longestPath = max(longestPath, len(f.Path))
+		maxChanges = max(maxChanges, f.Additions+f.Deletions)
+	}
+
+	var additions, deletions int32
+	for _, f := range files {
+		additions += f.Additions
+		deletions += f.Deletions
+
+		fmt.Print(" " + f.Path + strings.Repeat(" ", longestPath-len(f.Path)) + " | ")
+		if f.Binary {
+			fmt.Println("Bin")
+			continue
+		}
+		plus, minus := int(f.Additions), int(f.Deletions)
+		if maxChanges > diffStatWidth {
+			// scale the bar, but keep at least one sign for every kind of change
+			plus = scaleStat(f.Additions, maxChanges)
+			minus = scaleStat(f.Deletions, maxChanges)
+		}
+		fmt.Printf(
+			"%d %s%s%s%s%s\n",
+			f.Additions+f.Deletions,
+			color(colors.Green), strings.Repeat("+", plus),
+			color(colors.Red), strings.Repeat("-", minus),
+			color(colors.Reset),
+		)
+	}
+
+	fmt.Printf(" %d files changed, %d insertions(+), %d deletions(-)\n", len(files), additions, deletions)
+}
+
+func scaleStat(n, maxChanges int32) int {
+	if n == 0 {
+		return 0
+	}
+	return max(1, int(n)*diffStatWidth/int(maxChanges))
+}
+
+func init() {
+	diffCmd.Flags().String("from", "", "Revset of the old change (defaults to the parent of --to)")
+	diffCmd.Flags().Int64("from-snapshot", 0, "Snapshot of the evolution log as the old state (see pogo evolog)")
+	diffCmd.Flags().String("to", "", "Revset of the new change (defaults to @, or the change of --from-snapshot)")
+	diffCmd.Flags().Bool("stat", false, "Only show the number of changed lines per file")
+	RootCmd.AddCommand(diffCmd)
+}
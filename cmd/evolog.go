@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/tsukinoko-kun/pogo/client"
+	"github.com/tsukinoko-kun/pogo/colors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	evologCmd = &cobra.Command{
+		Use:   "evolog [change]",
+		Short: "Show how a change evolved",
+		Long: "List the snapshots of a change, newest first. The change defaults to the working copy (@).\n" +
+			"Every push, describe and rewrite records the state of the change as a snapshot.\n" +
+			"Use 'pogo diff --from-snapshot <snapshot>' to compare a snapshot to the change\n" +
+			"and 'pogo evolog restore <snapshot>' to bring it back.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			change := "@"
+			if len(args) == 1 {
+				change = args[0]
+			}
+
+			c, err := client.Open(".pogo")
+			if err != nil {
+				return errors.Join(errors.New("open repository"), err)
+			}
+
+			if err := c.Push(); err != nil {
+				return errors.Join(errors.New("push"), err)
+			}
+
+			res, err := c.Evolog(change)
+			if err != nil {
+				return errors.Join(errors.New("evolog"), err)
+			}
+
+			if len(res.Snapshots) == 0 {
+				fmt.Println(colors.BrightBlack + "(no snapshots of " + res.Name + ")" + colors.Reset)
+				return nil
+			}
+			for _, s := range res.Snapshots {
+				var sb strings.Builder
+				sb.WriteString(colors.Magenta + strconv.FormatInt(s.Id, 10) + colors.Reset + " ")
+				sb.WriteString(colors.BrightBlack + s.CreatedAt.AsTime().In(time.Local).Format(time.DateTime) + colors.Reset + " ")
+				if s.OperationId == 0 {
+					sb.WriteString(colors.Cyan + "(before the operation log)" + colors.Reset)
+				} else {
+					sb.WriteString(s.Username + "@" + s.Device + " ")
+					sb.WriteString(colors.Cyan + s.Command + colors.Reset)
+					sb.WriteString(colors.BrightBlack + " (operation " + strconv.FormatInt(s.OperationId, 10) + ")" + colors.Reset)
+				}
+				fmt.Println(sb.String())
+
+				if s.Description == nil || *s.Description == "" {
+					fmt.Println("  " + colors.BrightBlack + "(no description set)" + colors.Reset)
+				} else {
+					fmt.Println("  " + strings.SplitN(*s.Description, "\n", 2)[0])
+				}
+			}
+
+			return nil
+		},
+	}
+
+	evologRestoreCmd = &cobra.Command{
+		Use:   "restore <snapshot>",
+		Short: "Reset a change to one of its snapshots",
+		Long: "Reset the files and the description of a change to one of its snapshots.\n" +
+			"Descendants are rebased onto the restored change. The restore is recorded as an operation, so it can be undone.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			snapshotId, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return errors.Join(fmt.Errorf("invalid snapshot id '%s'", args[0]), err)
+			}
+
+			c, err := client.Open(".pogo")
+			if err != nil {
+				return errors.Join(errors.New("open repository"), err)
+			}
+
+			if err := c.Push(); err != nil {
+				return errors.Join(errors.New("push"), err)
+			}
+
+			res, err := c.RestoreSnapshot(snapshotId)
+			if err != nil {
+				return errors.Join(errors.New("restore snapshot"), err)
+			}
+
+			fmt.Printf("Restored %s to snapshot %d\n", res.Name, snapshotId)
+			if len(res.Rebased) > 0 {
+				fmt.Printf("Rebased %d descendants\n", len(res.Rebased))
+			}
+			if len(res.Conflicts) > 0 {
+				fmt.Println("Conflicts in " + strings.Join(res.Conflicts, ", "))
+			}
+
+			return nil
+		},
+	}
+)
+
+func init() {
+	evologCmd.AddCommand(evologRestoreCmd)
+	RootCmd.AddCommand(evologCmd)
+}
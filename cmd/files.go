@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/tsukinoko-kun/pogo/client"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+var filesCmd = &cobra.Command{
+	Use:   "files [change]",
+	Short: "List the files of a change",
+	Long: "List the files of a change with their mode, content hash and size in bytes.\n" +
+		"The change is a revset that selects one change and defaults to the working copy (@).",
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := client.Open(".pogo")
+		if err != nil {
+			return errors.Join(errors.New("open repository"), err)
+		}
+
+		if err := c.Push(); err != nil {
+			return errors.Join(errors.New("push"), err)
+		}
+
+		change := "@"
+		if len(args) == 1 {
+			change = args[0]
+		}
+
+		res, err := c.Files(change)
+		if err != nil {
+			return errors.Join(errors.New("list files"), err)
+		}
+
+		sizeWidth := 0
+		for _, f := range res.Files {
+			sizeWidth = max(sizeWidth, len(strconv.FormatInt(f.Size, 10)))
+		}
+		for _, f := range res.Files {
+			fmt.Printf("%s %s %*d\t%s\n", fileMode(f.Executable), base64.RawURLEncoding.EncodeToString(f.ContentHash), sizeWidth, f.Size, f.Name)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(filesCmd)
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/tsukinoko-kun/pogo/colors"
+
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+)
+
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Delete unreferenced files from the server (admin only)",
+	Long: "Delete operations older than the operation retention of the server and file rows and blobs that no change or remaining operation references anymore.\n" +
+		"Unreferenced data is marked first and only deleted by a later run after the grace period of the server passed.",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := connect(cmd)
+		if err != nil {
+			return err
+		}
+
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		res, err := c.GC(dryRun)
+		if err != nil {
+			return errors.Join(errors.New("garbage collection"), err)
+		}
+
+		if res.DryRun {
+			fmt.Println(colors.BrightBlack + "(dry run, nothing was changed)" + colors.Reset)
+		}
+		fmt.Printf("operations: %d deleted with %d snapshots\n", res.OperationsDeleted, res.SnapshotsDeleted)
+		fmt.Printf("file rows: %d deleted, %d marked as unreferenced\n", res.FilesDeleted, res.FilesMarked)
+		fmt.Printf("blobs: %d deleted (%s), %d marked as unreferenced\n", res.BlobsDeleted, humanize.IBytes(uint64(res.BytesFreed)), res.BlobsMarked)
+		return nil
+	},
+}
+
+func init() {
+	gcCmd.Flags().String("host", "", "Remote host (defaults to the host of the repository in the current directory)")
+	gcCmd.Flags().Bool("dry-run", false, "Only report what would be marked and deleted")
+	RootCmd.AddCommand(gcCmd)
+}
@@ -24,8 +24,9 @@ var logCmd = &cobra.Command{
 		if err != nil {
 			limit = 10
 		}
+		revisions, _ := cmd.Flags().GetString("revisions")
 
-		if err := c.LogLimit(limit); err != nil {
+		if err := c.LogRevset(revisions, limit); err != nil {
 			return errors.Join(errors.New("log"), err)
 		}
 
@@ -34,6 +35,7 @@ var logCmd = &cobra.Command{
 }
 
 func init() {
-	logCmd.Flags().Int64("limit", 10, "Limit the number of commits to show")
+	logCmd.Flags().Int32("limit", 10, "Limit the number of commits to show")
+	logCmd.Flags().StringP("revisions", "r", "", "Which changes to show (revset), defaults to the ancestors of @")
 	RootCmd.AddCommand(logCmd)
 }
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/tsukinoko-kun/pogo/client"
+	"github.com/tsukinoko-kun/pogo/colors"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	memberCmd = &cobra.Command{
+		Use:     "member",
+		Aliases: []string{"members"},
+		Short:   "Manage who can access the repository",
+	}
+
+	memberListCmd = &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"l"},
+		Short:   "List members of the repository and their roles",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := client.Open(localRepoFileName)
+			if err != nil {
+				return errors.Join(errors.New("open repository"), err)
+			}
+
+			members, err := c.ListMembers()
+			if err != nil {
+				return errors.Join(errors.New("list members"), err)
+			}
+
+			longestUsername := 0
+			for _, member := range members {
+				if len(member.Username) > longestUsername {
+					longestUsername = len(member.Username)
+				}
+			}
+
+			for _, member := range members {
+				fmt.Println(
+					strings.Repeat(" ", longestUsername-len(member.Username)) + member.Username +
+						" " + colors.Magenta + member.Role + colors.Reset,
+				)
+			}
+
+			return nil
+		},
+	}
+
+	memberSetCmd = &cobra.Command{
+		Use:     "set <username> <read|write|admin>",
+		Aliases: []string{"add"},
+		Short:   "Add a member to the repository or change their role",
+		Args:    cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := client.Open(localRepoFileName)
+			if err != nil {
+				return errors.Join(errors.New("open repository"), err)
+			}
+
+			if err := c.SetMember(args[0], args[1]); err != nil {
+				return errors.Join(errors.New("set member"), err)
+			}
+
+			fmt.Println(args[0] + " → " + colors.Magenta + args[1] + colors.Reset)
+			return nil
+		},
+	}
+
+	memberRemoveCmd = &cobra.Command{
+		Use:     "remove <username>",
+		Aliases: []string{"rm"},
+		Short:   "Remove a member from the repository",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := client.Open(localRepoFileName)
+			if err != nil {
+				return errors.Join(errors.New("open repository"), err)
+			}
+
+			if err := c.RemoveMember(args[0]); err != nil {
+				return errors.Join(errors.New("remove member"), err)
+			}
+
+			fmt.Println("removed " + args[0])
+			return nil
+		},
+	}
+)
+
+func init() {
+	memberCmd.AddCommand(memberListCmd)
+	memberCmd.AddCommand(memberSetCmd)
+	memberCmd.AddCommand(memberRemoveCmd)
+	RootCmd.AddCommand(memberCmd)
+}
@@ -25,11 +25,7 @@ var newCmd = &cobra.Command{
 		if len(args) > 0 {
 			parents = args
 		} else {
-			head, err := c.Head()
-			if err != nil {
-				return errors.Join(errors.New("get head"), err)
-			}
-			parents = []string{head}
+			parents = []string{"@"}
 		}
 
 		if newChangeResp, err := c.NewChange(
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/tsukinoko-kun/pogo/client"
+	"github.com/tsukinoko-kun/pogo/colors"
+	"github.com/tsukinoko-kun/pogo/protos"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	opCmd = &cobra.Command{
+		Use:     "op",
+		Aliases: []string{"operation"},
+		Short:   "Inspect and restore the operation log",
+		Long: "Every command that modifies the repository on the server is recorded as an operation,\n" +
+			"with the state of the changes and bookmarks it modified before and after.",
+	}
+
+	opLogCmd = &cobra.Command{
+		Use:     "log",
+		Aliases: []string{"l"},
+		Short:   "List the latest operations",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := client.Open(".pogo")
+			if err != nil {
+				return errors.Join(errors.New("open repository"), err)
+			}
+
+			limit, _ := cmd.Flags().GetInt32("limit")
+			operations, err := c.OperationLog(limit)
+			if err != nil {
+				return errors.Join(errors.New("list operations"), err)
+			}
+
+			if len(operations) == 0 {
+				fmt.Println(colors.BrightBlack + "(no operations)" + colors.Reset)
+				return nil
+			}
+			for _, operation := range operations {
+				fmt.Println(formatOperation(operation))
+			}
+
+			return nil
+		},
+	}
+
+	opRestoreCmd = &cobra.Command{
+		Use:   "restore <operation>",
+		Short: "Restore the state right after an operation (admin only)",
+		Long: "Restore the state right after an operation by reverting all later operations, newest first.\n" +
+			"Operations of all users are reverted. The restore is recorded as a new operation.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			operationId, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return errors.Join(fmt.Errorf("invalid operation id '%s'", args[0]), err)
+			}
+
+			c, err := client.Open(".pogo")
+			if err != nil {
+				return errors.Join(errors.New("open repository"), err)
+			}
+
+			if err := c.Push(); err != nil {
+				return errors.Join(errors.New("push"), err)
+			}
+
+			reverted, err := c.RestoreOperation(operationId)
+			if err != nil {
+				return errors.Join(errors.New("restore operation"), err)
+			}
+
+			fmt.Printf("Restored operation %d, reverted %d operations\n", operationId, len(reverted))
+			for _, operation := range reverted {
+				fmt.Println(formatOperation(operation))
+			}
+
+			return nil
+		},
+	}
+
+	undoCmd = &cobra.Command{
+		Use:   "undo [operation]",
+		Short: "Undo an operation",
+		Long: "Undo one of your operations on this machine, defaults to the latest one that isn't a push.\n" +
+			"What it modified on changes and bookmarks is reset and the changes it created are abandoned.\n" +
+			"The undo is recorded as a new operation, so undoing it again redoes the operation.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var operationId *int64
+			if len(args) == 1 {
+				id, err := strconv.ParseInt(args[0], 10, 64)
+				if err != nil {
+					return errors.Join(fmt.Errorf("invalid operation id '%s'", args[0]), err)
+				}
+				operationId = &id
+			}
+
+			c, err := client.Open(".pogo")
+			if err != nil {
+				return errors.Join(errors.New("open repository"), err)
+			}
+
+			if err := c.Push(); err != nil {
+				return errors.Join(errors.New("push"), err)
+			}
+
+			undone, err := c.Undo(operationId)
+			if err != nil {
+				return errors.Join(errors.New("undo"), err)
+			}
+
+			fmt.Println("Undid " + formatOperation(undone))
+
+			if err := c.Log(); err != nil {
+				return errors.Join(errors.New("log"), err)
+			}
+
+			return nil
+		},
+	}
+)
+
+func formatOperation(operation *protos.Operation) string {
+	var sb strings.Builder
+	sb.WriteString(colors.Magenta + strconv.FormatInt(operation.Id, 10) + colors.Reset + " ")
+	sb.WriteString(colors.BrightBlack + operation.CreatedAt.AsTime().In(time.Local).Format(time.DateTime) + colors.Reset + " ")
+	sb.WriteString(operation.Username + "@" + operation.Device + " ")
+	sb.WriteString(colors.Cyan + operation.Command + colors.Reset)
+	if len(operation.Changes) > 0 {
+		sb.WriteString(" changes: " + strings.Join(operation.Changes, ", "))
+	}
+	if len(operation.Bookmarks) > 0 {
+		sb.WriteString(" bookmarks: " + strings.Join(operation.Bookmarks, ", "))
+	}
+	return sb.String()
+}
+
+func init() {
+	opLogCmd.Flags().Int32P("limit", "n", 0, "Maximum number of operations to show")
+	opCmd.AddCommand(opLogCmd)
+	opCmd.AddCommand(opRestoreCmd)
+	RootCmd.AddCommand(opCmd)
+	RootCmd.AddCommand(undoCmd)
+}
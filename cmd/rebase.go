@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/tsukinoko-kun/pogo/client"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var rebaseCmd = &cobra.Command{
+	Use:   "rebase -s <change> -d <destination>",
+	Short: "Move a change and its descendants onto a new parent",
+	Long: "Move a change and its descendants onto a new parent.\n" +
+		"The diff of every moved change is replayed onto its new parent, overlapping edits become conflicts.\n" +
+		"The source defaults to the working copy (@). Bookmarks stay on the moved changes.\n" +
+		"Only changes created by you on this machine can be moved.",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		source, _ := cmd.Flags().GetString("source")
+		destination, _ := cmd.Flags().GetString("destination")
+		if destination == "" {
+			return errors.New("destination required")
+		}
+
+		c, err := client.Open(".pogo")
+		if err != nil {
+			return errors.Join(errors.New("open repository"), err)
+		}
+
+		if err := c.Push(); err != nil {
+			return errors.Join(errors.New("push"), err)
+		}
+
+		res, err := c.Rebase(source, destination)
+		if err != nil {
+			return errors.Join(errors.New("rebase"), err)
+		}
+
+		fmt.Printf("Rebased %d changes onto %s\n", len(res.Rebased), res.DestinationName)
+		if len(res.Conflicts) > 0 {
+			fmt.Println("Conflicts in " + strings.Join(res.Conflicts, ", "))
+		}
+
+		if err := c.Log(); err != nil {
+			return errors.Join(errors.New("log"), err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rebaseCmd.Flags().StringP("source", "s", "@", "The change to move along with its descendants")
+	rebaseCmd.Flags().StringP("destination", "d", "", "The new parent")
+	RootCmd.AddCommand(rebaseCmd)
+}
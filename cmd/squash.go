@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/tsukinoko-kun/pogo/client"
+	"github.com/tsukinoko-kun/pogo/editor"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var squashCmd = &cobra.Command{
+	Use:   "squash [paths...]",
+	Short: "Move the changes of a change into another change",
+	Long: "Move the diff of a change against its parent into another change.\n" +
+		"The change defaults to the working copy (@) and the target to its parent.\n" +
+		"If paths are given, only the diff of these files is moved.\n" +
+		"Descendants of both changes are rebased, and the change is abandoned if it ends up empty.\n" +
+		"If both changes have a description, the combined description is opened in the editor.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := client.Open(".pogo")
+		if err != nil {
+			return errors.Join(errors.New("open repository"), err)
+		}
+
+		if err := c.Push(); err != nil {
+			return errors.Join(errors.New("push"), err)
+		}
+
+		source, _ := cmd.Flags().GetString("revision")
+		into, _ := cmd.Flags().GetString("into")
+
+		paths := make([]string, len(args))
+		for i, arg := range args {
+			if paths[i], err = c.RepoPath(arg); err != nil {
+				return err
+			}
+		}
+
+		var description *string
+		if cmd.Flags().Changed("message") {
+			m, _ := cmd.Flags().GetString("message")
+			description = &m
+		} else if len(paths) == 0 {
+			// the source ends up empty, so both descriptions are combined
+			target := into
+			if target == "" {
+				target = "parents(" + source + ")"
+			}
+			var descriptions []string
+			for _, change := range []string{target, source} {
+				res, err := c.FindChange(change, true)
+				if err != nil {
+					return errors.Join(fmt.Errorf("find change %s", change), err)
+				}
+				if res.Description != nil && strings.TrimSpace(*res.Description) != "" {
+					descriptions = append(descriptions, strings.TrimSpace(*res.Description))
+				}
+			}
+			if len(descriptions) == 2 {
+				m, err := editor.String("Combine descriptions", strings.Join(descriptions, "\n\n"))
+				if err != nil {
+					return errors.Join(errors.New("edit description"), err)
+				}
+				description = &m
+			}
+		}
+
+		res, err := c.Squash(source, into, paths, description)
+		if err != nil {
+			return errors.Join(errors.New("squash"), err)
+		}
+
+		fmt.Println("Squashed " + res.SourceName + " into " + res.IntoName)
+		if res.Abandoned != nil {
+			fmt.Println("Abandoned change " + res.Abandoned.ChangeName)
+			if len(res.Abandoned.Bookmarks) > 0 {
+				fmt.Println("Moved bookmarks " + strings.Join(res.Abandoned.Bookmarks, ", ") + " to " + res.Abandoned.Parents[0])
+			}
+		}
+
+		if err := c.Log(); err != nil {
+			return errors.Join(errors.New("log"), err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	squashCmd.Flags().StringP("revision", "r", "@", "The change to squash")
+	squashCmd.Flags().StringP("into", "t", "", "The change to squash into, defaults to the parent")
+	squashCmd.Flags().StringP("message", "m", "", "Use the given message as the description of the target")
+	RootCmd.AddCommand(squashCmd)
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/tsukinoko-kun/pogo/client"
+	"github.com/tsukinoko-kun/pogo/colors"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var statusCmd = &cobra.Command{
+	Use:     "status",
+	Aliases: []string{"st"},
+	Short:   "Show the working copy changes without pushing them",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := client.Open(".pogo")
+		if err != nil {
+			return errors.Join(errors.New("open repository"), err)
+		}
+
+		status, err := c.Status()
+		if err != nil {
+			return errors.Join(errors.New("status"), err)
+		}
+
+		color := func(c string) string {
+			if colors.Enabled(os.Stdout) {
+				return c
+			}
+			return ""
+		}
+
+		change := status.Change
+		fmt.Print("Working copy: " + color(colors.Magenta) + change.ChangePrefix + color(colors.BrightBlack) + change.ChangeName[len(change.ChangePrefix):] + color(colors.Reset) + " ")
+		if change.Description != nil {
+			fmt.Println(strings.SplitN(*change.Description, "\n", 2)[0])
+		} else {
+			fmt.Println(color(colors.Green) + "(no description set)" + color(colors.Reset))
+		}
+
+		if status.NotOwned {
+			fmt.Printf("%s(change is owned by %s on %s, local changes will not be pushed)%s\n", color(colors.Red), change.Author, change.Device, color(colors.Reset))
+		}
+		if status.HasChildren {
+			fmt.Println(color(colors.Red) + "(change has children, local changes will not be pushed)" + color(colors.Reset))
+		}
+
+		if status.Clean() {
+			fmt.Println(color(colors.BrightBlack) + "(no changes)" + color(colors.Reset))
+		}
+		for _, name := range status.Added {
+			fmt.Println(color(colors.Green) + "A " + name + color(colors.Reset))
+		}
+		for _, name := range status.Modified {
+			fmt.Println(color(colors.Yellow) + "M " + name + color(colors.Reset))
+		}
+		for _, name := range status.Deleted {
+			fmt.Println(color(colors.Red) + "D " + name + color(colors.Reset))
+		}
+		if ignored, _ := cmd.Flags().GetBool("ignored"); ignored {
+			for _, name := range status.Ignored {
+				fmt.Println(color(colors.BrightBlack) + "! " + name + color(colors.Reset))
+			}
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	statusCmd.Flags().Bool("ignored", false, "Also show ignored files")
+	RootCmd.AddCommand(statusCmd)
+}
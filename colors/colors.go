@@ -1,11 +1,29 @@
 // Package color provides ANSI color escape sequences.
 package colors
 
+import (
+	"os"
+
+	"golang.org/x/term"
+)
+
 const (
 	Reset       = "\033[0m"
+	Bold        = "\033[1m"
 	Red         = "\033[31m"
 	Green       = "\033[32m"
+	Yellow      = "\033[33m"
 	Magenta     = "\033[35m"
+	Cyan        = "\033[36m"
 	White       = "\033[37m"
 	BrightBlack = "\033[90m"
 )
+
+// Enabled reports whether colors should be written to f.
+// This is the case if f is a terminal and NO_COLOR is not set.
+func Enabled(f *os.File) bool {
+	if _, noColor := os.LookupEnv("NO_COLOR"); noColor {
+		return false
+	}
+	return term.IsTerminal(int(f.Fd()))
+}
@@ -140,6 +140,9 @@ func (q *Queries) Begin(ctx context.Context) (*TxQueries, error) {
 	return nil, errors.New("db is neither *pgxpool.Pool nor pgx.Tx")
 }
 
+// ErrAmbiguousChange is returned by FindChange if the search matches more than one change.
+var ErrAmbiguousChange = errors.New("multiple changes found")
+
 func (q *Queries) FindChange(ctx context.Context, repositoryID int32, search string) (int64, error) {
 	changeIds, err := q.findChanges(ctx, repositoryID, search, 2)
 	if err != nil {
@@ -151,7 +154,7 @@ func (q *Queries) FindChange(ctx context.Context, repositoryID int32, search str
 	case 1:
 		return changeIds[0], nil
 	default:
-		return 0, fmt.Errorf("multiple changes found %#v", changeIds)
+		return 0, fmt.Errorf("%w %#v", ErrAmbiguousChange, changeIds)
 	}
 }
 
@@ -183,7 +186,7 @@ func (r *Queries) GenerateChangeName(ctx context.Context, repositoryID int32) (s
 	return "", errors.New("too many attempts")
 }
 
-func UpsertFile(q Querier, ctx context.Context, name string, executable *bool, contentHash []byte, conflict bool) (int64, error) {
+func UpsertFile(q Querier, ctx context.Context, name string, executable *bool, contentHash []byte) (int64, error) {
 	var id int64
 
 	if executable == nil {
@@ -191,7 +194,7 @@ func UpsertFile(q Querier, ctx context.Context, name string, executable *bool, c
 		id, err = q.getFileWithoutExecutable(ctx, contentHash, name)
 		if err != nil {
 			if err == pgx.ErrNoRows {
-				id, err = q.createFile(ctx, name, strings.HasSuffix(name, ".sh"), contentHash, conflict)
+				id, err = q.createFile(ctx, name, strings.HasSuffix(name, ".sh"), contentHash)
 				if err != nil {
 					return 0, err
 				}
@@ -204,7 +207,7 @@ func UpsertFile(q Querier, ctx context.Context, name string, executable *bool, c
 		id, err = q.getFileWithExecutable(ctx, contentHash, name, *executable)
 		if err != nil {
 			if err == pgx.ErrNoRows {
-				id, err = q.createFile(ctx, name, *executable, contentHash, conflict)
+				id, err = q.createFile(ctx, name, *executable, contentHash)
 				if err != nil {
 					return 0, err
 				}
@@ -214,5 +217,10 @@ func UpsertFile(q Querier, ctx context.Context, name string, executable *bool, c
 		}
 	}
 
+	// a file row that is used again must not be deleted by the garbage collector
+	if err := q.UnmarkFile(ctx, id); err != nil {
+		return 0, err
+	}
+
 	return id, nil
 }
@@ -9,24 +9,117 @@ import (
 	"context"
 )
 
+const getChangeFile = `-- name: GetChangeFile :one
+SELECT files.executable, files.content_hash FROM change_files
+INNER JOIN files ON files.id = change_files.file_id
+WHERE change_files.change_id = $1
+    AND files.name = $2
+LIMIT 1
+`
+
+type GetChangeFileRow struct {
+	Executable  bool
+	ContentHash []byte
+}
+
+// GetChangeFile
+//
+//	SELECT files.executable, files.content_hash FROM change_files
+//	INNER JOIN files ON files.id = change_files.file_id
+//	WHERE change_files.change_id = $1
+//	    AND files.name = $2
+//	LIMIT 1
+func (q *Queries) GetChangeFile(ctx context.Context, changeID int64, name string) (GetChangeFileRow, error) {
+	row := q.db.QueryRow(ctx, getChangeFile, changeID, name)
+	var i GetChangeFileRow
+	err := row.Scan(&i.Executable, &i.ContentHash)
+	return i, err
+}
+
+const listChangeFilesWithSize = `-- name: ListChangeFilesWithSize :many
+SELECT files.name, files.executable, files.content_hash, files.size FROM change_files
+INNER JOIN files ON files.id = change_files.file_id
+WHERE change_files.change_id = $1
+ORDER BY files.name
+`
+
+type ListChangeFilesWithSizeRow struct {
+	Name        string
+	Executable  bool
+	ContentHash []byte
+	Size        *int64
+}
+
+// ListChangeFilesWithSize
+//
+//	SELECT files.name, files.executable, files.content_hash, files.size FROM change_files
+//	INNER JOIN files ON files.id = change_files.file_id
+//	WHERE change_files.change_id = $1
+//	ORDER BY files.name
+func (q *Queries) ListChangeFilesWithSize(ctx context.Context, changeID int64) ([]ListChangeFilesWithSizeRow, error) {
+	rows, err := q.db.Query(ctx, listChangeFilesWithSize, changeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListChangeFilesWithSizeRow
+	for rows.Next() {
+		var i ListChangeFilesWithSizeRow
+		if err := rows.Scan(
+			&i.Name,
+			&i.Executable,
+			&i.ContentHash,
+			&i.Size,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setFileSize = `-- name: SetFileSize :exec
+UPDATE files SET size = $2
+WHERE content_hash = $1
+    AND size IS NULL
+`
+
+// SetFileSize
+//
+//	UPDATE files SET size = $2
+//	WHERE content_hash = $1
+//	    AND size IS NULL
+func (q *Queries) SetFileSize(ctx context.Context, contentHash []byte, size *int64) error {
+	_, err := q.db.Exec(ctx, setFileSize, contentHash, size)
+	return err
+}
+
 const createFile = `-- name: createFile :one
-INSERT INTO files (name, executable, content_hash, conflict)
-VALUES ($1, $2, $3, $4)
+INSERT INTO files (name, executable, content_hash, size)
+VALUES ($1, $2, $3, (
+    SELECT size FROM files
+    WHERE content_hash = $3
+        AND size IS NOT NULL
+    LIMIT 1
+))
 RETURNING id
 `
 
 // createFile
 //
-//	INSERT INTO files (name, executable, content_hash, conflict)
-//	VALUES ($1, $2, $3, $4)
+//	INSERT INTO files (name, executable, content_hash, size)
+//	VALUES ($1, $2, $3, (
+//	    SELECT size FROM files
+//	    WHERE content_hash = $3
+//	        AND size IS NOT NULL
+//	    LIMIT 1
+//	))
 //	RETURNING id
-func (q *Queries) createFile(ctx context.Context, name string, executable bool, contentHash []byte, conflict bool) (int64, error) {
-	row := q.db.QueryRow(ctx, createFile,
-		name,
-		executable,
-		contentHash,
-		conflict,
-	)
+func (q *Queries) createFile(ctx context.Context, name string, executable bool, contentHash []byte) (int64, error) {
+	row := q.db.QueryRow(ctx, createFile, name, executable, contentHash)
 	var id int64
 	err := row.Scan(&id)
 	return id, err
@@ -0,0 +1,9 @@
+CREATE TABLE repository_members (
+    repository_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    role TEXT NOT NULL CHECK (role IN ('read', 'write', 'admin')),
+    FOREIGN KEY (repository_id) REFERENCES repositories (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    UNIQUE (repository_id, user_id)
+);
+CREATE INDEX repository_member_repository_id ON repository_members (repository_id);
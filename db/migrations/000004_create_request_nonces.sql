@@ -0,0 +1,5 @@
+CREATE TABLE request_nonces (
+    nonce BYTEA PRIMARY KEY,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+CREATE INDEX request_nonce_expires_at ON request_nonces (expires_at);
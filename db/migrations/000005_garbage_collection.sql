@@ -0,0 +1,6 @@
+ALTER TABLE files ADD COLUMN unreferenced_since TIMESTAMP WITH TIME ZONE;
+
+CREATE TABLE unreferenced_blobs (
+    content_hash BYTEA PRIMARY KEY,
+    since TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
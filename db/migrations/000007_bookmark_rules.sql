@@ -0,0 +1,12 @@
+CREATE TABLE bookmark_rules (
+    id SERIAL PRIMARY KEY,
+    repository_id INTEGER NOT NULL,
+    bookmark TEXT NOT NULL,
+    descendants_only BOOLEAN NOT NULL,
+    no_conflicts BOOLEAN NOT NULL,
+    require_description BOOLEAN NOT NULL,
+    -- NULL allows every user with write access
+    allowed_users TEXT[],
+    FOREIGN KEY (repository_id) REFERENCES repositories (id) ON DELETE CASCADE,
+    UNIQUE (repository_id, bookmark)
+);
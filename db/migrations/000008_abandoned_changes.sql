@@ -0,0 +1 @@
+ALTER TABLE changes ADD COLUMN abandoned_at TIMESTAMP WITH TIME ZONE;
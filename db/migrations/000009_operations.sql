@@ -0,0 +1,61 @@
+-- immutable copies of change rows, referenced by the operation log
+CREATE TABLE change_snapshots (
+    id BIGSERIAL PRIMARY KEY,
+    change_id BIGINT NOT NULL,
+    description TEXT,
+    author TEXT NOT NULL,
+    device TEXT NOT NULL,
+    depth BIGINT NOT NULL,
+    parent_ids BIGINT[] NOT NULL,
+    abandoned_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (change_id) REFERENCES changes (id) ON DELETE CASCADE
+);
+CREATE INDEX change_snapshot_change_id ON change_snapshots (change_id);
+
+-- keeps the file rows of snapshots alive for the garbage collection
+CREATE TABLE change_snapshot_files (
+    snapshot_id BIGINT NOT NULL,
+    file_id BIGINT NOT NULL,
+    FOREIGN KEY (snapshot_id) REFERENCES change_snapshots (id) ON DELETE CASCADE,
+    FOREIGN KEY (file_id) REFERENCES files (id) ON DELETE CASCADE,
+    UNIQUE (snapshot_id, file_id)
+);
+CREATE INDEX change_snapshot_file_file_id ON change_snapshot_files (file_id);
+
+CREATE TABLE operations (
+    id BIGSERIAL PRIMARY KEY,
+    repository_id INTEGER NOT NULL,
+    command TEXT NOT NULL,
+    username TEXT NOT NULL,
+    device TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (repository_id) REFERENCES repositories (id) ON DELETE CASCADE
+);
+CREATE INDEX operation_repository_id ON operations (repository_id);
+
+CREATE TABLE operation_bookmarks (
+    operation_id BIGINT NOT NULL,
+    name TEXT NOT NULL,
+    -- NULL if the bookmark didn't exist before or after the operation
+    before_change_id BIGINT,
+    after_change_id BIGINT,
+    FOREIGN KEY (operation_id) REFERENCES operations (id) ON DELETE CASCADE,
+    FOREIGN KEY (before_change_id) REFERENCES changes (id) ON DELETE CASCADE,
+    FOREIGN KEY (after_change_id) REFERENCES changes (id) ON DELETE CASCADE,
+    UNIQUE (operation_id, name)
+);
+
+CREATE TABLE operation_changes (
+    operation_id BIGINT NOT NULL,
+    change_id BIGINT NOT NULL,
+    -- NULL if the change was created by the operation
+    before_snapshot_id BIGINT,
+    after_snapshot_id BIGINT NOT NULL,
+    FOREIGN KEY (operation_id) REFERENCES operations (id) ON DELETE CASCADE,
+    FOREIGN KEY (change_id) REFERENCES changes (id) ON DELETE CASCADE,
+    FOREIGN KEY (before_snapshot_id) REFERENCES change_snapshots (id) ON DELETE CASCADE,
+    FOREIGN KEY (after_snapshot_id) REFERENCES change_snapshots (id) ON DELETE CASCADE,
+    UNIQUE (operation_id, change_id)
+);
+CREATE INDEX operation_change_change_id ON operation_changes (change_id);
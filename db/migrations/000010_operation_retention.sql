@@ -0,0 +1,4 @@
+-- the garbage collection deletes expired operations and the snapshots no operation references anymore
+CREATE INDEX operation_created_at ON operations (created_at);
+CREATE INDEX operation_change_before_snapshot_id ON operation_changes (before_snapshot_id);
+CREATE INDEX operation_change_after_snapshot_id ON operation_changes (after_snapshot_id);
@@ -0,0 +1,64 @@
+-- conflicts belong to a path of a change instead of a shared file row
+CREATE TABLE conflicts (
+    id BIGSERIAL PRIMARY KEY,
+    kind TEXT NOT NULL,
+    -- the merged executable bit, used when the conflict is rendered
+    executable BOOLEAN NOT NULL,
+    -- the base is NULL if the sides added the file
+    base_change TEXT,
+    base_hash BYTEA,
+    base_executable BOOLEAN
+);
+
+CREATE TABLE conflict_sides (
+    conflict_id BIGINT NOT NULL,
+    position INT NOT NULL,
+    change_name TEXT NOT NULL,
+    -- NULL if the side deleted the file
+    content_hash BYTEA,
+    executable BOOLEAN NOT NULL,
+    FOREIGN KEY (conflict_id) REFERENCES conflicts (id) ON DELETE CASCADE,
+    UNIQUE (conflict_id, position)
+);
+
+-- a conflicted path without a file row is rendered from the sides of its conflict
+CREATE TABLE change_conflicts (
+    change_id BIGINT NOT NULL,
+    path TEXT NOT NULL,
+    conflict_id BIGINT NOT NULL,
+    FOREIGN KEY (change_id) REFERENCES changes (id) ON DELETE CASCADE,
+    FOREIGN KEY (conflict_id) REFERENCES conflicts (id) ON DELETE CASCADE,
+    PRIMARY KEY (change_id, path)
+);
+CREATE INDEX change_conflict_conflict_id ON change_conflicts (conflict_id);
+
+CREATE TABLE change_snapshot_conflicts (
+    snapshot_id BIGINT NOT NULL,
+    path TEXT NOT NULL,
+    conflict_id BIGINT NOT NULL,
+    FOREIGN KEY (snapshot_id) REFERENCES change_snapshots (id) ON DELETE CASCADE,
+    FOREIGN KEY (conflict_id) REFERENCES conflicts (id) ON DELETE CASCADE,
+    PRIMARY KEY (snapshot_id, path)
+);
+CREATE INDEX change_snapshot_conflict_conflict_id ON change_snapshot_conflicts (conflict_id);
+
+-- existing conflicts keep their markers in the file rows and have no versions, the file ids become the conflict ids
+INSERT INTO conflicts (id, kind, executable)
+SELECT id, '', executable
+FROM files
+WHERE conflict = true;
+SELECT setval(pg_get_serial_sequence('conflicts', 'id'), COALESCE((SELECT MAX(id) FROM conflicts), 0) + 1, false);
+
+INSERT INTO change_conflicts (change_id, path, conflict_id)
+SELECT cf.change_id, files.name, files.id
+FROM change_files cf
+INNER JOIN files ON files.id = cf.file_id
+WHERE files.conflict = true;
+
+INSERT INTO change_snapshot_conflicts (snapshot_id, path, conflict_id)
+SELECT sf.snapshot_id, files.name, files.id
+FROM change_snapshot_files sf
+INNER JOIN files ON files.id = sf.file_id
+WHERE files.conflict = true;
+
+ALTER TABLE files DROP COLUMN conflict;
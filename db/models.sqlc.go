@@ -15,6 +15,16 @@ type Bookmark struct {
 	ChangeID     int64
 }
 
+type BookmarkRule struct {
+	ID                 int32
+	RepositoryID       int32
+	Bookmark           string
+	DescendantsOnly    bool
+	NoConflicts        bool
+	RequireDescription bool
+	AllowedUsers       []string
+}
+
 type Change struct {
 	ID           int64
 	RepositoryID int32
@@ -25,6 +35,13 @@ type Change struct {
 	Depth        int64
 	CreatedAt    pgtype.Timestamptz
 	UpdatedAt    pgtype.Timestamptz
+	AbandonedAt  pgtype.Timestamptz
+}
+
+type ChangeConflict struct {
+	ChangeID   int64
+	Path       string
+	ConflictID int64
 }
 
 type ChangeFile struct {
@@ -37,15 +54,110 @@ type ChangeRelation struct {
 	ParentID *int64
 }
 
-type File struct {
+type ChangeSnapshot struct {
 	ID          int64
-	Name        string
-	Executable  bool
+	ChangeID    int64
+	Description *string
+	Author      string
+	Device      string
+	Depth       int64
+	ParentIds   []int64
+	AbandonedAt pgtype.Timestamptz
+	CreatedAt   pgtype.Timestamptz
+}
+
+type ChangeSnapshotConflict struct {
+	SnapshotID int64
+	Path       string
+	ConflictID int64
+}
+
+type ChangeSnapshotFile struct {
+	SnapshotID int64
+	FileID     int64
+}
+
+type Conflict struct {
+	ID             int64
+	Kind           string
+	Executable     bool
+	BaseChange     *string
+	BaseHash       []byte
+	BaseExecutable *bool
+}
+
+type ConflictSide struct {
+	ConflictID  int64
+	Position    int32
+	ChangeName  string
 	ContentHash []byte
-	Conflict    bool
+	Executable  bool
+}
+
+type File struct {
+	ID                int64
+	Name              string
+	Executable        bool
+	ContentHash       []byte
+	UnreferencedSince pgtype.Timestamptz
+	Size              *int64
+}
+
+type Operation struct {
+	ID           int64
+	RepositoryID int32
+	Command      string
+	Username     string
+	Device       string
+	CreatedAt    pgtype.Timestamptz
+}
+
+type OperationBookmark struct {
+	OperationID    int64
+	Name           string
+	BeforeChangeID *int64
+	AfterChangeID  *int64
+}
+
+type OperationChange struct {
+	OperationID      int64
+	ChangeID         int64
+	BeforeSnapshotID *int64
+	AfterSnapshotID  int64
 }
 
 type Repository struct {
 	ID   int32
 	Name string
 }
+
+type RepositoryMember struct {
+	RepositoryID int32
+	UserID       int32
+	Role         string
+}
+
+type RequestNonce struct {
+	Nonce     []byte
+	ExpiresAt pgtype.Timestamptz
+}
+
+type UnreferencedBlob struct {
+	ContentHash []byte
+	Since       pgtype.Timestamptz
+}
+
+type User struct {
+	ID        int32
+	Name      string
+	Admin     bool
+	CreatedAt pgtype.Timestamptz
+}
+
+type UserKey struct {
+	ID        int64
+	UserID    int32
+	PublicKey []byte
+	Approved  bool
+	CreatedAt pgtype.Timestamptz
+}
@@ -0,0 +1,42 @@
+package db
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// NonceStore records request nonces in the database,
+// so replayed requests are detected across multiple server instances.
+type NonceStore struct {
+	mu          sync.Mutex
+	lastCleanup time.Time
+}
+
+func NewNonceStore() *NonceStore {
+	return &NonceStore{}
+}
+
+func (s *NonceStore) Use(ctx context.Context, nonce []byte, expiresAt time.Time) (bool, error) {
+	s.cleanup(ctx)
+
+	n, err := Q.UseNonce(ctx, nonce, pgtype.Timestamptz{Time: expiresAt, Valid: true})
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+func (s *NonceStore) cleanup(ctx context.Context) {
+	s.mu.Lock()
+	if time.Since(s.lastCleanup) < time.Minute {
+		s.mu.Unlock()
+		return
+	}
+	s.lastCleanup = time.Now()
+	s.mu.Unlock()
+
+	_ = Q.DeleteExpiredNonces(ctx)
+}
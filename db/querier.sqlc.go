@@ -6,14 +6,102 @@ package db
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Querier interface {
+	//AbandonChange
+	//
+	//  UPDATE changes
+	//  SET abandoned_at = CURRENT_TIMESTAMP
+	//  WHERE id = $1
+	AbandonChange(ctx context.Context, id int64) error
+	//AddChangeConflict
+	//
+	//  INSERT INTO change_conflicts (change_id, path, conflict_id)
+	//  VALUES ($1, $2, $3)
+	AddChangeConflict(ctx context.Context, changeID int64, path string, conflictID int64) error
+	//AddChangeSnapshotFiles
+	//
+	//  WITH snapshot_conflicts AS (
+	//      INSERT INTO change_snapshot_conflicts (snapshot_id, path, conflict_id)
+	//      SELECT $1, change_conflicts.path, change_conflicts.conflict_id FROM change_conflicts
+	//      WHERE change_conflicts.change_id = $2
+	//  )
+	//  INSERT INTO change_snapshot_files (snapshot_id, file_id)
+	//  SELECT $1, change_files.file_id FROM change_files
+	//  WHERE change_files.change_id = $2
+	AddChangeSnapshotFiles(ctx context.Context, snapshotID int64, changeID int64) error
+	//AddConflictSide
+	//
+	//  INSERT INTO conflict_sides (conflict_id, position, change_name, content_hash, executable)
+	//  VALUES ($1, $2, $3, $4, $5)
+	AddConflictSide(ctx context.Context, conflictID int64, position int32, changeName string, contentHash []byte, executable bool) error
 	//AddFileToChange
 	//
 	//  INSERT INTO change_files (change_id, file_id)
 	//  VALUES ($1, $2)
 	AddFileToChange(ctx context.Context, changeID int64, fileID int64) error
+	//AddOperationBookmark
+	//
+	//  INSERT INTO operation_bookmarks (operation_id, name, before_change_id, after_change_id)
+	//  VALUES ($1, $2, $3, $4)
+	AddOperationBookmark(ctx context.Context, operationID int64, name string, beforeChangeID *int64, afterChangeID *int64) error
+	//AddOperationChange
+	//
+	//  INSERT INTO operation_changes (operation_id, change_id, before_snapshot_id, after_snapshot_id)
+	//  VALUES ($1, $2, $3, $4)
+	AddOperationChange(ctx context.Context, operationID int64, changeID int64, beforeSnapshotID *int64, afterSnapshotID int64) error
+	//AddUserKey
+	//
+	//  INSERT INTO user_keys (user_id, public_key, approved)
+	//  VALUES ($1, $2, $3)
+	AddUserKey(ctx context.Context, userID int32, publicKey []byte, approved bool) error
+	//ApproveUserKey
+	//
+	//  UPDATE user_keys
+	//  SET approved = true
+	//  WHERE id = $1
+	ApproveUserKey(ctx context.Context, id int64) error
+	//ChangeMatchesSnapshot
+	//
+	//  SELECT (
+	//      s.description IS NOT DISTINCT FROM c.description
+	//      AND s.author = c.author
+	//      AND s.device = c.device
+	//      AND s.depth = c.depth
+	//      AND s.abandoned_at IS NOT DISTINCT FROM c.abandoned_at
+	//      AND s.parent_ids = ARRAY(
+	//          SELECT cr.parent_id FROM change_relations cr
+	//          WHERE cr.change_id = c.id AND cr.parent_id IS NOT NULL
+	//          ORDER BY cr.parent_id
+	//      )::bigint[]
+	//      AND NOT EXISTS (
+	//          SELECT cf.file_id FROM change_files cf WHERE cf.change_id = c.id
+	//          EXCEPT
+	//          SELECT sf.file_id FROM change_snapshot_files sf WHERE sf.snapshot_id = s.id
+	//      )
+	//      AND NOT EXISTS (
+	//          SELECT sf.file_id FROM change_snapshot_files sf WHERE sf.snapshot_id = s.id
+	//          EXCEPT
+	//          SELECT cf.file_id FROM change_files cf WHERE cf.change_id = c.id
+	//      )
+	//      AND NOT EXISTS (
+	//          SELECT cc.path, cc.conflict_id FROM change_conflicts cc WHERE cc.change_id = c.id
+	//          EXCEPT
+	//          SELECT sc.path, sc.conflict_id FROM change_snapshot_conflicts sc WHERE sc.snapshot_id = s.id
+	//      )
+	//      AND NOT EXISTS (
+	//          SELECT sc.path, sc.conflict_id FROM change_snapshot_conflicts sc WHERE sc.snapshot_id = s.id
+	//          EXCEPT
+	//          SELECT cc.path, cc.conflict_id FROM change_conflicts cc WHERE cc.change_id = c.id
+	//      )
+	//  )::boolean AS matches
+	//  FROM change_snapshots s
+	//  INNER JOIN changes c ON c.id = s.change_id
+	//  WHERE s.id = $1
+	ChangeMatchesSnapshot(ctx context.Context, id int64) (bool, error)
 	//CheckIfChangesSameFileCount
 	//
 	//  SELECT
@@ -22,8 +110,17 @@ type Querier interface {
 	CheckIfChangesSameFileCount(ctx context.Context, changeID int64, changeID_2 int64) (bool, error)
 	//ClearChange
 	//
+	//  WITH cleared_conflicts AS (
+	//      DELETE FROM change_conflicts WHERE change_id = $1
+	//  )
 	//  DELETE FROM change_files WHERE change_id = $1
 	ClearChange(ctx context.Context, changeID int64) error
+	//ClearChangeParents
+	//
+	//  DELETE FROM change_relations
+	//  WHERE change_id = $1
+	//      AND parent_id IS NOT NULL
+	ClearChangeParents(ctx context.Context, changeID int64) error
 	//CopyFileList
 	//
 	//  INSERT INTO change_files (change_id, file_id)
@@ -32,21 +129,135 @@ type Querier interface {
 	//  AS old
 	//  WHERE old.change_id = $2
 	CopyFileList(ctx context.Context, newID int64, oldID int64) error
+	//CountExpiredOperations
+	//
+	//  SELECT
+	//      (
+	//          SELECT COUNT(*) FROM operations o
+	//          WHERE o.created_at < $1
+	//              AND (
+	//                  NOT EXISTS (SELECT 1 FROM operation_changes oc WHERE oc.operation_id = o.id)
+	//                  OR o.created_at < $2
+	//              )
+	//      ) AS operations,
+	//      (
+	//          SELECT COUNT(*) FROM change_snapshots s
+	//          WHERE NOT EXISTS (
+	//              SELECT 1 FROM operation_changes oc
+	//              INNER JOIN operations o ON o.id = oc.operation_id
+	//              WHERE (o.created_at >= $1 OR $2 IS NULL OR o.created_at >= $2)
+	//                  AND (oc.before_snapshot_id = s.id OR oc.after_snapshot_id = s.id)
+	//          )
+	//      ) AS snapshots
+	CountExpiredOperations(ctx context.Context, cutoff pgtype.Timestamptz, evologCutoff pgtype.Timestamptz) (CountExpiredOperationsRow, error)
+	//CountRepoAdmins
+	//
+	//  SELECT COUNT(*) FROM repository_members
+	//  WHERE repository_id = $1 AND role = 'admin'
+	CountRepoAdmins(ctx context.Context, repositoryID int32) (int64, error)
+	//CountUnreferencedFiles
+	//
+	//  SELECT
+	//      COUNT(*) FILTER (WHERE unreferenced_since IS NULL) AS unmarked,
+	//      COUNT(*) FILTER (WHERE unreferenced_since < $1) AS deletable
+	//  FROM files
+	//  WHERE NOT EXISTS (SELECT 1 FROM change_files cf WHERE cf.file_id = files.id)
+	//      AND NOT EXISTS (SELECT 1 FROM change_snapshot_files sf WHERE sf.file_id = files.id)
+	CountUnreferencedFiles(ctx context.Context, cutoff pgtype.Timestamptz) (CountUnreferencedFilesRow, error)
+	//CountUsers
+	//
+	//  SELECT COUNT(*) FROM users
+	CountUsers(ctx context.Context) (int64, error)
 	//CreateChange
 	//
 	//  INSERT INTO changes (repository_id, name, description, author, device, depth)
 	//  VALUES ($1, $2, $3, $4, $5, $6)
 	//  RETURNING id
 	CreateChange(ctx context.Context, repositoryID int32, name string, description *string, author string, device string, depth int64) (int64, error)
+	//CreateChangeSnapshot
+	//
+	//  INSERT INTO change_snapshots (change_id, description, author, device, depth, parent_ids, abandoned_at)
+	//  SELECT c.id, c.description, c.author, c.device, c.depth,
+	//      ARRAY(
+	//          SELECT cr.parent_id FROM change_relations cr
+	//          WHERE cr.change_id = c.id AND cr.parent_id IS NOT NULL
+	//          ORDER BY cr.parent_id
+	//      )::bigint[],
+	//      c.abandoned_at
+	//  FROM changes c
+	//  WHERE c.id = $1
+	//  RETURNING id
+	CreateChangeSnapshot(ctx context.Context, changeID int64) (int64, error)
+	//CreateConflict
+	//
+	//  INSERT INTO conflicts (kind, executable, base_change, base_hash, base_executable)
+	//  VALUES ($1, $2, $3, $4, $5)
+	//  RETURNING id
+	CreateConflict(ctx context.Context, kind string, executable bool, baseChange *string, baseHash []byte, baseExecutable *bool) (int64, error)
+	//CreateOperation
+	//
+	//  INSERT INTO operations (repository_id, command, username, device)
+	//  VALUES ($1, $2, $3, $4)
+	//  RETURNING id
+	CreateOperation(ctx context.Context, repositoryID int32, command string, username string, device string) (int64, error)
 	//CreateRepo
 	//
 	//  INSERT INTO repositories (name)
 	//  VALUES ($1)
 	//  RETURNING id
 	CreateRepo(ctx context.Context, name string) (int32, error)
+	//CreateUser
+	//
+	//  INSERT INTO users (name, admin)
+	//  VALUES ($1, $2)
+	//  RETURNING id
+	CreateUser(ctx context.Context, name string, admin bool) (int32, error)
+	//DeleteBookmark
+	//
+	//  DELETE FROM bookmarks WHERE repository_id = $1 AND name = $2
+	DeleteBookmark(ctx context.Context, repositoryID int32, name string) (int64, error)
+	//DeleteChangeSnapshot
+	//
+	//  DELETE FROM change_snapshots WHERE id = $1
+	DeleteChangeSnapshot(ctx context.Context, id int64) error
+	//DeleteExpiredNonces
+	//
+	//  DELETE FROM request_nonces
+	//  WHERE expires_at < now()
+	DeleteExpiredNonces(ctx context.Context) error
+	//DeleteExpiredOperations
+	//
+	//  DELETE FROM operations o
+	//  WHERE o.created_at < $1
+	//      AND (
+	//          NOT EXISTS (SELECT 1 FROM operation_changes oc WHERE oc.operation_id = o.id)
+	//          OR o.created_at < $2
+	//      )
+	DeleteExpiredOperations(ctx context.Context, cutoff pgtype.Timestamptz, evologCutoff pgtype.Timestamptz) (int64, error)
+	//DeleteOrphanedConflicts
+	//
+	//  DELETE FROM conflicts c
+	//  WHERE NOT EXISTS (SELECT 1 FROM change_conflicts cc WHERE cc.conflict_id = c.id)
+	//      AND NOT EXISTS (SELECT 1 FROM change_snapshot_conflicts sc WHERE sc.conflict_id = c.id)
+	DeleteOrphanedConflicts(ctx context.Context) (int64, error)
+	//DeleteOrphanedSnapshots
+	//
+	//  DELETE FROM change_snapshots s
+	//  WHERE NOT EXISTS (
+	//      SELECT 1 FROM operation_changes oc
+	//      WHERE oc.before_snapshot_id = s.id OR oc.after_snapshot_id = s.id
+	//  )
+	DeleteOrphanedSnapshots(ctx context.Context) (int64, error)
+	//DeleteUnreferencedFiles
+	//
+	//  DELETE FROM files
+	//  WHERE unreferenced_since < $1
+	//      AND NOT EXISTS (SELECT 1 FROM change_files cf WHERE cf.file_id = files.id)
+	//      AND NOT EXISTS (SELECT 1 FROM change_snapshot_files sf WHERE sf.file_id = files.id)
+	DeleteUnreferencedFiles(ctx context.Context, cutoff pgtype.Timestamptz) (int64, error)
 	//FindChangeExact
 	//
-	//  SELECT id, repository_id, name, description, author, device, depth, created_at, updated_at FROM changes WHERE repository_id = $1 AND name = $2 LIMIT 1
+	//  SELECT id, repository_id, name, description, author, device, depth, created_at, updated_at, abandoned_at FROM changes WHERE repository_id = $1 AND name = $2 LIMIT 1
 	FindChangeExact(ctx context.Context, repositoryID int32, name string) (Change, error)
 	//GetAllBookmarks
 	//
@@ -68,7 +279,7 @@ type Querier interface {
 	//    WHERE cr.parent_id IS NOT NULL
 	//  )
 	//  SELECT
-	//    c.id, c.repository_id, c.name, c.description, c.author, c.device, c.depth, c.created_at, c.updated_at,
+	//    c.id, c.repository_id, c.name, c.description, c.author, c.device, c.depth, c.created_at, c.updated_at, c.abandoned_at,
 	//    cr.parent_id,
 	//    cr.change_id
 	//  FROM ancestry a
@@ -83,13 +294,12 @@ type Querier interface {
 	//
 	//  SELECT change_id FROM bookmarks WHERE repository_id = $1 AND name = $2 LIMIT 1
 	GetBookmark(ctx context.Context, repositoryID int32, name string) (int64, error)
-	//GetChangeConflicts
+	//GetBookmarkRule
 	//
-	//  SELECT files.name
-	//  FROM change_files
-	//  INNER JOIN files ON files.id = change_files.file_id
-	//  WHERE change_files.change_id = $1 AND files.conflict = true
-	GetChangeConflicts(ctx context.Context, changeID int64) ([]string, error)
+	//  SELECT id, repository_id, bookmark, descendants_only, no_conflicts, require_description, allowed_users FROM bookmark_rules
+	//  WHERE repository_id = $1 AND bookmark = $2
+	//  LIMIT 1
+	GetBookmarkRule(ctx context.Context, repositoryID int32, bookmark string) (BookmarkRule, error)
 	//GetChangeDepth
 	//
 	//  SELECT depth FROM changes WHERE id = $1 AND repository_id = $2 LIMIT 1
@@ -98,6 +308,14 @@ type Querier interface {
 	//
 	//  SELECT description FROM changes WHERE id = $1 LIMIT 1
 	GetChangeDescription(ctx context.Context, id int64) (*string, error)
+	//GetChangeFile
+	//
+	//  SELECT files.executable, files.content_hash FROM change_files
+	//  INNER JOIN files ON files.id = change_files.file_id
+	//  WHERE change_files.change_id = $1
+	//      AND files.name = $2
+	//  LIMIT 1
+	GetChangeFile(ctx context.Context, changeID int64, name string) (GetChangeFileRow, error)
 	//GetChangeIgnorefiles
 	//
 	//  SELECT files.name, files.content_hash FROM files
@@ -145,34 +363,468 @@ type Querier interface {
 	//    AND c.repository_id = $2
 	//  LIMIT 1
 	GetChangePrefix(ctx context.Context, iD int64, repositoryID int32) (string, error)
+	//GetChangeSnapshot
+	//
+	//  SELECT id, change_id, description, author, device, depth, parent_ids, abandoned_at, created_at FROM change_snapshots
+	//  WHERE id = $1
+	//  LIMIT 1
+	GetChangeSnapshot(ctx context.Context, id int64) (ChangeSnapshot, error)
+	//GetChildren
+	//
+	//  SELECT cr.change_id
+	//  FROM change_relations cr
+	//  JOIN changes c
+	//      ON c.id = cr.change_id
+	//  WHERE cr.parent_id = $1
+	//      AND c.abandoned_at IS NULL
+	//  ORDER BY cr.change_id
+	GetChildren(ctx context.Context, parentID *int64) ([]int64, error)
+	//GetKeyOwner
+	//
+	//  SELECT users.name
+	//  FROM user_keys
+	//  INNER JOIN users ON users.id = user_keys.user_id
+	//  WHERE user_keys.public_key = $1
+	//  LIMIT 1
+	GetKeyOwner(ctx context.Context, publicKey []byte) (string, error)
+	//GetLatestOperation
+	//
+	//  SELECT id, repository_id, command, username, device, created_at FROM operations
+	//  WHERE repository_id = $1 AND username = $2 AND device = $3
+	//      AND command <> 'push'
+	//  ORDER BY id DESC
+	//  LIMIT 1
+	GetLatestOperation(ctx context.Context, repositoryID int32, username string, device string) (Operation, error)
+	//GetLogChanges
+	//
+	//  SELECT
+	//    c.id, c.repository_id, c.name, c.description, c.author, c.device, c.depth, c.created_at, c.updated_at, c.abandoned_at,
+	//    cr.parent_id,
+	//    cr.change_id
+	//  FROM changes c
+	//  LEFT JOIN change_relations cr
+	//    ON cr.change_id = c.id
+	//  WHERE c.repository_id = $1
+	//    AND c.id = ANY($2::bigint[])
+	//  ORDER BY c.depth DESC, c.id DESC
+	GetLogChanges(ctx context.Context, repositoryID int32, ids []int64) ([]GetLogChangesRow, error)
+	//GetOperation
+	//
+	//  SELECT id, repository_id, command, username, device, created_at FROM operations
+	//  WHERE repository_id = $1 AND id = $2
+	//  LIMIT 1
+	GetOperation(ctx context.Context, repositoryID int32, id int64) (Operation, error)
+	//GetParentsMaxDepth
+	//
+	//  SELECT COALESCE(MAX(p.depth), -1)::bigint AS depth
+	//  FROM change_relations cr
+	//  JOIN changes p
+	//      ON p.id = cr.parent_id
+	//  WHERE cr.change_id = $1
+	GetParentsMaxDepth(ctx context.Context, changeID int64) (int64, error)
 	//GetRepoByName
 	//
 	//  SELECT id FROM repositories WHERE name = $1 LIMIT 1
 	GetRepoByName(ctx context.Context, name string) (int32, error)
+	//GetRepoMemberRole
+	//
+	//  SELECT repository_members.role
+	//  FROM repository_members
+	//  INNER JOIN users ON users.id = repository_members.user_id
+	//  WHERE repository_members.repository_id = $1 AND users.name = $2
+	//  LIMIT 1
+	GetRepoMemberRole(ctx context.Context, repositoryID int32, name string) (string, error)
+	//GetSnapshotChange
+	//
+	//  SELECT s.change_id, changes.name FROM change_snapshots s
+	//  INNER JOIN changes ON changes.id = s.change_id
+	//  WHERE s.id = $1 AND changes.repository_id = $2
+	//  LIMIT 1
+	GetSnapshotChange(ctx context.Context, id int64, repositoryID int32) (GetSnapshotChangeRow, error)
+	//GetUserByName
+	//
+	//  SELECT id, name, admin, created_at FROM users WHERE name = $1 LIMIT 1
+	GetUserByName(ctx context.Context, name string) (User, error)
+	//GetUserKeyStatus
+	//
+	//  SELECT user_keys.approved, users.admin
+	//  FROM user_keys
+	//  INNER JOIN users ON users.id = user_keys.user_id
+	//  WHERE users.name = $1 AND user_keys.public_key = $2
+	//  LIMIT 1
+	GetUserKeyStatus(ctx context.Context, name string, publicKey []byte) (GetUserKeyStatusRow, error)
 	//HasChangeChild
 	//
 	//  SELECT EXISTS (
 	//      SELECT 1
 	//      FROM change_relations
-	//      WHERE parent_id = $1
+	//      INNER JOIN changes ON changes.id = change_relations.change_id
+	//      WHERE change_relations.parent_id = $1
+	//          AND changes.abandoned_at IS NULL
 	//  )
 	HasChangeChild(ctx context.Context, parentID *int64) (bool, error)
 	//HasChangeConflicts
 	//
 	//  SELECT EXISTS (
 	//      SELECT 1
-	//      FROM change_files
-	//      INNER JOIN files ON files.id = change_files.file_id
-	//      WHERE change_files.change_id = $1 AND files.conflict = true
+	//      FROM change_conflicts
+	//      WHERE change_conflicts.change_id = $1
 	//      LIMIT 1
 	//  )
 	HasChangeConflicts(ctx context.Context, changeID int64) (bool, error)
+	//IsAncestor
+	//
+	//  WITH RECURSIVE ancestors AS (
+	//    SELECT $1::bigint AS id
+	//    UNION
+	//    SELECT cr.parent_id
+	//    FROM ancestors a
+	//    JOIN change_relations cr
+	//      ON cr.change_id = a.id
+	//    JOIN changes c
+	//      ON c.id = cr.parent_id
+	//    WHERE c.depth >= (SELECT depth FROM changes WHERE id = $2::bigint)
+	//  )
+	//  SELECT EXISTS (SELECT 1 FROM ancestors WHERE id = $2::bigint)
+	IsAncestor(ctx context.Context, descendant int64, ancestor int64) (bool, error)
+	//IsChangeAbandoned
+	//
+	//  SELECT abandoned_at IS NOT NULL AS abandoned FROM changes
+	//  WHERE id = $1
+	IsChangeAbandoned(ctx context.Context, id int64) (bool, error)
+	//ListBookmarkRules
+	//
+	//  SELECT id, repository_id, bookmark, descendants_only, no_conflicts, require_description, allowed_users FROM bookmark_rules
+	//  WHERE repository_id = $1
+	//  ORDER BY bookmark
+	ListBookmarkRules(ctx context.Context, repositoryID int32) ([]BookmarkRule, error)
+	//ListBookmarkTargets
+	//
+	//  SELECT name, change_id FROM bookmarks
+	//  WHERE repository_id = $1
+	//  ORDER BY name
+	ListBookmarkTargets(ctx context.Context, repositoryID int32) ([]ListBookmarkTargetsRow, error)
+	//ListChangeBookmarks
+	//
+	//  SELECT name FROM bookmarks
+	//  WHERE repository_id = $1 AND change_id = $2
+	//  ORDER BY name
+	ListChangeBookmarks(ctx context.Context, repositoryID int32, changeID int64) ([]string, error)
+	//ListChangeConflicts
+	//
+	//  SELECT cc.path, c.id, c.kind, c.executable, c.base_change, c.base_hash, c.base_executable
+	//  FROM change_conflicts cc
+	//  INNER JOIN conflicts c ON c.id = cc.conflict_id
+	//  WHERE cc.change_id = $1
+	//  ORDER BY cc.path
+	ListChangeConflicts(ctx context.Context, changeID int64) ([]ListChangeConflictsRow, error)
+	//ListChangeDepths
+	//
+	//  SELECT id, repository_id, name, depth FROM changes
+	//  WHERE abandoned_at IS NULL
+	ListChangeDepths(ctx context.Context) ([]ListChangeDepthsRow, error)
 	//ListChangeFiles
 	//
 	//  SELECT files.name, files.executable, files.content_hash FROM change_files
 	//  INNER JOIN files ON files.id = change_files.file_id
 	//  WHERE change_files.change_id = $1
 	ListChangeFiles(ctx context.Context, changeID int64) ([]ListChangeFilesRow, error)
+	//ListChangeFilesWithSize
+	//
+	//  SELECT files.name, files.executable, files.content_hash, files.size FROM change_files
+	//  INNER JOIN files ON files.id = change_files.file_id
+	//  WHERE change_files.change_id = $1
+	//  ORDER BY files.name
+	ListChangeFilesWithSize(ctx context.Context, changeID int64) ([]ListChangeFilesWithSizeRow, error)
+	//ListChangeRelations
+	//
+	//  SELECT change_id, parent_id FROM change_relations
+	//  WHERE parent_id IS NOT NULL
+	ListChangeRelations(ctx context.Context) ([]ChangeRelation, error)
+	//ListChangeSnapshotConflicts
+	//
+	//  SELECT sc.path, c.id, c.kind, c.executable, c.base_change, c.base_hash, c.base_executable
+	//  FROM change_snapshot_conflicts sc
+	//  INNER JOIN conflicts c ON c.id = sc.conflict_id
+	//  WHERE sc.snapshot_id = $1
+	//  ORDER BY sc.path
+	ListChangeSnapshotConflicts(ctx context.Context, snapshotID int64) ([]ListChangeSnapshotConflictsRow, error)
+	//ListChangeSnapshotFiles
+	//
+	//  SELECT files.name, files.executable, files.content_hash FROM change_snapshot_files
+	//  INNER JOIN files ON files.id = change_snapshot_files.file_id
+	//  WHERE change_snapshot_files.snapshot_id = $1
+	ListChangeSnapshotFiles(ctx context.Context, snapshotID int64) ([]ListChangeSnapshotFilesRow, error)
+	//ListChangeSnapshots
+	//
+	//  SELECT s.id, s.description, s.created_at, oc.after_snapshot_id = s.id AS after,
+	//      o.id AS operation_id, o.command, o.username, o.device
+	//  FROM operation_changes oc
+	//  INNER JOIN change_snapshots s ON s.id = oc.after_snapshot_id OR s.id = oc.before_snapshot_id
+	//  INNER JOIN operations o ON o.id = oc.operation_id
+	//  WHERE oc.change_id = $1
+	//  ORDER BY s.id
+	ListChangeSnapshots(ctx context.Context, changeID int64) ([]ListChangeSnapshotsRow, error)
+	//ListConflictSides
+	//
+	//  SELECT conflict_id, position, change_name, content_hash, executable FROM conflict_sides
+	//  WHERE conflict_id = $1
+	//  ORDER BY position
+	ListConflictSides(ctx context.Context, conflictID int64) ([]ConflictSide, error)
+	//ListContentHashes
+	//
+	//  SELECT content_hash, bool_or(size IS NULL)::BOOLEAN AS missing_size FROM files
+	//  GROUP BY content_hash
+	ListContentHashes(ctx context.Context) ([]ListContentHashesRow, error)
+	//ListDanglingChangeRelations
+	//
+	//  SELECT cr.change_id, cr.parent_id
+	//  FROM change_relations cr
+	//  JOIN changes c ON c.id = cr.change_id
+	//  LEFT JOIN changes p ON p.id = cr.parent_id
+	//  WHERE cr.parent_id IS NOT NULL
+	//      AND (p.id IS NULL OR p.repository_id <> c.repository_id)
+	ListDanglingChangeRelations(ctx context.Context) ([]ListDanglingChangeRelationsRow, error)
+	//ListLiveContentHashes
+	//
+	//  WITH live_files AS (
+	//      SELECT id, content_hash
+	//      FROM files
+	//      WHERE unreferenced_since IS NULL
+	//          OR unreferenced_since >= $1
+	//          OR EXISTS (SELECT 1 FROM change_files cf WHERE cf.file_id = files.id)
+	//          OR EXISTS (SELECT 1 FROM change_snapshot_files sf WHERE sf.file_id = files.id)
+	//  )
+	//  SELECT live_files.content_hash FROM live_files
+	//  UNION
+	//  SELECT c.base_hash FROM conflicts c
+	//  WHERE c.base_hash IS NOT NULL
+	//  UNION
+	//  SELECT cs.content_hash FROM conflict_sides cs
+	//  WHERE cs.content_hash IS NOT NULL
+	ListLiveContentHashes(ctx context.Context, cutoff pgtype.Timestamptz) ([][]byte, error)
+	//ListOperationBookmarks
+	//
+	//  SELECT operation_id, name, before_change_id, after_change_id FROM operation_bookmarks
+	//  WHERE operation_id = $1
+	//  ORDER BY name
+	ListOperationBookmarks(ctx context.Context, operationID int64) ([]OperationBookmark, error)
+	//ListOperationChanges
+	//
+	//  SELECT oc.change_id, oc.before_snapshot_id, oc.after_snapshot_id, c.name
+	//  FROM operation_changes oc
+	//  INNER JOIN changes c ON c.id = oc.change_id
+	//  WHERE oc.operation_id = $1
+	//  ORDER BY c.depth, c.id
+	ListOperationChanges(ctx context.Context, operationID int64) ([]ListOperationChangesRow, error)
+	//ListOperations
+	//
+	//  SELECT id, repository_id, command, username, device, created_at FROM operations
+	//  WHERE repository_id = $1
+	//  ORDER BY id DESC
+	//  LIMIT $2
+	ListOperations(ctx context.Context, repositoryID int32, limit int32) ([]Operation, error)
+	//ListOperationsAfter
+	//
+	//  SELECT id FROM operations
+	//  WHERE repository_id = $1 AND id > $2
+	//  ORDER BY id DESC
+	ListOperationsAfter(ctx context.Context, repositoryID int32, id int64) ([]int64, error)
+	//ListParentDepths
+	//
+	//  SELECT p.id, p.depth
+	//  FROM change_relations cr
+	//  JOIN changes p
+	//      ON p.id = cr.parent_id
+	//  WHERE cr.change_id = $1
+	//  ORDER BY p.id
+	ListParentDepths(ctx context.Context, changeID int64) ([]ListParentDepthsRow, error)
+	//ListPendingUserKeys
+	//
+	//  SELECT user_keys.id, users.name, user_keys.public_key, user_keys.created_at
+	//  FROM user_keys
+	//  INNER JOIN users ON users.id = user_keys.user_id
+	//  WHERE user_keys.approved = false
+	//  ORDER BY user_keys.created_at
+	ListPendingUserKeys(ctx context.Context) ([]ListPendingUserKeysRow, error)
+	//ListRepoMembers
+	//
+	//  SELECT users.name, repository_members.role
+	//  FROM repository_members
+	//  INNER JOIN users ON users.id = repository_members.user_id
+	//  WHERE repository_members.repository_id = $1
+	//  ORDER BY users.name
+	ListRepoMembers(ctx context.Context, repositoryID int32) ([]ListRepoMembersRow, error)
+	//ListUnreferencedBlobs
+	//
+	//  SELECT content_hash, since FROM unreferenced_blobs
+	ListUnreferencedBlobs(ctx context.Context) ([]UnreferencedBlob, error)
+	//LockUserRegistration
+	//
+	//  SELECT pg_advisory_xact_lock(7032)
+	LockUserRegistration(ctx context.Context) error
+	//MarkUnreferencedBlob
+	//
+	//  INSERT INTO unreferenced_blobs (content_hash)
+	//  VALUES ($1)
+	//  ON CONFLICT (content_hash) DO NOTHING
+	MarkUnreferencedBlob(ctx context.Context, contentHash []byte) error
+	//MarkUnreferencedFiles
+	//
+	//  UPDATE files
+	//  SET unreferenced_since = now()
+	//  WHERE unreferenced_since IS NULL
+	//      AND NOT EXISTS (SELECT 1 FROM change_files cf WHERE cf.file_id = files.id)
+	//      AND NOT EXISTS (SELECT 1 FROM change_snapshot_files sf WHERE sf.file_id = files.id)
+	MarkUnreferencedFiles(ctx context.Context) (int64, error)
+	//RemoveBookmarkRule
+	//
+	//  DELETE FROM bookmark_rules
+	//  WHERE repository_id = $1 AND bookmark = $2
+	RemoveBookmarkRule(ctx context.Context, repositoryID int32, bookmark string) (int64, error)
+	//RemoveChangeParent
+	//
+	//  DELETE FROM change_relations
+	//  WHERE change_id = $1 AND parent_id = $2
+	RemoveChangeParent(ctx context.Context, changeID int64, parentID *int64) error
+	//RemoveRepoMember
+	//
+	//  DELETE FROM repository_members
+	//  WHERE repository_id = $1 AND user_id = $2
+	RemoveRepoMember(ctx context.Context, repositoryID int32, userID int32) (int64, error)
+	//RenameBookmark
+	//
+	//  UPDATE bookmarks SET name = $3 WHERE repository_id = $1 AND name = $2
+	RenameBookmark(ctx context.Context, repositoryID int32, name string, name_2 string) (int64, error)
+	//RepoExists
+	//
+	//  SELECT EXISTS (SELECT 1 FROM repositories WHERE id = $1)
+	RepoExists(ctx context.Context, id int32) (bool, error)
+	//RestoreChangeSnapshotAbandoned
+	//
+	//  UPDATE changes
+	//  SET abandoned_at = s.abandoned_at
+	//  FROM change_snapshots s
+	//  WHERE s.id = $1
+	//      AND changes.id = s.change_id
+	RestoreChangeSnapshotAbandoned(ctx context.Context, id int64) error
+	//RestoreChangeSnapshotDescription
+	//
+	//  UPDATE changes
+	//  SET description = s.description,
+	//      author = s.author,
+	//      device = s.device,
+	//      updated_at = CURRENT_TIMESTAMP
+	//  FROM change_snapshots s
+	//  WHERE s.id = $1
+	//      AND changes.id = s.change_id
+	RestoreChangeSnapshotDescription(ctx context.Context, id int64) error
+	//RestoreChangeSnapshotFiles
+	//
+	//  WITH restored_conflicts AS (
+	//      INSERT INTO change_conflicts (change_id, path, conflict_id)
+	//      SELECT s.change_id, sc.path, sc.conflict_id
+	//      FROM change_snapshot_conflicts sc
+	//      INNER JOIN change_snapshots s ON s.id = sc.snapshot_id
+	//      WHERE sc.snapshot_id = $1
+	//  )
+	//  INSERT INTO change_files (change_id, file_id)
+	//  SELECT s.change_id, sf.file_id
+	//  FROM change_snapshot_files sf
+	//  INNER JOIN change_snapshots s ON s.id = sf.snapshot_id
+	//  WHERE sf.snapshot_id = $1
+	RestoreChangeSnapshotFiles(ctx context.Context, snapshotID int64) error
+	//RestoreChangeSnapshotParents
+	//
+	//  INSERT INTO change_relations (change_id, parent_id)
+	//  SELECT s.change_id, unnest(s.parent_ids)
+	//  FROM change_snapshots s
+	//  WHERE s.id = $1
+	RestoreChangeSnapshotParents(ctx context.Context, id int64) error
+	//RevsetAll
+	//
+	//  SELECT id FROM changes WHERE repository_id = $1 AND abandoned_at IS NULL
+	RevsetAll(ctx context.Context, repositoryID int32) ([]int64, error)
+	//RevsetAncestors
+	//
+	//  WITH RECURSIVE ancestors AS (
+	//    SELECT unnest($1::bigint[]) AS id
+	//    UNION
+	//    SELECT cr.parent_id
+	//    FROM ancestors a
+	//    JOIN change_relations cr
+	//      ON cr.change_id = a.id
+	//    WHERE cr.parent_id IS NOT NULL
+	//  )
+	//  SELECT c.id
+	//  FROM ancestors a
+	//  JOIN changes c
+	//    ON c.id = a.id
+	//  WHERE c.repository_id = $2
+	//    AND c.abandoned_at IS NULL
+	RevsetAncestors(ctx context.Context, ids []int64, repositoryID int32) ([]int64, error)
+	//RevsetAuthor
+	//
+	//  SELECT id FROM changes WHERE repository_id = $1 AND author = $2 AND abandoned_at IS NULL
+	RevsetAuthor(ctx context.Context, repositoryID int32, author string) ([]int64, error)
+	//RevsetConflicts
+	//
+	//  SELECT DISTINCT c.id
+	//  FROM changes c
+	//  JOIN change_conflicts cc
+	//    ON cc.change_id = c.id
+	//  WHERE c.repository_id = $1
+	//    AND c.abandoned_at IS NULL
+	RevsetConflicts(ctx context.Context, repositoryID int32) ([]int64, error)
+	//RevsetDescendants
+	//
+	//  WITH RECURSIVE descendants AS (
+	//    SELECT unnest($1::bigint[]) AS id
+	//    UNION
+	//    SELECT cr.change_id
+	//    FROM descendants d
+	//    JOIN change_relations cr
+	//      ON cr.parent_id = d.id
+	//  )
+	//  SELECT c.id
+	//  FROM descendants d
+	//  JOIN changes c
+	//    ON c.id = d.id
+	//  WHERE c.repository_id = $2
+	//    AND c.abandoned_at IS NULL
+	RevsetDescendants(ctx context.Context, ids []int64, repositoryID int32) ([]int64, error)
+	//RevsetDescription
+	//
+	//  SELECT id FROM changes
+	//  WHERE repository_id = $1
+	//    AND abandoned_at IS NULL
+	//    AND description ~ $2::text
+	RevsetDescription(ctx context.Context, repositoryID int32, pattern string) ([]int64, error)
+	//RevsetHeads
+	//
+	//  SELECT c.id
+	//  FROM changes c
+	//  WHERE c.repository_id = $1
+	//    AND c.abandoned_at IS NULL
+	//    AND NOT EXISTS (
+	//      SELECT 1
+	//      FROM change_relations cr
+	//      JOIN changes child
+	//        ON child.id = cr.change_id
+	//      WHERE cr.parent_id = c.id
+	//        AND child.abandoned_at IS NULL
+	//    )
+	RevsetHeads(ctx context.Context, repositoryID int32) ([]int64, error)
+	//RevsetParents
+	//
+	//  SELECT DISTINCT c.id
+	//  FROM change_relations cr
+	//  JOIN changes c
+	//    ON c.id = cr.parent_id
+	//  WHERE cr.change_id = ANY($1::bigint[])
+	//    AND c.repository_id = $2
+	//    AND c.abandoned_at IS NULL
+	RevsetParents(ctx context.Context, ids []int64, repositoryID int32) ([]int64, error)
 	//SetBookmark
 	//
 	//  INSERT INTO bookmarks (repository_id, name, change_id)
@@ -180,6 +832,13 @@ type Querier interface {
 	//  ON CONFLICT (repository_id, name)
 	//  DO UPDATE SET change_id = $3
 	SetBookmark(ctx context.Context, repositoryID int32, name string, changeID int64) error
+	//SetBookmarkRule
+	//
+	//  INSERT INTO bookmark_rules (repository_id, bookmark, descendants_only, no_conflicts, require_description, allowed_users)
+	//  VALUES ($1, $2, $3, $4, $5, $6)
+	//  ON CONFLICT (repository_id, bookmark)
+	//  DO UPDATE SET descendants_only = $3, no_conflicts = $4, require_description = $5, allowed_users = $6
+	SetBookmarkRule(ctx context.Context, repositoryID int32, bookmark string, descendantsOnly bool, noConflicts bool, requireDescription bool, allowedUsers []string) error
 	//SetChangeDepth
 	//
 	//  UPDATE changes
@@ -199,12 +858,101 @@ type Querier interface {
 	//  ON CONFLICT (change_id, parent_id)
 	//  DO NOTHING
 	SetChangeParent(ctx context.Context, changeID int64, parentID *int64) error
+	//SetFileSize
+	//
+	//  UPDATE files SET size = $2
+	//  WHERE content_hash = $1
+	//      AND size IS NULL
+	SetFileSize(ctx context.Context, contentHash []byte, size *int64) error
+	//SetRepoMember
+	//
+	//  INSERT INTO repository_members (repository_id, user_id, role)
+	//  VALUES ($1, $2, $3)
+	//  ON CONFLICT (repository_id, user_id)
+	//  DO UPDATE SET role = $3
+	SetRepoMember(ctx context.Context, repositoryID int32, userID int32, role string) error
+	//SnapshotsHaveSameFiles
+	//
+	//  SELECT NOT EXISTS (
+	//      (
+	//          SELECT file_id FROM change_snapshot_files WHERE snapshot_id = $1
+	//          EXCEPT
+	//          SELECT file_id FROM change_snapshot_files WHERE snapshot_id = $2
+	//      )
+	//      UNION ALL
+	//      (
+	//          SELECT file_id FROM change_snapshot_files WHERE snapshot_id = $2
+	//          EXCEPT
+	//          SELECT file_id FROM change_snapshot_files WHERE snapshot_id = $1
+	//      )
+	//  ) AND NOT EXISTS (
+	//      (
+	//          SELECT path, conflict_id FROM change_snapshot_conflicts WHERE snapshot_id = $1
+	//          EXCEPT
+	//          SELECT path, conflict_id FROM change_snapshot_conflicts WHERE snapshot_id = $2
+	//      )
+	//      UNION ALL
+	//      (
+	//          SELECT path, conflict_id FROM change_snapshot_conflicts WHERE snapshot_id = $2
+	//          EXCEPT
+	//          SELECT path, conflict_id FROM change_snapshot_conflicts WHERE snapshot_id = $1
+	//      )
+	//  ) AS same
+	SnapshotsHaveSameFiles(ctx context.Context, a int64, b int64) (bool, error)
+	//SweepBlobMark
+	//
+	//  DELETE FROM unreferenced_blobs
+	//  WHERE content_hash = $1
+	//      AND since < $2
+	SweepBlobMark(ctx context.Context, contentHash []byte, cutoff pgtype.Timestamptz) (int64, error)
+	//TryLockGC
+	//
+	//  SELECT pg_try_advisory_xact_lock(7031) AS locked
+	TryLockGC(ctx context.Context) (bool, error)
+	//UnmarkBlob
+	//
+	//  DELETE FROM unreferenced_blobs
+	//  WHERE content_hash = $1
+	UnmarkBlob(ctx context.Context, contentHash []byte) error
+	//UnmarkBlobs
+	//
+	//  DELETE FROM unreferenced_blobs
+	//  WHERE content_hash = ANY($1::bytea[])
+	UnmarkBlobs(ctx context.Context, contentHashes [][]byte) error
+	//UnmarkFile
+	//
+	//  UPDATE files
+	//  SET unreferenced_since = NULL
+	//  WHERE id = $1
+	//      AND unreferenced_since IS NOT NULL
+	UnmarkFile(ctx context.Context, id int64) error
+	//UnmarkReferencedFiles
+	//
+	//  UPDATE files
+	//  SET unreferenced_since = NULL
+	//  WHERE unreferenced_since IS NOT NULL
+	//      AND (
+	//          EXISTS (SELECT 1 FROM change_files cf WHERE cf.file_id = files.id)
+	//          OR EXISTS (SELECT 1 FROM change_snapshot_files sf WHERE sf.file_id = files.id)
+	//      )
+	UnmarkReferencedFiles(ctx context.Context) (int64, error)
+	//UseNonce
+	//
+	//  INSERT INTO request_nonces (nonce, expires_at)
+	//  VALUES ($1, $2)
+	//  ON CONFLICT (nonce) DO NOTHING
+	UseNonce(ctx context.Context, nonce []byte, expiresAt pgtype.Timestamptz) (int64, error)
 	//createFile
 	//
-	//  INSERT INTO files (name, executable, content_hash, conflict)
-	//  VALUES ($1, $2, $3, $4)
+	//  INSERT INTO files (name, executable, content_hash, size)
+	//  VALUES ($1, $2, $3, (
+	//      SELECT size FROM files
+	//      WHERE content_hash = $3
+	//          AND size IS NOT NULL
+	//      LIMIT 1
+	//  ))
 	//  RETURNING id
-	createFile(ctx context.Context, name string, executable bool, contentHash []byte, conflict bool) (int64, error)
+	createFile(ctx context.Context, name string, executable bool, contentHash []byte) (int64, error)
 	//findChanges
 	//
 	//  SELECT DISTINCT c.id
@@ -212,6 +960,7 @@ type Querier interface {
 	//  LEFT JOIN bookmarks AS b
 	//      ON b.change_id = c.id AND b.repository_id = c.repository_id
 	//  WHERE c.repository_id = $1
+	//      AND c.abandoned_at IS NULL
 	//      AND (c.name LIKE $2::text || '%' OR b.name = $2::text)
 	//  LIMIT $3
 	findChanges(ctx context.Context, repositoryID int32, search string, limit int32) ([]int64, error)
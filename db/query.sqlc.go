@@ -11,6 +11,22 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const abandonChange = `-- name: AbandonChange :exec
+UPDATE changes
+SET abandoned_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+// AbandonChange
+//
+//	UPDATE changes
+//	SET abandoned_at = CURRENT_TIMESTAMP
+//	WHERE id = $1
+func (q *Queries) AbandonChange(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, abandonChange, id)
+	return err
+}
+
 const addFileToChange = `-- name: AddFileToChange :exec
 INSERT INTO change_files (change_id, file_id)
 VALUES ($1, $2)
@@ -44,11 +60,17 @@ func (q *Queries) CheckIfChangesSameFileCount(ctx context.Context, changeID int6
 }
 
 const clearChange = `-- name: ClearChange :exec
+WITH cleared_conflicts AS (
+    DELETE FROM change_conflicts WHERE change_id = $1
+)
 DELETE FROM change_files WHERE change_id = $1
 `
 
 // ClearChange
 //
+//	WITH cleared_conflicts AS (
+//	    DELETE FROM change_conflicts WHERE change_id = $1
+//	)
 //	DELETE FROM change_files WHERE change_id = $1
 func (q *Queries) ClearChange(ctx context.Context, changeID int64) error {
 	_, err := q.db.Exec(ctx, clearChange, changeID)
@@ -118,13 +140,28 @@ func (q *Queries) CreateRepo(ctx context.Context, name string) (int32, error) {
 	return id, err
 }
 
+const deleteBookmark = `-- name: DeleteBookmark :execrows
+DELETE FROM bookmarks WHERE repository_id = $1 AND name = $2
+`
+
+// DeleteBookmark
+//
+//	DELETE FROM bookmarks WHERE repository_id = $1 AND name = $2
+func (q *Queries) DeleteBookmark(ctx context.Context, repositoryID int32, name string) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteBookmark, repositoryID, name)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const findChangeExact = `-- name: FindChangeExact :one
-SELECT id, repository_id, name, description, author, device, depth, created_at, updated_at FROM changes WHERE repository_id = $1 AND name = $2 LIMIT 1
+SELECT id, repository_id, name, description, author, device, depth, created_at, updated_at, abandoned_at FROM changes WHERE repository_id = $1 AND name = $2 LIMIT 1
 `
 
 // FindChangeExact
 //
-//	SELECT id, repository_id, name, description, author, device, depth, created_at, updated_at FROM changes WHERE repository_id = $1 AND name = $2 LIMIT 1
+//	SELECT id, repository_id, name, description, author, device, depth, created_at, updated_at, abandoned_at FROM changes WHERE repository_id = $1 AND name = $2 LIMIT 1
 func (q *Queries) FindChangeExact(ctx context.Context, repositoryID int32, name string) (Change, error) {
 	row := q.db.QueryRow(ctx, findChangeExact, repositoryID, name)
 	var i Change
@@ -138,6 +175,7 @@ func (q *Queries) FindChangeExact(ctx context.Context, repositoryID int32, name
 		&i.Depth,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.AbandonedAt,
 	)
 	return i, err
 }
@@ -191,7 +229,7 @@ WITH RECURSIVE ancestry AS (
   WHERE cr.parent_id IS NOT NULL
 )
 SELECT
-  c.id, c.repository_id, c.name, c.description, c.author, c.device, c.depth, c.created_at, c.updated_at,
+  c.id, c.repository_id, c.name, c.description, c.author, c.device, c.depth, c.created_at, c.updated_at, c.abandoned_at,
   cr.parent_id,
   cr.change_id
 FROM ancestry a
@@ -213,6 +251,7 @@ type GetAncestryOfChangeRow struct {
 	Depth        int64
 	CreatedAt    pgtype.Timestamptz
 	UpdatedAt    pgtype.Timestamptz
+	AbandonedAt  pgtype.Timestamptz
 	ParentID     *int64
 	ChangeID     *int64
 }
@@ -231,7 +270,7 @@ type GetAncestryOfChangeRow struct {
 //	  WHERE cr.parent_id IS NOT NULL
 //	)
 //	SELECT
-//	  c.id, c.repository_id, c.name, c.description, c.author, c.device, c.depth, c.created_at, c.updated_at,
+//	  c.id, c.repository_id, c.name, c.description, c.author, c.device, c.depth, c.created_at, c.updated_at, c.abandoned_at,
 //	  cr.parent_id,
 //	  cr.change_id
 //	FROM ancestry a
@@ -260,6 +299,7 @@ func (q *Queries) GetAncestryOfChange(ctx context.Context, column1 int64, limit
 			&i.Depth,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.AbandonedAt,
 			&i.ParentID,
 			&i.ChangeID,
 		); err != nil {
@@ -287,39 +327,6 @@ func (q *Queries) GetBookmark(ctx context.Context, repositoryID int32, name stri
 	return change_id, err
 }
 
-const getChangeConflicts = `-- name: GetChangeConflicts :many
-SELECT files.name
-FROM change_files
-INNER JOIN files ON files.id = change_files.file_id
-WHERE change_files.change_id = $1 AND files.conflict = true
-`
-
-// GetChangeConflicts
-//
-//	SELECT files.name
-//	FROM change_files
-//	INNER JOIN files ON files.id = change_files.file_id
-//	WHERE change_files.change_id = $1 AND files.conflict = true
-func (q *Queries) GetChangeConflicts(ctx context.Context, changeID int64) ([]string, error) {
-	rows, err := q.db.Query(ctx, getChangeConflicts, changeID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []string
-	for rows.Next() {
-		var name string
-		if err := rows.Scan(&name); err != nil {
-			return nil, err
-		}
-		items = append(items, name)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
-}
-
 const getChangeDepth = `-- name: GetChangeDepth :one
 SELECT depth FROM changes WHERE id = $1 AND repository_id = $2 LIMIT 1
 `
@@ -489,6 +496,67 @@ func (q *Queries) GetChangePrefix(ctx context.Context, iD int64, repositoryID in
 	return unique_identifier, err
 }
 
+const getChildren = `-- name: GetChildren :many
+SELECT cr.change_id
+FROM change_relations cr
+JOIN changes c
+    ON c.id = cr.change_id
+WHERE cr.parent_id = $1
+    AND c.abandoned_at IS NULL
+ORDER BY cr.change_id
+`
+
+// GetChildren
+//
+//	SELECT cr.change_id
+//	FROM change_relations cr
+//	JOIN changes c
+//	    ON c.id = cr.change_id
+//	WHERE cr.parent_id = $1
+//	    AND c.abandoned_at IS NULL
+//	ORDER BY cr.change_id
+func (q *Queries) GetChildren(ctx context.Context, parentID *int64) ([]int64, error) {
+	rows, err := q.db.Query(ctx, getChildren, parentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int64
+	for rows.Next() {
+		var change_id int64
+		if err := rows.Scan(&change_id); err != nil {
+			return nil, err
+		}
+		items = append(items, change_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getParentsMaxDepth = `-- name: GetParentsMaxDepth :one
+SELECT COALESCE(MAX(p.depth), -1)::bigint AS depth
+FROM change_relations cr
+JOIN changes p
+    ON p.id = cr.parent_id
+WHERE cr.change_id = $1
+`
+
+// GetParentsMaxDepth
+//
+//	SELECT COALESCE(MAX(p.depth), -1)::bigint AS depth
+//	FROM change_relations cr
+//	JOIN changes p
+//	    ON p.id = cr.parent_id
+//	WHERE cr.change_id = $1
+func (q *Queries) GetParentsMaxDepth(ctx context.Context, changeID int64) (int64, error) {
+	row := q.db.QueryRow(ctx, getParentsMaxDepth, changeID)
+	var depth int64
+	err := row.Scan(&depth)
+	return depth, err
+}
+
 const getRepoByName = `-- name: GetRepoByName :one
 SELECT id FROM repositories WHERE name = $1 LIMIT 1
 `
@@ -507,7 +575,9 @@ const hasChangeChild = `-- name: HasChangeChild :one
 SELECT EXISTS (
     SELECT 1
     FROM change_relations
-    WHERE parent_id = $1
+    INNER JOIN changes ON changes.id = change_relations.change_id
+    WHERE change_relations.parent_id = $1
+        AND changes.abandoned_at IS NULL
 )
 `
 
@@ -516,7 +586,9 @@ SELECT EXISTS (
 //	SELECT EXISTS (
 //	    SELECT 1
 //	    FROM change_relations
-//	    WHERE parent_id = $1
+//	    INNER JOIN changes ON changes.id = change_relations.change_id
+//	    WHERE change_relations.parent_id = $1
+//	        AND changes.abandoned_at IS NULL
 //	)
 func (q *Queries) HasChangeChild(ctx context.Context, parentID *int64) (bool, error) {
 	row := q.db.QueryRow(ctx, hasChangeChild, parentID)
@@ -528,9 +600,8 @@ func (q *Queries) HasChangeChild(ctx context.Context, parentID *int64) (bool, er
 const hasChangeConflicts = `-- name: HasChangeConflicts :one
 SELECT EXISTS (
     SELECT 1
-    FROM change_files
-    INNER JOIN files ON files.id = change_files.file_id
-    WHERE change_files.change_id = $1 AND files.conflict = true
+    FROM change_conflicts
+    WHERE change_conflicts.change_id = $1
     LIMIT 1
 )
 `
@@ -539,9 +610,8 @@ SELECT EXISTS (
 //
 //	SELECT EXISTS (
 //	    SELECT 1
-//	    FROM change_files
-//	    INNER JOIN files ON files.id = change_files.file_id
-//	    WHERE change_files.change_id = $1 AND files.conflict = true
+//	    FROM change_conflicts
+//	    WHERE change_conflicts.change_id = $1
 //	    LIMIT 1
 //	)
 func (q *Queries) HasChangeConflicts(ctx context.Context, changeID int64) (bool, error) {
@@ -551,6 +621,37 @@ func (q *Queries) HasChangeConflicts(ctx context.Context, changeID int64) (bool,
 	return exists, err
 }
 
+const listChangeBookmarks = `-- name: ListChangeBookmarks :many
+SELECT name FROM bookmarks
+WHERE repository_id = $1 AND change_id = $2
+ORDER BY name
+`
+
+// ListChangeBookmarks
+//
+//	SELECT name FROM bookmarks
+//	WHERE repository_id = $1 AND change_id = $2
+//	ORDER BY name
+func (q *Queries) ListChangeBookmarks(ctx context.Context, repositoryID int32, changeID int64) ([]string, error) {
+	rows, err := q.db.Query(ctx, listChangeBookmarks, repositoryID, changeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		items = append(items, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listChangeFiles = `-- name: ListChangeFiles :many
 SELECT files.name, files.executable, files.content_hash FROM change_files
 INNER JOIN files ON files.id = change_files.file_id
@@ -588,6 +689,91 @@ func (q *Queries) ListChangeFiles(ctx context.Context, changeID int64) ([]ListCh
 	return items, nil
 }
 
+const listParentDepths = `-- name: ListParentDepths :many
+SELECT p.id, p.depth
+FROM change_relations cr
+JOIN changes p
+    ON p.id = cr.parent_id
+WHERE cr.change_id = $1
+ORDER BY p.id
+`
+
+type ListParentDepthsRow struct {
+	ID    int64
+	Depth int64
+}
+
+// ListParentDepths
+//
+//	SELECT p.id, p.depth
+//	FROM change_relations cr
+//	JOIN changes p
+//	    ON p.id = cr.parent_id
+//	WHERE cr.change_id = $1
+//	ORDER BY p.id
+func (q *Queries) ListParentDepths(ctx context.Context, changeID int64) ([]ListParentDepthsRow, error) {
+	rows, err := q.db.Query(ctx, listParentDepths, changeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListParentDepthsRow
+	for rows.Next() {
+		var i ListParentDepthsRow
+		if err := rows.Scan(&i.ID, &i.Depth); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const removeChangeParent = `-- name: RemoveChangeParent :exec
+DELETE FROM change_relations
+WHERE change_id = $1 AND parent_id = $2
+`
+
+// RemoveChangeParent
+//
+//	DELETE FROM change_relations
+//	WHERE change_id = $1 AND parent_id = $2
+func (q *Queries) RemoveChangeParent(ctx context.Context, changeID int64, parentID *int64) error {
+	_, err := q.db.Exec(ctx, removeChangeParent, changeID, parentID)
+	return err
+}
+
+const renameBookmark = `-- name: RenameBookmark :execrows
+UPDATE bookmarks SET name = $3 WHERE repository_id = $1 AND name = $2
+`
+
+// RenameBookmark
+//
+//	UPDATE bookmarks SET name = $3 WHERE repository_id = $1 AND name = $2
+func (q *Queries) RenameBookmark(ctx context.Context, repositoryID int32, name string, name_2 string) (int64, error) {
+	result, err := q.db.Exec(ctx, renameBookmark, repositoryID, name, name_2)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const repoExists = `-- name: RepoExists :one
+SELECT EXISTS (SELECT 1 FROM repositories WHERE id = $1)
+`
+
+// RepoExists
+//
+//	SELECT EXISTS (SELECT 1 FROM repositories WHERE id = $1)
+func (q *Queries) RepoExists(ctx context.Context, id int32) (bool, error) {
+	row := q.db.QueryRow(ctx, repoExists, id)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
 const setBookmark = `-- name: SetBookmark :exec
 INSERT INTO bookmarks (repository_id, name, change_id)
 VALUES ($1, $2, $3)
@@ -667,6 +853,7 @@ FROM changes AS c
 LEFT JOIN bookmarks AS b
     ON b.change_id = c.id AND b.repository_id = c.repository_id
 WHERE c.repository_id = $1
+    AND c.abandoned_at IS NULL
     AND (c.name LIKE $2::text || '%' OR b.name = $2::text)
 LIMIT $3
 `
@@ -678,6 +865,7 @@ LIMIT $3
 //	LEFT JOIN bookmarks AS b
 //	    ON b.change_id = c.id AND b.repository_id = c.repository_id
 //	WHERE c.repository_id = $1
+//	    AND c.abandoned_at IS NULL
 //	    AND (c.name LIKE $2::text || '%' OR b.name = $2::text)
 //	LIMIT $3
 func (q *Queries) findChanges(ctx context.Context, repositoryID int32, search string, limit int32) ([]int64, error) {
@@ -0,0 +1,19 @@
+-- name: SetBookmarkRule :exec
+INSERT INTO bookmark_rules (repository_id, bookmark, descendants_only, no_conflicts, require_description, allowed_users)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (repository_id, bookmark)
+DO UPDATE SET descendants_only = $3, no_conflicts = $4, require_description = $5, allowed_users = $6;
+
+-- name: RemoveBookmarkRule :execrows
+DELETE FROM bookmark_rules
+WHERE repository_id = $1 AND bookmark = $2;
+
+-- name: GetBookmarkRule :one
+SELECT * FROM bookmark_rules
+WHERE repository_id = $1 AND bookmark = $2
+LIMIT 1;
+
+-- name: ListBookmarkRules :many
+SELECT * FROM bookmark_rules
+WHERE repository_id = $1
+ORDER BY bookmark;
@@ -0,0 +1,31 @@
+-- name: CreateConflict :one
+INSERT INTO conflicts (kind, executable, base_change, base_hash, base_executable)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id;
+
+-- name: AddConflictSide :exec
+INSERT INTO conflict_sides (conflict_id, position, change_name, content_hash, executable)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: AddChangeConflict :exec
+INSERT INTO change_conflicts (change_id, path, conflict_id)
+VALUES ($1, $2, $3);
+
+-- name: ListConflictSides :many
+SELECT * FROM conflict_sides
+WHERE conflict_id = $1
+ORDER BY position;
+
+-- name: ListChangeConflicts :many
+SELECT cc.path, c.id, c.kind, c.executable, c.base_change, c.base_hash, c.base_executable
+FROM change_conflicts cc
+INNER JOIN conflicts c ON c.id = cc.conflict_id
+WHERE cc.change_id = $1
+ORDER BY cc.path;
+
+-- name: ListChangeSnapshotConflicts :many
+SELECT sc.path, c.id, c.kind, c.executable, c.base_change, c.base_hash, c.base_executable
+FROM change_snapshot_conflicts sc
+INNER JOIN conflicts c ON c.id = sc.conflict_id
+WHERE sc.snapshot_id = $1
+ORDER BY sc.path;
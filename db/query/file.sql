@@ -12,6 +12,29 @@ WHERE content_hash = $1
 LIMIT 1;
 
 -- name: createFile :one
-INSERT INTO files (name, executable, content_hash, conflict)
-VALUES ($1, $2, $3, $4)
+INSERT INTO files (name, executable, content_hash, size)
+VALUES ($1, $2, $3, (
+    SELECT size FROM files
+    WHERE content_hash = $3
+        AND size IS NOT NULL
+    LIMIT 1
+))
 RETURNING id;
+
+-- name: ListChangeFilesWithSize :many
+SELECT files.name, files.executable, files.content_hash, files.size FROM change_files
+INNER JOIN files ON files.id = change_files.file_id
+WHERE change_files.change_id = $1
+ORDER BY files.name;
+
+-- name: GetChangeFile :one
+SELECT files.executable, files.content_hash FROM change_files
+INNER JOIN files ON files.id = change_files.file_id
+WHERE change_files.change_id = $1
+    AND files.name = $2
+LIMIT 1;
+
+-- name: SetFileSize :exec
+UPDATE files SET size = $2
+WHERE content_hash = $1
+    AND size IS NULL;
@@ -0,0 +1,19 @@
+-- name: ListContentHashes :many
+SELECT content_hash, bool_or(size IS NULL)::BOOLEAN AS missing_size FROM files
+GROUP BY content_hash;
+
+-- name: ListChangeDepths :many
+SELECT id, repository_id, name, depth FROM changes
+WHERE abandoned_at IS NULL;
+
+-- name: ListChangeRelations :many
+SELECT change_id, parent_id FROM change_relations
+WHERE parent_id IS NOT NULL;
+
+-- name: ListDanglingChangeRelations :many
+SELECT cr.change_id, cr.parent_id
+FROM change_relations cr
+JOIN changes c ON c.id = cr.change_id
+LEFT JOIN changes p ON p.id = cr.parent_id
+WHERE cr.parent_id IS NOT NULL
+    AND (p.id IS NULL OR p.repository_id <> c.repository_id);
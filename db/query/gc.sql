@@ -0,0 +1,116 @@
+-- name: TryLockGC :one
+SELECT pg_try_advisory_xact_lock(7031) AS locked;
+
+-- name: DeleteExpiredOperations :execrows
+DELETE FROM operations o
+WHERE o.created_at < @cutoff
+    AND (
+        NOT EXISTS (SELECT 1 FROM operation_changes oc WHERE oc.operation_id = o.id)
+        OR o.created_at < sqlc.narg('evolog_cutoff')
+    );
+
+-- name: DeleteOrphanedSnapshots :execrows
+DELETE FROM change_snapshots s
+WHERE NOT EXISTS (
+    SELECT 1 FROM operation_changes oc
+    WHERE oc.before_snapshot_id = s.id OR oc.after_snapshot_id = s.id
+);
+
+-- name: DeleteOrphanedConflicts :execrows
+DELETE FROM conflicts c
+WHERE NOT EXISTS (SELECT 1 FROM change_conflicts cc WHERE cc.conflict_id = c.id)
+    AND NOT EXISTS (SELECT 1 FROM change_snapshot_conflicts sc WHERE sc.conflict_id = c.id);
+
+-- name: CountExpiredOperations :one
+SELECT
+    (
+        SELECT COUNT(*) FROM operations o
+        WHERE o.created_at < @cutoff
+            AND (
+                NOT EXISTS (SELECT 1 FROM operation_changes oc WHERE oc.operation_id = o.id)
+                OR o.created_at < sqlc.narg('evolog_cutoff')
+            )
+    ) AS operations,
+    (
+        SELECT COUNT(*) FROM change_snapshots s
+        WHERE NOT EXISTS (
+            SELECT 1 FROM operation_changes oc
+            INNER JOIN operations o ON o.id = oc.operation_id
+            WHERE (o.created_at >= @cutoff OR sqlc.narg('evolog_cutoff') IS NULL OR o.created_at >= sqlc.narg('evolog_cutoff'))
+                AND (oc.before_snapshot_id = s.id OR oc.after_snapshot_id = s.id)
+        )
+    ) AS snapshots;
+
+-- name: MarkUnreferencedFiles :execrows
+UPDATE files
+SET unreferenced_since = now()
+WHERE unreferenced_since IS NULL
+    AND NOT EXISTS (SELECT 1 FROM change_files cf WHERE cf.file_id = files.id)
+    AND NOT EXISTS (SELECT 1 FROM change_snapshot_files sf WHERE sf.file_id = files.id);
+
+-- name: UnmarkReferencedFiles :execrows
+UPDATE files
+SET unreferenced_since = NULL
+WHERE unreferenced_since IS NOT NULL
+    AND (
+        EXISTS (SELECT 1 FROM change_files cf WHERE cf.file_id = files.id)
+        OR EXISTS (SELECT 1 FROM change_snapshot_files sf WHERE sf.file_id = files.id)
+    );
+
+-- name: UnmarkFile :exec
+UPDATE files
+SET unreferenced_since = NULL
+WHERE id = $1
+    AND unreferenced_since IS NOT NULL;
+
+-- name: CountUnreferencedFiles :one
+SELECT
+    COUNT(*) FILTER (WHERE unreferenced_since IS NULL) AS unmarked,
+    COUNT(*) FILTER (WHERE unreferenced_since < @cutoff) AS deletable
+FROM files
+WHERE NOT EXISTS (SELECT 1 FROM change_files cf WHERE cf.file_id = files.id)
+    AND NOT EXISTS (SELECT 1 FROM change_snapshot_files sf WHERE sf.file_id = files.id);
+
+-- name: DeleteUnreferencedFiles :execrows
+DELETE FROM files
+WHERE unreferenced_since < @cutoff
+    AND NOT EXISTS (SELECT 1 FROM change_files cf WHERE cf.file_id = files.id)
+    AND NOT EXISTS (SELECT 1 FROM change_snapshot_files sf WHERE sf.file_id = files.id);
+
+-- name: ListLiveContentHashes :many
+WITH live_files AS (
+    SELECT id, content_hash
+    FROM files
+    WHERE unreferenced_since IS NULL
+        OR unreferenced_since >= @cutoff
+        OR EXISTS (SELECT 1 FROM change_files cf WHERE cf.file_id = files.id)
+        OR EXISTS (SELECT 1 FROM change_snapshot_files sf WHERE sf.file_id = files.id)
+)
+SELECT live_files.content_hash FROM live_files
+UNION
+SELECT c.base_hash FROM conflicts c
+WHERE c.base_hash IS NOT NULL
+UNION
+SELECT cs.content_hash FROM conflict_sides cs
+WHERE cs.content_hash IS NOT NULL;
+
+-- name: ListUnreferencedBlobs :many
+SELECT * FROM unreferenced_blobs;
+
+-- name: MarkUnreferencedBlob :exec
+INSERT INTO unreferenced_blobs (content_hash)
+VALUES ($1)
+ON CONFLICT (content_hash) DO NOTHING;
+
+-- name: UnmarkBlob :exec
+DELETE FROM unreferenced_blobs
+WHERE content_hash = $1;
+
+-- name: SweepBlobMark :execrows
+DELETE FROM unreferenced_blobs
+WHERE content_hash = @content_hash
+    AND since < @cutoff;
+
+-- name: UnmarkBlobs :exec
+DELETE FROM unreferenced_blobs
+WHERE content_hash = ANY(@content_hashes::bytea[]);
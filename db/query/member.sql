@@ -0,0 +1,27 @@
+-- name: SetRepoMember :exec
+INSERT INTO repository_members (repository_id, user_id, role)
+VALUES ($1, $2, $3)
+ON CONFLICT (repository_id, user_id)
+DO UPDATE SET role = $3;
+
+-- name: RemoveRepoMember :execrows
+DELETE FROM repository_members
+WHERE repository_id = $1 AND user_id = $2;
+
+-- name: GetRepoMemberRole :one
+SELECT repository_members.role
+FROM repository_members
+INNER JOIN users ON users.id = repository_members.user_id
+WHERE repository_members.repository_id = $1 AND users.name = $2
+LIMIT 1;
+
+-- name: ListRepoMembers :many
+SELECT users.name, repository_members.role
+FROM repository_members
+INNER JOIN users ON users.id = repository_members.user_id
+WHERE repository_members.repository_id = $1
+ORDER BY users.name;
+
+-- name: CountRepoAdmins :one
+SELECT COUNT(*) FROM repository_members
+WHERE repository_id = $1 AND role = 'admin';
@@ -0,0 +1,8 @@
+-- name: UseNonce :execrows
+INSERT INTO request_nonces (nonce, expires_at)
+VALUES ($1, $2)
+ON CONFLICT (nonce) DO NOTHING;
+
+-- name: DeleteExpiredNonces :exec
+DELETE FROM request_nonces
+WHERE expires_at < now();
@@ -0,0 +1,213 @@
+-- name: CreateChangeSnapshot :one
+INSERT INTO change_snapshots (change_id, description, author, device, depth, parent_ids, abandoned_at)
+SELECT c.id, c.description, c.author, c.device, c.depth,
+    ARRAY(
+        SELECT cr.parent_id FROM change_relations cr
+        WHERE cr.change_id = c.id AND cr.parent_id IS NOT NULL
+        ORDER BY cr.parent_id
+    )::bigint[],
+    c.abandoned_at
+FROM changes c
+WHERE c.id = @change_id
+RETURNING id;
+
+-- name: AddChangeSnapshotFiles :exec
+WITH snapshot_conflicts AS (
+    INSERT INTO change_snapshot_conflicts (snapshot_id, path, conflict_id)
+    SELECT $1, change_conflicts.path, change_conflicts.conflict_id FROM change_conflicts
+    WHERE change_conflicts.change_id = $2
+)
+INSERT INTO change_snapshot_files (snapshot_id, file_id)
+SELECT $1, change_files.file_id FROM change_files
+WHERE change_files.change_id = $2;
+
+-- name: ChangeMatchesSnapshot :one
+SELECT (
+    s.description IS NOT DISTINCT FROM c.description
+    AND s.author = c.author
+    AND s.device = c.device
+    AND s.depth = c.depth
+    AND s.abandoned_at IS NOT DISTINCT FROM c.abandoned_at
+    AND s.parent_ids = ARRAY(
+        SELECT cr.parent_id FROM change_relations cr
+        WHERE cr.change_id = c.id AND cr.parent_id IS NOT NULL
+        ORDER BY cr.parent_id
+    )::bigint[]
+    AND NOT EXISTS (
+        SELECT cf.file_id FROM change_files cf WHERE cf.change_id = c.id
+        EXCEPT
+        SELECT sf.file_id FROM change_snapshot_files sf WHERE sf.snapshot_id = s.id
+    )
+    AND NOT EXISTS (
+        SELECT sf.file_id FROM change_snapshot_files sf WHERE sf.snapshot_id = s.id
+        EXCEPT
+        SELECT cf.file_id FROM change_files cf WHERE cf.change_id = c.id
+    )
+    AND NOT EXISTS (
+        SELECT cc.path, cc.conflict_id FROM change_conflicts cc WHERE cc.change_id = c.id
+        EXCEPT
+        SELECT sc.path, sc.conflict_id FROM change_snapshot_conflicts sc WHERE sc.snapshot_id = s.id
+    )
+    AND NOT EXISTS (
+        SELECT sc.path, sc.conflict_id FROM change_snapshot_conflicts sc WHERE sc.snapshot_id = s.id
+        EXCEPT
+        SELECT cc.path, cc.conflict_id FROM change_conflicts cc WHERE cc.change_id = c.id
+    )
+)::boolean AS matches
+FROM change_snapshots s
+INNER JOIN changes c ON c.id = s.change_id
+WHERE s.id = $1;
+
+-- name: DeleteChangeSnapshot :exec
+DELETE FROM change_snapshots WHERE id = $1;
+
+-- name: GetChangeSnapshot :one
+SELECT * FROM change_snapshots
+WHERE id = $1
+LIMIT 1;
+
+-- name: SnapshotsHaveSameFiles :one
+SELECT NOT EXISTS (
+    (
+        SELECT file_id FROM change_snapshot_files WHERE snapshot_id = @a
+        EXCEPT
+        SELECT file_id FROM change_snapshot_files WHERE snapshot_id = @b
+    )
+    UNION ALL
+    (
+        SELECT file_id FROM change_snapshot_files WHERE snapshot_id = @b
+        EXCEPT
+        SELECT file_id FROM change_snapshot_files WHERE snapshot_id = @a
+    )
+) AND NOT EXISTS (
+    (
+        SELECT path, conflict_id FROM change_snapshot_conflicts WHERE snapshot_id = @a
+        EXCEPT
+        SELECT path, conflict_id FROM change_snapshot_conflicts WHERE snapshot_id = @b
+    )
+    UNION ALL
+    (
+        SELECT path, conflict_id FROM change_snapshot_conflicts WHERE snapshot_id = @b
+        EXCEPT
+        SELECT path, conflict_id FROM change_snapshot_conflicts WHERE snapshot_id = @a
+    )
+) AS same;
+
+-- name: RestoreChangeSnapshotDescription :exec
+UPDATE changes
+SET description = s.description,
+    author = s.author,
+    device = s.device,
+    updated_at = CURRENT_TIMESTAMP
+FROM change_snapshots s
+WHERE s.id = $1
+    AND changes.id = s.change_id;
+
+-- name: RestoreChangeSnapshotAbandoned :exec
+UPDATE changes
+SET abandoned_at = s.abandoned_at
+FROM change_snapshots s
+WHERE s.id = $1
+    AND changes.id = s.change_id;
+
+-- name: ClearChangeParents :exec
+DELETE FROM change_relations
+WHERE change_id = $1
+    AND parent_id IS NOT NULL;
+
+-- name: RestoreChangeSnapshotParents :exec
+INSERT INTO change_relations (change_id, parent_id)
+SELECT s.change_id, unnest(s.parent_ids)
+FROM change_snapshots s
+WHERE s.id = $1;
+
+-- name: RestoreChangeSnapshotFiles :exec
+WITH restored_conflicts AS (
+    INSERT INTO change_conflicts (change_id, path, conflict_id)
+    SELECT s.change_id, sc.path, sc.conflict_id
+    FROM change_snapshot_conflicts sc
+    INNER JOIN change_snapshots s ON s.id = sc.snapshot_id
+    WHERE sc.snapshot_id = $1
+)
+INSERT INTO change_files (change_id, file_id)
+SELECT s.change_id, sf.file_id
+FROM change_snapshot_files sf
+INNER JOIN change_snapshots s ON s.id = sf.snapshot_id
+WHERE sf.snapshot_id = $1;
+
+-- name: CreateOperation :one
+INSERT INTO operations (repository_id, command, username, device)
+VALUES ($1, $2, $3, $4)
+RETURNING id;
+
+-- name: AddOperationBookmark :exec
+INSERT INTO operation_bookmarks (operation_id, name, before_change_id, after_change_id)
+VALUES ($1, $2, $3, $4);
+
+-- name: AddOperationChange :exec
+INSERT INTO operation_changes (operation_id, change_id, before_snapshot_id, after_snapshot_id)
+VALUES ($1, $2, $3, $4);
+
+-- name: ListOperations :many
+SELECT * FROM operations
+WHERE repository_id = $1
+ORDER BY id DESC
+LIMIT $2;
+
+-- name: GetOperation :one
+SELECT * FROM operations
+WHERE repository_id = $1 AND id = $2
+LIMIT 1;
+
+-- name: GetLatestOperation :one
+SELECT * FROM operations
+WHERE repository_id = $1 AND username = $2 AND device = $3
+    AND command <> 'push'
+ORDER BY id DESC
+LIMIT 1;
+
+-- name: ListOperationsAfter :many
+SELECT id FROM operations
+WHERE repository_id = $1 AND id > $2
+ORDER BY id DESC;
+
+-- name: ListOperationBookmarks :many
+SELECT * FROM operation_bookmarks
+WHERE operation_id = $1
+ORDER BY name;
+
+-- name: ListOperationChanges :many
+SELECT oc.change_id, oc.before_snapshot_id, oc.after_snapshot_id, c.name
+FROM operation_changes oc
+INNER JOIN changes c ON c.id = oc.change_id
+WHERE oc.operation_id = $1
+ORDER BY c.depth, c.id;
+
+-- name: ListBookmarkTargets :many
+SELECT name, change_id FROM bookmarks
+WHERE repository_id = $1
+ORDER BY name;
+
+-- name: IsChangeAbandoned :one
+SELECT abandoned_at IS NOT NULL AS abandoned FROM changes
+WHERE id = $1;
+
+-- name: ListChangeSnapshots :many
+SELECT s.id, s.description, s.created_at, oc.after_snapshot_id = s.id AS after,
+    o.id AS operation_id, o.command, o.username, o.device
+FROM operation_changes oc
+INNER JOIN change_snapshots s ON s.id = oc.after_snapshot_id OR s.id = oc.before_snapshot_id
+INNER JOIN operations o ON o.id = oc.operation_id
+WHERE oc.change_id = $1
+ORDER BY s.id;
+
+-- name: GetSnapshotChange :one
+SELECT s.change_id, changes.name FROM change_snapshots s
+INNER JOIN changes ON changes.id = s.change_id
+WHERE s.id = $1 AND changes.repository_id = $2
+LIMIT 1;
+
+-- name: ListChangeSnapshotFiles :many
+SELECT files.name, files.executable, files.content_hash FROM change_snapshot_files
+INNER JOIN files ON files.id = change_snapshot_files.file_id
+WHERE change_snapshot_files.snapshot_id = $1;
@@ -1,6 +1,9 @@
 -- name: GetRepoByName :one
 SELECT id FROM repositories WHERE name = $1 LIMIT 1;
 
+-- name: RepoExists :one
+SELECT EXISTS (SELECT 1 FROM repositories WHERE id = $1);
+
 -- name: CreateRepo :one
 INSERT INTO repositories (name)
 VALUES ($1)
@@ -85,13 +88,18 @@ WHERE c.id = $1
 LIMIT 1;
 
 -- name: ClearChange :exec
+WITH cleared_conflicts AS (
+    DELETE FROM change_conflicts WHERE change_id = $1
+)
 DELETE FROM change_files WHERE change_id = $1;
 
 -- name: HasChangeChild :one
 SELECT EXISTS (
     SELECT 1
     FROM change_relations
-    WHERE parent_id = $1
+    INNER JOIN changes ON changes.id = change_relations.change_id
+    WHERE change_relations.parent_id = $1
+        AND changes.abandoned_at IS NULL
 );
 
 -- name: GetChangeOwner :one
@@ -132,12 +140,19 @@ DO UPDATE SET change_id = $3;
 -- name: GetBookmark :one
 SELECT change_id FROM bookmarks WHERE repository_id = $1 AND name = $2 LIMIT 1;
 
+-- name: DeleteBookmark :execrows
+DELETE FROM bookmarks WHERE repository_id = $1 AND name = $2;
+
+-- name: RenameBookmark :execrows
+UPDATE bookmarks SET name = $3 WHERE repository_id = $1 AND name = $2;
+
 -- name: findChanges :many
 SELECT DISTINCT c.id
 FROM changes AS c
 LEFT JOIN bookmarks AS b
     ON b.change_id = c.id AND b.repository_id = c.repository_id
 WHERE c.repository_id = sqlc.arg('repository_id')
+    AND c.abandoned_at IS NULL
     AND (c.name LIKE sqlc.arg('search')::text || '%' OR b.name = sqlc.arg('search')::text)
 LIMIT sqlc.arg('limit');
 
@@ -152,6 +167,44 @@ UPDATE changes
 SET depth = $2
 WHERE id = $1;
 
+-- name: GetChildren :many
+SELECT cr.change_id
+FROM change_relations cr
+JOIN changes c
+    ON c.id = cr.change_id
+WHERE cr.parent_id = $1
+    AND c.abandoned_at IS NULL
+ORDER BY cr.change_id;
+
+-- name: GetParentsMaxDepth :one
+SELECT COALESCE(MAX(p.depth), -1)::bigint AS depth
+FROM change_relations cr
+JOIN changes p
+    ON p.id = cr.parent_id
+WHERE cr.change_id = $1;
+
+-- name: ListParentDepths :many
+SELECT p.id, p.depth
+FROM change_relations cr
+JOIN changes p
+    ON p.id = cr.parent_id
+WHERE cr.change_id = $1
+ORDER BY p.id;
+
+-- name: RemoveChangeParent :exec
+DELETE FROM change_relations
+WHERE change_id = $1 AND parent_id = $2;
+
+-- name: AbandonChange :exec
+UPDATE changes
+SET abandoned_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: ListChangeBookmarks :many
+SELECT name FROM bookmarks
+WHERE repository_id = $1 AND change_id = $2
+ORDER BY name;
+
 -- name: GetAllBookmarks :many
 SELECT * FROM bookmarks
 WHERE repository_id = $1 
@@ -160,14 +213,7 @@ WHERE repository_id = $1
 -- name: HasChangeConflicts :one
 SELECT EXISTS (
     SELECT 1
-    FROM change_files
-    INNER JOIN files ON files.id = change_files.file_id
-    WHERE change_files.change_id = $1 AND files.conflict = true
+    FROM change_conflicts
+    WHERE change_conflicts.change_id = $1
     LIMIT 1
 );
-
--- name: GetChangeConflicts :many
-SELECT files.name
-FROM change_files
-INNER JOIN files ON files.id = change_files.file_id
-WHERE change_files.change_id = $1 AND files.conflict = true;
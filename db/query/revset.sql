@@ -0,0 +1,101 @@
+-- name: RevsetAll :many
+SELECT id FROM changes WHERE repository_id = $1 AND abandoned_at IS NULL;
+
+-- name: RevsetAncestors :many
+WITH RECURSIVE ancestors AS (
+  SELECT unnest(@ids::bigint[]) AS id
+  UNION
+  SELECT cr.parent_id
+  FROM ancestors a
+  JOIN change_relations cr
+    ON cr.change_id = a.id
+  WHERE cr.parent_id IS NOT NULL
+)
+SELECT c.id
+FROM ancestors a
+JOIN changes c
+  ON c.id = a.id
+WHERE c.repository_id = @repository_id
+  AND c.abandoned_at IS NULL;
+
+-- name: RevsetDescendants :many
+WITH RECURSIVE descendants AS (
+  SELECT unnest(@ids::bigint[]) AS id
+  UNION
+  SELECT cr.change_id
+  FROM descendants d
+  JOIN change_relations cr
+    ON cr.parent_id = d.id
+)
+SELECT c.id
+FROM descendants d
+JOIN changes c
+  ON c.id = d.id
+WHERE c.repository_id = @repository_id
+  AND c.abandoned_at IS NULL;
+
+-- name: RevsetParents :many
+SELECT DISTINCT c.id
+FROM change_relations cr
+JOIN changes c
+  ON c.id = cr.parent_id
+WHERE cr.change_id = ANY(@ids::bigint[])
+  AND c.repository_id = @repository_id
+  AND c.abandoned_at IS NULL;
+
+-- name: RevsetAuthor :many
+SELECT id FROM changes WHERE repository_id = $1 AND author = $2 AND abandoned_at IS NULL;
+
+-- name: RevsetDescription :many
+SELECT id FROM changes
+WHERE repository_id = sqlc.arg('repository_id')
+  AND abandoned_at IS NULL
+  AND description ~ sqlc.arg('pattern')::text;
+
+-- name: RevsetConflicts :many
+SELECT DISTINCT c.id
+FROM changes c
+JOIN change_conflicts cc
+  ON cc.change_id = c.id
+WHERE c.repository_id = $1
+  AND c.abandoned_at IS NULL;
+
+-- name: RevsetHeads :many
+SELECT c.id
+FROM changes c
+WHERE c.repository_id = $1
+  AND c.abandoned_at IS NULL
+  AND NOT EXISTS (
+    SELECT 1
+    FROM change_relations cr
+    JOIN changes child
+      ON child.id = cr.change_id
+    WHERE cr.parent_id = c.id
+      AND child.abandoned_at IS NULL
+  );
+
+-- name: GetLogChanges :many
+SELECT
+  c.*,
+  cr.parent_id,
+  cr.change_id
+FROM changes c
+LEFT JOIN change_relations cr
+  ON cr.change_id = c.id
+WHERE c.repository_id = @repository_id
+  AND c.id = ANY(@ids::bigint[])
+ORDER BY c.depth DESC, c.id DESC;
+
+-- name: IsAncestor :one
+WITH RECURSIVE ancestors AS (
+  SELECT @descendant::bigint AS id
+  UNION
+  SELECT cr.parent_id
+  FROM ancestors a
+  JOIN change_relations cr
+    ON cr.change_id = a.id
+  JOIN changes c
+    ON c.id = cr.parent_id
+  WHERE c.depth >= (SELECT depth FROM changes WHERE id = @ancestor::bigint)
+)
+SELECT EXISTS (SELECT 1 FROM ancestors WHERE id = @ancestor::bigint);
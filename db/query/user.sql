@@ -0,0 +1,43 @@
+-- name: CreateUser :one
+INSERT INTO users (name, admin)
+VALUES ($1, $2)
+RETURNING id;
+
+-- name: GetUserByName :one
+SELECT * FROM users WHERE name = $1 LIMIT 1;
+
+-- name: LockUserRegistration :exec
+SELECT pg_advisory_xact_lock(7032);
+
+-- name: CountUsers :one
+SELECT COUNT(*) FROM users;
+
+-- name: AddUserKey :exec
+INSERT INTO user_keys (user_id, public_key, approved)
+VALUES ($1, $2, $3);
+
+-- name: GetUserKeyStatus :one
+SELECT user_keys.approved, users.admin
+FROM user_keys
+INNER JOIN users ON users.id = user_keys.user_id
+WHERE users.name = $1 AND user_keys.public_key = $2
+LIMIT 1;
+
+-- name: GetKeyOwner :one
+SELECT users.name
+FROM user_keys
+INNER JOIN users ON users.id = user_keys.user_id
+WHERE user_keys.public_key = $1
+LIMIT 1;
+
+-- name: ListPendingUserKeys :many
+SELECT user_keys.id, users.name, user_keys.public_key, user_keys.created_at
+FROM user_keys
+INNER JOIN users ON users.id = user_keys.user_id
+WHERE user_keys.approved = false
+ORDER BY user_keys.created_at;
+
+-- name: ApproveUserKey :exec
+UPDATE user_keys
+SET approved = true
+WHERE id = $1;
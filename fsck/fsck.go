@@ -0,0 +1,289 @@
+// Package fsck verifies the integrity of the database and the blob store.
+package fsck
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+
+	"github.com/tsukinoko-kun/pogo/db"
+	"github.com/tsukinoko-kun/pogo/repos"
+	"github.com/tsukinoko-kun/pogo/utils"
+)
+
+// Kind is a class of corruption.
+type Kind string
+
+const (
+	// KindMissingBlob is a content hash of a file row without a blob in the blob store.
+	KindMissingBlob Kind = "missing blob"
+	// KindCorruptBlob is a blob that can't be decompressed or doesn't match its content hash.
+	KindCorruptBlob Kind = "corrupt blob"
+	// KindDanglingParent is a change relation whose parent doesn't exist or belongs to another repository.
+	KindDanglingParent Kind = "dangling parent"
+	// KindCycle is a change that is its own ancestor.
+	KindCycle Kind = "cycle"
+	// KindDepthMismatch is a change whose depth is not one more than the maximum depth of its parents.
+	KindDepthMismatch Kind = "depth mismatch"
+	// KindMissingSize is a content hash of file rows that don't know the size of their content,
+	// like files pushed before sizes were recorded.
+	KindMissingSize Kind = "missing size"
+)
+
+type Problem struct {
+	Kind        Kind
+	Description string
+	// Repaired is true if the problem was fixed.
+	Repaired bool
+}
+
+func (p Problem) String() string {
+	if p.Repaired {
+		return fmt.Sprintf("%s: %s (repaired)", p.Kind, p.Description)
+	}
+	return fmt.Sprintf("%s: %s", p.Kind, p.Description)
+}
+
+type Options struct {
+	// Repair fixes the problems that can be fixed safely.
+	// Only depth mismatches and missing sizes are repaired, everything else needs a human decision.
+	Repair bool
+	// Problem is called for every problem found.
+	Problem func(Problem)
+}
+
+type Report struct {
+	Problems []Problem
+}
+
+// Unrepaired returns the number of problems that still exist.
+func (r Report) Unrepaired() int {
+	n := 0
+	for _, p := range r.Problems {
+		if !p.Repaired {
+			n++
+		}
+	}
+	return n
+}
+
+// Run checks the database and the blob store.
+func Run(ctx context.Context, opts Options) (Report, error) {
+	c := checker{ctx: ctx, opts: opts}
+
+	if err := c.checkBlobs(); err != nil {
+		return c.report, errors.Join(errors.New("check blobs"), err)
+	}
+	if err := c.checkRelations(); err != nil {
+		return c.report, errors.Join(errors.New("check change relations"), err)
+	}
+	if err := c.checkDepths(); err != nil {
+		return c.report, errors.Join(errors.New("check change depths"), err)
+	}
+
+	return c.report, nil
+}
+
+type checker struct {
+	ctx    context.Context
+	opts   Options
+	report Report
+}
+
+func (c *checker) add(p Problem) {
+	c.report.Problems = append(c.report.Problems, p)
+	if c.opts.Problem != nil {
+		c.opts.Problem(p)
+	}
+}
+
+// checkBlobs verifies that every content hash of a file row has a blob that decompresses to content with that hash.
+// The size of the content is recorded for file rows that don't know it.
+func (c *checker) checkBlobs() error {
+	files, err := db.Q.ListContentHashes(c.ctx)
+	if err != nil {
+		return errors.Join(errors.New("list content hashes"), err)
+	}
+	store := repos.BlobStore()
+	for _, file := range files {
+		contentHash := file.ContentHash
+		name := base64.RawURLEncoding.EncodeToString(contentHash)
+		f, err := store.Open(contentHash)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				c.add(Problem{Kind: KindMissingBlob, Description: name})
+				continue
+			}
+			return errors.Join(fmt.Errorf("open blob %s", name), err)
+		}
+		hasher := sha256.New()
+		size, err := io.Copy(hasher, utils.Decompress(f))
+		_ = f.Close()
+		if err != nil {
+			c.add(Problem{Kind: KindCorruptBlob, Description: fmt.Sprintf("%s: %s", name, err.Error())})
+			continue
+		}
+		if !bytes.Equal(hasher.Sum(nil), contentHash) {
+			c.add(Problem{Kind: KindCorruptBlob, Description: fmt.Sprintf("%s: content does not match its hash", name)})
+			continue
+		}
+		if file.MissingSize {
+			p := Problem{Kind: KindMissingSize, Description: fmt.Sprintf("%s has %d bytes", name, size)}
+			if c.opts.Repair {
+				if err := db.Q.SetFileSize(c.ctx, contentHash, &size); err != nil {
+					return errors.Join(fmt.Errorf("set size of %s", name), err)
+				}
+				p.Repaired = true
+			}
+			c.add(p)
+		}
+	}
+	return nil
+}
+
+// checkRelations finds parents that don't exist or belong to another repository.
+func (c *checker) checkRelations() error {
+	dangling, err := db.Q.ListDanglingChangeRelations(c.ctx)
+	if err != nil {
+		return errors.Join(errors.New("list dangling change relations"), err)
+	}
+	for _, relation := range dangling {
+		c.add(Problem{
+			Kind:        KindDanglingParent,
+			Description: fmt.Sprintf("change %d has parent %d, which does not exist in its repository", relation.ChangeID, *relation.ParentID),
+		})
+	}
+	return nil
+}
+
+type changeNode struct {
+	id           int64
+	repositoryID int32
+	name         string
+	depth        int64
+	parents      []int64
+}
+
+// checkDepths recomputes the depth of every change from its parents.
+// A change without parents has depth 0, every other change is one deeper than its deepest parent.
+func (c *checker) checkDepths() error {
+	changes, err := db.Q.ListChangeDepths(c.ctx)
+	if err != nil {
+		return errors.Join(errors.New("list changes"), err)
+	}
+	relations, err := db.Q.ListChangeRelations(c.ctx)
+	if err != nil {
+		return errors.Join(errors.New("list change relations"), err)
+	}
+
+	nodes := make(map[int64]*changeNode, len(changes))
+	for _, change := range changes {
+		nodes[change.ID] = &changeNode{
+			id:           change.ID,
+			repositoryID: change.RepositoryID,
+			name:         change.Name,
+			depth:        change.Depth,
+		}
+	}
+	for _, relation := range relations {
+		node, ok := nodes[relation.ChangeID]
+		if !ok || relation.ParentID == nil {
+			continue
+		}
+		parent, ok := nodes[*relation.ParentID]
+		if !ok || parent.repositoryID != node.repositoryID {
+			// reported as dangling parent
+			continue
+		}
+		node.parents = append(node.parents, parent.id)
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[int64]int, len(nodes))
+	expected := make(map[int64]int64, len(nodes))
+
+	// iterative depth-first search, deep histories would overflow the stack otherwise
+	for _, start := range changes {
+		if state[start.ID] != unvisited {
+			continue
+		}
+		stack := []int64{start.ID}
+		for len(stack) > 0 {
+			id := stack[len(stack)-1]
+			node := nodes[id]
+			if state[id] == unvisited {
+				state[id] = visiting
+				for _, parentID := range node.parents {
+					switch state[parentID] {
+					case unvisited:
+						stack = append(stack, parentID)
+					case visiting:
+						c.add(Problem{
+							Kind:        KindCycle,
+							Description: fmt.Sprintf("change %s (%d) is its own ancestor", node.name, node.id),
+						})
+					}
+				}
+				continue
+			}
+			stack = stack[:len(stack)-1]
+			if state[id] == done {
+				continue
+			}
+			state[id] = done
+			var depth int64
+			if len(node.parents) > 0 {
+				for _, parentID := range node.parents {
+					if parentDepth, ok := expected[parentID]; ok && parentDepth+1 > depth {
+						depth = parentDepth + 1
+					}
+				}
+			}
+			expected[id] = depth
+		}
+	}
+
+	var tx *db.TxQueries
+	if c.opts.Repair {
+		tx, err = db.Q.Begin(c.ctx)
+		if err != nil {
+			return errors.Join(errors.New("begin transaction"), err)
+		}
+		defer tx.Close()
+	}
+
+	for _, change := range changes {
+		node := nodes[change.ID]
+		depth := expected[change.ID]
+		if node.depth == depth {
+			continue
+		}
+		p := Problem{
+			Kind:        KindDepthMismatch,
+			Description: fmt.Sprintf("change %s (%d) has depth %d, expected %d", node.name, node.id, node.depth, depth),
+		}
+		if tx != nil {
+			if err := tx.SetChangeDepth(c.ctx, node.id, depth); err != nil {
+				return errors.Join(fmt.Errorf("set depth of change %d", node.id), err)
+			}
+			p.Repaired = true
+		}
+		c.add(p)
+	}
+
+	if tx != nil {
+		if err := tx.Commit(); err != nil {
+			return errors.Join(errors.New("commit transaction"), err)
+		}
+	}
+	return nil
+}
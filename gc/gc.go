@@ -0,0 +1,329 @@
+// Package gc removes file rows and blobs that are not referenced by any change anymore.
+// Operations older than the operation retention can't be undone anymore. They are deleted first, together with the
+// snapshots only they referenced, so the files of old snapshots become unreferenced too.
+// Operations that modified changes are kept for the evolution log until the evolog retention expires, which is never by default.
+//
+// Collection works in two phases that are at least one grace period apart.
+// Every run marks what is unreferenced and deletes what was marked more than one grace period ago and is still unreferenced.
+// Anything that is used again in the meantime loses its mark.
+// This way, a push that is in flight while the collector runs can't lose the rows and blobs it relies on.
+package gc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/tsukinoko-kun/pogo/blobstore"
+	"github.com/tsukinoko-kun/pogo/db"
+	"github.com/tsukinoko-kun/pogo/repos"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// DefaultGracePeriod is how long something must stay unreferenced before it is deleted, if nothing else is configured.
+const DefaultGracePeriod = 24 * time.Hour
+
+// DefaultOperationRetention is how long operations can be undone, if nothing else is configured.
+const DefaultOperationRetention = 30 * 24 * time.Hour
+
+var ErrAlreadyRunning = errors.New("garbage collection is already running")
+
+type Options struct {
+	// GracePeriod is how long something must stay unreferenced before it is deleted.
+	GracePeriod time.Duration
+	// OperationRetention is how long operations can be undone, 0 keeps them forever.
+	OperationRetention time.Duration
+	// EvologRetention is how long operations that modified changes are kept for the evolution log
+	// after their operation retention, 0 keeps them forever.
+	EvologRetention time.Duration
+	// DryRun only reports what would be marked and deleted.
+	DryRun bool
+}
+
+type Report struct {
+	DryRun            bool
+	OperationsDeleted int64
+	SnapshotsDeleted  int64
+	FilesMarked       int64
+	FilesDeleted      int64
+	BlobsMarked       int64
+	BlobsDeleted      int64
+	BytesFreed        int64
+}
+
+func (r Report) String() string {
+	verb := "deleted"
+	if r.DryRun {
+		verb = "would delete"
+	}
+	return fmt.Sprintf(
+		"%s %d operations, %d snapshots, %d file rows and %d blobs (%d bytes), marked %d file rows and %d blobs as unreferenced",
+		verb, r.OperationsDeleted, r.SnapshotsDeleted, r.FilesDeleted, r.BlobsDeleted, r.BytesFreed, r.FilesMarked, r.BlobsMarked,
+	)
+}
+
+// Run runs one garbage collection over the database and the blob store.
+// Only one run at a time is possible, even across multiple server instances.
+func Run(ctx context.Context, opts Options) (Report, error) {
+	// the lock is held as long as this transaction is open
+	lockTx, err := db.Q.Begin(ctx)
+	if err != nil {
+		return Report{}, errors.Join(errors.New("begin transaction"), err)
+	}
+	defer lockTx.Close()
+	if locked, err := lockTx.TryLockGC(ctx); err != nil {
+		return Report{}, errors.Join(errors.New("lock garbage collection"), err)
+	} else if !locked {
+		return Report{}, ErrAlreadyRunning
+	}
+
+	report := Report{DryRun: opts.DryRun}
+	cutoff := pgtype.Timestamptz{Time: time.Now().Add(-opts.GracePeriod), Valid: true}
+
+	if opts.OperationRetention > 0 {
+		retentionCutoff := pgtype.Timestamptz{Time: time.Now().Add(-opts.OperationRetention), Valid: true}
+		// NULL keeps the operations of the evolution log
+		var evologCutoff pgtype.Timestamptz
+		if opts.EvologRetention > 0 {
+			evologCutoff = pgtype.Timestamptz{Time: retentionCutoff.Time.Add(-opts.EvologRetention), Valid: true}
+		}
+		if err := collectOperations(ctx, retentionCutoff, evologCutoff, opts.DryRun, &report); err != nil {
+			return report, errors.Join(errors.New("collect operations"), err)
+		}
+	}
+	if err := collectFiles(ctx, cutoff, opts.DryRun, &report); err != nil {
+		return report, errors.Join(errors.New("collect file rows"), err)
+	}
+	if err := collectBlobs(ctx, dbBlobMarks{}, repos.BlobStore(), cutoff.Time, opts.DryRun, &report); err != nil {
+		return report, errors.Join(errors.New("collect blobs"), err)
+	}
+
+	return report, nil
+}
+
+// collectOperations deletes operations created before cutoff and the snapshots no operation references anymore.
+// Operations that modified changes are kept until evologCutoff, so the evolution log keeps their snapshots.
+func collectOperations(ctx context.Context, cutoff pgtype.Timestamptz, evologCutoff pgtype.Timestamptz, dryRun bool, report *Report) error {
+	if dryRun {
+		counts, err := db.Q.CountExpiredOperations(ctx, cutoff, evologCutoff)
+		if err != nil {
+			return errors.Join(errors.New("count expired operations"), err)
+		}
+		report.OperationsDeleted = counts.Operations
+		report.SnapshotsDeleted = counts.Snapshots
+		return nil
+	}
+
+	// the changes and bookmarks of the operations are deleted with them
+	deleted, err := db.Q.DeleteExpiredOperations(ctx, cutoff, evologCutoff)
+	if err != nil {
+		return errors.Join(errors.New("delete expired operations"), err)
+	}
+	report.OperationsDeleted = deleted
+	// the files of the snapshots are deleted with them, so their file rows can be marked right away
+	deleted, err = db.Q.DeleteOrphanedSnapshots(ctx)
+	if err != nil {
+		return errors.Join(errors.New("delete orphaned snapshots"), err)
+	}
+	report.SnapshotsDeleted = deleted
+	// conflicts are created in the transaction of the merge that references them, so the unreferenced ones are gone for good
+	if _, err := db.Q.DeleteOrphanedConflicts(ctx); err != nil {
+		return errors.Join(errors.New("delete orphaned conflicts"), err)
+	}
+	return nil
+}
+
+// collectFiles deletes file rows that are not part of any change.
+func collectFiles(ctx context.Context, cutoff pgtype.Timestamptz, dryRun bool, report *Report) error {
+	if dryRun {
+		counts, err := db.Q.CountUnreferencedFiles(ctx, cutoff)
+		if err != nil {
+			return errors.Join(errors.New("count unreferenced file rows"), err)
+		}
+		report.FilesMarked = counts.Unmarked
+		report.FilesDeleted = counts.Deletable
+		return nil
+	}
+
+	if _, err := db.Q.UnmarkReferencedFiles(ctx); err != nil {
+		return errors.Join(errors.New("unmark referenced file rows"), err)
+	}
+	deleted, err := db.Q.DeleteUnreferencedFiles(ctx, cutoff)
+	if err != nil {
+		return errors.Join(errors.New("delete unreferenced file rows"), err)
+	}
+	report.FilesDeleted = deleted
+	marked, err := db.Q.MarkUnreferencedFiles(ctx)
+	if err != nil {
+		return errors.Join(errors.New("mark unreferenced file rows"), err)
+	}
+	report.FilesMarked = marked
+	return nil
+}
+
+// blobMarks keeps track of unreferenced blobs, it is backed by the database outside of tests.
+type blobMarks interface {
+	// live returns the content hashes that file rows still point at.
+	live(ctx context.Context, cutoff time.Time) ([][]byte, error)
+	// marked returns when each marked blob was marked.
+	marked(ctx context.Context) (map[string]time.Time, error)
+	mark(ctx context.Context, contentHash []byte) error
+	unmark(ctx context.Context, contentHash []byte) error
+	// sweep removes the mark of a blob if it was set before cutoff and calls deleteBlob while the mark is locked.
+	// A concurrent unmark waits for the sweep, so a blob that was unmarked in the meantime is never deleted.
+	// It reports whether the mark was removed.
+	sweep(ctx context.Context, contentHash []byte, cutoff time.Time, deleteBlob func() error) (bool, error)
+}
+
+type dbBlobMarks struct{}
+
+func (dbBlobMarks) live(ctx context.Context, cutoff time.Time) ([][]byte, error) {
+	return db.Q.ListLiveContentHashes(ctx, pgtype.Timestamptz{Time: cutoff, Valid: true})
+}
+
+func (dbBlobMarks) marked(ctx context.Context) (map[string]time.Time, error) {
+	marks, err := db.Q.ListUnreferencedBlobs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	markedSince := make(map[string]time.Time, len(marks))
+	for _, mark := range marks {
+		markedSince[string(mark.ContentHash)] = mark.Since.Time
+	}
+	return markedSince, nil
+}
+
+func (dbBlobMarks) mark(ctx context.Context, contentHash []byte) error {
+	return db.Q.MarkUnreferencedBlob(ctx, contentHash)
+}
+
+func (dbBlobMarks) unmark(ctx context.Context, contentHash []byte) error {
+	return db.Q.UnmarkBlob(ctx, contentHash)
+}
+
+func (dbBlobMarks) sweep(ctx context.Context, contentHash []byte, cutoff time.Time, deleteBlob func() error) (bool, error) {
+	// the deleted mark row stays locked until the transaction ends
+	tx, err := db.Q.Begin(ctx)
+	if err != nil {
+		return false, errors.Join(errors.New("begin transaction"), err)
+	}
+	defer tx.Close()
+
+	swept, err := tx.SweepBlobMark(ctx, contentHash, pgtype.Timestamptz{Time: cutoff, Valid: true})
+	if err != nil {
+		return false, errors.Join(errors.New("sweep blob mark"), err)
+	}
+	if swept == 0 {
+		return false, nil
+	}
+	if err := deleteBlob(); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, errors.Join(errors.New("commit transaction"), err)
+	}
+	return true, nil
+}
+
+// collectBlobs deletes blobs whose content hash no file row points at.
+// Marks are only trusted once the mark is removed again, everything else can change while the store is listed.
+func collectBlobs(ctx context.Context, marks blobMarks, store blobstore.BlobStore, cutoff time.Time, dryRun bool, report *Report) error {
+	live, err := marks.live(ctx, cutoff)
+	if err != nil {
+		return errors.Join(errors.New("list content hashes"), err)
+	}
+	liveSet := make(map[string]struct{}, len(live))
+	for _, contentHash := range live {
+		liveSet[string(contentHash)] = struct{}{}
+	}
+
+	markedSince, err := marks.marked(ctx)
+	if err != nil {
+		return errors.Join(errors.New("list unreferenced blobs"), err)
+	}
+
+	seen := make(map[string]struct{}, len(markedSince))
+	err = store.List(func(contentHash []byte, info blobstore.BlobInfo) error {
+		k := string(contentHash)
+		seen[k] = struct{}{}
+		since, marked := markedSince[k]
+
+		if _, ok := liveSet[k]; ok {
+			if marked && !dryRun {
+				return marks.unmark(ctx, contentHash)
+			}
+			return nil
+		}
+
+		if !marked {
+			report.BlobsMarked++
+			if dryRun {
+				return nil
+			}
+			return marks.mark(ctx, contentHash)
+		}
+
+		if !since.Before(cutoff) {
+			return nil
+		}
+		if dryRun {
+			report.BlobsDeleted++
+			report.BytesFreed += info.Size
+			return nil
+		}
+		// the mark may be gone by now, the blob is only deleted if it still is expired
+		swept, err := marks.sweep(ctx, contentHash, cutoff, func() error {
+			return store.Delete(contentHash)
+		})
+		if err != nil {
+			return err
+		}
+		if swept {
+			report.BlobsDeleted++
+			report.BytesFreed += info.Size
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		return nil
+	}
+	// forget marks of blobs that are gone
+	for k := range markedSince {
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		if err := marks.unmark(ctx, []byte(k)); err != nil {
+			return errors.Join(errors.New("unmark missing blob"), err)
+		}
+	}
+	return nil
+}
+
+// RunPeriodically runs the garbage collection every interval until ctx is done.
+func RunPeriodically(ctx context.Context, interval time.Duration, opts Options) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := Run(ctx, opts)
+			if err != nil {
+				if errors.Is(err, ErrAlreadyRunning) {
+					continue
+				}
+				log.Println("garbage collection failed:", err)
+				continue
+			}
+			log.Println("garbage collection:", report)
+		}
+	}
+}
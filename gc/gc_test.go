@@ -0,0 +1,195 @@
+package gc
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"testing"
+	"time"
+
+	"github.com/tsukinoko-kun/pogo/blobstore"
+)
+
+// fakeMarks keeps the marks in memory. onSweep runs right before a mark is swept,
+// like a request that is handled concurrently.
+type fakeMarks struct {
+	liveHashes [][]byte
+	marks      map[string]time.Time
+	onSweep    func(contentHash []byte)
+}
+
+func (f *fakeMarks) live(context.Context, time.Time) ([][]byte, error) {
+	return f.liveHashes, nil
+}
+
+func (f *fakeMarks) marked(context.Context) (map[string]time.Time, error) {
+	marks := make(map[string]time.Time, len(f.marks))
+	for k, since := range f.marks {
+		marks[k] = since
+	}
+	return marks, nil
+}
+
+func (f *fakeMarks) mark(_ context.Context, contentHash []byte) error {
+	if _, ok := f.marks[string(contentHash)]; !ok {
+		f.marks[string(contentHash)] = time.Now()
+	}
+	return nil
+}
+
+func (f *fakeMarks) unmark(_ context.Context, contentHash []byte) error {
+	delete(f.marks, string(contentHash))
+	return nil
+}
+
+func (f *fakeMarks) sweep(_ context.Context, contentHash []byte, cutoff time.Time, deleteBlob func() error) (bool, error) {
+	if f.onSweep != nil {
+		f.onSweep(contentHash)
+	}
+	since, ok := f.marks[string(contentHash)]
+	if !ok || !since.Before(cutoff) {
+		return false, nil
+	}
+	if err := deleteBlob(); err != nil {
+		return false, err
+	}
+	delete(f.marks, string(contentHash))
+	return true, nil
+}
+
+type gcTest struct {
+	store *blobstore.FileSystem
+	marks *fakeMarks
+}
+
+func newGCTest(t *testing.T) *gcTest {
+	return &gcTest{
+		store: blobstore.NewFileSystem(t.TempDir()),
+		marks: &fakeMarks{marks: make(map[string]time.Time)},
+	}
+}
+
+func (g *gcTest) blob(t *testing.T, content string) []byte {
+	h := sha256.Sum256([]byte(content))
+	if err := g.store.Put(h[:], bytes.NewReader([]byte(content))); err != nil {
+		t.Fatal(err)
+	}
+	return h[:]
+}
+
+func (g *gcTest) exists(t *testing.T, contentHash []byte) bool {
+	exists, err := g.store.Exists(contentHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return exists
+}
+
+func (g *gcTest) collect(t *testing.T, cutoff time.Time, dryRun bool) Report {
+	var report Report
+	if err := collectBlobs(context.Background(), g.marks, g.store, cutoff, dryRun, &report); err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
+
+func TestCollectBlobsMarks(t *testing.T) {
+	g := newGCTest(t)
+	live := g.blob(t, "live")
+	unreferenced := g.blob(t, "unreferenced")
+	g.marks.liveHashes = [][]byte{live}
+
+	report := g.collect(t, time.Now().Add(-time.Hour), false)
+	if report.BlobsMarked != 1 || report.BlobsDeleted != 0 {
+		t.Fatalf("one blob should be marked and none deleted, got %+v", report)
+	}
+	if _, ok := g.marks.marks[string(unreferenced)]; !ok {
+		t.Fatal("the unreferenced blob should be marked")
+	}
+	if _, ok := g.marks.marks[string(live)]; ok {
+		t.Fatal("the live blob should not be marked")
+	}
+	if !g.exists(t, unreferenced) || !g.exists(t, live) {
+		t.Fatal("marking must not delete blobs")
+	}
+}
+
+func TestCollectBlobsGracePeriod(t *testing.T) {
+	g := newGCTest(t)
+	cutoff := time.Now().Add(-time.Hour)
+	recent := g.blob(t, "recent")
+	expired := g.blob(t, "expired")
+	usedAgain := g.blob(t, "used again")
+	g.marks.marks[string(recent)] = cutoff.Add(time.Minute)
+	g.marks.marks[string(expired)] = cutoff.Add(-time.Minute)
+	g.marks.marks[string(usedAgain)] = cutoff.Add(-time.Minute)
+	g.marks.liveHashes = [][]byte{usedAgain}
+
+	report := g.collect(t, cutoff, false)
+	if report.BlobsDeleted != 1 || report.BytesFreed != int64(len("expired")) {
+		t.Fatalf("only the expired blob should be deleted, got %+v", report)
+	}
+	if g.exists(t, expired) {
+		t.Fatal("the expired blob should be deleted")
+	}
+	if !g.exists(t, recent) {
+		t.Fatal("a blob within the grace period should be kept")
+	}
+	if !g.exists(t, usedAgain) {
+		t.Fatal("a blob that is used again should be kept")
+	}
+	if _, ok := g.marks.marks[string(usedAgain)]; ok {
+		t.Fatal("a blob that is used again should lose its mark")
+	}
+	if _, ok := g.marks.marks[string(expired)]; ok {
+		t.Fatal("the mark of a deleted blob should be removed")
+	}
+}
+
+func TestCollectBlobsSweepUnmarkedConcurrently(t *testing.T) {
+	g := newGCTest(t)
+	cutoff := time.Now().Add(-time.Hour)
+	blob := g.blob(t, "checked by a push")
+	g.marks.marks[string(blob)] = cutoff.Add(-time.Minute)
+	// check_files_exists unmarks the blob after the marks were listed
+	g.marks.onSweep = func(contentHash []byte) {
+		delete(g.marks.marks, string(contentHash))
+	}
+
+	report := g.collect(t, cutoff, false)
+	if report.BlobsDeleted != 0 {
+		t.Fatalf("no blob should be deleted, got %+v", report)
+	}
+	if !g.exists(t, blob) {
+		t.Fatal("a blob that was unmarked during the collection must not be deleted")
+	}
+}
+
+func TestCollectBlobsDryRun(t *testing.T) {
+	g := newGCTest(t)
+	cutoff := time.Now().Add(-time.Hour)
+	expired := g.blob(t, "expired")
+	unmarked := g.blob(t, "unmarked")
+	g.marks.marks[string(expired)] = cutoff.Add(-time.Minute)
+
+	report := g.collect(t, cutoff, true)
+	if report.BlobsDeleted != 1 || report.BlobsMarked != 1 {
+		t.Fatalf("dry run should report one deletion and one mark, got %+v", report)
+	}
+	if !g.exists(t, expired) || !g.exists(t, unmarked) {
+		t.Fatal("dry run must not delete blobs")
+	}
+	if _, ok := g.marks.marks[string(unmarked)]; ok {
+		t.Fatal("dry run must not mark blobs")
+	}
+}
+
+func TestCollectBlobsForgetsMissingBlobs(t *testing.T) {
+	g := newGCTest(t)
+	g.marks.marks["gone"] = time.Now()
+
+	g.collect(t, time.Now().Add(-time.Hour), false)
+	if _, ok := g.marks.marks["gone"]; ok {
+		t.Fatal("the mark of a blob that is gone should be removed")
+	}
+}
@@ -0,0 +1,212 @@
+// Package loggraph renders the change graph returned by the log RPC.
+package loggraph
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nulab/autog"
+	"github.com/nulab/autog/graph"
+	"github.com/tsukinoko-kun/pogo/colors"
+	"github.com/tsukinoko-kun/pogo/protos"
+	"github.com/tsukinoko-kun/pogo/runedrawer"
+	"golang.org/x/term"
+)
+
+// DefaultWidth is used when the output width is unknown.
+const DefaultWidth = 80
+
+type Options struct {
+	// TimeZone used to display timestamps. Defaults to time.Local.
+	TimeZone *time.Location
+	// Width of the output in columns. Descriptions are truncated to fit.
+	// Values <= 0 fall back to DefaultWidth.
+	Width int
+	// Colors enables ANSI escape sequences.
+	Colors bool
+}
+
+// TerminalOptions returns Options matching the terminal f is connected to.
+// Colors are disabled if f is not a terminal or NO_COLOR is set.
+func TerminalOptions(f *os.File) Options {
+	opts := Options{
+		TimeZone: time.Local,
+		Colors:   colors.Enabled(f),
+	}
+	if width, _, err := term.GetSize(int(f.Fd())); err == nil {
+		opts.Width = width
+	}
+	return opts
+}
+
+// Render lays out the change graph of log and writes it to w.
+func Render(w io.Writer, log *protos.LogResponse, opts Options) error {
+	if opts.TimeZone == nil {
+		opts.TimeZone = time.Local
+	}
+	if opts.Width <= 0 {
+		opts.Width = DefaultWidth
+	}
+	color := func(c string) string {
+		if opts.Colors {
+			return c
+		}
+		return ""
+	}
+
+	changes := log.GetChanges()
+	if len(changes) == 0 {
+		// a revset can select nothing
+		return nil
+	}
+
+	if len(changes) == 1 {
+		c := changes[0]
+		_, _ = fmt.Fprint(w, color(colors.Magenta)+c.Prefix+color(colors.Reset))
+		_, _ = fmt.Fprintln(w, color(colors.BrightBlack)+c.Name[len(c.Prefix):]+color(colors.Reset))
+		if c.Description != nil {
+			_, _ = fmt.Fprintln(w, *c.Description)
+		} else {
+			_, _ = fmt.Fprintln(w, color(colors.Green)+"(no description set)"+color(colors.Reset))
+		}
+		_, _ = fmt.Fprintln(w, color(colors.BrightBlack)+
+			fmt.Sprintf(
+				"%s %s",
+				c.Author,
+				c.UpdatedAt.AsTime().In(opts.TimeZone).Format(time.DateTime),
+			)+color(colors.Reset),
+		)
+		return nil
+	}
+
+	// construct the graph object from an ordered adjacency list
+	nodeIdChangeMap := make(map[string]*protos.LogChange, len(changes))
+	for _, c := range changes {
+		nodeIdChangeMap[fmt.Sprintf("%d", c.Id)] = c
+	}
+
+	var adjacencyList [][]string
+	for _, e := range log.GetEdges() {
+		stringId := fmt.Sprintf("%d", e.ChangeId)
+		if _, ok := nodeIdChangeMap[stringId]; !ok {
+			continue
+		}
+		parentId := "~"
+		if e.ParentId != nil {
+			if _, ok := nodeIdChangeMap[fmt.Sprintf("%d", *e.ParentId)]; ok {
+				parentId = fmt.Sprintf("%d", *e.ParentId)
+			}
+		}
+		adjacencyList = append(adjacencyList, []string{stringId, parentId})
+	}
+
+	if len(adjacencyList) == 0 {
+		return errors.New("found history, but generated adjacency list is empty")
+	}
+
+	// obtain a graph.Source (here by converting the input to EdgeSlice)
+	src := graph.EdgeSlice(adjacencyList)
+
+	// run the default autolayout pipeline
+	layout := autog.Layout(
+		src,
+		autog.WithNodeFixedSize(0, 0),
+		autog.WithOrdering(autog.OrderingWMedian),
+		autog.WithPositioning(autog.PositioningBrandesKoepf),
+		autog.WithEdgeRouting(autog.EdgeRoutingOrtho),
+		autog.WithNodeVerticalSpacing(2),
+		autog.WithNodeSpacing(4),
+		autog.WithLayerSpacing(0),
+	)
+
+	drawer := runedrawer.New()
+
+	for _, e := range layout.Edges {
+		if e.FromID == "~" || e.ToID == "~" {
+			continue
+		}
+		var spline runedrawer.Spline
+		for _, p := range e.Points {
+			spline = append(spline, runedrawer.Point{
+				X: int(math.Round(p[0])),
+				Y: int(math.Round(p[1])),
+			})
+		}
+		drawer.DrawSpline(spline)
+	}
+	drawer.EncodeCorners()
+
+	changeMinHeight := make(map[int64]int)
+	for _, n := range layout.Nodes {
+		if n.ID == "~" {
+			continue
+		}
+		x := int(math.Round(n.X))
+		y := int(math.Round(n.Y))
+		change := nodeIdChangeMap[n.ID]
+		if prevY, ok := changeMinHeight[change.Id]; ok {
+			changeMinHeight[change.Id] = min(prevY, y)
+		} else {
+			changeMinHeight[change.Id] = y
+		}
+		var nodeColor string
+		if change.HasConflicts {
+			nodeColor = colors.Red
+		} else {
+			nodeColor = colors.White
+		}
+
+		if change.Id == log.Head {
+			drawer.WriteX(x, y, color(nodeColor), "●", color(colors.Reset))
+		} else {
+			drawer.WriteX(x, y, color(nodeColor), "○", color(colors.Reset))
+		}
+	}
+
+	// write change details
+	paddingLeft := drawer.Width() + 2
+	for _, change := range changes {
+		height := 0
+		if minHeight, ok := changeMinHeight[change.Id]; ok {
+			height = minHeight
+		}
+
+		suffix := change.Name[len(change.Prefix):]
+		drawer.WriteX(paddingLeft, height, color(colors.Magenta), change.Prefix, color(colors.Reset))
+		drawer.WriteX(paddingLeft+len(change.Prefix), height, color(colors.BrightBlack), suffix, color(colors.Reset))
+		meta := fmt.Sprintf(
+			"%s %s",
+			change.Author,
+			change.UpdatedAt.AsTime().In(opts.TimeZone).Format(time.DateTime),
+		)
+		if change.HasConflicts && change.Id == log.Head {
+			meta += " ⚠️ " + color(colors.Red) + "conflict" + color(colors.Reset)
+		}
+		column := paddingLeft + len(change.Name) + 1
+		drawer.WriteX(column, height+1, color(colors.BrightBlack), meta, color(colors.Reset))
+		if change.Description != nil {
+			drawer.Write(column, height, firstLine(*change.Description, opts.Width-column))
+		} else {
+			drawer.WriteX(column, height, color(colors.Green), "(no description set)", color(colors.Reset))
+		}
+	}
+
+	_, _ = fmt.Fprintln(w, drawer.String())
+
+	return nil
+}
+
+// firstLine returns the first line of s, truncated to at most width runes.
+func firstLine(s string, width int) string {
+	width = max(width, 16)
+	fl := []rune(strings.TrimSpace(strings.Split(s, "\n")[0]))
+	if len(fl) > width {
+		return strings.TrimSpace(string(fl[:width-1])) + "…"
+	}
+	return string(fl)
+}
@@ -0,0 +1,183 @@
+package loggraph
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tsukinoko-kun/pogo/protos"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var testTime = time.Date(2024, time.January, 2, 3, 4, 5, 0, time.UTC)
+
+func testChange(id int64, name string, description string) *protos.LogChange {
+	c := &protos.LogChange{
+		Id:        id,
+		Name:      name,
+		Prefix:    name[:2],
+		Author:    "alice",
+		UpdatedAt: timestamppb.New(testTime),
+	}
+	if description != "" {
+		c.Description = &description
+	}
+	return c
+}
+
+func testEdge(changeId int64, parentId int64) *protos.LogEdge {
+	if parentId == 0 {
+		return &protos.LogEdge{ChangeId: changeId}
+	}
+	return &protos.LogEdge{ChangeId: changeId, ParentId: &parentId}
+}
+
+func render(t *testing.T, log *protos.LogResponse) []string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := Render(&buf, log, Options{TimeZone: time.UTC}); err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+}
+
+// lineOf returns the index of the first line that contains s.
+func lineOf(t *testing.T, lines []string, s string) int {
+	t.Helper()
+	for i, line := range lines {
+		if strings.Contains(line, s) {
+			return i
+		}
+	}
+	t.Fatalf("%q not found in\n%s", s, strings.Join(lines, "\n"))
+	return -1
+}
+
+func TestRenderEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Render(&buf, &protos.LogResponse{}, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("an empty log should render nothing, got %q", buf.String())
+	}
+}
+
+func TestRenderSingleChange(t *testing.T) {
+	lines := render(t, &protos.LogResponse{
+		Head:    1,
+		Changes: []*protos.LogChange{testChange(1, "abcdef", "")},
+		Edges:   []*protos.LogEdge{testEdge(1, 0)},
+	})
+	want := []string{"abcdef", "(no description set)", "alice 2024-01-02 03:04:05"}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("single change should render as\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(lines, "\n"))
+	}
+}
+
+func TestRenderLinear(t *testing.T) {
+	lines := render(t, &protos.LogResponse{
+		Head: 3,
+		Changes: []*protos.LogChange{
+			testChange(3, "cccccc", "third"),
+			testChange(2, "bbbbbb", "second"),
+			testChange(1, "aaaaaa", "first"),
+		},
+		Edges: []*protos.LogEdge{testEdge(3, 2), testEdge(2, 1), testEdge(1, 0)},
+	})
+
+	third, second, first := lineOf(t, lines, "cccccc"), lineOf(t, lines, "bbbbbb"), lineOf(t, lines, "aaaaaa")
+	if !(third < second && second < first) {
+		t.Fatalf("children should be rendered above their parents\n%s", strings.Join(lines, "\n"))
+	}
+	if !strings.Contains(lines[third], "●") || !strings.Contains(lines[third], "third") {
+		t.Fatalf("the head should be marked with ● next to its description, got %q", lines[third])
+	}
+	for _, i := range []int{second, first} {
+		if !strings.Contains(lines[i], "○") || strings.Contains(lines[i], "●") {
+			t.Fatalf("other changes should be marked with ○, got %q", lines[i])
+		}
+	}
+	if !strings.Contains(lines[third+1], "alice 2024-01-02 03:04:05") {
+		t.Fatalf("author and time should be rendered below the name, got %q", lines[third+1])
+	}
+	if strings.Contains(strings.Join(lines, "\n"), "\x1b[") {
+		t.Fatal("colors are disabled and must not be rendered")
+	}
+}
+
+func TestRenderFork(t *testing.T) {
+	lines := render(t, &protos.LogResponse{
+		Head: 3,
+		Changes: []*protos.LogChange{
+			testChange(3, "cccccc", "left"),
+			testChange(2, "bbbbbb", "right"),
+			testChange(1, "aaaaaa", "base"),
+		},
+		Edges: []*protos.LogEdge{testEdge(3, 1), testEdge(2, 1), testEdge(1, 0)},
+	})
+
+	left, right, base := lineOf(t, lines, "cccccc"), lineOf(t, lines, "bbbbbb"), lineOf(t, lines, "aaaaaa")
+	if left >= base || right >= base {
+		t.Fatalf("both sides of the fork should be rendered above the base\n%s", strings.Join(lines, "\n"))
+	}
+	nodes := strings.Count(strings.Join(lines, "\n"), "○") + strings.Count(strings.Join(lines, "\n"), "●")
+	if nodes != 3 {
+		t.Fatalf("every change should have one node, got %d\n%s", nodes, strings.Join(lines, "\n"))
+	}
+}
+
+func TestRenderConflictedHead(t *testing.T) {
+	head := testChange(2, "bbbbbb", "conflicted")
+	head.HasConflicts = true
+	lines := render(t, &protos.LogResponse{
+		Head:    2,
+		Changes: []*protos.LogChange{head, testChange(1, "aaaaaa", "base")},
+		Edges:   []*protos.LogEdge{testEdge(2, 1), testEdge(1, 0)},
+	})
+	if !strings.Contains(lines[lineOf(t, lines, "bbbbbb")+1], "conflict") {
+		t.Fatalf("a conflicted head should be flagged\n%s", strings.Join(lines, "\n"))
+	}
+}
+
+func TestRenderParentOutsideLog(t *testing.T) {
+	// the parent of the oldest change is not part of a limited log
+	lines := render(t, &protos.LogResponse{
+		Head:    3,
+		Changes: []*protos.LogChange{testChange(3, "cccccc", "third"), testChange(2, "bbbbbb", "second")},
+		Edges:   []*protos.LogEdge{testEdge(3, 2), testEdge(2, 1)},
+	})
+	lineOf(t, lines, "cccccc")
+	lineOf(t, lines, "bbbbbb")
+}
+
+func TestRenderNoEdges(t *testing.T) {
+	var buf bytes.Buffer
+	err := Render(&buf, &protos.LogResponse{
+		Changes: []*protos.LogChange{testChange(2, "bbbbbb", ""), testChange(1, "aaaaaa", "")},
+	}, Options{})
+	if err == nil {
+		t.Fatal("a log with changes but without edges should fail")
+	}
+}
+
+func TestFirstLine(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"first\nsecond", 80, "first"},
+		{"  padded  \n", 80, "padded"},
+		{"a description that is too long", 20, "a description that…"},
+		// descriptions keep at least 16 columns
+		{"a description that is too long", 4, "a description t…"},
+		{"äöü", 16, "äöü"},
+	}
+	for _, tt := range tests {
+		if got := firstLine(tt.s, tt.width); got != tt.want {
+			t.Errorf("firstLine(%q, %d) should be %q, got %q", tt.s, tt.width, tt.want, got)
+		}
+	}
+}
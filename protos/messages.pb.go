@@ -31,6 +31,7 @@ type HTTPSignature struct {
 	Username    string                 `protobuf:"bytes,4,opt,name=username,proto3" json:"username,omitempty"`
 	MachineId   string                 `protobuf:"bytes,5,opt,name=machine_id,json=machineId,proto3" json:"machine_id,omitempty"`
 	PublicKey   []byte                 `protobuf:"bytes,6,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	Nonce       []byte                 `protobuf:"bytes,10,opt,name=nonce,proto3" json:"nonce,omitempty"`
 	// The signature
 	Format        string `protobuf:"bytes,7,opt,name=format,proto3" json:"format,omitempty"`
 	Blob          []byte `protobuf:"bytes,8,opt,name=blob,proto3" json:"blob,omitempty"`
@@ -111,6 +112,13 @@ func (x *HTTPSignature) GetPublicKey() []byte {
 	return nil
 }
 
+func (x *HTTPSignature) GetNonce() []byte {
+	if x != nil {
+		return x.Nonce
+	}
+	return nil
+}
+
 func (x *HTTPSignature) GetFormat() string {
 	if x != nil {
 		return x.Format
@@ -228,6 +236,110 @@ func (x *InitResponse) GetRepoID() int32 {
 	return 0
 }
 
+type CloneRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Repo          string                 `protobuf:"bytes,1,opt,name=Repo,proto3" json:"Repo,omitempty"`
+	Bookmark      string                 `protobuf:"bytes,2,opt,name=Bookmark,proto3" json:"Bookmark,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CloneRequest) Reset() {
+	*x = CloneRequest{}
+	mi := &file_protos_messages_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CloneRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CloneRequest) ProtoMessage() {}
+
+func (x *CloneRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CloneRequest.ProtoReflect.Descriptor instead.
+func (*CloneRequest) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *CloneRequest) GetRepo() string {
+	if x != nil {
+		return x.Repo
+	}
+	return ""
+}
+
+func (x *CloneRequest) GetBookmark() string {
+	if x != nil {
+		return x.Bookmark
+	}
+	return ""
+}
+
+type CloneResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RepoID        int32                  `protobuf:"varint,1,opt,name=RepoID,proto3" json:"RepoID,omitempty"`
+	ChangeId      int64                  `protobuf:"varint,2,opt,name=ChangeId,proto3" json:"ChangeId,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CloneResponse) Reset() {
+	*x = CloneResponse{}
+	mi := &file_protos_messages_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CloneResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CloneResponse) ProtoMessage() {}
+
+func (x *CloneResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CloneResponse.ProtoReflect.Descriptor instead.
+func (*CloneResponse) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CloneResponse) GetRepoID() int32 {
+	if x != nil {
+		return x.RepoID
+	}
+	return 0
+}
+
+func (x *CloneResponse) GetChangeId() int64 {
+	if x != nil {
+		return x.ChangeId
+	}
+	return 0
+}
+
 type PushFileInfo struct {
 	state           protoimpl.MessageState `protogen:"open.v1"`
 	Name            string                 `protobuf:"bytes,1,opt,name=Name,proto3" json:"Name,omitempty"`
@@ -240,7 +352,7 @@ type PushFileInfo struct {
 
 func (x *PushFileInfo) Reset() {
 	*x = PushFileInfo{}
-	mi := &file_protos_messages_proto_msgTypes[3]
+	mi := &file_protos_messages_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -252,7 +364,7 @@ func (x *PushFileInfo) String() string {
 func (*PushFileInfo) ProtoMessage() {}
 
 func (x *PushFileInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_messages_proto_msgTypes[3]
+	mi := &file_protos_messages_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -265,7 +377,7 @@ func (x *PushFileInfo) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PushFileInfo.ProtoReflect.Descriptor instead.
 func (*PushFileInfo) Descriptor() ([]byte, []int) {
-	return file_protos_messages_proto_rawDescGZIP(), []int{3}
+	return file_protos_messages_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *PushFileInfo) GetName() string {
@@ -305,7 +417,7 @@ type CheckFilesExistsRequest struct {
 
 func (x *CheckFilesExistsRequest) Reset() {
 	*x = CheckFilesExistsRequest{}
-	mi := &file_protos_messages_proto_msgTypes[4]
+	mi := &file_protos_messages_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -317,7 +429,7 @@ func (x *CheckFilesExistsRequest) String() string {
 func (*CheckFilesExistsRequest) ProtoMessage() {}
 
 func (x *CheckFilesExistsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_messages_proto_msgTypes[4]
+	mi := &file_protos_messages_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -330,7 +442,7 @@ func (x *CheckFilesExistsRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CheckFilesExistsRequest.ProtoReflect.Descriptor instead.
 func (*CheckFilesExistsRequest) Descriptor() ([]byte, []int) {
-	return file_protos_messages_proto_rawDescGZIP(), []int{4}
+	return file_protos_messages_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *CheckFilesExistsRequest) GetContentHash() [][]byte {
@@ -349,7 +461,7 @@ type CheckFilesExistsResponse struct {
 
 func (x *CheckFilesExistsResponse) Reset() {
 	*x = CheckFilesExistsResponse{}
-	mi := &file_protos_messages_proto_msgTypes[5]
+	mi := &file_protos_messages_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -361,7 +473,7 @@ func (x *CheckFilesExistsResponse) String() string {
 func (*CheckFilesExistsResponse) ProtoMessage() {}
 
 func (x *CheckFilesExistsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_messages_proto_msgTypes[5]
+	mi := &file_protos_messages_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -374,7 +486,7 @@ func (x *CheckFilesExistsResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CheckFilesExistsResponse.ProtoReflect.Descriptor instead.
 func (*CheckFilesExistsResponse) Descriptor() ([]byte, []int) {
-	return file_protos_messages_proto_rawDescGZIP(), []int{5}
+	return file_protos_messages_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *CheckFilesExistsResponse) GetExists() []bool {
@@ -395,7 +507,7 @@ type NewChangeRequest struct {
 
 func (x *NewChangeRequest) Reset() {
 	*x = NewChangeRequest{}
-	mi := &file_protos_messages_proto_msgTypes[6]
+	mi := &file_protos_messages_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -407,7 +519,7 @@ func (x *NewChangeRequest) String() string {
 func (*NewChangeRequest) ProtoMessage() {}
 
 func (x *NewChangeRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_messages_proto_msgTypes[6]
+	mi := &file_protos_messages_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -420,7 +532,7 @@ func (x *NewChangeRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use NewChangeRequest.ProtoReflect.Descriptor instead.
 func (*NewChangeRequest) Descriptor() ([]byte, []int) {
-	return file_protos_messages_proto_rawDescGZIP(), []int{6}
+	return file_protos_messages_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *NewChangeRequest) GetParents() []string {
@@ -445,16 +557,23 @@ func (x *NewChangeRequest) GetSetBookmarks() []string {
 }
 
 type SetBookmarkRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Bookmark      string                 `protobuf:"bytes,1,opt,name=Bookmark,proto3" json:"Bookmark,omitempty"`
-	ChangeId      int64                  `protobuf:"varint,2,opt,name=ChangeId,proto3" json:"ChangeId,omitempty"`
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Bookmark string                 `protobuf:"bytes,1,opt,name=Bookmark,proto3" json:"Bookmark,omitempty"`
+	// ChangeId is used if Change is empty.
+	ChangeId int64 `protobuf:"varint,2,opt,name=ChangeId,proto3" json:"ChangeId,omitempty"`
+	// Change is a revset that selects one change.
+	Change string `protobuf:"bytes,3,opt,name=Change,proto3" json:"Change,omitempty"`
+	// AllowBackwards allows moving the bookmark to a change that is not a descendant of its current target.
+	AllowBackwards bool `protobuf:"varint,4,opt,name=AllowBackwards,proto3" json:"AllowBackwards,omitempty"`
+	// MustExist only moves an existing bookmark instead of creating a new one.
+	MustExist     bool `protobuf:"varint,5,opt,name=MustExist,proto3" json:"MustExist,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *SetBookmarkRequest) Reset() {
 	*x = SetBookmarkRequest{}
-	mi := &file_protos_messages_proto_msgTypes[7]
+	mi := &file_protos_messages_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -466,7 +585,7 @@ func (x *SetBookmarkRequest) String() string {
 func (*SetBookmarkRequest) ProtoMessage() {}
 
 func (x *SetBookmarkRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_messages_proto_msgTypes[7]
+	mi := &file_protos_messages_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -479,7 +598,7 @@ func (x *SetBookmarkRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SetBookmarkRequest.ProtoReflect.Descriptor instead.
 func (*SetBookmarkRequest) Descriptor() ([]byte, []int) {
-	return file_protos_messages_proto_rawDescGZIP(), []int{7}
+	return file_protos_messages_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *SetBookmarkRequest) GetBookmark() string {
@@ -496,28 +615,49 @@ func (x *SetBookmarkRequest) GetChangeId() int64 {
 	return 0
 }
 
-type NewChangeResponse struct {
+func (x *SetBookmarkRequest) GetChange() string {
+	if x != nil {
+		return x.Change
+	}
+	return ""
+}
+
+func (x *SetBookmarkRequest) GetAllowBackwards() bool {
+	if x != nil {
+		return x.AllowBackwards
+	}
+	return false
+}
+
+func (x *SetBookmarkRequest) GetMustExist() bool {
+	if x != nil {
+		return x.MustExist
+	}
+	return false
+}
+
+type DeleteBookmarkRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	ChangeId      int64                  `protobuf:"varint,1,opt,name=ChangeId,proto3" json:"ChangeId,omitempty"`
+	Bookmark      string                 `protobuf:"bytes,1,opt,name=Bookmark,proto3" json:"Bookmark,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *NewChangeResponse) Reset() {
-	*x = NewChangeResponse{}
-	mi := &file_protos_messages_proto_msgTypes[8]
+func (x *DeleteBookmarkRequest) Reset() {
+	*x = DeleteBookmarkRequest{}
+	mi := &file_protos_messages_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *NewChangeResponse) String() string {
+func (x *DeleteBookmarkRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*NewChangeResponse) ProtoMessage() {}
+func (*DeleteBookmarkRequest) ProtoMessage() {}
 
-func (x *NewChangeResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_messages_proto_msgTypes[8]
+func (x *DeleteBookmarkRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -528,40 +668,41 @@ func (x *NewChangeResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use NewChangeResponse.ProtoReflect.Descriptor instead.
-func (*NewChangeResponse) Descriptor() ([]byte, []int) {
-	return file_protos_messages_proto_rawDescGZIP(), []int{8}
+// Deprecated: Use DeleteBookmarkRequest.ProtoReflect.Descriptor instead.
+func (*DeleteBookmarkRequest) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{10}
 }
 
-func (x *NewChangeResponse) GetChangeId() int64 {
+func (x *DeleteBookmarkRequest) GetBookmark() string {
 	if x != nil {
-		return x.ChangeId
+		return x.Bookmark
 	}
-	return 0
+	return ""
 }
 
-type CheckoutRequest struct {
+type RenameBookmarkRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	ChangeId      int64                  `protobuf:"varint,1,opt,name=ChangeId,proto3" json:"ChangeId,omitempty"`
+	Bookmark      string                 `protobuf:"bytes,1,opt,name=Bookmark,proto3" json:"Bookmark,omitempty"`
+	NewName       string                 `protobuf:"bytes,2,opt,name=NewName,proto3" json:"NewName,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *CheckoutRequest) Reset() {
-	*x = CheckoutRequest{}
-	mi := &file_protos_messages_proto_msgTypes[9]
+func (x *RenameBookmarkRequest) Reset() {
+	*x = RenameBookmarkRequest{}
+	mi := &file_protos_messages_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *CheckoutRequest) String() string {
+func (x *RenameBookmarkRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*CheckoutRequest) ProtoMessage() {}
+func (*RenameBookmarkRequest) ProtoMessage() {}
 
-func (x *CheckoutRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_messages_proto_msgTypes[9]
+func (x *RenameBookmarkRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -572,42 +713,56 @@ func (x *CheckoutRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use CheckoutRequest.ProtoReflect.Descriptor instead.
-func (*CheckoutRequest) Descriptor() ([]byte, []int) {
-	return file_protos_messages_proto_rawDescGZIP(), []int{9}
+// Deprecated: Use RenameBookmarkRequest.ProtoReflect.Descriptor instead.
+func (*RenameBookmarkRequest) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{11}
 }
 
-func (x *CheckoutRequest) GetChangeId() int64 {
+func (x *RenameBookmarkRequest) GetBookmark() string {
 	if x != nil {
-		return x.ChangeId
+		return x.Bookmark
 	}
-	return 0
+	return ""
 }
 
-type LogRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Head          string                 `protobuf:"bytes,1,opt,name=Head,proto3" json:"Head,omitempty"`
-	Limit         int32                  `protobuf:"varint,2,opt,name=Limit,proto3" json:"Limit,omitempty"`
-	TimeZone      string                 `protobuf:"bytes,3,opt,name=TimeZone,proto3" json:"TimeZone,omitempty"`
+func (x *RenameBookmarkRequest) GetNewName() string {
+	if x != nil {
+		return x.NewName
+	}
+	return ""
+}
+
+// BookmarkRule protects a bookmark. Every move of the bookmark must satisfy all enabled checks.
+type BookmarkRule struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Bookmark string                 `protobuf:"bytes,1,opt,name=Bookmark,proto3" json:"Bookmark,omitempty"`
+	// DescendantsOnly only allows moving the bookmark to descendants of its current change.
+	DescendantsOnly bool `protobuf:"varint,2,opt,name=DescendantsOnly,proto3" json:"DescendantsOnly,omitempty"`
+	// NoConflicts forbids moving the bookmark to a change with conflicts.
+	NoConflicts bool `protobuf:"varint,3,opt,name=NoConflicts,proto3" json:"NoConflicts,omitempty"`
+	// RequireDescription forbids moving the bookmark to a change without a description.
+	RequireDescription bool `protobuf:"varint,4,opt,name=RequireDescription,proto3" json:"RequireDescription,omitempty"`
+	// AllowedUsers may move the bookmark. If empty, every user with write access may move it.
+	AllowedUsers  []string `protobuf:"bytes,5,rep,name=AllowedUsers,proto3" json:"AllowedUsers,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *LogRequest) Reset() {
-	*x = LogRequest{}
-	mi := &file_protos_messages_proto_msgTypes[10]
+func (x *BookmarkRule) Reset() {
+	*x = BookmarkRule{}
+	mi := &file_protos_messages_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *LogRequest) String() string {
+func (x *BookmarkRule) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*LogRequest) ProtoMessage() {}
+func (*BookmarkRule) ProtoMessage() {}
 
-func (x *LogRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_messages_proto_msgTypes[10]
+func (x *BookmarkRule) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -618,54 +773,68 @@ func (x *LogRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use LogRequest.ProtoReflect.Descriptor instead.
-func (*LogRequest) Descriptor() ([]byte, []int) {
-	return file_protos_messages_proto_rawDescGZIP(), []int{10}
+// Deprecated: Use BookmarkRule.ProtoReflect.Descriptor instead.
+func (*BookmarkRule) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{12}
 }
 
-func (x *LogRequest) GetHead() string {
+func (x *BookmarkRule) GetBookmark() string {
 	if x != nil {
-		return x.Head
+		return x.Bookmark
 	}
 	return ""
 }
 
-func (x *LogRequest) GetLimit() int32 {
+func (x *BookmarkRule) GetDescendantsOnly() bool {
 	if x != nil {
-		return x.Limit
+		return x.DescendantsOnly
 	}
-	return 0
+	return false
 }
 
-func (x *LogRequest) GetTimeZone() string {
+func (x *BookmarkRule) GetNoConflicts() bool {
 	if x != nil {
-		return x.TimeZone
+		return x.NoConflicts
 	}
-	return ""
+	return false
 }
 
-type LogResponse struct {
+func (x *BookmarkRule) GetRequireDescription() bool {
+	if x != nil {
+		return x.RequireDescription
+	}
+	return false
+}
+
+func (x *BookmarkRule) GetAllowedUsers() []string {
+	if x != nil {
+		return x.AllowedUsers
+	}
+	return nil
+}
+
+type RemoveBookmarkRuleRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Log           string                 `protobuf:"bytes,1,opt,name=Log,proto3" json:"Log,omitempty"`
+	Bookmark      string                 `protobuf:"bytes,1,opt,name=Bookmark,proto3" json:"Bookmark,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *LogResponse) Reset() {
-	*x = LogResponse{}
-	mi := &file_protos_messages_proto_msgTypes[11]
+func (x *RemoveBookmarkRuleRequest) Reset() {
+	*x = RemoveBookmarkRuleRequest{}
+	mi := &file_protos_messages_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *LogResponse) String() string {
+func (x *RemoveBookmarkRuleRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*LogResponse) ProtoMessage() {}
+func (*RemoveBookmarkRuleRequest) ProtoMessage() {}
 
-func (x *LogResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_messages_proto_msgTypes[11]
+func (x *RemoveBookmarkRuleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -676,41 +845,40 @@ func (x *LogResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use LogResponse.ProtoReflect.Descriptor instead.
-func (*LogResponse) Descriptor() ([]byte, []int) {
-	return file_protos_messages_proto_rawDescGZIP(), []int{11}
+// Deprecated: Use RemoveBookmarkRuleRequest.ProtoReflect.Descriptor instead.
+func (*RemoveBookmarkRuleRequest) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{13}
 }
 
-func (x *LogResponse) GetLog() string {
+func (x *RemoveBookmarkRuleRequest) GetBookmark() string {
 	if x != nil {
-		return x.Log
+		return x.Bookmark
 	}
 	return ""
 }
 
-type FindChangeRequest struct {
-	state              protoimpl.MessageState `protogen:"open.v1"`
-	Name               string                 `protobuf:"bytes,1,opt,name=Name,proto3" json:"Name,omitempty"`
-	IncludeDescription bool                   `protobuf:"varint,2,opt,name=IncludeDescription,proto3" json:"IncludeDescription,omitempty"`
-	unknownFields      protoimpl.UnknownFields
-	sizeCache          protoimpl.SizeCache
+type ListBookmarkRulesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Rules         []*BookmarkRule        `protobuf:"bytes,1,rep,name=Rules,proto3" json:"Rules,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *FindChangeRequest) Reset() {
-	*x = FindChangeRequest{}
-	mi := &file_protos_messages_proto_msgTypes[12]
+func (x *ListBookmarkRulesResponse) Reset() {
+	*x = ListBookmarkRulesResponse{}
+	mi := &file_protos_messages_proto_msgTypes[14]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *FindChangeRequest) String() string {
+func (x *ListBookmarkRulesResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*FindChangeRequest) ProtoMessage() {}
+func (*ListBookmarkRulesResponse) ProtoMessage() {}
 
-func (x *FindChangeRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_messages_proto_msgTypes[12]
+func (x *ListBookmarkRulesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[14]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -721,48 +889,2717 @@ func (x *FindChangeRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use FindChangeRequest.ProtoReflect.Descriptor instead.
-func (*FindChangeRequest) Descriptor() ([]byte, []int) {
-	return file_protos_messages_proto_rawDescGZIP(), []int{12}
+// Deprecated: Use ListBookmarkRulesResponse.ProtoReflect.Descriptor instead.
+func (*ListBookmarkRulesResponse) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{14}
 }
 
-func (x *FindChangeRequest) GetName() string {
+func (x *ListBookmarkRulesResponse) GetRules() []*BookmarkRule {
 	if x != nil {
-		return x.Name
+		return x.Rules
+	}
+	return nil
+}
+
+type NewChangeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ChangeId      int64                  `protobuf:"varint,1,opt,name=ChangeId,proto3" json:"ChangeId,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NewChangeResponse) Reset() {
+	*x = NewChangeResponse{}
+	mi := &file_protos_messages_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NewChangeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NewChangeResponse) ProtoMessage() {}
+
+func (x *NewChangeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NewChangeResponse.ProtoReflect.Descriptor instead.
+func (*NewChangeResponse) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *NewChangeResponse) GetChangeId() int64 {
+	if x != nil {
+		return x.ChangeId
+	}
+	return 0
+}
+
+type SetHeadRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ChangeId      int64                  `protobuf:"varint,1,opt,name=ChangeId,proto3" json:"ChangeId,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetHeadRequest) Reset() {
+	*x = SetHeadRequest{}
+	mi := &file_protos_messages_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetHeadRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetHeadRequest) ProtoMessage() {}
+
+func (x *SetHeadRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetHeadRequest.ProtoReflect.Descriptor instead.
+func (*SetHeadRequest) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *SetHeadRequest) GetChangeId() int64 {
+	if x != nil {
+		return x.ChangeId
+	}
+	return 0
+}
+
+type CheckoutRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ChangeId      int64                  `protobuf:"varint,1,opt,name=ChangeId,proto3" json:"ChangeId,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CheckoutRequest) Reset() {
+	*x = CheckoutRequest{}
+	mi := &file_protos_messages_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CheckoutRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckoutRequest) ProtoMessage() {}
+
+func (x *CheckoutRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckoutRequest.ProtoReflect.Descriptor instead.
+func (*CheckoutRequest) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *CheckoutRequest) GetChangeId() int64 {
+	if x != nil {
+		return x.ChangeId
+	}
+	return 0
+}
+
+type LogRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Head is a revset that selects the change the log is relative to, usually @.
+	Head  string `protobuf:"bytes,1,opt,name=Head,proto3" json:"Head,omitempty"`
+	Limit int32  `protobuf:"varint,2,opt,name=Limit,proto3" json:"Limit,omitempty"`
+	// Revset selects the changes to log. If empty, the ancestry of Head is logged.
+	Revset        string `protobuf:"bytes,4,opt,name=Revset,proto3" json:"Revset,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LogRequest) Reset() {
+	*x = LogRequest{}
+	mi := &file_protos_messages_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogRequest) ProtoMessage() {}
+
+func (x *LogRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogRequest.ProtoReflect.Descriptor instead.
+func (*LogRequest) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *LogRequest) GetHead() string {
+	if x != nil {
+		return x.Head
+	}
+	return ""
+}
+
+func (x *LogRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *LogRequest) GetRevset() string {
+	if x != nil {
+		return x.Revset
+	}
+	return ""
+}
+
+type LogResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Head is the ID of the change the log starts from.
+	Head          int64        `protobuf:"varint,2,opt,name=Head,proto3" json:"Head,omitempty"`
+	Changes       []*LogChange `protobuf:"bytes,3,rep,name=Changes,proto3" json:"Changes,omitempty"`
+	Edges         []*LogEdge   `protobuf:"bytes,4,rep,name=Edges,proto3" json:"Edges,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LogResponse) Reset() {
+	*x = LogResponse{}
+	mi := &file_protos_messages_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogResponse) ProtoMessage() {}
+
+func (x *LogResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogResponse.ProtoReflect.Descriptor instead.
+func (*LogResponse) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *LogResponse) GetHead() int64 {
+	if x != nil {
+		return x.Head
+	}
+	return 0
+}
+
+func (x *LogResponse) GetChanges() []*LogChange {
+	if x != nil {
+		return x.Changes
+	}
+	return nil
+}
+
+func (x *LogResponse) GetEdges() []*LogEdge {
+	if x != nil {
+		return x.Edges
+	}
+	return nil
+}
+
+type LogChange struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    int64                  `protobuf:"varint,1,opt,name=Id,proto3" json:"Id,omitempty"`
+	Name  string                 `protobuf:"bytes,2,opt,name=Name,proto3" json:"Name,omitempty"`
+	// Prefix is the shortest prefix of Name that is unique in the repository.
+	Prefix        string                 `protobuf:"bytes,3,opt,name=Prefix,proto3" json:"Prefix,omitempty"`
+	Description   *string                `protobuf:"bytes,4,opt,name=Description,proto3,oneof" json:"Description,omitempty"`
+	Author        string                 `protobuf:"bytes,5,opt,name=Author,proto3" json:"Author,omitempty"`
+	Device        string                 `protobuf:"bytes,6,opt,name=Device,proto3" json:"Device,omitempty"`
+	Depth         int64                  `protobuf:"varint,7,opt,name=Depth,proto3" json:"Depth,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=CreatedAt,proto3" json:"CreatedAt,omitempty"`
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=UpdatedAt,proto3" json:"UpdatedAt,omitempty"`
+	HasConflicts  bool                   `protobuf:"varint,10,opt,name=HasConflicts,proto3" json:"HasConflicts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LogChange) Reset() {
+	*x = LogChange{}
+	mi := &file_protos_messages_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogChange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogChange) ProtoMessage() {}
+
+func (x *LogChange) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogChange.ProtoReflect.Descriptor instead.
+func (*LogChange) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *LogChange) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *LogChange) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *LogChange) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+func (x *LogChange) GetDescription() string {
+	if x != nil && x.Description != nil {
+		return *x.Description
+	}
+	return ""
+}
+
+func (x *LogChange) GetAuthor() string {
+	if x != nil {
+		return x.Author
+	}
+	return ""
+}
+
+func (x *LogChange) GetDevice() string {
+	if x != nil {
+		return x.Device
+	}
+	return ""
+}
+
+func (x *LogChange) GetDepth() int64 {
+	if x != nil {
+		return x.Depth
+	}
+	return 0
+}
+
+func (x *LogChange) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *LogChange) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+func (x *LogChange) GetHasConflicts() bool {
+	if x != nil {
+		return x.HasConflicts
+	}
+	return false
+}
+
+type LogEdge struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	ChangeId int64                  `protobuf:"varint,1,opt,name=ChangeId,proto3" json:"ChangeId,omitempty"`
+	// ParentId is not set for a change without parents.
+	ParentId      *int64 `protobuf:"varint,2,opt,name=ParentId,proto3,oneof" json:"ParentId,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LogEdge) Reset() {
+	*x = LogEdge{}
+	mi := &file_protos_messages_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogEdge) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogEdge) ProtoMessage() {}
+
+func (x *LogEdge) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogEdge.ProtoReflect.Descriptor instead.
+func (*LogEdge) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *LogEdge) GetChangeId() int64 {
+	if x != nil {
+		return x.ChangeId
+	}
+	return 0
+}
+
+func (x *LogEdge) GetParentId() int64 {
+	if x != nil && x.ParentId != nil {
+		return *x.ParentId
+	}
+	return 0
+}
+
+type FindChangeRequest struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Name               string                 `protobuf:"bytes,1,opt,name=Name,proto3" json:"Name,omitempty"`
+	IncludeDescription bool                   `protobuf:"varint,2,opt,name=IncludeDescription,proto3" json:"IncludeDescription,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *FindChangeRequest) Reset() {
+	*x = FindChangeRequest{}
+	mi := &file_protos_messages_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FindChangeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FindChangeRequest) ProtoMessage() {}
+
+func (x *FindChangeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FindChangeRequest.ProtoReflect.Descriptor instead.
+func (*FindChangeRequest) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *FindChangeRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *FindChangeRequest) GetIncludeDescription() bool {
+	if x != nil {
+		return x.IncludeDescription
+	}
+	return false
+}
+
+type FindChangeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ChangeId      int64                  `protobuf:"varint,1,opt,name=ChangeId,proto3" json:"ChangeId,omitempty"`
+	Description   *string                `protobuf:"bytes,2,opt,name=Description,proto3,oneof" json:"Description,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FindChangeResponse) Reset() {
+	*x = FindChangeResponse{}
+	mi := &file_protos_messages_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FindChangeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FindChangeResponse) ProtoMessage() {}
+
+func (x *FindChangeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FindChangeResponse.ProtoReflect.Descriptor instead.
+func (*FindChangeResponse) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *FindChangeResponse) GetChangeId() int64 {
+	if x != nil {
+		return x.ChangeId
+	}
+	return 0
+}
+
+func (x *FindChangeResponse) GetDescription() string {
+	if x != nil && x.Description != nil {
+		return *x.Description
+	}
+	return ""
+}
+
+type DescribeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Change        string                 `protobuf:"bytes,1,opt,name=Change,proto3" json:"Change,omitempty"`
+	Description   string                 `protobuf:"bytes,2,opt,name=Description,proto3" json:"Description,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DescribeRequest) Reset() {
+	*x = DescribeRequest{}
+	mi := &file_protos_messages_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DescribeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DescribeRequest) ProtoMessage() {}
+
+func (x *DescribeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DescribeRequest.ProtoReflect.Descriptor instead.
+func (*DescribeRequest) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *DescribeRequest) GetChange() string {
+	if x != nil {
+		return x.Change
+	}
+	return ""
+}
+
+func (x *DescribeRequest) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+type ListBookmarksResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Bookmarks     []*Bookmark            `protobuf:"bytes,1,rep,name=Bookmarks,proto3" json:"Bookmarks,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListBookmarksResponse) Reset() {
+	*x = ListBookmarksResponse{}
+	mi := &file_protos_messages_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListBookmarksResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListBookmarksResponse) ProtoMessage() {}
+
+func (x *ListBookmarksResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListBookmarksResponse.ProtoReflect.Descriptor instead.
+func (*ListBookmarksResponse) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *ListBookmarksResponse) GetBookmarks() []*Bookmark {
+	if x != nil {
+		return x.Bookmarks
+	}
+	return nil
+}
+
+type Bookmark struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BookmarkName  string                 `protobuf:"bytes,1,opt,name=BookmarkName,proto3" json:"BookmarkName,omitempty"`
+	ChangeID      int64                  `protobuf:"varint,2,opt,name=ChangeID,proto3" json:"ChangeID,omitempty"`
+	ChangeName    string                 `protobuf:"bytes,3,opt,name=ChangeName,proto3" json:"ChangeName,omitempty"`
+	ChangePrefix  string                 `protobuf:"bytes,4,opt,name=ChangePrefix,proto3" json:"ChangePrefix,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Bookmark) Reset() {
+	*x = Bookmark{}
+	mi := &file_protos_messages_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Bookmark) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Bookmark) ProtoMessage() {}
+
+func (x *Bookmark) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Bookmark.ProtoReflect.Descriptor instead.
+func (*Bookmark) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *Bookmark) GetBookmarkName() string {
+	if x != nil {
+		return x.BookmarkName
+	}
+	return ""
+}
+
+func (x *Bookmark) GetChangeID() int64 {
+	if x != nil {
+		return x.ChangeID
+	}
+	return 0
+}
+
+func (x *Bookmark) GetChangeName() string {
+	if x != nil {
+		return x.ChangeName
+	}
+	return ""
+}
+
+func (x *Bookmark) GetChangePrefix() string {
+	if x != nil {
+		return x.ChangePrefix
+	}
+	return ""
+}
+
+type ConflictsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Change        string                 `protobuf:"bytes,1,opt,name=Change,proto3" json:"Change,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConflictsRequest) Reset() {
+	*x = ConflictsRequest{}
+	mi := &file_protos_messages_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConflictsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConflictsRequest) ProtoMessage() {}
+
+func (x *ConflictsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConflictsRequest.ProtoReflect.Descriptor instead.
+func (*ConflictsRequest) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *ConflictsRequest) GetChange() string {
+	if x != nil {
+		return x.Change
+	}
+	return ""
+}
+
+type ConflictsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Conflicts     []string               `protobuf:"bytes,1,rep,name=Conflicts,proto3" json:"Conflicts,omitempty"`
+	Files         []*FileConflict        `protobuf:"bytes,2,rep,name=Files,proto3" json:"Files,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConflictsResponse) Reset() {
+	*x = ConflictsResponse{}
+	mi := &file_protos_messages_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConflictsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConflictsResponse) ProtoMessage() {}
+
+func (x *ConflictsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConflictsResponse.ProtoReflect.Descriptor instead.
+func (*ConflictsResponse) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *ConflictsResponse) GetConflicts() []string {
+	if x != nil {
+		return x.Conflicts
+	}
+	return nil
+}
+
+func (x *ConflictsResponse) GetFiles() []*FileConflict {
+	if x != nil {
+		return x.Files
+	}
+	return nil
+}
+
+// FileConflict is a conflicted file of a change with the versions it was merged from.
+type FileConflict struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Path  string                 `protobuf:"bytes,1,opt,name=Path,proto3" json:"Path,omitempty"`
+	// Kind is "content", "modify/delete" or "add/add".
+	// It is empty for conflicts that were recorded before their sides were.
+	Kind string `protobuf:"bytes,2,opt,name=Kind,proto3" json:"Kind,omitempty"`
+	// Base is not set if the merge base doesn't have the file.
+	Base          *ConflictSide   `protobuf:"bytes,3,opt,name=Base,proto3,oneof" json:"Base,omitempty"`
+	Sides         []*ConflictSide `protobuf:"bytes,4,rep,name=Sides,proto3" json:"Sides,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FileConflict) Reset() {
+	*x = FileConflict{}
+	mi := &file_protos_messages_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FileConflict) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileConflict) ProtoMessage() {}
+
+func (x *FileConflict) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileConflict.ProtoReflect.Descriptor instead.
+func (*FileConflict) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *FileConflict) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *FileConflict) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *FileConflict) GetBase() *ConflictSide {
+	if x != nil {
+		return x.Base
+	}
+	return nil
+}
+
+func (x *FileConflict) GetSides() []*ConflictSide {
+	if x != nil {
+		return x.Sides
+	}
+	return nil
+}
+
+type ConflictSide struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Change string                 `protobuf:"bytes,1,opt,name=Change,proto3" json:"Change,omitempty"`
+	// ContentHash is empty if the change deleted the file.
+	ContentHash   []byte `protobuf:"bytes,2,opt,name=ContentHash,proto3" json:"ContentHash,omitempty"`
+	Executable    bool   `protobuf:"varint,3,opt,name=Executable,proto3" json:"Executable,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConflictSide) Reset() {
+	*x = ConflictSide{}
+	mi := &file_protos_messages_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConflictSide) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConflictSide) ProtoMessage() {}
+
+func (x *ConflictSide) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConflictSide.ProtoReflect.Descriptor instead.
+func (*ConflictSide) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *ConflictSide) GetChange() string {
+	if x != nil {
+		return x.Change
+	}
+	return ""
+}
+
+func (x *ConflictSide) GetContentHash() []byte {
+	if x != nil {
+		return x.ContentHash
+	}
+	return nil
+}
+
+func (x *ConflictSide) GetExecutable() bool {
+	if x != nil {
+		return x.Executable
+	}
+	return false
+}
+
+type RegisterResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Approved      bool                   `protobuf:"varint,1,opt,name=Approved,proto3" json:"Approved,omitempty"`
+	Admin         bool                   `protobuf:"varint,2,opt,name=Admin,proto3" json:"Admin,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RegisterResponse) Reset() {
+	*x = RegisterResponse{}
+	mi := &file_protos_messages_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterResponse) ProtoMessage() {}
+
+func (x *RegisterResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterResponse.ProtoReflect.Descriptor instead.
+func (*RegisterResponse) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *RegisterResponse) GetApproved() bool {
+	if x != nil {
+		return x.Approved
+	}
+	return false
+}
+
+func (x *RegisterResponse) GetAdmin() bool {
+	if x != nil {
+		return x.Admin
+	}
+	return false
+}
+
+type PendingKey struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Username      string                 `protobuf:"bytes,1,opt,name=Username,proto3" json:"Username,omitempty"`
+	Fingerprint   string                 `protobuf:"bytes,2,opt,name=Fingerprint,proto3" json:"Fingerprint,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=CreatedAt,proto3" json:"CreatedAt,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PendingKey) Reset() {
+	*x = PendingKey{}
+	mi := &file_protos_messages_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PendingKey) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PendingKey) ProtoMessage() {}
+
+func (x *PendingKey) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PendingKey.ProtoReflect.Descriptor instead.
+func (*PendingKey) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *PendingKey) GetUsername() string {
+	if x != nil {
+		return x.Username
+	}
+	return ""
+}
+
+func (x *PendingKey) GetFingerprint() string {
+	if x != nil {
+		return x.Fingerprint
+	}
+	return ""
+}
+
+func (x *PendingKey) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+type ListPendingKeysResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Keys          []*PendingKey          `protobuf:"bytes,1,rep,name=Keys,proto3" json:"Keys,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPendingKeysResponse) Reset() {
+	*x = ListPendingKeysResponse{}
+	mi := &file_protos_messages_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPendingKeysResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPendingKeysResponse) ProtoMessage() {}
+
+func (x *ListPendingKeysResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPendingKeysResponse.ProtoReflect.Descriptor instead.
+func (*ListPendingKeysResponse) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *ListPendingKeysResponse) GetKeys() []*PendingKey {
+	if x != nil {
+		return x.Keys
+	}
+	return nil
+}
+
+type ApproveKeyRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Username      string                 `protobuf:"bytes,1,opt,name=Username,proto3" json:"Username,omitempty"`
+	Fingerprint   string                 `protobuf:"bytes,2,opt,name=Fingerprint,proto3" json:"Fingerprint,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ApproveKeyRequest) Reset() {
+	*x = ApproveKeyRequest{}
+	mi := &file_protos_messages_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ApproveKeyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ApproveKeyRequest) ProtoMessage() {}
+
+func (x *ApproveKeyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ApproveKeyRequest.ProtoReflect.Descriptor instead.
+func (*ApproveKeyRequest) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *ApproveKeyRequest) GetUsername() string {
+	if x != nil {
+		return x.Username
+	}
+	return ""
+}
+
+func (x *ApproveKeyRequest) GetFingerprint() string {
+	if x != nil {
+		return x.Fingerprint
+	}
+	return ""
+}
+
+type Member struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Username      string                 `protobuf:"bytes,1,opt,name=Username,proto3" json:"Username,omitempty"`
+	Role          string                 `protobuf:"bytes,2,opt,name=Role,proto3" json:"Role,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Member) Reset() {
+	*x = Member{}
+	mi := &file_protos_messages_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Member) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Member) ProtoMessage() {}
+
+func (x *Member) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Member.ProtoReflect.Descriptor instead.
+func (*Member) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *Member) GetUsername() string {
+	if x != nil {
+		return x.Username
+	}
+	return ""
+}
+
+func (x *Member) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+type ListMembersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Members       []*Member              `protobuf:"bytes,1,rep,name=Members,proto3" json:"Members,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListMembersResponse) Reset() {
+	*x = ListMembersResponse{}
+	mi := &file_protos_messages_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListMembersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListMembersResponse) ProtoMessage() {}
+
+func (x *ListMembersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListMembersResponse.ProtoReflect.Descriptor instead.
+func (*ListMembersResponse) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *ListMembersResponse) GetMembers() []*Member {
+	if x != nil {
+		return x.Members
+	}
+	return nil
+}
+
+type SetMemberRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Username      string                 `protobuf:"bytes,1,opt,name=Username,proto3" json:"Username,omitempty"`
+	Role          string                 `protobuf:"bytes,2,opt,name=Role,proto3" json:"Role,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetMemberRequest) Reset() {
+	*x = SetMemberRequest{}
+	mi := &file_protos_messages_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetMemberRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetMemberRequest) ProtoMessage() {}
+
+func (x *SetMemberRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[37]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetMemberRequest.ProtoReflect.Descriptor instead.
+func (*SetMemberRequest) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *SetMemberRequest) GetUsername() string {
+	if x != nil {
+		return x.Username
+	}
+	return ""
+}
+
+func (x *SetMemberRequest) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+type RemoveMemberRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Username      string                 `protobuf:"bytes,1,opt,name=Username,proto3" json:"Username,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveMemberRequest) Reset() {
+	*x = RemoveMemberRequest{}
+	mi := &file_protos_messages_proto_msgTypes[38]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveMemberRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveMemberRequest) ProtoMessage() {}
+
+func (x *RemoveMemberRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[38]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveMemberRequest.ProtoReflect.Descriptor instead.
+func (*RemoveMemberRequest) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{38}
+}
+
+func (x *RemoveMemberRequest) GetUsername() string {
+	if x != nil {
+		return x.Username
+	}
+	return ""
+}
+
+type GCRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DryRun        bool                   `protobuf:"varint,1,opt,name=DryRun,proto3" json:"DryRun,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GCRequest) Reset() {
+	*x = GCRequest{}
+	mi := &file_protos_messages_proto_msgTypes[39]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GCRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GCRequest) ProtoMessage() {}
+
+func (x *GCRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[39]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GCRequest.ProtoReflect.Descriptor instead.
+func (*GCRequest) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{39}
+}
+
+func (x *GCRequest) GetDryRun() bool {
+	if x != nil {
+		return x.DryRun
+	}
+	return false
+}
+
+type GCResponse struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	DryRun            bool                   `protobuf:"varint,1,opt,name=DryRun,proto3" json:"DryRun,omitempty"`
+	FilesMarked       int64                  `protobuf:"varint,2,opt,name=FilesMarked,proto3" json:"FilesMarked,omitempty"`
+	FilesDeleted      int64                  `protobuf:"varint,3,opt,name=FilesDeleted,proto3" json:"FilesDeleted,omitempty"`
+	BlobsMarked       int64                  `protobuf:"varint,4,opt,name=BlobsMarked,proto3" json:"BlobsMarked,omitempty"`
+	BlobsDeleted      int64                  `protobuf:"varint,5,opt,name=BlobsDeleted,proto3" json:"BlobsDeleted,omitempty"`
+	BytesFreed        int64                  `protobuf:"varint,6,opt,name=BytesFreed,proto3" json:"BytesFreed,omitempty"`
+	OperationsDeleted int64                  `protobuf:"varint,7,opt,name=OperationsDeleted,proto3" json:"OperationsDeleted,omitempty"`
+	SnapshotsDeleted  int64                  `protobuf:"varint,8,opt,name=SnapshotsDeleted,proto3" json:"SnapshotsDeleted,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *GCResponse) Reset() {
+	*x = GCResponse{}
+	mi := &file_protos_messages_proto_msgTypes[40]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GCResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GCResponse) ProtoMessage() {}
+
+func (x *GCResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[40]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GCResponse.ProtoReflect.Descriptor instead.
+func (*GCResponse) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{40}
+}
+
+func (x *GCResponse) GetDryRun() bool {
+	if x != nil {
+		return x.DryRun
+	}
+	return false
+}
+
+func (x *GCResponse) GetFilesMarked() int64 {
+	if x != nil {
+		return x.FilesMarked
+	}
+	return 0
+}
+
+func (x *GCResponse) GetFilesDeleted() int64 {
+	if x != nil {
+		return x.FilesDeleted
+	}
+	return 0
+}
+
+func (x *GCResponse) GetBlobsMarked() int64 {
+	if x != nil {
+		return x.BlobsMarked
+	}
+	return 0
+}
+
+func (x *GCResponse) GetBlobsDeleted() int64 {
+	if x != nil {
+		return x.BlobsDeleted
+	}
+	return 0
+}
+
+func (x *GCResponse) GetBytesFreed() int64 {
+	if x != nil {
+		return x.BytesFreed
+	}
+	return 0
+}
+
+func (x *GCResponse) GetOperationsDeleted() int64 {
+	if x != nil {
+		return x.OperationsDeleted
+	}
+	return 0
+}
+
+func (x *GCResponse) GetSnapshotsDeleted() int64 {
+	if x != nil {
+		return x.SnapshotsDeleted
+	}
+	return 0
+}
+
+type DiffRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// From is a revset that selects the old change. If empty, the parent of To is used.
+	From string `protobuf:"bytes,1,opt,name=From,proto3" json:"From,omitempty"`
+	// To is a revset that selects the new change. If empty, @ is used.
+	To string `protobuf:"bytes,2,opt,name=To,proto3" json:"To,omitempty"`
+	// Paths limit the diff to these files and directories (relative to the repository root).
+	Paths []string `protobuf:"bytes,3,rep,name=Paths,proto3" json:"Paths,omitempty"`
+	// Stat omits the patches, only the number of changed lines is returned.
+	Stat bool `protobuf:"varint,4,opt,name=Stat,proto3" json:"Stat,omitempty"`
+	// FromSnapshot selects a snapshot of the evolution log as the old state instead of From.
+	// If To is empty, the change of the snapshot is used.
+	FromSnapshot  *int64 `protobuf:"varint,5,opt,name=FromSnapshot,proto3,oneof" json:"FromSnapshot,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DiffRequest) Reset() {
+	*x = DiffRequest{}
+	mi := &file_protos_messages_proto_msgTypes[41]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DiffRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DiffRequest) ProtoMessage() {}
+
+func (x *DiffRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[41]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DiffRequest.ProtoReflect.Descriptor instead.
+func (*DiffRequest) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{41}
+}
+
+func (x *DiffRequest) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *DiffRequest) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+func (x *DiffRequest) GetPaths() []string {
+	if x != nil {
+		return x.Paths
+	}
+	return nil
+}
+
+func (x *DiffRequest) GetStat() bool {
+	if x != nil {
+		return x.Stat
+	}
+	return false
+}
+
+func (x *DiffRequest) GetFromSnapshot() int64 {
+	if x != nil && x.FromSnapshot != nil {
+		return *x.FromSnapshot
+	}
+	return 0
+}
+
+type DiffResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	From          string                 `protobuf:"bytes,1,opt,name=From,proto3" json:"From,omitempty"`
+	To            string                 `protobuf:"bytes,2,opt,name=To,proto3" json:"To,omitempty"`
+	Files         []*FileDiff            `protobuf:"bytes,3,rep,name=Files,proto3" json:"Files,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DiffResponse) Reset() {
+	*x = DiffResponse{}
+	mi := &file_protos_messages_proto_msgTypes[42]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DiffResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DiffResponse) ProtoMessage() {}
+
+func (x *DiffResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[42]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DiffResponse.ProtoReflect.Descriptor instead.
+func (*DiffResponse) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{42}
+}
+
+func (x *DiffResponse) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *DiffResponse) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+func (x *DiffResponse) GetFiles() []*FileDiff {
+	if x != nil {
+		return x.Files
+	}
+	return nil
+}
+
+type FileDiff struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Path  string                 `protobuf:"bytes,1,opt,name=Path,proto3" json:"Path,omitempty"`
+	// OldContentHash is empty if the file was added.
+	OldContentHash []byte `protobuf:"bytes,2,opt,name=OldContentHash,proto3" json:"OldContentHash,omitempty"`
+	// NewContentHash is empty if the file was removed.
+	NewContentHash []byte `protobuf:"bytes,3,opt,name=NewContentHash,proto3" json:"NewContentHash,omitempty"`
+	OldExecutable  bool   `protobuf:"varint,4,opt,name=OldExecutable,proto3" json:"OldExecutable,omitempty"`
+	NewExecutable  bool   `protobuf:"varint,5,opt,name=NewExecutable,proto3" json:"NewExecutable,omitempty"`
+	Binary         bool   `protobuf:"varint,6,opt,name=Binary,proto3" json:"Binary,omitempty"`
+	Additions      int32  `protobuf:"varint,7,opt,name=Additions,proto3" json:"Additions,omitempty"`
+	Deletions      int32  `protobuf:"varint,8,opt,name=Deletions,proto3" json:"Deletions,omitempty"`
+	// Patch is the unified diff of a text file.
+	Patch         string `protobuf:"bytes,9,opt,name=Patch,proto3" json:"Patch,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FileDiff) Reset() {
+	*x = FileDiff{}
+	mi := &file_protos_messages_proto_msgTypes[43]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FileDiff) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileDiff) ProtoMessage() {}
+
+func (x *FileDiff) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[43]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileDiff.ProtoReflect.Descriptor instead.
+func (*FileDiff) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{43}
+}
+
+func (x *FileDiff) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *FileDiff) GetOldContentHash() []byte {
+	if x != nil {
+		return x.OldContentHash
+	}
+	return nil
+}
+
+func (x *FileDiff) GetNewContentHash() []byte {
+	if x != nil {
+		return x.NewContentHash
+	}
+	return nil
+}
+
+func (x *FileDiff) GetOldExecutable() bool {
+	if x != nil {
+		return x.OldExecutable
+	}
+	return false
+}
+
+func (x *FileDiff) GetNewExecutable() bool {
+	if x != nil {
+		return x.NewExecutable
+	}
+	return false
+}
+
+func (x *FileDiff) GetBinary() bool {
+	if x != nil {
+		return x.Binary
+	}
+	return false
+}
+
+func (x *FileDiff) GetAdditions() int32 {
+	if x != nil {
+		return x.Additions
+	}
+	return 0
+}
+
+func (x *FileDiff) GetDeletions() int32 {
+	if x != nil {
+		return x.Deletions
+	}
+	return 0
+}
+
+func (x *FileDiff) GetPatch() string {
+	if x != nil {
+		return x.Patch
+	}
+	return ""
+}
+
+type StatusResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// the change the head of the requesting user and machine points at
+	ChangeId      int64         `protobuf:"varint,1,opt,name=ChangeId,proto3" json:"ChangeId,omitempty"`
+	ChangeName    string        `protobuf:"bytes,2,opt,name=ChangeName,proto3" json:"ChangeName,omitempty"`
+	ChangePrefix  string        `protobuf:"bytes,3,opt,name=ChangePrefix,proto3" json:"ChangePrefix,omitempty"`
+	Description   *string       `protobuf:"bytes,4,opt,name=Description,proto3,oneof" json:"Description,omitempty"`
+	Author        string        `protobuf:"bytes,5,opt,name=Author,proto3" json:"Author,omitempty"`
+	Device        string        `protobuf:"bytes,6,opt,name=Device,proto3" json:"Device,omitempty"`
+	HasChildren   bool          `protobuf:"varint,7,opt,name=HasChildren,proto3" json:"HasChildren,omitempty"`
+	Files         []*StatusFile `protobuf:"bytes,8,rep,name=Files,proto3" json:"Files,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatusResponse) Reset() {
+	*x = StatusResponse{}
+	mi := &file_protos_messages_proto_msgTypes[44]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatusResponse) ProtoMessage() {}
+
+func (x *StatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[44]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatusResponse.ProtoReflect.Descriptor instead.
+func (*StatusResponse) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{44}
+}
+
+func (x *StatusResponse) GetChangeId() int64 {
+	if x != nil {
+		return x.ChangeId
+	}
+	return 0
+}
+
+func (x *StatusResponse) GetChangeName() string {
+	if x != nil {
+		return x.ChangeName
+	}
+	return ""
+}
+
+func (x *StatusResponse) GetChangePrefix() string {
+	if x != nil {
+		return x.ChangePrefix
+	}
+	return ""
+}
+
+func (x *StatusResponse) GetDescription() string {
+	if x != nil && x.Description != nil {
+		return *x.Description
+	}
+	return ""
+}
+
+func (x *StatusResponse) GetAuthor() string {
+	if x != nil {
+		return x.Author
+	}
+	return ""
+}
+
+func (x *StatusResponse) GetDevice() string {
+	if x != nil {
+		return x.Device
+	}
+	return ""
+}
+
+func (x *StatusResponse) GetHasChildren() bool {
+	if x != nil {
+		return x.HasChildren
+	}
+	return false
+}
+
+func (x *StatusResponse) GetFiles() []*StatusFile {
+	if x != nil {
+		return x.Files
+	}
+	return nil
+}
+
+type StatusFile struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=Name,proto3" json:"Name,omitempty"`
+	Executable    bool                   `protobuf:"varint,2,opt,name=Executable,proto3" json:"Executable,omitempty"`
+	ContentHash   []byte                 `protobuf:"bytes,3,opt,name=ContentHash,proto3" json:"ContentHash,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatusFile) Reset() {
+	*x = StatusFile{}
+	mi := &file_protos_messages_proto_msgTypes[45]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatusFile) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatusFile) ProtoMessage() {}
+
+func (x *StatusFile) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[45]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatusFile.ProtoReflect.Descriptor instead.
+func (*StatusFile) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{45}
+}
+
+func (x *StatusFile) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *StatusFile) GetExecutable() bool {
+	if x != nil {
+		return x.Executable
+	}
+	return false
+}
+
+func (x *StatusFile) GetContentHash() []byte {
+	if x != nil {
+		return x.ContentHash
+	}
+	return nil
+}
+
+type FilesRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Change is a revset that selects one change.
+	Change        string `protobuf:"bytes,1,opt,name=Change,proto3" json:"Change,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FilesRequest) Reset() {
+	*x = FilesRequest{}
+	mi := &file_protos_messages_proto_msgTypes[46]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FilesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FilesRequest) ProtoMessage() {}
+
+func (x *FilesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[46]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FilesRequest.ProtoReflect.Descriptor instead.
+func (*FilesRequest) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{46}
+}
+
+func (x *FilesRequest) GetChange() string {
+	if x != nil {
+		return x.Change
+	}
+	return ""
+}
+
+type FilesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ChangeName    string                 `protobuf:"bytes,1,opt,name=ChangeName,proto3" json:"ChangeName,omitempty"`
+	Files         []*ChangeFile          `protobuf:"bytes,2,rep,name=Files,proto3" json:"Files,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FilesResponse) Reset() {
+	*x = FilesResponse{}
+	mi := &file_protos_messages_proto_msgTypes[47]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FilesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FilesResponse) ProtoMessage() {}
+
+func (x *FilesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[47]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FilesResponse.ProtoReflect.Descriptor instead.
+func (*FilesResponse) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{47}
+}
+
+func (x *FilesResponse) GetChangeName() string {
+	if x != nil {
+		return x.ChangeName
+	}
+	return ""
+}
+
+func (x *FilesResponse) GetFiles() []*ChangeFile {
+	if x != nil {
+		return x.Files
+	}
+	return nil
+}
+
+type ChangeFile struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Name        string                 `protobuf:"bytes,1,opt,name=Name,proto3" json:"Name,omitempty"`
+	Executable  bool                   `protobuf:"varint,2,opt,name=Executable,proto3" json:"Executable,omitempty"`
+	ContentHash []byte                 `protobuf:"bytes,3,opt,name=ContentHash,proto3" json:"ContentHash,omitempty"`
+	// Size is the size of the uncompressed content in bytes.
+	Size          int64 `protobuf:"varint,4,opt,name=Size,proto3" json:"Size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChangeFile) Reset() {
+	*x = ChangeFile{}
+	mi := &file_protos_messages_proto_msgTypes[48]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChangeFile) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChangeFile) ProtoMessage() {}
+
+func (x *ChangeFile) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[48]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChangeFile.ProtoReflect.Descriptor instead.
+func (*ChangeFile) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{48}
+}
+
+func (x *ChangeFile) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ChangeFile) GetExecutable() bool {
+	if x != nil {
+		return x.Executable
+	}
+	return false
+}
+
+func (x *ChangeFile) GetContentHash() []byte {
+	if x != nil {
+		return x.ContentHash
+	}
+	return nil
+}
+
+func (x *ChangeFile) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+type CatRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Change is a revset that selects one change.
+	Change string `protobuf:"bytes,1,opt,name=Change,proto3" json:"Change,omitempty"`
+	// Path of the file relative to the repository root.
+	Path          string `protobuf:"bytes,2,opt,name=Path,proto3" json:"Path,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CatRequest) Reset() {
+	*x = CatRequest{}
+	mi := &file_protos_messages_proto_msgTypes[49]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CatRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CatRequest) ProtoMessage() {}
+
+func (x *CatRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[49]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CatRequest.ProtoReflect.Descriptor instead.
+func (*CatRequest) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{49}
+}
+
+func (x *CatRequest) GetChange() string {
+	if x != nil {
+		return x.Change
+	}
+	return ""
+}
+
+func (x *CatRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+type AbandonRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Change is a revset that selects one change.
+	Change        string `protobuf:"bytes,1,opt,name=Change,proto3" json:"Change,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AbandonRequest) Reset() {
+	*x = AbandonRequest{}
+	mi := &file_protos_messages_proto_msgTypes[50]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AbandonRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AbandonRequest) ProtoMessage() {}
+
+func (x *AbandonRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[50]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AbandonRequest.ProtoReflect.Descriptor instead.
+func (*AbandonRequest) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{50}
+}
+
+func (x *AbandonRequest) GetChange() string {
+	if x != nil {
+		return x.Change
+	}
+	return ""
+}
+
+type AbandonResponse struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	ChangeName string                 `protobuf:"bytes,1,opt,name=ChangeName,proto3" json:"ChangeName,omitempty"`
+	// Parents of the abandoned change. Its children were reparented onto them.
+	Parents []string `protobuf:"bytes,2,rep,name=Parents,proto3" json:"Parents,omitempty"`
+	// Bookmarks (without heads) that pointed at the abandoned change and now point at its first parent.
+	Bookmarks []string `protobuf:"bytes,3,rep,name=Bookmarks,proto3" json:"Bookmarks,omitempty"`
+	// HeadAbandoned is true if the head of the requesting user and machine pointed at the abandoned change.
+	HeadAbandoned bool `protobuf:"varint,4,opt,name=HeadAbandoned,proto3" json:"HeadAbandoned,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AbandonResponse) Reset() {
+	*x = AbandonResponse{}
+	mi := &file_protos_messages_proto_msgTypes[51]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AbandonResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AbandonResponse) ProtoMessage() {}
+
+func (x *AbandonResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[51]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AbandonResponse.ProtoReflect.Descriptor instead.
+func (*AbandonResponse) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{51}
+}
+
+func (x *AbandonResponse) GetChangeName() string {
+	if x != nil {
+		return x.ChangeName
+	}
+	return ""
+}
+
+func (x *AbandonResponse) GetParents() []string {
+	if x != nil {
+		return x.Parents
+	}
+	return nil
+}
+
+func (x *AbandonResponse) GetBookmarks() []string {
+	if x != nil {
+		return x.Bookmarks
+	}
+	return nil
+}
+
+func (x *AbandonResponse) GetHeadAbandoned() bool {
+	if x != nil {
+		return x.HeadAbandoned
+	}
+	return false
+}
+
+type SquashRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Source is a revset that selects the change to squash.
+	Source string `protobuf:"bytes,1,opt,name=Source,proto3" json:"Source,omitempty"`
+	// Into is a revset that selects the target change. The parent of the source is used if empty.
+	Into string `protobuf:"bytes,2,opt,name=Into,proto3" json:"Into,omitempty"`
+	// Paths limits the squash to these paths relative to the repository root. All files are squashed if empty.
+	Paths []string `protobuf:"bytes,3,rep,name=Paths,proto3" json:"Paths,omitempty"`
+	// Description replaces the description of the target. The descriptions of both changes are combined if unset
+	// and the source ends up empty.
+	Description   *string `protobuf:"bytes,4,opt,name=Description,proto3,oneof" json:"Description,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SquashRequest) Reset() {
+	*x = SquashRequest{}
+	mi := &file_protos_messages_proto_msgTypes[52]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SquashRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SquashRequest) ProtoMessage() {}
+
+func (x *SquashRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[52]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SquashRequest.ProtoReflect.Descriptor instead.
+func (*SquashRequest) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{52}
+}
+
+func (x *SquashRequest) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *SquashRequest) GetInto() string {
+	if x != nil {
+		return x.Into
+	}
+	return ""
+}
+
+func (x *SquashRequest) GetPaths() []string {
+	if x != nil {
+		return x.Paths
+	}
+	return nil
+}
+
+func (x *SquashRequest) GetDescription() string {
+	if x != nil && x.Description != nil {
+		return *x.Description
 	}
 	return ""
 }
 
-func (x *FindChangeRequest) GetIncludeDescription() bool {
+type SquashResponse struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	SourceName string                 `protobuf:"bytes,1,opt,name=SourceName,proto3" json:"SourceName,omitempty"`
+	IntoName   string                 `protobuf:"bytes,2,opt,name=IntoName,proto3" json:"IntoName,omitempty"`
+	// Abandoned is set if the source ended up empty and was abandoned.
+	Abandoned     *AbandonResponse `protobuf:"bytes,3,opt,name=Abandoned,proto3" json:"Abandoned,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SquashResponse) Reset() {
+	*x = SquashResponse{}
+	mi := &file_protos_messages_proto_msgTypes[53]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SquashResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SquashResponse) ProtoMessage() {}
+
+func (x *SquashResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[53]
 	if x != nil {
-		return x.IncludeDescription
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SquashResponse.ProtoReflect.Descriptor instead.
+func (*SquashResponse) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{53}
+}
+
+func (x *SquashResponse) GetSourceName() string {
+	if x != nil {
+		return x.SourceName
+	}
+	return ""
+}
+
+func (x *SquashResponse) GetIntoName() string {
+	if x != nil {
+		return x.IntoName
+	}
+	return ""
+}
+
+func (x *SquashResponse) GetAbandoned() *AbandonResponse {
+	if x != nil {
+		return x.Abandoned
+	}
+	return nil
+}
+
+type RebaseRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Source is a revset that selects the change to move. Its descendants are moved along.
+	Source string `protobuf:"bytes,1,opt,name=Source,proto3" json:"Source,omitempty"`
+	// Destination is a revset that selects the new parent.
+	Destination   string `protobuf:"bytes,2,opt,name=Destination,proto3" json:"Destination,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RebaseRequest) Reset() {
+	*x = RebaseRequest{}
+	mi := &file_protos_messages_proto_msgTypes[54]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RebaseRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RebaseRequest) ProtoMessage() {}
+
+func (x *RebaseRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[54]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RebaseRequest.ProtoReflect.Descriptor instead.
+func (*RebaseRequest) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{54}
+}
+
+func (x *RebaseRequest) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *RebaseRequest) GetDestination() string {
+	if x != nil {
+		return x.Destination
+	}
+	return ""
+}
+
+type RebaseResponse struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	SourceName      string                 `protobuf:"bytes,1,opt,name=SourceName,proto3" json:"SourceName,omitempty"`
+	DestinationName string                 `protobuf:"bytes,2,opt,name=DestinationName,proto3" json:"DestinationName,omitempty"`
+	// Rebased are the names of the source and its descendants, parents first.
+	Rebased []string `protobuf:"bytes,3,rep,name=Rebased,proto3" json:"Rebased,omitempty"`
+	// Conflicts are the names of rebased changes that have conflicts.
+	Conflicts []string `protobuf:"bytes,4,rep,name=Conflicts,proto3" json:"Conflicts,omitempty"`
+	// Bookmarks (without heads) that point at rebased changes.
+	Bookmarks []string `protobuf:"bytes,5,rep,name=Bookmarks,proto3" json:"Bookmarks,omitempty"`
+	// HeadRebased is true if the head of the requesting user and machine points at a rebased change.
+	HeadRebased   bool `protobuf:"varint,6,opt,name=HeadRebased,proto3" json:"HeadRebased,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RebaseResponse) Reset() {
+	*x = RebaseResponse{}
+	mi := &file_protos_messages_proto_msgTypes[55]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RebaseResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RebaseResponse) ProtoMessage() {}
+
+func (x *RebaseResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[55]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RebaseResponse.ProtoReflect.Descriptor instead.
+func (*RebaseResponse) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{55}
+}
+
+func (x *RebaseResponse) GetSourceName() string {
+	if x != nil {
+		return x.SourceName
+	}
+	return ""
+}
+
+func (x *RebaseResponse) GetDestinationName() string {
+	if x != nil {
+		return x.DestinationName
+	}
+	return ""
+}
+
+func (x *RebaseResponse) GetRebased() []string {
+	if x != nil {
+		return x.Rebased
+	}
+	return nil
+}
+
+func (x *RebaseResponse) GetConflicts() []string {
+	if x != nil {
+		return x.Conflicts
+	}
+	return nil
+}
+
+func (x *RebaseResponse) GetBookmarks() []string {
+	if x != nil {
+		return x.Bookmarks
+	}
+	return nil
+}
+
+func (x *RebaseResponse) GetHeadRebased() bool {
+	if x != nil {
+		return x.HeadRebased
 	}
 	return false
 }
 
-type FindChangeResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	ChangeId      int64                  `protobuf:"varint,1,opt,name=ChangeId,proto3" json:"ChangeId,omitempty"`
-	Description   *string                `protobuf:"bytes,2,opt,name=Description,proto3,oneof" json:"Description,omitempty"`
+type Operation struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    int64                  `protobuf:"varint,1,opt,name=Id,proto3" json:"Id,omitempty"`
+	// Command that performed the operation, like "describe" or "undo 12".
+	Command   string                 `protobuf:"bytes,2,opt,name=Command,proto3" json:"Command,omitempty"`
+	Username  string                 `protobuf:"bytes,3,opt,name=Username,proto3" json:"Username,omitempty"`
+	Device    string                 `protobuf:"bytes,4,opt,name=Device,proto3" json:"Device,omitempty"`
+	CreatedAt *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=CreatedAt,proto3" json:"CreatedAt,omitempty"`
+	// Changes are the names of the changes the operation modified or created.
+	Changes []string `protobuf:"bytes,6,rep,name=Changes,proto3" json:"Changes,omitempty"`
+	// Bookmarks (without heads) the operation moved, created or deleted.
+	Bookmarks     []string `protobuf:"bytes,7,rep,name=Bookmarks,proto3" json:"Bookmarks,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Operation) Reset() {
+	*x = Operation{}
+	mi := &file_protos_messages_proto_msgTypes[56]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Operation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Operation) ProtoMessage() {}
+
+func (x *Operation) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[56]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Operation.ProtoReflect.Descriptor instead.
+func (*Operation) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{56}
+}
+
+func (x *Operation) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Operation) GetCommand() string {
+	if x != nil {
+		return x.Command
+	}
+	return ""
+}
+
+func (x *Operation) GetUsername() string {
+	if x != nil {
+		return x.Username
+	}
+	return ""
+}
+
+func (x *Operation) GetDevice() string {
+	if x != nil {
+		return x.Device
+	}
+	return ""
+}
+
+func (x *Operation) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Operation) GetChanges() []string {
+	if x != nil {
+		return x.Changes
+	}
+	return nil
+}
+
+func (x *Operation) GetBookmarks() []string {
+	if x != nil {
+		return x.Bookmarks
+	}
+	return nil
+}
+
+type OperationLogRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Limit is the maximum number of operations, newest first. 0 uses the server default.
+	Limit         int32 `protobuf:"varint,1,opt,name=Limit,proto3" json:"Limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OperationLogRequest) Reset() {
+	*x = OperationLogRequest{}
+	mi := &file_protos_messages_proto_msgTypes[57]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OperationLogRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OperationLogRequest) ProtoMessage() {}
+
+func (x *OperationLogRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[57]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OperationLogRequest.ProtoReflect.Descriptor instead.
+func (*OperationLogRequest) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{57}
+}
+
+func (x *OperationLogRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type OperationLogResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Operations    []*Operation           `protobuf:"bytes,1,rep,name=Operations,proto3" json:"Operations,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OperationLogResponse) Reset() {
+	*x = OperationLogResponse{}
+	mi := &file_protos_messages_proto_msgTypes[58]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OperationLogResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OperationLogResponse) ProtoMessage() {}
+
+func (x *OperationLogResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[58]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OperationLogResponse.ProtoReflect.Descriptor instead.
+func (*OperationLogResponse) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{58}
+}
+
+func (x *OperationLogResponse) GetOperations() []*Operation {
+	if x != nil {
+		return x.Operations
+	}
+	return nil
+}
+
+type UndoRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// OperationId defaults to the latest operation of the requesting user and machine.
+	OperationId   *int64 `protobuf:"varint,1,opt,name=OperationId,proto3,oneof" json:"OperationId,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *FindChangeResponse) Reset() {
-	*x = FindChangeResponse{}
-	mi := &file_protos_messages_proto_msgTypes[13]
+func (x *UndoRequest) Reset() {
+	*x = UndoRequest{}
+	mi := &file_protos_messages_proto_msgTypes[59]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *FindChangeResponse) String() string {
+func (x *UndoRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*FindChangeResponse) ProtoMessage() {}
+func (*UndoRequest) ProtoMessage() {}
 
-func (x *FindChangeResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_messages_proto_msgTypes[13]
+func (x *UndoRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[59]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -773,48 +3610,40 @@ func (x *FindChangeResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use FindChangeResponse.ProtoReflect.Descriptor instead.
-func (*FindChangeResponse) Descriptor() ([]byte, []int) {
-	return file_protos_messages_proto_rawDescGZIP(), []int{13}
+// Deprecated: Use UndoRequest.ProtoReflect.Descriptor instead.
+func (*UndoRequest) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{59}
 }
 
-func (x *FindChangeResponse) GetChangeId() int64 {
-	if x != nil {
-		return x.ChangeId
+func (x *UndoRequest) GetOperationId() int64 {
+	if x != nil && x.OperationId != nil {
+		return *x.OperationId
 	}
 	return 0
 }
 
-func (x *FindChangeResponse) GetDescription() string {
-	if x != nil && x.Description != nil {
-		return *x.Description
-	}
-	return ""
-}
-
-type DescribeRequest struct {
+type UndoResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Change        string                 `protobuf:"bytes,1,opt,name=Change,proto3" json:"Change,omitempty"`
-	Description   string                 `protobuf:"bytes,2,opt,name=Description,proto3" json:"Description,omitempty"`
+	Undone        *Operation             `protobuf:"bytes,1,opt,name=Undone,proto3" json:"Undone,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *DescribeRequest) Reset() {
-	*x = DescribeRequest{}
-	mi := &file_protos_messages_proto_msgTypes[14]
+func (x *UndoResponse) Reset() {
+	*x = UndoResponse{}
+	mi := &file_protos_messages_proto_msgTypes[60]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *DescribeRequest) String() string {
+func (x *UndoResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*DescribeRequest) ProtoMessage() {}
+func (*UndoResponse) ProtoMessage() {}
 
-func (x *DescribeRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_messages_proto_msgTypes[14]
+func (x *UndoResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[60]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -825,47 +3654,86 @@ func (x *DescribeRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use DescribeRequest.ProtoReflect.Descriptor instead.
-func (*DescribeRequest) Descriptor() ([]byte, []int) {
-	return file_protos_messages_proto_rawDescGZIP(), []int{14}
+// Deprecated: Use UndoResponse.ProtoReflect.Descriptor instead.
+func (*UndoResponse) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{60}
 }
 
-func (x *DescribeRequest) GetChange() string {
+func (x *UndoResponse) GetUndone() *Operation {
 	if x != nil {
-		return x.Change
+		return x.Undone
 	}
-	return ""
+	return nil
 }
 
-func (x *DescribeRequest) GetDescription() string {
+type RestoreOperationRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// OperationId is the operation whose resulting state is restored.
+	OperationId   int64 `protobuf:"varint,1,opt,name=OperationId,proto3" json:"OperationId,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RestoreOperationRequest) Reset() {
+	*x = RestoreOperationRequest{}
+	mi := &file_protos_messages_proto_msgTypes[61]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RestoreOperationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestoreOperationRequest) ProtoMessage() {}
+
+func (x *RestoreOperationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[61]
 	if x != nil {
-		return x.Description
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return ""
+	return mi.MessageOf(x)
 }
 
-type ListBookmarksResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Bookmarks     []*Bookmark            `protobuf:"bytes,1,rep,name=Bookmarks,proto3" json:"Bookmarks,omitempty"`
+// Deprecated: Use RestoreOperationRequest.ProtoReflect.Descriptor instead.
+func (*RestoreOperationRequest) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{61}
+}
+
+func (x *RestoreOperationRequest) GetOperationId() int64 {
+	if x != nil {
+		return x.OperationId
+	}
+	return 0
+}
+
+type RestoreOperationResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Reverted are the operations after the restored one, newest first.
+	Reverted      []*Operation `protobuf:"bytes,1,rep,name=Reverted,proto3" json:"Reverted,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ListBookmarksResponse) Reset() {
-	*x = ListBookmarksResponse{}
-	mi := &file_protos_messages_proto_msgTypes[15]
+func (x *RestoreOperationResponse) Reset() {
+	*x = RestoreOperationResponse{}
+	mi := &file_protos_messages_proto_msgTypes[62]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ListBookmarksResponse) String() string {
+func (x *RestoreOperationResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ListBookmarksResponse) ProtoMessage() {}
+func (*RestoreOperationResponse) ProtoMessage() {}
 
-func (x *ListBookmarksResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_messages_proto_msgTypes[15]
+func (x *RestoreOperationResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[62]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -876,43 +3744,49 @@ func (x *ListBookmarksResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ListBookmarksResponse.ProtoReflect.Descriptor instead.
-func (*ListBookmarksResponse) Descriptor() ([]byte, []int) {
-	return file_protos_messages_proto_rawDescGZIP(), []int{15}
+// Deprecated: Use RestoreOperationResponse.ProtoReflect.Descriptor instead.
+func (*RestoreOperationResponse) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{62}
 }
 
-func (x *ListBookmarksResponse) GetBookmarks() []*Bookmark {
+func (x *RestoreOperationResponse) GetReverted() []*Operation {
 	if x != nil {
-		return x.Bookmarks
+		return x.Reverted
 	}
 	return nil
 }
 
-type Bookmark struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	BookmarkName  string                 `protobuf:"bytes,1,opt,name=BookmarkName,proto3" json:"BookmarkName,omitempty"`
-	ChangeID      int64                  `protobuf:"varint,2,opt,name=ChangeID,proto3" json:"ChangeID,omitempty"`
-	ChangeName    string                 `protobuf:"bytes,3,opt,name=ChangeName,proto3" json:"ChangeName,omitempty"`
-	ChangePrefix  string                 `protobuf:"bytes,4,opt,name=ChangePrefix,proto3" json:"ChangePrefix,omitempty"`
+// ChangeSnapshot is a recorded state of a change in its evolution log.
+type ChangeSnapshot struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Id          int64                  `protobuf:"varint,1,opt,name=Id,proto3" json:"Id,omitempty"`
+	CreatedAt   *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=CreatedAt,proto3" json:"CreatedAt,omitempty"`
+	Description *string                `protobuf:"bytes,3,opt,name=Description,proto3,oneof" json:"Description,omitempty"`
+	// OperationId is the operation that produced the snapshot.
+	// It is 0 for the state the change had before its first logged operation.
+	OperationId   int64  `protobuf:"varint,4,opt,name=OperationId,proto3" json:"OperationId,omitempty"`
+	Command       string `protobuf:"bytes,5,opt,name=Command,proto3" json:"Command,omitempty"`
+	Username      string `protobuf:"bytes,6,opt,name=Username,proto3" json:"Username,omitempty"`
+	Device        string `protobuf:"bytes,7,opt,name=Device,proto3" json:"Device,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *Bookmark) Reset() {
-	*x = Bookmark{}
-	mi := &file_protos_messages_proto_msgTypes[16]
+func (x *ChangeSnapshot) Reset() {
+	*x = ChangeSnapshot{}
+	mi := &file_protos_messages_proto_msgTypes[63]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *Bookmark) String() string {
+func (x *ChangeSnapshot) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Bookmark) ProtoMessage() {}
+func (*ChangeSnapshot) ProtoMessage() {}
 
-func (x *Bookmark) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_messages_proto_msgTypes[16]
+func (x *ChangeSnapshot) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[63]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -923,61 +3797,83 @@ func (x *Bookmark) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Bookmark.ProtoReflect.Descriptor instead.
-func (*Bookmark) Descriptor() ([]byte, []int) {
-	return file_protos_messages_proto_rawDescGZIP(), []int{16}
+// Deprecated: Use ChangeSnapshot.ProtoReflect.Descriptor instead.
+func (*ChangeSnapshot) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{63}
 }
 
-func (x *Bookmark) GetBookmarkName() string {
+func (x *ChangeSnapshot) GetId() int64 {
 	if x != nil {
-		return x.BookmarkName
+		return x.Id
+	}
+	return 0
+}
+
+func (x *ChangeSnapshot) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *ChangeSnapshot) GetDescription() string {
+	if x != nil && x.Description != nil {
+		return *x.Description
 	}
 	return ""
 }
 
-func (x *Bookmark) GetChangeID() int64 {
+func (x *ChangeSnapshot) GetOperationId() int64 {
 	if x != nil {
-		return x.ChangeID
+		return x.OperationId
 	}
 	return 0
 }
 
-func (x *Bookmark) GetChangeName() string {
+func (x *ChangeSnapshot) GetCommand() string {
 	if x != nil {
-		return x.ChangeName
+		return x.Command
 	}
 	return ""
 }
 
-func (x *Bookmark) GetChangePrefix() string {
+func (x *ChangeSnapshot) GetUsername() string {
 	if x != nil {
-		return x.ChangePrefix
+		return x.Username
 	}
 	return ""
 }
 
-type ConflictsRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Change        string                 `protobuf:"bytes,1,opt,name=Change,proto3" json:"Change,omitempty"`
+func (x *ChangeSnapshot) GetDevice() string {
+	if x != nil {
+		return x.Device
+	}
+	return ""
+}
+
+type EvologRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Change is a revset that selects one change.
+	Change        string `protobuf:"bytes,1,opt,name=Change,proto3" json:"Change,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ConflictsRequest) Reset() {
-	*x = ConflictsRequest{}
-	mi := &file_protos_messages_proto_msgTypes[17]
+func (x *EvologRequest) Reset() {
+	*x = EvologRequest{}
+	mi := &file_protos_messages_proto_msgTypes[64]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ConflictsRequest) String() string {
+func (x *EvologRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ConflictsRequest) ProtoMessage() {}
+func (*EvologRequest) ProtoMessage() {}
 
-func (x *ConflictsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_messages_proto_msgTypes[17]
+func (x *EvologRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[64]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -988,40 +3884,93 @@ func (x *ConflictsRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ConflictsRequest.ProtoReflect.Descriptor instead.
-func (*ConflictsRequest) Descriptor() ([]byte, []int) {
-	return file_protos_messages_proto_rawDescGZIP(), []int{17}
+// Deprecated: Use EvologRequest.ProtoReflect.Descriptor instead.
+func (*EvologRequest) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{64}
 }
 
-func (x *ConflictsRequest) GetChange() string {
+func (x *EvologRequest) GetChange() string {
 	if x != nil {
 		return x.Change
 	}
 	return ""
 }
 
-type ConflictsResponse struct {
+type EvologResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Name  string                 `protobuf:"bytes,1,opt,name=Name,proto3" json:"Name,omitempty"`
+	// Snapshots newest first.
+	Snapshots     []*ChangeSnapshot `protobuf:"bytes,2,rep,name=Snapshots,proto3" json:"Snapshots,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EvologResponse) Reset() {
+	*x = EvologResponse{}
+	mi := &file_protos_messages_proto_msgTypes[65]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EvologResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EvologResponse) ProtoMessage() {}
+
+func (x *EvologResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[65]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EvologResponse.ProtoReflect.Descriptor instead.
+func (*EvologResponse) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{65}
+}
+
+func (x *EvologResponse) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *EvologResponse) GetSnapshots() []*ChangeSnapshot {
+	if x != nil {
+		return x.Snapshots
+	}
+	return nil
+}
+
+type RestoreSnapshotRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Conflicts     []string               `protobuf:"bytes,1,rep,name=Conflicts,proto3" json:"Conflicts,omitempty"`
+	SnapshotId    int64                  `protobuf:"varint,1,opt,name=SnapshotId,proto3" json:"SnapshotId,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ConflictsResponse) Reset() {
-	*x = ConflictsResponse{}
-	mi := &file_protos_messages_proto_msgTypes[18]
+func (x *RestoreSnapshotRequest) Reset() {
+	*x = RestoreSnapshotRequest{}
+	mi := &file_protos_messages_proto_msgTypes[66]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ConflictsResponse) String() string {
+func (x *RestoreSnapshotRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ConflictsResponse) ProtoMessage() {}
+func (*RestoreSnapshotRequest) ProtoMessage() {}
 
-func (x *ConflictsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_messages_proto_msgTypes[18]
+func (x *RestoreSnapshotRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[66]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1032,23 +3981,94 @@ func (x *ConflictsResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ConflictsResponse.ProtoReflect.Descriptor instead.
-func (*ConflictsResponse) Descriptor() ([]byte, []int) {
-	return file_protos_messages_proto_rawDescGZIP(), []int{18}
+// Deprecated: Use RestoreSnapshotRequest.ProtoReflect.Descriptor instead.
+func (*RestoreSnapshotRequest) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{66}
 }
 
-func (x *ConflictsResponse) GetConflicts() []string {
+func (x *RestoreSnapshotRequest) GetSnapshotId() int64 {
+	if x != nil {
+		return x.SnapshotId
+	}
+	return 0
+}
+
+type RestoreSnapshotResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Name of the change that got the files and description of the snapshot.
+	Name string `protobuf:"bytes,1,opt,name=Name,proto3" json:"Name,omitempty"`
+	// Rebased are the descendants that were replayed onto the restored change.
+	Rebased   []string `protobuf:"bytes,2,rep,name=Rebased,proto3" json:"Rebased,omitempty"`
+	Conflicts []string `protobuf:"bytes,3,rep,name=Conflicts,proto3" json:"Conflicts,omitempty"`
+	// HeadChanged is set if the change of the requester's working copy was modified.
+	HeadChanged   bool `protobuf:"varint,4,opt,name=HeadChanged,proto3" json:"HeadChanged,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RestoreSnapshotResponse) Reset() {
+	*x = RestoreSnapshotResponse{}
+	mi := &file_protos_messages_proto_msgTypes[67]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RestoreSnapshotResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestoreSnapshotResponse) ProtoMessage() {}
+
+func (x *RestoreSnapshotResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_messages_proto_msgTypes[67]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestoreSnapshotResponse.ProtoReflect.Descriptor instead.
+func (*RestoreSnapshotResponse) Descriptor() ([]byte, []int) {
+	return file_protos_messages_proto_rawDescGZIP(), []int{67}
+}
+
+func (x *RestoreSnapshotResponse) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *RestoreSnapshotResponse) GetRebased() []string {
+	if x != nil {
+		return x.Rebased
+	}
+	return nil
+}
+
+func (x *RestoreSnapshotResponse) GetConflicts() []string {
 	if x != nil {
 		return x.Conflicts
 	}
 	return nil
 }
 
+func (x *RestoreSnapshotResponse) GetHeadChanged() bool {
+	if x != nil {
+		return x.HeadChanged
+	}
+	return false
+}
+
 var File_protos_messages_proto protoreflect.FileDescriptor
 
 const file_protos_messages_proto_rawDesc = "" +
 	"\n" +
-	"\x15protos/messages.proto\x12\x06protos\x1a\x1fgoogle/protobuf/timestamp.proto\"\xa3\x02\n" +
+	"\x15protos/messages.proto\x12\x06protos\x1a\x1fgoogle/protobuf/timestamp.proto\"\xb9\x02\n" +
 	"\rHTTPSignature\x12\x1b\n" +
 	"\tbody_hash\x18\x01 \x01(\fR\bbodyHash\x12!\n" +
 	"\frequest_path\x18\x02 \x01(\tR\vrequestPath\x128\n" +
@@ -1057,7 +4077,9 @@ const file_protos_messages_proto_rawDesc = "" +
 	"\n" +
 	"machine_id\x18\x05 \x01(\tR\tmachineId\x12\x1d\n" +
 	"\n" +
-	"public_key\x18\x06 \x01(\fR\tpublicKey\x12\x16\n" +
+	"public_key\x18\x06 \x01(\fR\tpublicKey\x12\x14\n" +
+	"\x05nonce\x18\n" +
+	" \x01(\fR\x05nonce\x12\x16\n" +
 	"\x06format\x18\a \x01(\tR\x06format\x12\x12\n" +
 	"\x04blob\x18\b \x01(\fR\x04blob\x12\x12\n" +
 	"\x04rest\x18\t \x01(\fR\x04rest\"?\n" +
@@ -1065,7 +4087,13 @@ const file_protos_messages_proto_rawDesc = "" +
 	"\x04Name\x18\x01 \x01(\tR\x04Name\x12\x1c\n" +
 	"\tBookmarks\x18\x02 \x03(\tR\tBookmarks\"&\n" +
 	"\fInitResponse\x12\x16\n" +
-	"\x06RepoID\x18\x01 \x01(\x05R\x06RepoID\"\xa2\x01\n" +
+	"\x06RepoID\x18\x01 \x01(\x05R\x06RepoID\">\n" +
+	"\fCloneRequest\x12\x12\n" +
+	"\x04Repo\x18\x01 \x01(\tR\x04Repo\x12\x1a\n" +
+	"\bBookmark\x18\x02 \x01(\tR\bBookmark\"C\n" +
+	"\rCloneResponse\x12\x16\n" +
+	"\x06RepoID\x18\x01 \x01(\x05R\x06RepoID\x12\x1a\n" +
+	"\bChangeId\x18\x02 \x01(\x03R\bChangeId\"\xa2\x01\n" +
 	"\fPushFileInfo\x12\x12\n" +
 	"\x04Name\x18\x01 \x01(\tR\x04Name\x12#\n" +
 	"\n" +
@@ -1082,21 +4110,60 @@ const file_protos_messages_proto_rawDesc = "" +
 	"\aParents\x18\x01 \x03(\tR\aParents\x12%\n" +
 	"\vdescription\x18\x02 \x01(\tH\x00R\vdescription\x88\x01\x01\x12\"\n" +
 	"\fSetBookmarks\x18\x03 \x03(\tR\fSetBookmarksB\x0e\n" +
-	"\f_description\"L\n" +
+	"\f_description\"\xaa\x01\n" +
 	"\x12SetBookmarkRequest\x12\x1a\n" +
 	"\bBookmark\x18\x01 \x01(\tR\bBookmark\x12\x1a\n" +
-	"\bChangeId\x18\x02 \x01(\x03R\bChangeId\"/\n" +
+	"\bChangeId\x18\x02 \x01(\x03R\bChangeId\x12\x16\n" +
+	"\x06Change\x18\x03 \x01(\tR\x06Change\x12&\n" +
+	"\x0eAllowBackwards\x18\x04 \x01(\bR\x0eAllowBackwards\x12\x1c\n" +
+	"\tMustExist\x18\x05 \x01(\bR\tMustExist\"3\n" +
+	"\x15DeleteBookmarkRequest\x12\x1a\n" +
+	"\bBookmark\x18\x01 \x01(\tR\bBookmark\"M\n" +
+	"\x15RenameBookmarkRequest\x12\x1a\n" +
+	"\bBookmark\x18\x01 \x01(\tR\bBookmark\x12\x18\n" +
+	"\aNewName\x18\x02 \x01(\tR\aNewName\"\xca\x01\n" +
+	"\fBookmarkRule\x12\x1a\n" +
+	"\bBookmark\x18\x01 \x01(\tR\bBookmark\x12(\n" +
+	"\x0fDescendantsOnly\x18\x02 \x01(\bR\x0fDescendantsOnly\x12 \n" +
+	"\vNoConflicts\x18\x03 \x01(\bR\vNoConflicts\x12.\n" +
+	"\x12RequireDescription\x18\x04 \x01(\bR\x12RequireDescription\x12\"\n" +
+	"\fAllowedUsers\x18\x05 \x03(\tR\fAllowedUsers\"7\n" +
+	"\x19RemoveBookmarkRuleRequest\x12\x1a\n" +
+	"\bBookmark\x18\x01 \x01(\tR\bBookmark\"G\n" +
+	"\x19ListBookmarkRulesResponse\x12*\n" +
+	"\x05Rules\x18\x01 \x03(\v2\x14.protos.BookmarkRuleR\x05Rules\"/\n" +
 	"\x11NewChangeResponse\x12\x1a\n" +
+	"\bChangeId\x18\x01 \x01(\x03R\bChangeId\",\n" +
+	"\x0eSetHeadRequest\x12\x1a\n" +
 	"\bChangeId\x18\x01 \x01(\x03R\bChangeId\"-\n" +
 	"\x0fCheckoutRequest\x12\x1a\n" +
-	"\bChangeId\x18\x01 \x01(\x03R\bChangeId\"R\n" +
+	"\bChangeId\x18\x01 \x01(\x03R\bChangeId\"^\n" +
 	"\n" +
 	"LogRequest\x12\x12\n" +
 	"\x04Head\x18\x01 \x01(\tR\x04Head\x12\x14\n" +
-	"\x05Limit\x18\x02 \x01(\x05R\x05Limit\x12\x1a\n" +
-	"\bTimeZone\x18\x03 \x01(\tR\bTimeZone\"\x1f\n" +
-	"\vLogResponse\x12\x10\n" +
-	"\x03Log\x18\x01 \x01(\tR\x03Log\"W\n" +
+	"\x05Limit\x18\x02 \x01(\x05R\x05Limit\x12\x16\n" +
+	"\x06Revset\x18\x04 \x01(\tR\x06RevsetJ\x04\b\x03\x10\x04R\bTimeZone\"\x80\x01\n" +
+	"\vLogResponse\x12\x12\n" +
+	"\x04Head\x18\x02 \x01(\x03R\x04Head\x12+\n" +
+	"\aChanges\x18\x03 \x03(\v2\x11.protos.LogChangeR\aChanges\x12%\n" +
+	"\x05Edges\x18\x04 \x03(\v2\x0f.protos.LogEdgeR\x05EdgesJ\x04\b\x01\x10\x02R\x03Log\"\xdc\x02\n" +
+	"\tLogChange\x12\x0e\n" +
+	"\x02Id\x18\x01 \x01(\x03R\x02Id\x12\x12\n" +
+	"\x04Name\x18\x02 \x01(\tR\x04Name\x12\x16\n" +
+	"\x06Prefix\x18\x03 \x01(\tR\x06Prefix\x12%\n" +
+	"\vDescription\x18\x04 \x01(\tH\x00R\vDescription\x88\x01\x01\x12\x16\n" +
+	"\x06Author\x18\x05 \x01(\tR\x06Author\x12\x16\n" +
+	"\x06Device\x18\x06 \x01(\tR\x06Device\x12\x14\n" +
+	"\x05Depth\x18\a \x01(\x03R\x05Depth\x128\n" +
+	"\tCreatedAt\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\tCreatedAt\x128\n" +
+	"\tUpdatedAt\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\tUpdatedAt\x12\"\n" +
+	"\fHasConflicts\x18\n" +
+	" \x01(\bR\fHasConflictsB\x0e\n" +
+	"\f_Description\"S\n" +
+	"\aLogEdge\x12\x1a\n" +
+	"\bChangeId\x18\x01 \x01(\x03R\bChangeId\x12\x1f\n" +
+	"\bParentId\x18\x02 \x01(\x03H\x00R\bParentId\x88\x01\x01B\v\n" +
+	"\t_ParentId\"W\n" +
 	"\x11FindChangeRequest\x12\x12\n" +
 	"\x04Name\x18\x01 \x01(\tR\x04Name\x12.\n" +
 	"\x12IncludeDescription\x18\x02 \x01(\bR\x12IncludeDescription\"g\n" +
@@ -1117,9 +4184,197 @@ const file_protos_messages_proto_rawDesc = "" +
 	"ChangeName\x12\"\n" +
 	"\fChangePrefix\x18\x04 \x01(\tR\fChangePrefix\"*\n" +
 	"\x10ConflictsRequest\x12\x16\n" +
-	"\x06Change\x18\x01 \x01(\tR\x06Change\"1\n" +
+	"\x06Change\x18\x01 \x01(\tR\x06Change\"]\n" +
 	"\x11ConflictsResponse\x12\x1c\n" +
-	"\tConflicts\x18\x01 \x03(\tR\tConflictsB&Z$github.com/tsukinoko-kun/pogo/protosb\x06proto3"
+	"\tConflicts\x18\x01 \x03(\tR\tConflicts\x12*\n" +
+	"\x05Files\x18\x02 \x03(\v2\x14.protos.FileConflictR\x05Files\"\x9a\x01\n" +
+	"\fFileConflict\x12\x12\n" +
+	"\x04Path\x18\x01 \x01(\tR\x04Path\x12\x12\n" +
+	"\x04Kind\x18\x02 \x01(\tR\x04Kind\x12-\n" +
+	"\x04Base\x18\x03 \x01(\v2\x14.protos.ConflictSideH\x00R\x04Base\x88\x01\x01\x12*\n" +
+	"\x05Sides\x18\x04 \x03(\v2\x14.protos.ConflictSideR\x05SidesB\a\n" +
+	"\x05_Base\"h\n" +
+	"\fConflictSide\x12\x16\n" +
+	"\x06Change\x18\x01 \x01(\tR\x06Change\x12 \n" +
+	"\vContentHash\x18\x02 \x01(\fR\vContentHash\x12\x1e\n" +
+	"\n" +
+	"Executable\x18\x03 \x01(\bR\n" +
+	"Executable\"D\n" +
+	"\x10RegisterResponse\x12\x1a\n" +
+	"\bApproved\x18\x01 \x01(\bR\bApproved\x12\x14\n" +
+	"\x05Admin\x18\x02 \x01(\bR\x05Admin\"\x84\x01\n" +
+	"\n" +
+	"PendingKey\x12\x1a\n" +
+	"\bUsername\x18\x01 \x01(\tR\bUsername\x12 \n" +
+	"\vFingerprint\x18\x02 \x01(\tR\vFingerprint\x128\n" +
+	"\tCreatedAt\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\tCreatedAt\"A\n" +
+	"\x17ListPendingKeysResponse\x12&\n" +
+	"\x04Keys\x18\x01 \x03(\v2\x12.protos.PendingKeyR\x04Keys\"Q\n" +
+	"\x11ApproveKeyRequest\x12\x1a\n" +
+	"\bUsername\x18\x01 \x01(\tR\bUsername\x12 \n" +
+	"\vFingerprint\x18\x02 \x01(\tR\vFingerprint\"8\n" +
+	"\x06Member\x12\x1a\n" +
+	"\bUsername\x18\x01 \x01(\tR\bUsername\x12\x12\n" +
+	"\x04Role\x18\x02 \x01(\tR\x04Role\"?\n" +
+	"\x13ListMembersResponse\x12(\n" +
+	"\aMembers\x18\x01 \x03(\v2\x0e.protos.MemberR\aMembers\"B\n" +
+	"\x10SetMemberRequest\x12\x1a\n" +
+	"\bUsername\x18\x01 \x01(\tR\bUsername\x12\x12\n" +
+	"\x04Role\x18\x02 \x01(\tR\x04Role\"1\n" +
+	"\x13RemoveMemberRequest\x12\x1a\n" +
+	"\bUsername\x18\x01 \x01(\tR\bUsername\"#\n" +
+	"\tGCRequest\x12\x16\n" +
+	"\x06DryRun\x18\x01 \x01(\bR\x06DryRun\"\xaa\x02\n" +
+	"\n" +
+	"GCResponse\x12\x16\n" +
+	"\x06DryRun\x18\x01 \x01(\bR\x06DryRun\x12 \n" +
+	"\vFilesMarked\x18\x02 \x01(\x03R\vFilesMarked\x12\"\n" +
+	"\fFilesDeleted\x18\x03 \x01(\x03R\fFilesDeleted\x12 \n" +
+	"\vBlobsMarked\x18\x04 \x01(\x03R\vBlobsMarked\x12\"\n" +
+	"\fBlobsDeleted\x18\x05 \x01(\x03R\fBlobsDeleted\x12\x1e\n" +
+	"\n" +
+	"BytesFreed\x18\x06 \x01(\x03R\n" +
+	"BytesFreed\x12,\n" +
+	"\x11OperationsDeleted\x18\a \x01(\x03R\x11OperationsDeleted\x12*\n" +
+	"\x10SnapshotsDeleted\x18\b \x01(\x03R\x10SnapshotsDeleted\"\x95\x01\n" +
+	"\vDiffRequest\x12\x12\n" +
+	"\x04From\x18\x01 \x01(\tR\x04From\x12\x0e\n" +
+	"\x02To\x18\x02 \x01(\tR\x02To\x12\x14\n" +
+	"\x05Paths\x18\x03 \x03(\tR\x05Paths\x12\x12\n" +
+	"\x04Stat\x18\x04 \x01(\bR\x04Stat\x12'\n" +
+	"\fFromSnapshot\x18\x05 \x01(\x03H\x00R\fFromSnapshot\x88\x01\x01B\x0f\n" +
+	"\r_FromSnapshot\"Z\n" +
+	"\fDiffResponse\x12\x12\n" +
+	"\x04From\x18\x01 \x01(\tR\x04From\x12\x0e\n" +
+	"\x02To\x18\x02 \x01(\tR\x02To\x12&\n" +
+	"\x05Files\x18\x03 \x03(\v2\x10.protos.FileDiffR\x05Files\"\xa4\x02\n" +
+	"\bFileDiff\x12\x12\n" +
+	"\x04Path\x18\x01 \x01(\tR\x04Path\x12&\n" +
+	"\x0eOldContentHash\x18\x02 \x01(\fR\x0eOldContentHash\x12&\n" +
+	"\x0eNewContentHash\x18\x03 \x01(\fR\x0eNewContentHash\x12$\n" +
+	"\rOldExecutable\x18\x04 \x01(\bR\rOldExecutable\x12$\n" +
+	"\rNewExecutable\x18\x05 \x01(\bR\rNewExecutable\x12\x16\n" +
+	"\x06Binary\x18\x06 \x01(\bR\x06Binary\x12\x1c\n" +
+	"\tAdditions\x18\a \x01(\x05R\tAdditions\x12\x1c\n" +
+	"\tDeletions\x18\b \x01(\x05R\tDeletions\x12\x14\n" +
+	"\x05Patch\x18\t \x01(\tR\x05Patch\"\xa3\x02\n" +
+	"\x0eStatusResponse\x12\x1a\n" +
+	"\bChangeId\x18\x01 \x01(\x03R\bChangeId\x12\x1e\n" +
+	"\n" +
+	"ChangeName\x18\x02 \x01(\tR\n" +
+	"ChangeName\x12\"\n" +
+	"\fChangePrefix\x18\x03 \x01(\tR\fChangePrefix\x12%\n" +
+	"\vDescription\x18\x04 \x01(\tH\x00R\vDescription\x88\x01\x01\x12\x16\n" +
+	"\x06Author\x18\x05 \x01(\tR\x06Author\x12\x16\n" +
+	"\x06Device\x18\x06 \x01(\tR\x06Device\x12 \n" +
+	"\vHasChildren\x18\a \x01(\bR\vHasChildren\x12(\n" +
+	"\x05Files\x18\b \x03(\v2\x12.protos.StatusFileR\x05FilesB\x0e\n" +
+	"\f_Description\"b\n" +
+	"\n" +
+	"StatusFile\x12\x12\n" +
+	"\x04Name\x18\x01 \x01(\tR\x04Name\x12\x1e\n" +
+	"\n" +
+	"Executable\x18\x02 \x01(\bR\n" +
+	"Executable\x12 \n" +
+	"\vContentHash\x18\x03 \x01(\fR\vContentHash\"&\n" +
+	"\fFilesRequest\x12\x16\n" +
+	"\x06Change\x18\x01 \x01(\tR\x06Change\"Y\n" +
+	"\rFilesResponse\x12\x1e\n" +
+	"\n" +
+	"ChangeName\x18\x01 \x01(\tR\n" +
+	"ChangeName\x12(\n" +
+	"\x05Files\x18\x02 \x03(\v2\x12.protos.ChangeFileR\x05Files\"v\n" +
+	"\n" +
+	"ChangeFile\x12\x12\n" +
+	"\x04Name\x18\x01 \x01(\tR\x04Name\x12\x1e\n" +
+	"\n" +
+	"Executable\x18\x02 \x01(\bR\n" +
+	"Executable\x12 \n" +
+	"\vContentHash\x18\x03 \x01(\fR\vContentHash\x12\x12\n" +
+	"\x04Size\x18\x04 \x01(\x03R\x04Size\"8\n" +
+	"\n" +
+	"CatRequest\x12\x16\n" +
+	"\x06Change\x18\x01 \x01(\tR\x06Change\x12\x12\n" +
+	"\x04Path\x18\x02 \x01(\tR\x04Path\"(\n" +
+	"\x0eAbandonRequest\x12\x16\n" +
+	"\x06Change\x18\x01 \x01(\tR\x06Change\"\x8f\x01\n" +
+	"\x0fAbandonResponse\x12\x1e\n" +
+	"\n" +
+	"ChangeName\x18\x01 \x01(\tR\n" +
+	"ChangeName\x12\x18\n" +
+	"\aParents\x18\x02 \x03(\tR\aParents\x12\x1c\n" +
+	"\tBookmarks\x18\x03 \x03(\tR\tBookmarks\x12$\n" +
+	"\rHeadAbandoned\x18\x04 \x01(\bR\rHeadAbandoned\"\x88\x01\n" +
+	"\rSquashRequest\x12\x16\n" +
+	"\x06Source\x18\x01 \x01(\tR\x06Source\x12\x12\n" +
+	"\x04Into\x18\x02 \x01(\tR\x04Into\x12\x14\n" +
+	"\x05Paths\x18\x03 \x03(\tR\x05Paths\x12%\n" +
+	"\vDescription\x18\x04 \x01(\tH\x00R\vDescription\x88\x01\x01B\x0e\n" +
+	"\f_Description\"\x83\x01\n" +
+	"\x0eSquashResponse\x12\x1e\n" +
+	"\n" +
+	"SourceName\x18\x01 \x01(\tR\n" +
+	"SourceName\x12\x1a\n" +
+	"\bIntoName\x18\x02 \x01(\tR\bIntoName\x125\n" +
+	"\tAbandoned\x18\x03 \x01(\v2\x17.protos.AbandonResponseR\tAbandoned\"I\n" +
+	"\rRebaseRequest\x12\x16\n" +
+	"\x06Source\x18\x01 \x01(\tR\x06Source\x12 \n" +
+	"\vDestination\x18\x02 \x01(\tR\vDestination\"\xd2\x01\n" +
+	"\x0eRebaseResponse\x12\x1e\n" +
+	"\n" +
+	"SourceName\x18\x01 \x01(\tR\n" +
+	"SourceName\x12(\n" +
+	"\x0fDestinationName\x18\x02 \x01(\tR\x0fDestinationName\x12\x18\n" +
+	"\aRebased\x18\x03 \x03(\tR\aRebased\x12\x1c\n" +
+	"\tConflicts\x18\x04 \x03(\tR\tConflicts\x12\x1c\n" +
+	"\tBookmarks\x18\x05 \x03(\tR\tBookmarks\x12 \n" +
+	"\vHeadRebased\x18\x06 \x01(\bR\vHeadRebased\"\xdb\x01\n" +
+	"\tOperation\x12\x0e\n" +
+	"\x02Id\x18\x01 \x01(\x03R\x02Id\x12\x18\n" +
+	"\aCommand\x18\x02 \x01(\tR\aCommand\x12\x1a\n" +
+	"\bUsername\x18\x03 \x01(\tR\bUsername\x12\x16\n" +
+	"\x06Device\x18\x04 \x01(\tR\x06Device\x128\n" +
+	"\tCreatedAt\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\tCreatedAt\x12\x18\n" +
+	"\aChanges\x18\x06 \x03(\tR\aChanges\x12\x1c\n" +
+	"\tBookmarks\x18\a \x03(\tR\tBookmarks\"+\n" +
+	"\x13OperationLogRequest\x12\x14\n" +
+	"\x05Limit\x18\x01 \x01(\x05R\x05Limit\"I\n" +
+	"\x14OperationLogResponse\x121\n" +
+	"\n" +
+	"Operations\x18\x01 \x03(\v2\x11.protos.OperationR\n" +
+	"Operations\"D\n" +
+	"\vUndoRequest\x12%\n" +
+	"\vOperationId\x18\x01 \x01(\x03H\x00R\vOperationId\x88\x01\x01B\x0e\n" +
+	"\f_OperationId\"9\n" +
+	"\fUndoResponse\x12)\n" +
+	"\x06Undone\x18\x01 \x01(\v2\x11.protos.OperationR\x06Undone\";\n" +
+	"\x17RestoreOperationRequest\x12 \n" +
+	"\vOperationId\x18\x01 \x01(\x03R\vOperationId\"I\n" +
+	"\x18RestoreOperationResponse\x12-\n" +
+	"\bReverted\x18\x01 \x03(\v2\x11.protos.OperationR\bReverted\"\x81\x02\n" +
+	"\x0eChangeSnapshot\x12\x0e\n" +
+	"\x02Id\x18\x01 \x01(\x03R\x02Id\x128\n" +
+	"\tCreatedAt\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\tCreatedAt\x12%\n" +
+	"\vDescription\x18\x03 \x01(\tH\x00R\vDescription\x88\x01\x01\x12 \n" +
+	"\vOperationId\x18\x04 \x01(\x03R\vOperationId\x12\x18\n" +
+	"\aCommand\x18\x05 \x01(\tR\aCommand\x12\x1a\n" +
+	"\bUsername\x18\x06 \x01(\tR\bUsername\x12\x16\n" +
+	"\x06Device\x18\a \x01(\tR\x06DeviceB\x0e\n" +
+	"\f_Description\"'\n" +
+	"\rEvologRequest\x12\x16\n" +
+	"\x06Change\x18\x01 \x01(\tR\x06Change\"Z\n" +
+	"\x0eEvologResponse\x12\x12\n" +
+	"\x04Name\x18\x01 \x01(\tR\x04Name\x124\n" +
+	"\tSnapshots\x18\x02 \x03(\v2\x16.protos.ChangeSnapshotR\tSnapshots\"8\n" +
+	"\x16RestoreSnapshotRequest\x12\x1e\n" +
+	"\n" +
+	"SnapshotId\x18\x01 \x01(\x03R\n" +
+	"SnapshotId\"\x87\x01\n" +
+	"\x17RestoreSnapshotResponse\x12\x12\n" +
+	"\x04Name\x18\x01 \x01(\tR\x04Name\x12\x18\n" +
+	"\aRebased\x18\x02 \x03(\tR\aRebased\x12\x1c\n" +
+	"\tConflicts\x18\x03 \x03(\tR\tConflicts\x12 \n" +
+	"\vHeadChanged\x18\x04 \x01(\bR\vHeadChangedB&Z$github.com/tsukinoko-kun/pogo/protosb\x06proto3"
 
 var (
 	file_protos_messages_proto_rawDescOnce sync.Once
@@ -1133,37 +4388,107 @@ func file_protos_messages_proto_rawDescGZIP() []byte {
 	return file_protos_messages_proto_rawDescData
 }
 
-var file_protos_messages_proto_msgTypes = make([]protoimpl.MessageInfo, 19)
+var file_protos_messages_proto_msgTypes = make([]protoimpl.MessageInfo, 68)
 var file_protos_messages_proto_goTypes = []any{
-	(*HTTPSignature)(nil),            // 0: protos.HTTPSignature
-	(*InitRequest)(nil),              // 1: protos.InitRequest
-	(*InitResponse)(nil),             // 2: protos.InitResponse
-	(*PushFileInfo)(nil),             // 3: protos.PushFileInfo
-	(*CheckFilesExistsRequest)(nil),  // 4: protos.CheckFilesExistsRequest
-	(*CheckFilesExistsResponse)(nil), // 5: protos.CheckFilesExistsResponse
-	(*NewChangeRequest)(nil),         // 6: protos.NewChangeRequest
-	(*SetBookmarkRequest)(nil),       // 7: protos.SetBookmarkRequest
-	(*NewChangeResponse)(nil),        // 8: protos.NewChangeResponse
-	(*CheckoutRequest)(nil),          // 9: protos.CheckoutRequest
-	(*LogRequest)(nil),               // 10: protos.LogRequest
-	(*LogResponse)(nil),              // 11: protos.LogResponse
-	(*FindChangeRequest)(nil),        // 12: protos.FindChangeRequest
-	(*FindChangeResponse)(nil),       // 13: protos.FindChangeResponse
-	(*DescribeRequest)(nil),          // 14: protos.DescribeRequest
-	(*ListBookmarksResponse)(nil),    // 15: protos.ListBookmarksResponse
-	(*Bookmark)(nil),                 // 16: protos.Bookmark
-	(*ConflictsRequest)(nil),         // 17: protos.ConflictsRequest
-	(*ConflictsResponse)(nil),        // 18: protos.ConflictsResponse
-	(*timestamppb.Timestamp)(nil),    // 19: google.protobuf.Timestamp
+	(*HTTPSignature)(nil),             // 0: protos.HTTPSignature
+	(*InitRequest)(nil),               // 1: protos.InitRequest
+	(*InitResponse)(nil),              // 2: protos.InitResponse
+	(*CloneRequest)(nil),              // 3: protos.CloneRequest
+	(*CloneResponse)(nil),             // 4: protos.CloneResponse
+	(*PushFileInfo)(nil),              // 5: protos.PushFileInfo
+	(*CheckFilesExistsRequest)(nil),   // 6: protos.CheckFilesExistsRequest
+	(*CheckFilesExistsResponse)(nil),  // 7: protos.CheckFilesExistsResponse
+	(*NewChangeRequest)(nil),          // 8: protos.NewChangeRequest
+	(*SetBookmarkRequest)(nil),        // 9: protos.SetBookmarkRequest
+	(*DeleteBookmarkRequest)(nil),     // 10: protos.DeleteBookmarkRequest
+	(*RenameBookmarkRequest)(nil),     // 11: protos.RenameBookmarkRequest
+	(*BookmarkRule)(nil),              // 12: protos.BookmarkRule
+	(*RemoveBookmarkRuleRequest)(nil), // 13: protos.RemoveBookmarkRuleRequest
+	(*ListBookmarkRulesResponse)(nil), // 14: protos.ListBookmarkRulesResponse
+	(*NewChangeResponse)(nil),         // 15: protos.NewChangeResponse
+	(*SetHeadRequest)(nil),            // 16: protos.SetHeadRequest
+	(*CheckoutRequest)(nil),           // 17: protos.CheckoutRequest
+	(*LogRequest)(nil),                // 18: protos.LogRequest
+	(*LogResponse)(nil),               // 19: protos.LogResponse
+	(*LogChange)(nil),                 // 20: protos.LogChange
+	(*LogEdge)(nil),                   // 21: protos.LogEdge
+	(*FindChangeRequest)(nil),         // 22: protos.FindChangeRequest
+	(*FindChangeResponse)(nil),        // 23: protos.FindChangeResponse
+	(*DescribeRequest)(nil),           // 24: protos.DescribeRequest
+	(*ListBookmarksResponse)(nil),     // 25: protos.ListBookmarksResponse
+	(*Bookmark)(nil),                  // 26: protos.Bookmark
+	(*ConflictsRequest)(nil),          // 27: protos.ConflictsRequest
+	(*ConflictsResponse)(nil),         // 28: protos.ConflictsResponse
+	(*FileConflict)(nil),              // 29: protos.FileConflict
+	(*ConflictSide)(nil),              // 30: protos.ConflictSide
+	(*RegisterResponse)(nil),          // 31: protos.RegisterResponse
+	(*PendingKey)(nil),                // 32: protos.PendingKey
+	(*ListPendingKeysResponse)(nil),   // 33: protos.ListPendingKeysResponse
+	(*ApproveKeyRequest)(nil),         // 34: protos.ApproveKeyRequest
+	(*Member)(nil),                    // 35: protos.Member
+	(*ListMembersResponse)(nil),       // 36: protos.ListMembersResponse
+	(*SetMemberRequest)(nil),          // 37: protos.SetMemberRequest
+	(*RemoveMemberRequest)(nil),       // 38: protos.RemoveMemberRequest
+	(*GCRequest)(nil),                 // 39: protos.GCRequest
+	(*GCResponse)(nil),                // 40: protos.GCResponse
+	(*DiffRequest)(nil),               // 41: protos.DiffRequest
+	(*DiffResponse)(nil),              // 42: protos.DiffResponse
+	(*FileDiff)(nil),                  // 43: protos.FileDiff
+	(*StatusResponse)(nil),            // 44: protos.StatusResponse
+	(*StatusFile)(nil),                // 45: protos.StatusFile
+	(*FilesRequest)(nil),              // 46: protos.FilesRequest
+	(*FilesResponse)(nil),             // 47: protos.FilesResponse
+	(*ChangeFile)(nil),                // 48: protos.ChangeFile
+	(*CatRequest)(nil),                // 49: protos.CatRequest
+	(*AbandonRequest)(nil),            // 50: protos.AbandonRequest
+	(*AbandonResponse)(nil),           // 51: protos.AbandonResponse
+	(*SquashRequest)(nil),             // 52: protos.SquashRequest
+	(*SquashResponse)(nil),            // 53: protos.SquashResponse
+	(*RebaseRequest)(nil),             // 54: protos.RebaseRequest
+	(*RebaseResponse)(nil),            // 55: protos.RebaseResponse
+	(*Operation)(nil),                 // 56: protos.Operation
+	(*OperationLogRequest)(nil),       // 57: protos.OperationLogRequest
+	(*OperationLogResponse)(nil),      // 58: protos.OperationLogResponse
+	(*UndoRequest)(nil),               // 59: protos.UndoRequest
+	(*UndoResponse)(nil),              // 60: protos.UndoResponse
+	(*RestoreOperationRequest)(nil),   // 61: protos.RestoreOperationRequest
+	(*RestoreOperationResponse)(nil),  // 62: protos.RestoreOperationResponse
+	(*ChangeSnapshot)(nil),            // 63: protos.ChangeSnapshot
+	(*EvologRequest)(nil),             // 64: protos.EvologRequest
+	(*EvologResponse)(nil),            // 65: protos.EvologResponse
+	(*RestoreSnapshotRequest)(nil),    // 66: protos.RestoreSnapshotRequest
+	(*RestoreSnapshotResponse)(nil),   // 67: protos.RestoreSnapshotResponse
+	(*timestamppb.Timestamp)(nil),     // 68: google.protobuf.Timestamp
 }
 var file_protos_messages_proto_depIdxs = []int32{
-	19, // 0: protos.HTTPSignature.timestamp:type_name -> google.protobuf.Timestamp
-	16, // 1: protos.ListBookmarksResponse.Bookmarks:type_name -> protos.Bookmark
-	2,  // [2:2] is the sub-list for method output_type
-	2,  // [2:2] is the sub-list for method input_type
-	2,  // [2:2] is the sub-list for extension type_name
-	2,  // [2:2] is the sub-list for extension extendee
-	0,  // [0:2] is the sub-list for field type_name
+	68, // 0: protos.HTTPSignature.timestamp:type_name -> google.protobuf.Timestamp
+	12, // 1: protos.ListBookmarkRulesResponse.Rules:type_name -> protos.BookmarkRule
+	20, // 2: protos.LogResponse.Changes:type_name -> protos.LogChange
+	21, // 3: protos.LogResponse.Edges:type_name -> protos.LogEdge
+	68, // 4: protos.LogChange.CreatedAt:type_name -> google.protobuf.Timestamp
+	68, // 5: protos.LogChange.UpdatedAt:type_name -> google.protobuf.Timestamp
+	26, // 6: protos.ListBookmarksResponse.Bookmarks:type_name -> protos.Bookmark
+	29, // 7: protos.ConflictsResponse.Files:type_name -> protos.FileConflict
+	30, // 8: protos.FileConflict.Base:type_name -> protos.ConflictSide
+	30, // 9: protos.FileConflict.Sides:type_name -> protos.ConflictSide
+	68, // 10: protos.PendingKey.CreatedAt:type_name -> google.protobuf.Timestamp
+	32, // 11: protos.ListPendingKeysResponse.Keys:type_name -> protos.PendingKey
+	35, // 12: protos.ListMembersResponse.Members:type_name -> protos.Member
+	43, // 13: protos.DiffResponse.Files:type_name -> protos.FileDiff
+	45, // 14: protos.StatusResponse.Files:type_name -> protos.StatusFile
+	48, // 15: protos.FilesResponse.Files:type_name -> protos.ChangeFile
+	51, // 16: protos.SquashResponse.Abandoned:type_name -> protos.AbandonResponse
+	68, // 17: protos.Operation.CreatedAt:type_name -> google.protobuf.Timestamp
+	56, // 18: protos.OperationLogResponse.Operations:type_name -> protos.Operation
+	56, // 19: protos.UndoResponse.Undone:type_name -> protos.Operation
+	56, // 20: protos.RestoreOperationResponse.Reverted:type_name -> protos.Operation
+	68, // 21: protos.ChangeSnapshot.CreatedAt:type_name -> google.protobuf.Timestamp
+	63, // 22: protos.EvologResponse.Snapshots:type_name -> protos.ChangeSnapshot
+	23, // [23:23] is the sub-list for method output_type
+	23, // [23:23] is the sub-list for method input_type
+	23, // [23:23] is the sub-list for extension type_name
+	23, // [23:23] is the sub-list for extension extendee
+	0,  // [0:23] is the sub-list for field type_name
 }
 
 func init() { file_protos_messages_proto_init() }
@@ -1171,16 +4496,24 @@ func file_protos_messages_proto_init() {
 	if File_protos_messages_proto != nil {
 		return
 	}
-	file_protos_messages_proto_msgTypes[3].OneofWrappers = []any{}
-	file_protos_messages_proto_msgTypes[6].OneofWrappers = []any{}
-	file_protos_messages_proto_msgTypes[13].OneofWrappers = []any{}
+	file_protos_messages_proto_msgTypes[5].OneofWrappers = []any{}
+	file_protos_messages_proto_msgTypes[8].OneofWrappers = []any{}
+	file_protos_messages_proto_msgTypes[20].OneofWrappers = []any{}
+	file_protos_messages_proto_msgTypes[21].OneofWrappers = []any{}
+	file_protos_messages_proto_msgTypes[23].OneofWrappers = []any{}
+	file_protos_messages_proto_msgTypes[29].OneofWrappers = []any{}
+	file_protos_messages_proto_msgTypes[41].OneofWrappers = []any{}
+	file_protos_messages_proto_msgTypes[44].OneofWrappers = []any{}
+	file_protos_messages_proto_msgTypes[52].OneofWrappers = []any{}
+	file_protos_messages_proto_msgTypes[59].OneofWrappers = []any{}
+	file_protos_messages_proto_msgTypes[63].OneofWrappers = []any{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_protos_messages_proto_rawDesc), len(file_protos_messages_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   19,
+			NumMessages:   68,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -1,52 +1,75 @@
 package repos
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/tsukinoko-kun/pogo/blobstore"
 	"github.com/tsukinoko-kun/pogo/utils"
+	"hash"
 	"io"
-	"os"
-	"path/filepath"
 )
 
-func (r Repo) ContentHashToFileName(contentHash []byte) string {
-	str := base64.RawURLEncoding.EncodeToString(contentHash)
-	return filepath.Join("content", str[:2], str[2:])
+// blobs holds the file contents of all repositories.
+var blobs blobstore.BlobStore = blobstore.NewFileSystem("content")
+
+// SetBlobStore sets where file contents are stored. The default is the directory "content" in the working directory.
+func SetBlobStore(store blobstore.BlobStore) {
+	blobs = store
+}
+
+// BlobStore returns the store that holds the file contents of all repositories.
+func BlobStore() blobstore.BlobStore {
+	return blobs
 }
 
 func (r Repo) FileExists(contentHash []byte) (bool, error) {
-	name := r.ContentHashToFileName(contentHash)
-	_, err := os.Stat(name)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return false, nil
-		}
-		return false, err
-	}
-	return true, nil
+	return blobs.Exists(contentHash)
 }
 
-func (r Repo) GetFileInfo(contentHash []byte) (os.FileInfo, error) {
-	name := r.ContentHashToFileName(contentHash)
-	return os.Stat(name)
+func (r Repo) GetFileInfo(contentHash []byte) (blobstore.BlobInfo, error) {
+	return blobs.Stat(contentHash)
 }
 
 func (r Repo) GetFileContent(contentHash []byte) (io.ReadCloser, error) {
-	name := r.ContentHashToFileName(contentHash)
-	f, err := os.Open(name)
-	if err != nil {
-		return nil, err
+	return blobs.Open(contentHash)
+}
+
+// SetFileContent stores content under contentHash and returns the uncompressed size of the content.
+// The content is hashed while it is compressed into the blob store.
+// If the hash doesn't match, ErrContentHashMismatch is returned and nothing is stored.
+func (r Repo) SetFileContent(contentHash []byte, content io.Reader) (int64, error) {
+	verified := &hashVerifyingReader{
+		r:            content,
+		hasher:       sha256.New(),
+		expectedHash: contentHash,
 	}
-	return f, nil
+	if err := blobs.Put(contentHash, utils.Compress(verified)); err != nil {
+		return 0, err
+	}
+	return verified.size, nil
+}
+
+func (r Repo) RemoveFileContent(contentHash []byte) error {
+	return blobs.Delete(contentHash)
+}
+
+// hashVerifyingReader returns ErrContentHashMismatch instead of io.EOF if the content doesn't match expectedHash.
+type hashVerifyingReader struct {
+	r            io.Reader
+	hasher       hash.Hash
+	expectedHash []byte
+	size         int64
 }
 
-func (r Repo) SetFileContent(contentHash []byte, content io.Reader) error {
-	name := r.ContentHashToFileName(contentHash)
-	_ = os.MkdirAll(filepath.Dir(name), 0755)
-	f, err := os.Create(name)
-	if err != nil {
-		return err
+func (v *hashVerifyingReader) Read(p []byte) (int, error) {
+	n, err := v.r.Read(p)
+	v.hasher.Write(p[:n])
+	v.size += int64(n)
+	if errors.Is(err, io.EOF) && !bytes.Equal(v.hasher.Sum(nil), v.expectedHash) {
+		return n, fmt.Errorf("%w: %s", ErrContentHashMismatch, base64.RawURLEncoding.EncodeToString(v.expectedHash))
 	}
-	defer f.Close()
-	_, err = io.Copy(f, utils.Compress(content))
-	return err
+	return n, err
 }
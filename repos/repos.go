@@ -4,19 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"github.com/tsukinoko-kun/pogo/colors"
 	"github.com/tsukinoko-kun/pogo/db"
-	"github.com/tsukinoko-kun/pogo/runedrawer"
-	"io"
-	"math"
-	"os"
-	"slices"
-	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
-	"github.com/nulab/autog"
-	"github.com/nulab/autog/graph"
 )
 
 type Repo int32
@@ -26,7 +17,8 @@ func (r Repo) ID() int32 {
 }
 
 var (
-	ErrRepoNotFound = errors.New("repo not found")
+	ErrRepoNotFound        = errors.New("repo not found")
+	ErrContentHashMismatch = errors.New("content does not match its content hash")
 )
 
 func OpenByName(name string) (Repo, error) {
@@ -37,6 +29,18 @@ func OpenByName(name string) (Repo, error) {
 	return Repo(r), err
 }
 
+// OpenByID opens a repository by its id and returns ErrRepoNotFound if it doesn't exist.
+func OpenByID(id int32) (Repo, error) {
+	exists, err := db.Q.RepoExists(context.Background(), id)
+	if err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, ErrRepoNotFound
+	}
+	return Repo(id), nil
+}
+
 func Open(id int32) Repo {
 	return Repo(id)
 }
@@ -49,14 +53,13 @@ func Create(q db.Querier, name string) (Repo, error) {
 type LogOptions struct {
 	// Ctx is the context to use for the database queries.
 	Ctx context.Context
-	// Target is the writer to which the log output is written. If Target is nil, the default is os.Stdout.
-	Target io.Writer
 	// Limit limits the number of commits to show. If Limit is 0 or less, the default limit is used. The default limit is 10.
 	Limit int32
-	// TimeZone specifies the time zone to use for the change timestamps.
-	TimeZone *time.Location
 	// Head is the commit to start the log from.
 	Head int64
+	// Changes, if not nil, are logged instead of the ancestry of Head.
+	// The newest changes are logged first until the limit is reached.
+	Changes []int64
 }
 
 type LogChangeInfo struct {
@@ -72,197 +75,87 @@ type LogChangeInfo struct {
 	HasConflicts bool
 }
 
-func (r Repo) PrintLog(opts LogOptions) error {
+// LogEdge connects a change to one of its parents.
+type LogEdge struct {
+	ChangeID int64
+	// ParentID is nil for a change without parents.
+	ParentID *int64
+}
+
+// Log returns the history of opts.Head as changes and edges between them.
+// Edges can point to parents that are not part of the returned changes because of the limit.
+func (r Repo) Log(opts LogOptions) ([]LogChangeInfo, []LogEdge, error) {
 	if opts.Limit <= 0 {
 		opts.Limit = 10
 	}
-	if opts.Target == nil {
-		opts.Target = os.Stdout
-	}
 
 	tx, err := db.Q.Begin(opts.Ctx)
 	if err != nil {
-		return errors.Join(errors.New("begin transaction"), err)
+		return nil, nil, errors.Join(errors.New("begin transaction"), err)
 	}
 	defer tx.Close()
 
-	// construct the graph object from an ordered adjacency list
-	var adjacencyList [][]string
-	nodeIdChangeMap := make(map[string]LogChangeInfo)
-
-	{
-		ancestry, err := tx.GetAncestryOfChange(opts.Ctx, opts.Head, opts.Limit, r.ID())
+	var ancestry []db.GetAncestryOfChangeRow
+	if opts.Changes != nil {
+		rows, err := tx.GetLogChanges(opts.Ctx, r.ID(), opts.Changes)
 		if err != nil {
-			return errors.Join(errors.New("get ancestry of change"), err)
-		}
-
-		if len(ancestry) == 0 {
-			return errors.New("no history found")
+			return nil, nil, errors.Join(errors.New("get log changes"), err)
 		}
-
-		if len(ancestry) == 1 {
-			c := ancestry[0]
-			prefix, err := tx.GetChangePrefix(opts.Ctx, c.ID, r.ID())
-			if err != nil {
-				prefix = c.Name
-			}
-			_, _ = fmt.Fprint(opts.Target, colors.Magenta+prefix+colors.Reset)
-			_, _ = fmt.Fprintln(opts.Target, colors.BrightBlack+c.Name[len(prefix):]+colors.Reset)
-			if c.Description != nil {
-				_, _ = fmt.Fprintln(opts.Target, *c.Description)
-			} else {
-				_, _ = fmt.Fprintln(opts.Target, colors.Green+"(no description set)"+colors.Reset)
+		// rows are ordered newest first, one row per parent
+		count := int32(0)
+		for i, row := range rows {
+			if i == 0 || rows[i-1].ID != row.ID {
+				if count == opts.Limit {
+					break
+				}
+				count++
 			}
-			_, _ = fmt.Fprintln(opts.Target, colors.BrightBlack+
-				fmt.Sprintf(
-					"%s %s",
-					c.Author,
-					c.UpdatedAt.Time.In(opts.TimeZone).Format(time.DateTime),
-				)+colors.Reset,
-			)
-			return nil
+			ancestry = append(ancestry, db.GetAncestryOfChangeRow(row))
 		}
-
-		for _, c := range ancestry {
-			stringId := fmt.Sprintf("%d", c.ID)
-			conflicts, err := tx.HasChangeConflicts(opts.Ctx, c.ID)
-			if err != nil {
-				return errors.Join(fmt.Errorf("get change %s conflicts", c.Name), err)
-			}
-			prefix, err := tx.GetChangePrefix(opts.Ctx, c.ID, r.ID())
-			if err != nil {
-				return errors.Join(fmt.Errorf("get change %s prefix", c.Name), err)
-			}
-			nodeIdChangeMap[stringId] = LogChangeInfo{
-				c.ID,
-				prefix,
-				c.Name,
-				c.Description,
-				c.Author,
-				c.Device,
-				c.Depth,
-				c.CreatedAt.Time.In(opts.TimeZone),
-				c.UpdatedAt.Time.In(opts.TimeZone),
-				conflicts,
-			}
-			if c.ParentID != nil {
-				adjacencyList = append(adjacencyList, []string{
-					stringId,
-					fmt.Sprintf("%d", *c.ParentID),
-				})
-			} else {
-				adjacencyList = append(adjacencyList, []string{stringId, "~"})
-			}
+	} else {
+		ancestry, err = tx.GetAncestryOfChange(opts.Ctx, opts.Head, opts.Limit, r.ID())
+		if err != nil {
+			return nil, nil, errors.Join(errors.New("get ancestry of change"), err)
 		}
 	}
 
-	if len(adjacencyList) == 0 {
-		return errors.New("found history, but generated adjacency list is empty")
+	if len(ancestry) == 0 && opts.Changes == nil {
+		return nil, nil, errors.New("no history found")
 	}
 
-	// obtain a graph.Source (here by converting the input to EdgeSlice)
-	src := graph.EdgeSlice(adjacencyList)
-
-	// run the default autolayout pipeline
-	layout := autog.Layout(
-		src,
-		autog.WithNodeFixedSize(0, 0),
-		autog.WithOrdering(autog.OrderingWMedian),
-		autog.WithPositioning(autog.PositioningBrandesKoepf),
-		autog.WithEdgeRouting(autog.EdgeRoutingOrtho),
-		autog.WithNodeVerticalSpacing(2),
-		autog.WithNodeSpacing(4),
-		autog.WithLayerSpacing(0),
-	)
-
-	drawer := runedrawer.New()
+	var changes []LogChangeInfo
+	var edges []LogEdge
+	known := make(map[int64]struct{}, len(ancestry))
 
-	for _, e := range layout.Edges {
-		if e.FromID == "~" || e.ToID == "~" {
+	// there is one row per parent of a change
+	for _, c := range ancestry {
+		edges = append(edges, LogEdge{ChangeID: c.ID, ParentID: c.ParentID})
+		if _, ok := known[c.ID]; ok {
 			continue
 		}
-		var spline runedrawer.Spline
-		for _, p := range e.Points {
-			spline = append(spline, runedrawer.Point{
-				X: int(math.Round(p[0])),
-				Y: int(math.Round(p[1])),
-			})
-		}
-		drawer.DrawSpline(spline)
-	}
-	drawer.EncodeCorners()
-
-	changeMinHeight := make(map[int64]int)
-	for _, n := range layout.Nodes {
-		if n.ID == "~" {
-			continue
-		}
-		x := int(math.Round(n.X))
-		y := int(math.Round(n.Y))
-		change := nodeIdChangeMap[n.ID]
-		if prevY, ok := changeMinHeight[change.ID]; ok {
-			changeMinHeight[change.ID] = min(prevY, y)
-		} else {
-			changeMinHeight[change.ID] = y
-		}
-		var color string
-		if change.HasConflicts {
-			color = colors.Red
-		} else {
-			color = colors.White
-		}
-
-		if change.ID == opts.Head {
-			drawer.WriteX(x, y, color, "●", colors.Reset)
-		} else {
-			drawer.WriteX(x, y, color, "○", colors.Reset)
-		}
-	}
-
-	// write change details
-	paddingLeft := drawer.Width() + 2
-	var writtenChangeIds []int64
-	for _, change := range nodeIdChangeMap {
-		if slices.Contains(writtenChangeIds, change.ID) {
-			continue
-		}
-		writtenChangeIds = append(writtenChangeIds, change.ID)
-
-		height := 0
-		if minHeight, ok := changeMinHeight[change.ID]; ok {
-			height = minHeight
-		}
+		known[c.ID] = struct{}{}
 
-		suffix := change.Name[len(change.Prefix):]
-		drawer.WriteX(paddingLeft, height, colors.Magenta, change.Prefix, colors.Reset)
-		drawer.WriteX(paddingLeft+len(change.Prefix), height, colors.BrightBlack, suffix, colors.Reset)
-		meta := fmt.Sprintf(
-			"%s %s",
-			change.Author,
-			change.UpdatedAt.Format(time.DateTime),
-		)
-		if change.HasConflicts && change.ID == opts.Head {
-			meta += " ⚠️ " + colors.Red + "conflict" + colors.Reset
+		conflicts, err := tx.HasChangeConflicts(opts.Ctx, c.ID)
+		if err != nil {
+			return nil, nil, errors.Join(fmt.Errorf("get change %s conflicts", c.Name), err)
 		}
-		drawer.WriteX(paddingLeft+len(change.Name)+1, height+1, colors.BrightBlack, meta, colors.Reset)
-		if change.Description != nil {
-			drawer.Write(paddingLeft+len(change.Name)+1, height, strFirstLine(*change.Description))
-		} else {
-			drawer.WriteX(paddingLeft+len(change.Name)+1, height, colors.Green, "(no description set)", colors.Reset)
+		prefix, err := tx.GetChangePrefix(opts.Ctx, c.ID, r.ID())
+		if err != nil {
+			return nil, nil, errors.Join(fmt.Errorf("get change %s prefix", c.Name), err)
 		}
+		changes = append(changes, LogChangeInfo{
+			c.ID,
+			prefix,
+			c.Name,
+			c.Description,
+			c.Author,
+			c.Device,
+			c.Depth,
+			c.CreatedAt.Time,
+			c.UpdatedAt.Time,
+			conflicts,
+		})
 	}
 
-	_, _ = fmt.Fprintln(opts.Target, drawer.String())
-
-	return nil
-}
-
-func strFirstLine(s string) string {
-	fl := strings.Split(s, "\n")[0]
-	if len(fl) > 80 {
-		fl = strings.TrimSpace(fl[:80]) + "…"
-	} else {
-		fl = strings.TrimSpace(fl)
-	}
-	return fl
+	return changes, edges, nil
 }
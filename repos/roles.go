@@ -0,0 +1,37 @@
+package repos
+
+import "fmt"
+
+// Role is the access level of a user in a repository.
+// Each role includes the rights of the roles before it: read < write < admin.
+type Role string
+
+const (
+	RoleNone  Role = ""
+	RoleRead  Role = "read"
+	RoleWrite Role = "write"
+	RoleAdmin Role = "admin"
+)
+
+var roleLevels = map[Role]int{
+	RoleNone:  0,
+	RoleRead:  1,
+	RoleWrite: 2,
+	RoleAdmin: 3,
+}
+
+func ParseRole(s string) (Role, error) {
+	role := Role(s)
+	if role == RoleNone {
+		return RoleNone, fmt.Errorf("role must not be empty")
+	}
+	if _, ok := roleLevels[role]; !ok {
+		return RoleNone, fmt.Errorf("unknown role '%s', expected '%s', '%s' or '%s'", s, RoleRead, RoleWrite, RoleAdmin)
+	}
+	return role, nil
+}
+
+// Includes reports whether r grants at least the rights of other.
+func (r Role) Includes(other Role) bool {
+	return roleLevels[r] >= roleLevels[other]
+}
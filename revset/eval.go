@@ -0,0 +1,306 @@
+package revset
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/tsukinoko-kun/pogo/db"
+)
+
+// invalidRegularExpression is the SQLSTATE of an invalid pattern for ~
+const invalidRegularExpression = "2201B"
+
+var (
+	ErrUnknownRevision = errors.New("unknown revision")
+	ErrNoChange        = errors.New("revset selects no change")
+	ErrMultipleChanges = errors.New("revset selects more than one change")
+	ErrInvalidPattern  = errors.New("invalid regular expression")
+)
+
+// Env is what revsets are evaluated against.
+type Env struct {
+	// Ctx is the context to use for the database queries.
+	Ctx context.Context
+	Q   *db.Queries
+	// RepositoryID is the repository the changes are selected from.
+	RepositoryID int32
+	// Head is the name of the bookmark @ refers to.
+	Head string
+}
+
+// Resolve parses and evaluates a revset and returns the IDs of the selected changes in ascending order.
+func (env Env) Resolve(revset string) ([]int64, error) {
+	expr, err := Parse(revset)
+	if err != nil {
+		return nil, err
+	}
+	return env.Evaluate(expr)
+}
+
+// ResolveOne resolves a revset that must select exactly one change.
+func (env Env) ResolveOne(revset string) (int64, error) {
+	ids, err := env.Resolve(revset)
+	if err != nil {
+		return 0, err
+	}
+	switch len(ids) {
+	case 0:
+		return 0, fmt.Errorf("%w: %s", ErrNoChange, revset)
+	case 1:
+		return ids[0], nil
+	default:
+		return 0, fmt.Errorf("%w: %s selects %d changes", ErrMultipleChanges, revset, len(ids))
+	}
+}
+
+// Evaluate returns the IDs of the changes selected by expr in ascending order.
+func (env Env) Evaluate(expr Expr) ([]int64, error) {
+	s, err := env.eval(expr)
+	if err != nil {
+		return nil, err
+	}
+	return s.ids(), nil
+}
+
+type set map[int64]struct{}
+
+func newSet(ids []int64) set {
+	s := make(set, len(ids))
+	for _, id := range ids {
+		s[id] = struct{}{}
+	}
+	return s
+}
+
+func (s set) ids() []int64 {
+	ids := make([]int64, 0, len(s))
+	for id := range s {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func (s set) union(o set) set {
+	r := make(set, len(s)+len(o))
+	for id := range s {
+		r[id] = struct{}{}
+	}
+	for id := range o {
+		r[id] = struct{}{}
+	}
+	return r
+}
+
+func (s set) intersection(o set) set {
+	r := make(set)
+	for id := range s {
+		if _, ok := o[id]; ok {
+			r[id] = struct{}{}
+		}
+	}
+	return r
+}
+
+func (s set) difference(o set) set {
+	r := make(set)
+	for id := range s {
+		if _, ok := o[id]; !ok {
+			r[id] = struct{}{}
+		}
+	}
+	return r
+}
+
+func (env Env) eval(expr Expr) (set, error) {
+	switch expr := expr.(type) {
+	case Symbol:
+		id, err := env.symbol(expr.Name)
+		if err != nil {
+			return nil, err
+		}
+		return newSet([]int64{id}), nil
+	case Call:
+		return env.call(expr)
+	case Range:
+		var to set
+		if expr.To != nil {
+			ids, err := env.evalIds(expr.To)
+			if err != nil {
+				return nil, err
+			}
+			if to, err = env.query(env.Q.RevsetAncestors(env.Ctx, ids, env.RepositoryID)); err != nil {
+				return nil, errors.Join(errors.New("get ancestors"), err)
+			}
+		} else {
+			var err error
+			if to, err = env.query(env.Q.RevsetAll(env.Ctx, env.RepositoryID)); err != nil {
+				return nil, errors.Join(errors.New("get all changes"), err)
+			}
+		}
+		if expr.From == nil {
+			return to, nil
+		}
+		ids, err := env.evalIds(expr.From)
+		if err != nil {
+			return nil, err
+		}
+		from, err := env.query(env.Q.RevsetAncestors(env.Ctx, ids, env.RepositoryID))
+		if err != nil {
+			return nil, errors.Join(errors.New("get ancestors"), err)
+		}
+		return to.difference(from), nil
+	case Not:
+		x, err := env.eval(expr.X)
+		if err != nil {
+			return nil, err
+		}
+		all, err := env.query(env.Q.RevsetAll(env.Ctx, env.RepositoryID))
+		if err != nil {
+			return nil, errors.Join(errors.New("get all changes"), err)
+		}
+		return all.difference(x), nil
+	case Binary:
+		x, err := env.eval(expr.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := env.eval(expr.Y)
+		if err != nil {
+			return nil, err
+		}
+		switch expr.Op {
+		case Union:
+			return x.union(y), nil
+		case Intersection:
+			return x.intersection(y), nil
+		case Difference:
+			return x.difference(y), nil
+		}
+		return nil, fmt.Errorf("unknown operator %q", expr.Op)
+	case Text:
+		return nil, fmt.Errorf("unexpected string %s", expr)
+	}
+	return nil, fmt.Errorf("unknown expression %T", expr)
+}
+
+func (env Env) evalIds(expr Expr) ([]int64, error) {
+	s, err := env.eval(expr)
+	if err != nil {
+		return nil, err
+	}
+	return s.ids(), nil
+}
+
+func (env Env) query(ids []int64, err error) (set, error) {
+	if err != nil {
+		return nil, err
+	}
+	return newSet(ids), nil
+}
+
+// symbol resolves @, a bookmark or a change name prefix.
+// Bookmarks take precedence over change names.
+func (env Env) symbol(name string) (int64, error) {
+	bookmark := name
+	if name == "@" {
+		bookmark = env.Head
+	}
+	id, err := env.Q.GetBookmark(env.Ctx, env.RepositoryID, bookmark)
+	if err == nil {
+		return id, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return 0, errors.Join(fmt.Errorf("get bookmark %s", bookmark), err)
+	}
+	if name == "@" {
+		return 0, fmt.Errorf("%w: @ (no change checked out)", ErrUnknownRevision)
+	}
+
+	id, err = env.Q.FindChange(env.Ctx, env.RepositoryID, name)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, fmt.Errorf("%w: %s", ErrUnknownRevision, name)
+		}
+		return 0, errors.Join(fmt.Errorf("find change %s", name), err)
+	}
+	return id, nil
+}
+
+func (env Env) call(call Call) (set, error) {
+	switch call.Name {
+	case "all":
+		s, err := env.query(env.Q.RevsetAll(env.Ctx, env.RepositoryID))
+		if err != nil {
+			return nil, errors.Join(errors.New("get all changes"), err)
+		}
+		return s, nil
+	case "ancestors", "descendants", "parents":
+		ids, err := env.evalIds(call.Args[0])
+		if err != nil {
+			return nil, err
+		}
+		var s set
+		switch call.Name {
+		case "ancestors":
+			s, err = env.query(env.Q.RevsetAncestors(env.Ctx, ids, env.RepositoryID))
+		case "descendants":
+			s, err = env.query(env.Q.RevsetDescendants(env.Ctx, ids, env.RepositoryID))
+		case "parents":
+			s, err = env.query(env.Q.RevsetParents(env.Ctx, ids, env.RepositoryID))
+		}
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("get %s", call.Name), err)
+		}
+		return s, nil
+	case "author":
+		s, err := env.query(env.Q.RevsetAuthor(env.Ctx, env.RepositoryID, call.Args[0].(Text).Value))
+		if err != nil {
+			return nil, errors.Join(errors.New("get changes by author"), err)
+		}
+		return s, nil
+	case "description":
+		s, err := env.query(env.Q.RevsetDescription(env.Ctx, env.RepositoryID, call.Args[0].(Text).Value))
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == invalidRegularExpression {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidPattern, pgErr.Message)
+			}
+			return nil, errors.Join(errors.New("get changes by description"), err)
+		}
+		return s, nil
+	case "conflicts":
+		s, err := env.query(env.Q.RevsetConflicts(env.Ctx, env.RepositoryID))
+		if err != nil {
+			return nil, errors.Join(errors.New("get changes with conflicts"), err)
+		}
+		return s, nil
+	case "heads":
+		if len(call.Args) == 0 {
+			s, err := env.query(env.Q.RevsetHeads(env.Ctx, env.RepositoryID))
+			if err != nil {
+				return nil, errors.Join(errors.New("get heads"), err)
+			}
+			return s, nil
+		}
+		// heads(x) = x ~ ancestors(parents(x))
+		x, err := env.eval(call.Args[0])
+		if err != nil {
+			return nil, err
+		}
+		parents, err := env.Q.RevsetParents(env.Ctx, x.ids(), env.RepositoryID)
+		if err != nil {
+			return nil, errors.Join(errors.New("get parents"), err)
+		}
+		ancestors, err := env.query(env.Q.RevsetAncestors(env.Ctx, parents, env.RepositoryID))
+		if err != nil {
+			return nil, errors.Join(errors.New("get ancestors"), err)
+		}
+		return x.difference(ancestors), nil
+	}
+	return nil, fmt.Errorf("unknown function %s", call.Name)
+}
@@ -0,0 +1,417 @@
+// Package revset implements a query language to select changes, similar to the revsets of Jujutsu.
+//
+// A revset is built from symbols, functions and operators:
+//
+//	@                  the change of the working copy
+//	main, kxwp         a bookmark or the (unique prefix of the) name of a change
+//	ancestors(x)       x and all of its ancestors
+//	descendants(x)     x and all of its descendants
+//	parents(x)         the parents of x
+//	heads([x])         changes in x (default all changes) without children in x
+//	author(name)       changes by the given author
+//	description(re)    changes whose description matches the POSIX regular expression
+//	conflicts()        changes with conflicts
+//	all()              all changes
+//	x..y               ancestors of y that are not ancestors of x
+//	..y                ancestors(y)
+//	x..                changes that are not ancestors of x
+//	~x                 changes that are not in x
+//	x & y              changes in both x and y
+//	x ~ y              changes in x but not in y
+//	x | y              changes in x or y
+//
+// Operators are listed from the strongest to the weakest binding, parentheses can be used for grouping.
+// Arguments of author and description can be quoted with " or ' to include spaces or operator characters.
+package revset
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type Expr interface {
+	String() string
+}
+
+// Symbol is a bookmark, a change name prefix or @.
+type Symbol struct {
+	Name string
+}
+
+func (s Symbol) String() string {
+	return s.Name
+}
+
+// Text is a quoted string. It is only valid as the argument of a function that expects text.
+type Text struct {
+	Value string
+}
+
+func (t Text) String() string {
+	return fmt.Sprintf("%q", t.Value)
+}
+
+type Call struct {
+	Name string
+	Args []Expr
+}
+
+func (c Call) String() string {
+	args := make([]string, len(c.Args))
+	for i, arg := range c.Args {
+		args[i] = arg.String()
+	}
+	return c.Name + "(" + strings.Join(args, ", ") + ")"
+}
+
+// Range selects the ancestors of To that are not ancestors of From. Either side can be nil.
+type Range struct {
+	From, To Expr
+}
+
+func (r Range) String() string {
+	var sb strings.Builder
+	sb.WriteString("(")
+	if r.From != nil {
+		sb.WriteString(r.From.String())
+	}
+	sb.WriteString("..")
+	if r.To != nil {
+		sb.WriteString(r.To.String())
+	}
+	sb.WriteString(")")
+	return sb.String()
+}
+
+type Not struct {
+	X Expr
+}
+
+func (n Not) String() string {
+	return "~" + n.X.String()
+}
+
+type Op byte
+
+const (
+	Union        Op = '|'
+	Intersection Op = '&'
+	Difference   Op = '~'
+)
+
+type Binary struct {
+	Op   Op
+	X, Y Expr
+}
+
+func (b Binary) String() string {
+	return "(" + b.X.String() + " " + string(b.Op) + " " + b.Y.String() + ")"
+}
+
+type function struct {
+	minArgs, maxArgs int
+	// text functions take a Text or Symbol argument that is used verbatim
+	text bool
+}
+
+var functions = map[string]function{
+	"all":         {0, 0, false},
+	"ancestors":   {1, 1, false},
+	"author":      {1, 1, true},
+	"conflicts":   {0, 0, false},
+	"descendants": {1, 1, false},
+	"description": {1, 1, true},
+	"heads":       {0, 1, false},
+	"parents":     {1, 1, false},
+}
+
+// SyntaxError reports an invalid revset.
+type SyntaxError struct {
+	// Pos is the byte offset in the revset where the error was detected.
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("revset syntax error at %d: %s", e.Pos, e.Msg)
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenSymbol
+	tokenText
+	tokenLParen
+	tokenRParen
+	tokenComma
+	tokenOp
+	tokenRange
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of revset"
+	case tokenText:
+		return fmt.Sprintf("%q", t.value)
+	default:
+		return "'" + t.value + "'"
+	}
+}
+
+func isSymbolRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_-/@.", r)
+}
+
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		r := rune(s[i])
+		switch {
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			i++
+		case r == '(':
+			tokens = append(tokens, token{tokenLParen, "(", i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{tokenRParen, ")", i})
+			i++
+		case r == ',':
+			tokens = append(tokens, token{tokenComma, ",", i})
+			i++
+		case r == '|' || r == '&' || r == '~':
+			tokens = append(tokens, token{tokenOp, string(r), i})
+			i++
+		case strings.HasPrefix(s[i:], ".."):
+			tokens = append(tokens, token{tokenRange, "..", i})
+			i += 2
+		case r == '"' || r == '\'':
+			start := i
+			var sb strings.Builder
+			i++
+			for {
+				if i >= len(s) {
+					return nil, &SyntaxError{start, "unterminated string"}
+				}
+				if s[i] == byte(r) {
+					i++
+					break
+				}
+				// only the quote and the backslash itself are escaped, so regular expressions stay readable
+				if s[i] == '\\' && i+1 < len(s) && (s[i+1] == byte(r) || s[i+1] == '\\') {
+					i++
+				}
+				sb.WriteByte(s[i])
+				i++
+			}
+			tokens = append(tokens, token{tokenText, sb.String(), start})
+		default:
+			start := i
+			for _, r := range s[i:] {
+				if !isSymbolRune(r) || strings.HasPrefix(s[i:], "..") {
+					break
+				}
+				i += len(string(r))
+			}
+			if i == start {
+				r, _ := utf8.DecodeRuneInString(s[start:])
+				return nil, &SyntaxError{start, fmt.Sprintf("unexpected character %q", r)}
+			}
+			tokens = append(tokens, token{tokenSymbol, s[start:i], start})
+		}
+	}
+	tokens = append(tokens, token{tokenEOF, "", len(s)})
+	return tokens, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// Parse parses a revset expression.
+func Parse(s string) (Expr, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	expr, err := p.parseUnion()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, &SyntaxError{t.pos, "unexpected " + t.String()}
+	}
+	return expr, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isOp(op Op) bool {
+	t := p.peek()
+	return t.kind == tokenOp && t.value == string(op)
+}
+
+// parseUnion parses x | y
+func (p *parser) parseUnion() (Expr, error) {
+	x, err := p.parseIntersection()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp(Union) {
+		p.next()
+		y, err := p.parseIntersection()
+		if err != nil {
+			return nil, err
+		}
+		x = Binary{Union, x, y}
+	}
+	return x, nil
+}
+
+// parseIntersection parses x & y and x ~ y
+func (p *parser) parseIntersection() (Expr, error) {
+	x, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp(Intersection) || p.isOp(Difference) {
+		op := Op(p.next().value[0])
+		y, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		x = Binary{op, x, y}
+	}
+	return x, nil
+}
+
+// parseNot parses ~x
+func (p *parser) parseNot() (Expr, error) {
+	if p.isOp(Difference) {
+		p.next()
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return Not{x}, nil
+	}
+	return p.parseRange()
+}
+
+// parseRange parses x..y, ..y and x..
+func (p *parser) parseRange() (Expr, error) {
+	if p.peek().kind == tokenRange {
+		p.next()
+		to, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		return Range{nil, to}, nil
+	}
+	from, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokenRange {
+		return from, nil
+	}
+	p.next()
+	switch p.peek().kind {
+	case tokenSymbol, tokenLParen:
+		to, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		return Range{from, to}, nil
+	default:
+		return Range{from, nil}, nil
+	}
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenLParen:
+		x, err := p.parseUnion()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, &SyntaxError{closing.pos, "expected ')' but got " + closing.String()}
+		}
+		return x, nil
+	case tokenSymbol:
+		if p.peek().kind == tokenLParen {
+			return p.parseCall(t)
+		}
+		return Symbol{t.value}, nil
+	case tokenText:
+		return nil, &SyntaxError{t.pos, "unexpected string " + t.String() + ", strings are only allowed as function arguments"}
+	default:
+		return nil, &SyntaxError{t.pos, "unexpected " + t.String()}
+	}
+}
+
+func (p *parser) parseCall(name token) (Expr, error) {
+	f, ok := functions[name.value]
+	if !ok {
+		return nil, &SyntaxError{name.pos, "unknown function '" + name.value + "'"}
+	}
+	p.next() // (
+	call := Call{Name: name.value}
+	for p.peek().kind != tokenRParen {
+		if len(call.Args) > 0 {
+			if comma := p.next(); comma.kind != tokenComma {
+				return nil, &SyntaxError{comma.pos, "expected ',' or ')' but got " + comma.String()}
+			}
+		}
+		if f.text {
+			arg := p.next()
+			switch arg.kind {
+			case tokenText, tokenSymbol:
+				call.Args = append(call.Args, Text{arg.value})
+			default:
+				return nil, &SyntaxError{arg.pos, "expected text but got " + arg.String()}
+			}
+			continue
+		}
+		arg, err := p.parseUnion()
+		if err != nil {
+			return nil, err
+		}
+		call.Args = append(call.Args, arg)
+	}
+	p.next() // )
+	if len(call.Args) < f.minArgs || len(call.Args) > f.maxArgs {
+		var want string
+		switch {
+		case f.minArgs == f.maxArgs:
+			want = fmt.Sprintf("%d", f.minArgs)
+		default:
+			want = fmt.Sprintf("%d to %d", f.minArgs, f.maxArgs)
+		}
+		return nil, &SyntaxError{name.pos, fmt.Sprintf("function '%s' expects %s arguments but got %d", name.value, want, len(call.Args))}
+	}
+	return call, nil
+}
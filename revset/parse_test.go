@@ -0,0 +1,78 @@
+package revset
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		revset string
+		want   string
+	}{
+		{"@", "@"},
+		{"main", "main"},
+		{"feature/login-v2.1", "feature/login-v2.1"},
+		{"ancestors(@)", "ancestors(@)"},
+		{"heads()", "heads()"},
+		{"heads(descendants(main))", "heads(descendants(main))"},
+		{"main..@", "(main..@)"},
+		{"..@", "(..@)"},
+		{"main..", "(main..)"},
+		{"main.. | @", "((main..) | @)"},
+		{"v1.0..v2.0", "(v1.0..v2.0)"},
+		{"a | b & c", "(a | (b & c))"},
+		{"a & b ~ c", "((a & b) ~ c)"},
+		{"~a & b", "(~a & b)"},
+		{"~~a", "~~a"},
+		{"(a | b) & c", "((a | b) & c)"},
+		{"author(alice)", `author("alice")`},
+		{`author("Alice Liddell")`, `author("Alice Liddell")`},
+		{`description("^fix\s+\"bug\"")`, `description("^fix\\s+\"bug\"")`},
+		{`description('a|b')`, `description("a|b")`},
+		{"conflicts() & ancestors(@)", "(conflicts() & ancestors(@))"},
+		{" parents( @ ) ", "parents(@)"},
+	}
+
+	for _, tt := range tests {
+		expr, err := Parse(tt.revset)
+		if err != nil {
+			t.Errorf("Parse(%q): unexpected error %v", tt.revset, err)
+			continue
+		}
+		if got := expr.String(); got != tt.want {
+			t.Errorf("Parse(%q) = %s, expected %s", tt.revset, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		revset string
+		pos    int
+	}{
+		{"", 0},
+		{"a |", 3},
+		{"(a", 2},
+		{"a b", 2},
+		{"foo(a)", 0},
+		{"ancestors()", 0},
+		{"parents(a, b)", 0},
+		{`"main"`, 0},
+		{`description("x`, 12},
+		{"a $ b", 2},
+		{"author(a | b)", 9},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse(tt.revset)
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("Parse(%q): expected syntax error, got %v", tt.revset, err)
+			continue
+		}
+		if syntaxErr.Pos != tt.pos {
+			t.Errorf("Parse(%q): expected error at %d, got %d (%v)", tt.revset, tt.pos, syntaxErr.Pos, err)
+		}
+	}
+}
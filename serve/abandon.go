@@ -0,0 +1,165 @@
+package serve
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/tsukinoko-kun/pogo/db"
+	"github.com/tsukinoko-kun/pogo/protos"
+	"github.com/tsukinoko-kun/pogo/repos"
+	"github.com/tsukinoko-kun/pogo/serve/serveerrors"
+	"github.com/tsukinoko-kun/pogo/signedhttp"
+)
+
+// errAbandonRoot is returned by abandonChange for a change without parents.
+var errAbandonRoot = errors.New("cannot abandon the root change")
+
+func (a *App) handleAbandon(w http.ResponseWriter, r *signedhttp.Request) {
+	repo, err := a.openRepo(r.PathValue("repo"))
+	if err != nil {
+		if errors.Is(err, repos.ErrRepoNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "open repository: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	req := new(protos.AbandonRequest)
+	err = protos.Unmarshal(r.Body(), req)
+	if err != nil {
+		http.Error(w, "unmarshal abandon request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Q.Begin(r.Context())
+	if err != nil {
+		http.Error(w, "begin transaction: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Close()
+
+	changeId, err := revsetEnv(r, tx.Queries, repo).ResolveOne(req.Change)
+	if err != nil {
+		writeRevsetError(w, "find change "+req.Change, err)
+		return
+	}
+
+	if owner, err := tx.GetChangeOwner(r.Context(), changeId); err != nil {
+		http.Error(w, "get change owner: "+err.Error(), http.StatusInternalServerError)
+		return
+	} else if owner.Author != r.Username() || owner.Device != r.MachineID() {
+		http.Error(w, serveerrors.ErrRewriteChangeNotOwned.Error(), http.StatusBadRequest)
+		return
+	}
+
+	op, err := beginOperation(r, tx.Queries, repo, "abandon")
+	if err != nil {
+		http.Error(w, "begin operation: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := abandonChange(r, tx.Queries, repo, op, changeId)
+	if err != nil {
+		if errors.Is(err, errAbandonRoot) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeBookmarkRuleError(w, "abandon change", err)
+		return
+	}
+
+	if err := op.finish(); err != nil {
+		http.Error(w, "log operation: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		http.Error(w, "commit transaction: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_ = protos.MarshalWrite(resp, w)
+}
+
+// abandonChange hides a change. Its children are reparented onto its parents and keep their files,
+// bookmarks that pointed at it are moved to its first parent.
+// The abandoned change itself is kept with its files and parents, but revsets don't select it anymore.
+// If a bookmark rule forbids moving a bookmark, a *bookmarkRuleError is returned.
+func abandonChange(r *signedhttp.Request, q db.Querier, repo repos.Repo, op *operation, changeId int64) (*protos.AbandonResponse, error) {
+	var err error
+	resp := new(protos.AbandonResponse)
+	if resp.ChangeName, err = q.GetChangeName(r.Context(), changeId, repo.ID()); err != nil {
+		return nil, errors.Join(errors.New("get change name"), err)
+	}
+
+	parents, err := q.RevsetParents(r.Context(), []int64{changeId}, repo.ID())
+	if err != nil {
+		return nil, errors.Join(errors.New("get parents"), err)
+	}
+	if len(parents) == 0 {
+		return nil, fmt.Errorf("%w %s", errAbandonRoot, resp.ChangeName)
+	}
+	slices.Sort(parents)
+	for _, parent := range parents {
+		parentName, err := q.GetChangeName(r.Context(), parent, repo.ID())
+		if err != nil {
+			return nil, errors.Join(errors.New("get parent change name"), err)
+		}
+		resp.Parents = append(resp.Parents, parentName)
+	}
+
+	children, err := q.GetChildren(r.Context(), &changeId)
+	if err != nil {
+		return nil, errors.Join(errors.New("get children"), err)
+	}
+	for _, child := range children {
+		if err := op.touch(child); err != nil {
+			return nil, err
+		}
+		if err := q.RemoveChangeParent(r.Context(), child, &changeId); err != nil {
+			return nil, errors.Join(errors.New("remove parent"), err)
+		}
+		for _, parent := range parents {
+			if err := q.SetChangeParent(r.Context(), child, &parent); err != nil {
+				return nil, errors.Join(errors.New("set parent change"), err)
+			}
+		}
+	}
+	if len(children) > 0 {
+		if err := updateDepths(r.Context(), q, repo, op, children); err != nil {
+			return nil, errors.Join(errors.New("update depths"), err)
+		}
+	}
+
+	bookmarks, err := q.ListChangeBookmarks(r.Context(), repo.ID(), changeId)
+	if err != nil {
+		return nil, errors.Join(errors.New("list bookmarks"), err)
+	}
+	headName := headBookmark(r)
+	for _, bookmark := range bookmarks {
+		if err := checkBookmarkRule(r.Context(), q, repo, r.Username(), bookmark, &changeId, parents[0]); err != nil {
+			return nil, err
+		}
+		if err := q.SetBookmark(r.Context(), repo.ID(), bookmark, parents[0]); err != nil {
+			return nil, errors.Join(errors.New("set bookmark"), err)
+		}
+		switch {
+		case bookmark == headName:
+			resp.HeadAbandoned = true
+		case !isHeadBookmark(bookmark):
+			resp.Bookmarks = append(resp.Bookmarks, bookmark)
+		}
+	}
+
+	if err := op.touch(changeId); err != nil {
+		return nil, err
+	}
+	if err := q.AbandonChange(r.Context(), changeId); err != nil {
+		return nil, errors.Join(errors.New("abandon change"), err)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,273 @@
+package serve
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/tsukinoko-kun/pogo/db"
+	"github.com/tsukinoko-kun/pogo/protos"
+	"github.com/tsukinoko-kun/pogo/repos"
+	"github.com/tsukinoko-kun/pogo/signedhttp"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// rpcRoles maps every repository RPC to the role that is required to call it.
+var rpcRoles = map[string]repos.Role{
+	"cat":                 repos.RoleRead,
+	"check_files_exists":  repos.RoleRead,
+	"checkout":            repos.RoleRead,
+	"conflicts":           repos.RoleRead,
+	"diff":                repos.RoleRead,
+	"evolog":              repos.RoleRead,
+	"files":               repos.RoleRead,
+	"find_change":         repos.RoleRead,
+	"list_bookmark_rules": repos.RoleRead,
+	"list_bookmarks":      repos.RoleRead,
+	"list_members":        repos.RoleRead,
+	"log":                 repos.RoleRead,
+	"op_log":              repos.RoleRead,
+	"set_head":            repos.RoleRead,
+	"status":              repos.RoleRead,
+
+	"abandon":          repos.RoleWrite,
+	"delete_bookmark":  repos.RoleWrite,
+	"describe":         repos.RoleWrite,
+	"new_change":       repos.RoleWrite,
+	"push":             repos.RoleWrite,
+	"rebase":           repos.RoleWrite,
+	"rename_bookmark":  repos.RoleWrite,
+	"restore_snapshot": repos.RoleWrite,
+	"set_bookmark":     repos.RoleWrite,
+	"squash":           repos.RoleWrite,
+	"undo":             repos.RoleWrite,
+
+	"remove_bookmark_rule": repos.RoleAdmin,
+	"remove_member":        repos.RoleAdmin,
+	"restore_operation":    repos.RoleAdmin,
+	"set_bookmark_rule":    repos.RoleAdmin,
+	"set_member":           repos.RoleAdmin,
+}
+
+// userRole returns the role of the user in the repository.
+// Server admins have admin rights in every repository.
+func userRole(r *signedhttp.Request, repo repos.Repo) (repos.Role, error) {
+	user, err := db.Q.GetUserByName(r.Context(), r.Username())
+	if err != nil {
+		return repos.RoleNone, errors.Join(errors.New("get user"), err)
+	}
+	if user.Admin {
+		return repos.RoleAdmin, nil
+	}
+	role, err := db.Q.GetRepoMemberRole(r.Context(), repo.ID(), r.Username())
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return repos.RoleNone, nil
+		}
+		return repos.RoleNone, errors.Join(errors.New("get repository member role"), err)
+	}
+	return repos.Role(role), nil
+}
+
+// authorize checks that the user of r has at least the required role in the repository.
+// If not, an error response is written and false is returned.
+func (a *App) authorize(w http.ResponseWriter, r *signedhttp.Request, repo repos.Repo, required repos.Role) bool {
+	role, err := userRole(r, repo)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	if role == repos.RoleNone {
+		http.Error(w, fmt.Sprintf("user %s is not a member of this repository", r.Username()), http.StatusForbidden)
+		return false
+	}
+	if !role.Includes(required) {
+		http.Error(w, fmt.Sprintf("user %s has %s access to this repository, but %s access is required", r.Username(), role, required), http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func (a *App) handleListMembers(w http.ResponseWriter, r *signedhttp.Request) {
+	repo, err := a.openRepo(r.PathValue("repo"))
+	if err != nil {
+		if errors.Is(err, repos.ErrRepoNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "open repository: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	members, err := db.Q.ListRepoMembers(r.Context(), repo.ID())
+	if err != nil {
+		http.Error(w, "list repository members: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := new(protos.ListMembersResponse)
+	for _, member := range members {
+		resp.Members = append(resp.Members, &protos.Member{
+			Username: member.Name,
+			Role:     member.Role,
+		})
+	}
+
+	_ = protos.MarshalWrite(resp, w)
+}
+
+func (a *App) handleSetMember(w http.ResponseWriter, r *signedhttp.Request) {
+	repo, err := a.openRepo(r.PathValue("repo"))
+	if err != nil {
+		if errors.Is(err, repos.ErrRepoNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "open repository: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	req := new(protos.SetMemberRequest)
+	err = protos.Unmarshal(r.Body(), req)
+	if err != nil {
+		http.Error(w, "unmarshal set member request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	role, err := repos.ParseRole(req.Role)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Q.Begin(r.Context())
+	if err != nil {
+		http.Error(w, "begin transaction: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Close()
+
+	user, err := tx.GetUserByName(r.Context(), req.Username)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, "user "+req.Username+" is not registered", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "get user: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	wasAdmin, ok := isRepoAdmin(w, r, tx, repo, req.Username)
+	if !ok {
+		return
+	}
+
+	if err = tx.SetRepoMember(r.Context(), repo.ID(), user.ID, string(role)); err != nil {
+		http.Error(w, "set repository member: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if wasAdmin && role != repos.RoleAdmin {
+		if ok := ensureRepoHasAdmin(w, r, tx, repo); !ok {
+			return
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		http.Error(w, "commit transaction: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a *App) handleRemoveMember(w http.ResponseWriter, r *signedhttp.Request) {
+	repo, err := a.openRepo(r.PathValue("repo"))
+	if err != nil {
+		if errors.Is(err, repos.ErrRepoNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "open repository: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	req := new(protos.RemoveMemberRequest)
+	err = protos.Unmarshal(r.Body(), req)
+	if err != nil {
+		http.Error(w, "unmarshal remove member request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Q.Begin(r.Context())
+	if err != nil {
+		http.Error(w, "begin transaction: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Close()
+
+	user, err := tx.GetUserByName(r.Context(), req.Username)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, "user "+req.Username+" is not registered", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "get user: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	wasAdmin, ok := isRepoAdmin(w, r, tx, repo, req.Username)
+	if !ok {
+		return
+	}
+
+	if removed, err := tx.RemoveRepoMember(r.Context(), repo.ID(), user.ID); err != nil {
+		http.Error(w, "remove repository member: "+err.Error(), http.StatusInternalServerError)
+		return
+	} else if removed == 0 {
+		http.Error(w, "user "+req.Username+" is not a member of this repository", http.StatusNotFound)
+		return
+	}
+
+	if wasAdmin {
+		if ok := ensureRepoHasAdmin(w, r, tx, repo); !ok {
+			return
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		http.Error(w, "commit transaction: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// isRepoAdmin reports whether the user is currently an admin member of the repository.
+// If the lookup fails, an error response is written and ok is false.
+func isRepoAdmin(w http.ResponseWriter, r *signedhttp.Request, q db.Querier, repo repos.Repo, username string) (isAdmin bool, ok bool) {
+	role, err := q.GetRepoMemberRole(r.Context(), repo.ID(), username)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, true
+		}
+		http.Error(w, "get repository member role: "+err.Error(), http.StatusInternalServerError)
+		return false, false
+	}
+	return repos.Role(role) == repos.RoleAdmin, true
+}
+
+// ensureRepoHasAdmin prevents membership changes that would leave a repository without an admin.
+func ensureRepoHasAdmin(w http.ResponseWriter, r *signedhttp.Request, q db.Querier, repo repos.Repo) bool {
+	admins, err := q.CountRepoAdmins(r.Context(), repo.ID())
+	if err != nil {
+		http.Error(w, "count repository admins: "+err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	if admins == 0 {
+		http.Error(w, "a repository must have at least one admin", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,290 @@
+package serve
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/tsukinoko-kun/pogo/db"
+	"github.com/tsukinoko-kun/pogo/protos"
+	"github.com/tsukinoko-kun/pogo/repos"
+	"github.com/tsukinoko-kun/pogo/signedhttp"
+)
+
+var bookmarkNamingRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_./-]*$`)
+
+// validateBookmarkName rejects names that can't be used in revsets and the reserved head bookmarks.
+func validateBookmarkName(name string) error {
+	if isHeadBookmark(name) {
+		return fmt.Errorf("bookmark name '%s' is reserved for heads", name)
+	}
+	if !bookmarkNamingRegex.MatchString(name) || strings.Contains(name, "..") {
+		return fmt.Errorf("invalid bookmark name '%s': must start with a letter and only contain letters, numbers, dashes, underscores, dots, and slashes, but no '..'", name)
+	}
+	return nil
+}
+
+// movesForward reports whether a bookmark at current moves forward to changeId, which is a descendant of current.
+// A new bookmark and a bookmark that stays where it is move forward.
+func movesForward(ctx context.Context, q db.Querier, current *int64, changeId int64) (bool, error) {
+	if current == nil || *current == changeId {
+		return true, nil
+	}
+	return q.IsAncestor(ctx, changeId, *current)
+}
+
+func writeBookmarkBackwardsError(w http.ResponseWriter, bookmark string) {
+	http.Error(w, "bookmark '"+bookmark+"' would move backwards or sideways, allow backwards moves to do it anyway", http.StatusConflict)
+}
+
+func (a *App) handleSetBookmark(w http.ResponseWriter, r *signedhttp.Request) {
+	repo, err := a.openRepo(r.PathValue("repo"))
+	if err != nil {
+		if errors.Is(err, repos.ErrRepoNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "open repository: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	req := new(protos.SetBookmarkRequest)
+	err = protos.Unmarshal(r.Body(), req)
+	if err != nil {
+		http.Error(w, "unmarshal set bookmark request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := validateBookmarkName(req.Bookmark); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Q.Begin(r.Context())
+	if err != nil {
+		http.Error(w, "begin transaction: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Close()
+
+	changeId := req.ChangeId
+	if req.Change != "" {
+		if changeId, err = revsetEnv(r, tx.Queries, repo).ResolveOne(req.Change); err != nil {
+			writeRevsetError(w, "find change "+req.Change, err)
+			return
+		}
+	} else if ok, err := changeInRepo(r.Context(), tx.Queries, repo, changeId); err != nil {
+		http.Error(w, "find change: "+err.Error(), http.StatusInternalServerError)
+		return
+	} else if !ok {
+		http.Error(w, fmt.Sprintf("change %d not found", changeId), http.StatusNotFound)
+		return
+	}
+
+	var current *int64
+	if currentId, err := tx.GetBookmark(r.Context(), repo.ID(), req.Bookmark); err == nil {
+		current = &currentId
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "get bookmark: "+err.Error(), http.StatusInternalServerError)
+		return
+	} else if req.MustExist {
+		http.Error(w, "bookmark '"+req.Bookmark+"' not found", http.StatusNotFound)
+		return
+	}
+
+	if err := checkBookmarkRule(r.Context(), tx.Queries, repo, r.Username(), req.Bookmark, current, changeId); err != nil {
+		writeBookmarkRuleError(w, "check bookmark rule", err)
+		return
+	}
+
+	if !req.AllowBackwards {
+		if forward, err := movesForward(r.Context(), tx.Queries, current, changeId); err != nil {
+			http.Error(w, "check bookmark direction: "+err.Error(), http.StatusInternalServerError)
+			return
+		} else if !forward {
+			writeBookmarkBackwardsError(w, req.Bookmark)
+			return
+		}
+	}
+
+	op, err := beginOperation(r, tx.Queries, repo, "bookmark set")
+	if err != nil {
+		http.Error(w, "begin operation: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err = tx.SetBookmark(r.Context(), repo.ID(), req.Bookmark, changeId); err != nil {
+		http.Error(w, "set bookmark: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := op.finish(); err != nil {
+		http.Error(w, "log operation: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		http.Error(w, "commit transaction: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (a *App) handleDeleteBookmark(w http.ResponseWriter, r *signedhttp.Request) {
+	repo, err := a.openRepo(r.PathValue("repo"))
+	if err != nil {
+		if errors.Is(err, repos.ErrRepoNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "open repository: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	req := new(protos.DeleteBookmarkRequest)
+	err = protos.Unmarshal(r.Body(), req)
+	if err != nil {
+		http.Error(w, "unmarshal delete bookmark request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if isHeadBookmark(req.Bookmark) {
+		http.Error(w, "cannot delete head bookmark '"+req.Bookmark+"'", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Q.Begin(r.Context())
+	if err != nil {
+		http.Error(w, "begin transaction: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Close()
+
+	if protected, err := isProtectedBookmark(r.Context(), tx.Queries, repo, req.Bookmark); err != nil {
+		http.Error(w, "get bookmark rule: "+err.Error(), http.StatusInternalServerError)
+		return
+	} else if protected {
+		http.Error(w, "bookmark '"+req.Bookmark+"' is protected, remove its rule first", http.StatusForbidden)
+		return
+	}
+
+	op, err := beginOperation(r, tx.Queries, repo, "bookmark delete")
+	if err != nil {
+		http.Error(w, "begin operation: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	deleted, err := tx.DeleteBookmark(r.Context(), repo.ID(), req.Bookmark)
+	if err != nil {
+		http.Error(w, "delete bookmark: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if deleted == 0 {
+		http.Error(w, "bookmark '"+req.Bookmark+"' not found", http.StatusNotFound)
+		return
+	}
+
+	if err := op.finish(); err != nil {
+		http.Error(w, "log operation: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		http.Error(w, "commit transaction: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *App) handleRenameBookmark(w http.ResponseWriter, r *signedhttp.Request) {
+	repo, err := a.openRepo(r.PathValue("repo"))
+	if err != nil {
+		if errors.Is(err, repos.ErrRepoNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "open repository: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	req := new(protos.RenameBookmarkRequest)
+	err = protos.Unmarshal(r.Body(), req)
+	if err != nil {
+		http.Error(w, "unmarshal rename bookmark request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if isHeadBookmark(req.Bookmark) {
+		http.Error(w, "cannot rename head bookmark '"+req.Bookmark+"'", http.StatusBadRequest)
+		return
+	}
+	if err := validateBookmarkName(req.NewName); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Q.Begin(r.Context())
+	if err != nil {
+		http.Error(w, "begin transaction: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Close()
+
+	if _, err := tx.GetBookmark(r.Context(), repo.ID(), req.NewName); err == nil {
+		http.Error(w, "bookmark '"+req.NewName+"' already exists", http.StatusConflict)
+		return
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "get bookmark: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if protected, err := isProtectedBookmark(r.Context(), tx.Queries, repo, req.Bookmark); err != nil {
+		http.Error(w, "get bookmark rule: "+err.Error(), http.StatusInternalServerError)
+		return
+	} else if protected {
+		http.Error(w, "bookmark '"+req.Bookmark+"' is protected, remove its rule first", http.StatusForbidden)
+		return
+	}
+
+	// the renamed bookmark must satisfy the rule of its new name like a newly created one
+	changeId, err := tx.GetBookmark(r.Context(), repo.ID(), req.Bookmark)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, "bookmark '"+req.Bookmark+"' not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "get bookmark: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := checkBookmarkRule(r.Context(), tx.Queries, repo, r.Username(), req.NewName, nil, changeId); err != nil {
+		writeBookmarkRuleError(w, "check bookmark rule", err)
+		return
+	}
+
+	op, err := beginOperation(r, tx.Queries, repo, "bookmark rename")
+	if err != nil {
+		http.Error(w, "begin operation: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if _, err := tx.RenameBookmark(r.Context(), repo.ID(), req.Bookmark, req.NewName); err != nil {
+		http.Error(w, "rename bookmark: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := op.finish(); err != nil {
+		http.Error(w, "log operation: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		http.Error(w, "commit transaction: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
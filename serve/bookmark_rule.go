@@ -0,0 +1,271 @@
+package serve
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/tsukinoko-kun/pogo/db"
+	"github.com/tsukinoko-kun/pogo/protos"
+	"github.com/tsukinoko-kun/pogo/repos"
+	"github.com/tsukinoko-kun/pogo/signedhttp"
+)
+
+// bookmarkRuleError is returned if the rule of a protected bookmark forbids a move.
+type bookmarkRuleError struct {
+	Bookmark string
+	Reason   string
+}
+
+func (e *bookmarkRuleError) Error() string {
+	return fmt.Sprintf("bookmark '%s' is protected: %s", e.Bookmark, e.Reason)
+}
+
+// checkBookmarkRule checks if username may move the bookmark from currentId (nil if it doesn't exist yet) to targetId.
+// If the rule of the bookmark forbids it, a *bookmarkRuleError is returned.
+func checkBookmarkRule(ctx context.Context, q db.Querier, repo repos.Repo, username string, bookmark string, currentId *int64, targetId int64) error {
+	rule, err := q.GetBookmarkRule(ctx, repo.ID(), bookmark)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return errors.Join(errors.New("get bookmark rule"), err)
+	}
+
+	if len(rule.AllowedUsers) > 0 && !slices.Contains(rule.AllowedUsers, username) {
+		return &bookmarkRuleError{bookmark, "only " + strings.Join(rule.AllowedUsers, ", ") + " may move it"}
+	}
+
+	if rule.DescendantsOnly && currentId != nil && *currentId != targetId {
+		forward, err := q.IsAncestor(ctx, targetId, *currentId)
+		if err != nil {
+			return errors.Join(errors.New("check bookmark direction"), err)
+		}
+		if !forward {
+			return &bookmarkRuleError{bookmark, "it can only move to descendants of its current change"}
+		}
+	}
+
+	if !rule.NoConflicts && !rule.RequireDescription {
+		return nil
+	}
+	targetName, err := q.GetChangeName(ctx, targetId, repo.ID())
+	if err != nil {
+		return errors.Join(errors.New("get change name"), err)
+	}
+
+	if rule.NoConflicts {
+		conflicts, err := q.HasChangeConflicts(ctx, targetId)
+		if err != nil {
+			return errors.Join(errors.New("check change conflicts"), err)
+		}
+		if conflicts {
+			return &bookmarkRuleError{bookmark, "change " + targetName + " has conflicts"}
+		}
+	}
+
+	if rule.RequireDescription {
+		description, err := q.GetChangeDescription(ctx, targetId)
+		if err != nil {
+			return errors.Join(errors.New("get change description"), err)
+		}
+		if description == nil || strings.TrimSpace(*description) == "" {
+			return &bookmarkRuleError{bookmark, "change " + targetName + " has no description"}
+		}
+	}
+
+	return nil
+}
+
+// checkChangeBookmarkRules checks the rules of all bookmarks on a change that was modified in place.
+// The bookmarks don't move, but a rule like no conflicts or a required description must still hold for the change.
+func checkChangeBookmarkRules(ctx context.Context, q db.Querier, repo repos.Repo, username string, changeId int64) error {
+	bookmarks, err := q.ListChangeBookmarks(ctx, repo.ID(), changeId)
+	if err != nil {
+		return errors.Join(errors.New("list change bookmarks"), err)
+	}
+	for _, bookmark := range bookmarks {
+		if isHeadBookmark(bookmark) {
+			continue
+		}
+		if err := checkBookmarkRule(ctx, q, repo, username, bookmark, &changeId, changeId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkRebaseBookmarkRules checks the bookmarks on a change whose ancestry is rebased from oldParentId onto newParentId.
+// The bookmarks keep pointing at the change, but move in the history with it: only a rebase onto a descendant of the old
+// parent moves them forward. The rules of the rebased change itself are checked with checkChangeBookmarkRules afterwards.
+func checkRebaseBookmarkRules(ctx context.Context, q db.Querier, repo repos.Repo, changeId int64, oldParentId int64, newParentId int64) error {
+	bookmarks, err := q.ListChangeBookmarks(ctx, repo.ID(), changeId)
+	if err != nil {
+		return errors.Join(errors.New("list change bookmarks"), err)
+	}
+	for _, bookmark := range bookmarks {
+		if isHeadBookmark(bookmark) {
+			continue
+		}
+		rule, err := q.GetBookmarkRule(ctx, repo.ID(), bookmark)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				continue
+			}
+			return errors.Join(errors.New("get bookmark rule"), err)
+		}
+		if !rule.DescendantsOnly {
+			continue
+		}
+		forward, err := q.IsAncestor(ctx, newParentId, oldParentId)
+		if err != nil {
+			return errors.Join(errors.New("check bookmark direction"), err)
+		}
+		if !forward {
+			return &bookmarkRuleError{bookmark, "it can only move to descendants of its current change"}
+		}
+	}
+	return nil
+}
+
+// writeBookmarkRuleError writes an error response for a failed checkBookmarkRule.
+// Violated rules are forbidden, everything else is an internal error.
+func writeBookmarkRuleError(w http.ResponseWriter, context string, err error) {
+	var ruleErr *bookmarkRuleError
+	if errors.As(err, &ruleErr) {
+		http.Error(w, ruleErr.Error(), http.StatusForbidden)
+		return
+	}
+	http.Error(w, context+": "+err.Error(), http.StatusInternalServerError)
+}
+
+// isProtectedBookmark reports whether the bookmark has a rule.
+func isProtectedBookmark(ctx context.Context, q db.Querier, repo repos.Repo, bookmark string) (bool, error) {
+	if _, err := q.GetBookmarkRule(ctx, repo.ID(), bookmark); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (a *App) handleSetBookmarkRule(w http.ResponseWriter, r *signedhttp.Request) {
+	repo, err := a.openRepo(r.PathValue("repo"))
+	if err != nil {
+		if errors.Is(err, repos.ErrRepoNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "open repository: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	req := new(protos.BookmarkRule)
+	err = protos.Unmarshal(r.Body(), req)
+	if err != nil {
+		http.Error(w, "unmarshal bookmark rule: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := validateBookmarkName(req.Bookmark); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var allowedUsers []string
+	for _, username := range req.AllowedUsers {
+		if _, err := db.Q.GetUserByName(r.Context(), username); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				http.Error(w, "user "+username+" is not registered", http.StatusNotFound)
+				return
+			}
+			http.Error(w, "get user: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !slices.Contains(allowedUsers, username) {
+			allowedUsers = append(allowedUsers, username)
+		}
+	}
+
+	if err = db.Q.SetBookmarkRule(
+		r.Context(),
+		repo.ID(),
+		req.Bookmark,
+		req.DescendantsOnly,
+		req.NoConflicts,
+		req.RequireDescription,
+		allowedUsers,
+	); err != nil {
+		http.Error(w, "set bookmark rule: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *App) handleRemoveBookmarkRule(w http.ResponseWriter, r *signedhttp.Request) {
+	repo, err := a.openRepo(r.PathValue("repo"))
+	if err != nil {
+		if errors.Is(err, repos.ErrRepoNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "open repository: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	req := new(protos.RemoveBookmarkRuleRequest)
+	err = protos.Unmarshal(r.Body(), req)
+	if err != nil {
+		http.Error(w, "unmarshal remove bookmark rule request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	removed, err := db.Q.RemoveBookmarkRule(r.Context(), repo.ID(), req.Bookmark)
+	if err != nil {
+		http.Error(w, "remove bookmark rule: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if removed == 0 {
+		http.Error(w, "bookmark '"+req.Bookmark+"' is not protected", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *App) handleListBookmarkRules(w http.ResponseWriter, r *signedhttp.Request) {
+	repo, err := a.openRepo(r.PathValue("repo"))
+	if err != nil {
+		if errors.Is(err, repos.ErrRepoNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "open repository: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rules, err := db.Q.ListBookmarkRules(r.Context(), repo.ID())
+	if err != nil {
+		http.Error(w, "list bookmark rules: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := &protos.ListBookmarkRulesResponse{Rules: make([]*protos.BookmarkRule, len(rules))}
+	for i, rule := range rules {
+		resp.Rules[i] = &protos.BookmarkRule{
+			Bookmark:           rule.Bookmark,
+			DescendantsOnly:    rule.DescendantsOnly,
+			NoConflicts:        rule.NoConflicts,
+			RequireDescription: rule.RequireDescription,
+			AllowedUsers:       rule.AllowedUsers,
+		}
+	}
+
+	_ = protos.MarshalWrite(resp, w)
+}
@@ -0,0 +1,172 @@
+package serve
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/tsukinoko-kun/pogo/db"
+	"github.com/tsukinoko-kun/pogo/repos"
+	"github.com/tsukinoko-kun/pogo/utils"
+)
+
+// ruleQuerier answers the queries of checkBookmarkRule from memory. Change i has the parent i-1.
+type ruleQuerier struct {
+	db.Querier
+	rules        map[string]db.BookmarkRule
+	bookmarks    map[int64][]string
+	conflicts    map[int64]bool
+	descriptions map[int64]string
+}
+
+func (q *ruleQuerier) GetBookmarkRule(_ context.Context, _ int32, bookmark string) (db.BookmarkRule, error) {
+	rule, ok := q.rules[bookmark]
+	if !ok {
+		return db.BookmarkRule{}, pgx.ErrNoRows
+	}
+	return rule, nil
+}
+
+func (q *ruleQuerier) IsAncestor(_ context.Context, descendant int64, ancestor int64) (bool, error) {
+	return ancestor <= descendant, nil
+}
+
+func (q *ruleQuerier) GetChangeName(_ context.Context, id int64, _ int32) (string, error) {
+	return fmt.Sprintf("change%d", id), nil
+}
+
+func (q *ruleQuerier) HasChangeConflicts(_ context.Context, changeId int64) (bool, error) {
+	return q.conflicts[changeId], nil
+}
+
+func (q *ruleQuerier) GetChangeDescription(_ context.Context, id int64) (*string, error) {
+	description, ok := q.descriptions[id]
+	if !ok {
+		return nil, nil
+	}
+	return &description, nil
+}
+
+func (q *ruleQuerier) ListChangeBookmarks(_ context.Context, _ int32, changeId int64) ([]string, error) {
+	return q.bookmarks[changeId], nil
+}
+
+func newRuleQuerier() *ruleQuerier {
+	return &ruleQuerier{
+		rules: map[string]db.BookmarkRule{
+			"main":     {Bookmark: "main", DescendantsOnly: true, NoConflicts: true, RequireDescription: true},
+			"release":  {Bookmark: "release", AllowedUsers: []string{"alice"}},
+			"unstable": {Bookmark: "unstable"},
+		},
+		bookmarks:    make(map[int64][]string),
+		conflicts:    map[int64]bool{3: true},
+		descriptions: map[int64]string{1: "first", 2: "second", 3: "third", 4: "  \n"},
+	}
+}
+
+func TestCheckBookmarkRule(t *testing.T) {
+	tests := []struct {
+		name      string
+		username  string
+		bookmark  string
+		current   *int64
+		target    int64
+		forbidden bool
+	}{
+		{name: "unprotected", username: "bob", bookmark: "feature", current: utils.Ptr[int64](2), target: 1},
+		{name: "rule without restrictions", username: "bob", bookmark: "unstable", current: utils.Ptr[int64](2), target: 1},
+		{name: "forward", username: "bob", bookmark: "main", current: utils.Ptr[int64](1), target: 2},
+		{name: "new bookmark", username: "bob", bookmark: "main", target: 2},
+		{name: "stays", username: "bob", bookmark: "main", current: utils.Ptr[int64](2), target: 2},
+		{name: "backwards", username: "bob", bookmark: "main", current: utils.Ptr[int64](2), target: 1, forbidden: true},
+		{name: "conflicts", username: "bob", bookmark: "main", current: utils.Ptr[int64](2), target: 3, forbidden: true},
+		{name: "blank description", username: "bob", bookmark: "main", current: utils.Ptr[int64](2), target: 4, forbidden: true},
+		{name: "no description", username: "bob", bookmark: "main", current: utils.Ptr[int64](2), target: 5, forbidden: true},
+		{name: "allowed user", username: "alice", bookmark: "release", current: utils.Ptr[int64](2), target: 1},
+		{name: "other user", username: "bob", bookmark: "release", current: utils.Ptr[int64](1), target: 2, forbidden: true},
+	}
+
+	q := newRuleQuerier()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkBookmarkRule(context.Background(), q, repos.Repo(1), tt.username, tt.bookmark, tt.current, tt.target)
+			var ruleErr *bookmarkRuleError
+			if tt.forbidden && !errors.As(err, &ruleErr) {
+				t.Fatalf("move should be forbidden by the rule, got %v", err)
+			}
+			if !tt.forbidden && err != nil {
+				t.Fatalf("move should be allowed, got %v", err)
+			}
+			if tt.forbidden && ruleErr.Bookmark != tt.bookmark {
+				t.Fatalf("error should name bookmark %s, got %s", tt.bookmark, ruleErr.Bookmark)
+			}
+		})
+	}
+}
+
+func TestCheckChangeBookmarkRules(t *testing.T) {
+	q := newRuleQuerier()
+	q.bookmarks[2] = []string{"main", "__head-bob-machine"}
+	q.bookmarks[3] = []string{"feature", "unstable"}
+	q.bookmarks[4] = []string{"feature", "main"}
+
+	if err := checkChangeBookmarkRules(context.Background(), q, repos.Repo(1), "bob", 2); err != nil {
+		t.Fatalf("a change that satisfies the rules should pass, got %v", err)
+	}
+	if err := checkChangeBookmarkRules(context.Background(), q, repos.Repo(1), "bob", 3); err != nil {
+		t.Fatalf("bookmarks without restrictions should pass, got %v", err)
+	}
+	// like `pogo describe main ""`
+	err := checkChangeBookmarkRules(context.Background(), q, repos.Repo(1), "bob", 4)
+	var ruleErr *bookmarkRuleError
+	if !errors.As(err, &ruleErr) || ruleErr.Bookmark != "main" {
+		t.Fatalf("removing the description of main should be forbidden, got %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	writeBookmarkRuleError(rec, "check bookmark rules", err)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("a violated rule should be forbidden, got status %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	writeBookmarkRuleError(rec, "check bookmark rules", errors.New("connection lost"))
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("other errors should be internal errors, got status %d", rec.Code)
+	}
+}
+
+func TestCheckChangeBookmarkRulesOfPush(t *testing.T) {
+	q := newRuleQuerier()
+	q.bookmarks[2] = []string{"release", "__head-bob-machine"}
+
+	// a push rewrites the change in place, like a describe
+	err := checkChangeBookmarkRules(context.Background(), q, repos.Repo(1), "bob", 2)
+	var ruleErr *bookmarkRuleError
+	if !errors.As(err, &ruleErr) || ruleErr.Bookmark != "release" {
+		t.Fatalf("bob should not push into the change of release, got %v", err)
+	}
+	if err := checkChangeBookmarkRules(context.Background(), q, repos.Repo(1), "alice", 2); err != nil {
+		t.Fatalf("alice may push into the change of release, got %v", err)
+	}
+}
+
+func TestCheckRebaseBookmarkRules(t *testing.T) {
+	q := newRuleQuerier()
+	q.bookmarks[3] = []string{"main", "unstable", "__head-bob-machine"}
+
+	if err := checkRebaseBookmarkRules(context.Background(), q, repos.Repo(1), 3, 1, 2); err != nil {
+		t.Fatalf("a rebase onto a descendant of the old parent should move main forward, got %v", err)
+	}
+	err := checkRebaseBookmarkRules(context.Background(), q, repos.Repo(1), 3, 2, 1)
+	var ruleErr *bookmarkRuleError
+	if !errors.As(err, &ruleErr) || ruleErr.Bookmark != "main" {
+		t.Fatalf("a rebase onto an ancestor of the old parent should move main backwards, got %v", err)
+	}
+	if err := checkRebaseBookmarkRules(context.Background(), q, repos.Repo(1), 4, 2, 1); err != nil {
+		t.Fatalf("changes without protected bookmarks can be rebased anywhere, got %v", err)
+	}
+}
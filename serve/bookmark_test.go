@@ -0,0 +1,32 @@
+package serve
+
+import "testing"
+
+func TestValidateBookmarkName(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"main", true},
+		{"feature/login-v2.1", true},
+		{"release_1.0", true},
+		{"", false},
+		{"1.0", false},
+		{"-main", false},
+		{"__head-alice-abc", false},
+		{"a..b", false},
+		{"with space", false},
+		{"main@", false},
+		{"a|b", false},
+	}
+
+	for _, tt := range tests {
+		err := validateBookmarkName(tt.name)
+		if tt.valid && err != nil {
+			t.Errorf("validateBookmarkName(%q): unexpected error %v", tt.name, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("validateBookmarkName(%q): expected an error", tt.name)
+		}
+	}
+}
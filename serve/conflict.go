@@ -0,0 +1,365 @@
+package serve
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/tsukinoko-kun/pogo/db"
+	"github.com/tsukinoko-kun/pogo/protos"
+	"github.com/tsukinoko-kun/pogo/repos"
+	"github.com/tsukinoko-kun/pogo/text"
+	"github.com/tsukinoko-kun/pogo/utils"
+)
+
+// fileConflict is the conflict of a path of a change, with the versions the path was merged from.
+// Conflicts are stored per change and path, changes that keep a path unchanged share its conflict.
+// The .binconflict_ files of a binary conflict share one conflict.
+type fileConflict struct {
+	// id is 0 until the conflict is stored
+	id   int64
+	kind conflictKind
+	// executable is the merged executable bit of a rendered conflict
+	executable bool
+	base       sideFile
+	sides      []sideFile
+}
+
+func parseConflictKind(kind string) conflictKind {
+	switch kind {
+	case "content":
+		return contentConflict
+	case "modify/delete":
+		return modifyDeleteConflict
+	case "add/add":
+		return addAddConflict
+	default:
+		// conflicts from before the sides were stored have no kind
+		return noConflict
+	}
+}
+
+// sameConflict reports whether two files are in the same conflict, or both in none.
+func sameConflict(a, b *fileConflict) bool {
+	return a == b || a != nil && b != nil && a.id != 0 && a.id == b.id
+}
+
+// listChangeFiles returns the files of a change together with its conflicts.
+func listChangeFiles(ctx context.Context, q db.Querier, changeId int64) ([]changeFile, error) {
+	files, err := q.ListChangeFiles(ctx, changeId)
+	if err != nil {
+		return nil, errors.Join(errors.New("list change files"), err)
+	}
+	conflicts, err := q.ListChangeConflicts(ctx, changeId)
+	if err != nil {
+		return nil, errors.Join(errors.New("list change conflicts"), err)
+	}
+	return withConflicts(ctx, q, files, conflicts)
+}
+
+// listSnapshotFiles returns the files of a change snapshot together with its conflicts.
+func listSnapshotFiles(ctx context.Context, q db.Querier, snapshotId int64) ([]changeFile, error) {
+	snapshotFiles, err := q.ListChangeSnapshotFiles(ctx, snapshotId)
+	if err != nil {
+		return nil, errors.Join(errors.New("list snapshot files"), err)
+	}
+	snapshotConflicts, err := q.ListChangeSnapshotConflicts(ctx, snapshotId)
+	if err != nil {
+		return nil, errors.Join(errors.New("list snapshot conflicts"), err)
+	}
+	files := make([]db.ListChangeFilesRow, len(snapshotFiles))
+	for i, f := range snapshotFiles {
+		files[i] = db.ListChangeFilesRow(f)
+	}
+	conflicts := make([]db.ListChangeConflictsRow, len(snapshotConflicts))
+	for i, c := range snapshotConflicts {
+		conflicts[i] = db.ListChangeConflictsRow(c)
+	}
+	return withConflicts(ctx, q, files, conflicts)
+}
+
+// withConflicts attaches the conflicts to the files of their paths.
+// A conflicted path without a file has no content of its own, it is rendered from the versions of its conflict.
+func withConflicts(ctx context.Context, q db.Querier, files []db.ListChangeFilesRow, conflicts []db.ListChangeConflictsRow) ([]changeFile, error) {
+	tree := make(fileTree, len(files)+len(conflicts))
+	for _, f := range files {
+		tree[f.Name] = changeFile{ListChangeFilesRow: f}
+	}
+	for _, row := range conflicts {
+		conflict, err := getConflict(ctx, q, row)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("get conflict of %s", row.Path), err)
+		}
+		f, ok := tree[row.Path]
+		if !ok {
+			f.ListChangeFilesRow = db.ListChangeFilesRow{Name: row.Path, Executable: conflict.executable}
+		}
+		f.conflict = conflict
+		tree[row.Path] = f
+	}
+	return tree.files(), nil
+}
+
+// getConflict reads a stored conflict with its versions.
+// Conflicts from before the versions were stored only have their path, the markers are in the file.
+func getConflict(ctx context.Context, q db.Querier, row db.ListChangeConflictsRow) (*fileConflict, error) {
+	conflict := &fileConflict{
+		id:         row.ID,
+		kind:       parseConflictKind(row.Kind),
+		executable: row.Executable,
+	}
+	if row.BaseChange != nil {
+		conflict.base = sideFile{
+			changeName: *row.BaseChange,
+			file: &changeFile{ListChangeFilesRow: db.ListChangeFilesRow{
+				Name:        row.Path,
+				Executable:  row.BaseExecutable != nil && *row.BaseExecutable,
+				ContentHash: row.BaseHash,
+			}},
+		}
+	}
+
+	sides, err := q.ListConflictSides(ctx, row.ID)
+	if err != nil {
+		return nil, errors.Join(errors.New("list conflict sides"), err)
+	}
+	for _, side := range sides {
+		version := sideFile{changeName: side.ChangeName}
+		// a side without content hash deleted the file
+		if side.ContentHash != nil {
+			version.file = &changeFile{ListChangeFilesRow: db.ListChangeFilesRow{
+				Name:        row.Path,
+				Executable:  side.Executable,
+				ContentHash: side.ContentHash,
+			}}
+		}
+		conflict.sides = append(conflict.sides, version)
+	}
+	return conflict, nil
+}
+
+// addChangeConflict puts a path of a change in conflict. A conflict that isn't stored yet is stored first.
+func addChangeConflict(ctx context.Context, q db.Querier, changeId int64, path string, conflict *fileConflict) error {
+	if conflict.id == 0 {
+		var (
+			baseChange     *string
+			baseHash       []byte
+			baseExecutable *bool
+		)
+		if conflict.base.file != nil {
+			baseChange = &conflict.base.changeName
+			baseHash = conflict.base.file.ContentHash
+			baseExecutable = &conflict.base.file.Executable
+		}
+		id, err := q.CreateConflict(ctx, conflict.kind.String(), conflict.executable, baseChange, baseHash, baseExecutable)
+		if err != nil {
+			return errors.Join(errors.New("create conflict"), err)
+		}
+		for i, side := range conflict.sides {
+			var (
+				contentHash []byte
+				executable  bool
+			)
+			if side.file != nil {
+				contentHash = side.file.ContentHash
+				executable = side.file.Executable
+			}
+			if err := q.AddConflictSide(ctx, id, int32(i), side.changeName, contentHash, executable); err != nil {
+				return errors.Join(errors.New("add conflict side"), err)
+			}
+		}
+		// the files of the same merge share the id
+		conflict.id = id
+	}
+	if err := q.AddChangeConflict(ctx, changeId, path, conflict.id); err != nil {
+		return errors.Join(errors.New("add change conflict"), err)
+	}
+	return nil
+}
+
+// protoConflict describes the conflict of a path for the client.
+func protoConflict(path string, conflict *fileConflict) *protos.FileConflict {
+	fileConflict := &protos.FileConflict{Path: path, Kind: conflict.kind.String()}
+	if conflict.base.file != nil {
+		fileConflict.Base = &protos.ConflictSide{
+			Change:      conflict.base.changeName,
+			ContentHash: conflict.base.file.ContentHash,
+			Executable:  conflict.base.file.Executable,
+		}
+	}
+	for _, side := range conflict.sides {
+		protoSide := &protos.ConflictSide{Change: side.changeName}
+		if side.file != nil {
+			protoSide.ContentHash = side.file.ContentHash
+			protoSide.Executable = side.file.Executable
+		}
+		fileConflict.Sides = append(fileConflict.Sides, protoSide)
+	}
+	return fileConflict
+}
+
+// renderedConflicts maps the content hashes of rendered conflicts to their text.
+// Rendered conflicts are not stored, readers look their content up here before they read the blob store.
+type renderedConflicts map[string]*text.Text
+
+// render renders every file without content of its own and sets its content hash to the one of the markers.
+func (rc renderedConflicts) render(repo repos.Repo, files []changeFile) error {
+	for i, f := range files {
+		if f.ContentHash != nil {
+			continue
+		}
+		txt, err := renderConflict(repo, f.conflict)
+		if err != nil {
+			return errors.Join(fmt.Errorf("render conflict of %s", f.Name), err)
+		}
+		files[i].ContentHash = utils.HashReader(txt.Reader())
+		rc[string(files[i].ContentHash)] = txt
+	}
+	return nil
+}
+
+// readText decodes the content of a file like readDiffText, rendered conflicts included.
+func (rc renderedConflicts) readText(repo repos.Repo, contentHash []byte) (*text.Text, error) {
+	if txt, ok := rc[string(contentHash)]; ok {
+		return txt, nil
+	}
+	return readDiffText(repo, contentHash)
+}
+
+// carryConflict decides whether a pushed file stays in the conflict its path had before the push.
+// previous is the conflicted file of the same path before the push. Only files that were in conflict can stay in it,
+// content that just looks like a conflict never starts one.
+// A file that still is the rendered conflict is kept without content of its own, render reports that.
+func carryConflict(repo repos.Repo, previous changeFile, contentHash []byte, executable *bool) (carry bool, render bool, err error) {
+	if previous.ContentHash == nil {
+		txt, err := renderConflict(repo, previous.conflict)
+		if err != nil {
+			return false, false, errors.Join(errors.New("render conflict"), err)
+		}
+		if bytes.Equal(utils.HashReader(txt.Reader()), contentHash) && (executable == nil || *executable == previous.Executable) {
+			return true, true, nil
+		}
+	} else if bytes.Equal(previous.ContentHash, contentHash) {
+		return true, false, nil
+	}
+
+	unresolved, err := conflictUnresolved(repo, previous.conflict, previous.Name, contentHash)
+	return unresolved, false, err
+}
+
+// conflictUnresolved decides whether new content of a conflicted file still is in conflict.
+// A binary conflict file is unresolved as long as it has the content of one of the sides.
+// A text file is unresolved as long as it has a region of conflict markers that are labeled with the sides
+// and only contains lines of the stored contents of the sides it names.
+func conflictUnresolved(repo repos.Repo, conflict *fileConflict, name string, contentHash []byte) (bool, error) {
+	if len(conflict.sides) == 0 {
+		// conflicts from before the sides were stored only have their markers
+		return isInConflict(repo, name, contentHash)
+	}
+
+	if strings.Contains(name, ".binconflict_") {
+		return slices.ContainsFunc(conflict.sides, func(side sideFile) bool {
+			return side.file != nil && bytes.Equal(side.file.ContentHash, contentHash)
+		}), nil
+	}
+
+	txt, err := readDiffText(repo, contentHash)
+	if err != nil {
+		return false, errors.Join(errors.New("read file text"), err)
+	}
+	if txt == nil {
+		return false, nil
+	}
+	sides := make(conflictSideLines, len(conflict.sides))
+	for _, side := range conflict.sides {
+		// sides that deleted the file or are binary have no lines
+		sides[side.changeName] = nil
+		if side.file == nil {
+			continue
+		}
+		sideTxt, err := readDiffText(repo, side.file.ContentHash)
+		if err != nil {
+			return false, errors.Join(fmt.Errorf("read text of side %s", side.changeName), err)
+		}
+		if sideTxt != nil {
+			sides[side.changeName] = lineSet(sideTxt.String())
+		}
+	}
+	return hasConflictRegion(txt.String(), sides), nil
+}
+
+// conflictSideLines maps the change names of the sides of a conflict to the lines of their contents.
+type conflictSideLines map[string]map[string]bool
+
+// lineSet returns the lines of content without their line endings.
+func lineSet(content string) map[string]bool {
+	lines := make(map[string]bool)
+	for line := range strings.Lines(content) {
+		lines[strings.TrimRight(line, "\r\n")] = true
+	}
+	return lines
+}
+
+// hasConflictRegion reports whether content has a complete region of conflict markers the way the merge writes them:
+// both labels name only sides of the conflict and each part of the region only has lines of the sides its label names.
+// Regions that were edited don't count, even if their markers are left.
+func hasConflictRegion(content string, sides conflictSideLines) bool {
+	const (
+		outside = iota
+		ours
+		theirs
+	)
+	state := outside
+	var (
+		label string
+		lines []string
+	)
+	for line := range strings.Lines(content) {
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case strings.HasPrefix(line, "<<<<<<<<< "):
+			// regions of merges with more than two sides can be nested, the innermost region counts
+			state = ours
+			label = strings.TrimPrefix(line, "<<<<<<<<< ")
+			lines = nil
+		case line == "=========" && state == ours:
+			if conflictPartOfSides(label, lines, sides) {
+				state = theirs
+			} else {
+				state = outside
+			}
+			lines = nil
+		case strings.HasPrefix(line, ">>>>>>>>> "):
+			if state == theirs && conflictPartOfSides(strings.TrimPrefix(line, ">>>>>>>>> "), lines, sides) {
+				return true
+			}
+			state = outside
+		default:
+			lines = append(lines, line)
+		}
+	}
+	return false
+}
+
+// conflictPartOfSides reports whether a marker label lists only sides of the conflict
+// and every line of its part of the region is a line of one of them.
+func conflictPartOfSides(label string, lines []string, sides conflictSideLines) bool {
+	label = strings.TrimSuffix(label, " (deleted)")
+	var named []map[string]bool
+	for name := range strings.SplitSeq(label, ", ") {
+		sideLines, ok := sides[name]
+		if name == "" || !ok {
+			return false
+		}
+		named = append(named, sideLines)
+	}
+	for _, line := range lines {
+		if !slices.ContainsFunc(named, func(sideLines map[string]bool) bool { return sideLines[line] }) {
+			return false
+		}
+	}
+	return true
+}
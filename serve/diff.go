@@ -0,0 +1,266 @@
+package serve
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/tsukinoko-kun/pogo/db"
+	"github.com/tsukinoko-kun/pogo/protos"
+	"github.com/tsukinoko-kun/pogo/repos"
+	"github.com/tsukinoko-kun/pogo/signedhttp"
+	"github.com/tsukinoko-kun/pogo/text"
+	"github.com/tsukinoko-kun/pogo/utils"
+)
+
+// diffContextLines is the number of unchanged lines around each change of a patch.
+const diffContextLines = 3
+
+func (a *App) handleDiff(w http.ResponseWriter, r *signedhttp.Request) {
+	repo, err := a.openRepo(r.PathValue("repo"))
+	if err != nil {
+		if errors.Is(err, repos.ErrRepoNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "open repository: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	req := new(protos.DiffRequest)
+	err = protos.Unmarshal(r.Body(), req)
+	if err != nil {
+		http.Error(w, "unmarshal diff request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	env := revsetEnv(r, db.Q, repo)
+	resp := new(protos.DiffResponse)
+
+	var snapshotChange *db.GetSnapshotChangeRow
+	if req.FromSnapshot != nil {
+		c, err := db.Q.GetSnapshotChange(r.Context(), *req.FromSnapshot, repo.ID())
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				http.Error(w, fmt.Sprintf("snapshot %d not found", *req.FromSnapshot), http.StatusNotFound)
+				return
+			}
+			http.Error(w, "get snapshot: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		snapshotChange = &c
+	}
+
+	var toId int64
+	switch {
+	case req.To != "":
+		if toId, err = env.ResolveOne(req.To); err != nil {
+			writeRevsetError(w, "find change "+req.To, err)
+			return
+		}
+	case snapshotChange != nil:
+		toId = snapshotChange.ChangeID
+	default:
+		if toId, err = env.ResolveOne("@"); err != nil {
+			writeRevsetError(w, "find change @", err)
+			return
+		}
+	}
+	if resp.To, err = db.Q.GetChangeName(r.Context(), toId, repo.ID()); err != nil {
+		http.Error(w, "get change name: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var fromFiles []changeFile
+	if snapshotChange != nil {
+		resp.From = fmt.Sprintf("%s (snapshot %d)", snapshotChange.Name, *req.FromSnapshot)
+		if fromFiles, err = listSnapshotFiles(r.Context(), db.Q, *req.FromSnapshot); err != nil {
+			http.Error(w, "list snapshot files: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	} else {
+		// a change without parents is compared to an empty change
+		var fromId *int64
+		if req.From != "" {
+			id, err := env.ResolveOne(req.From)
+			if err != nil {
+				writeRevsetError(w, "find change "+req.From, err)
+				return
+			}
+			fromId = &id
+		} else {
+			parents, err := db.Q.RevsetParents(r.Context(), []int64{toId}, repo.ID())
+			if err != nil {
+				http.Error(w, "get parents: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			switch len(parents) {
+			case 0:
+			case 1:
+				fromId = &parents[0]
+			default:
+				http.Error(w, fmt.Sprintf("change %s has %d parents, select the one to compare with", resp.To, len(parents)), http.StatusBadRequest)
+				return
+			}
+		}
+
+		if fromId != nil {
+			if resp.From, err = db.Q.GetChangeName(r.Context(), *fromId, repo.ID()); err != nil {
+				http.Error(w, "get change name: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if fromFiles, err = listChangeFiles(r.Context(), db.Q, *fromId); err != nil {
+				http.Error(w, "list change files: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+	toFiles, err := listChangeFiles(r.Context(), db.Q, toId)
+	if err != nil {
+		http.Error(w, "list change files: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// conflicts are compared by their markers
+	rendered := make(renderedConflicts)
+	if err := rendered.render(repo, fromFiles); err != nil {
+		http.Error(w, "render conflicts: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := rendered.render(repo, toFiles); err != nil {
+		http.Error(w, "render conflicts: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	oldFiles := make(map[string]changeFile, len(fromFiles))
+	newFiles := make(map[string]changeFile, len(toFiles))
+	var paths []string
+	for _, f := range fromFiles {
+		if matchesPaths(f.Name, req.Paths) {
+			oldFiles[f.Name] = f
+			paths = append(paths, f.Name)
+		}
+	}
+	for _, f := range toFiles {
+		if matchesPaths(f.Name, req.Paths) {
+			newFiles[f.Name] = f
+			if _, ok := oldFiles[f.Name]; !ok {
+				paths = append(paths, f.Name)
+			}
+		}
+	}
+	slices.Sort(paths)
+
+	for _, path := range paths {
+		oldFile, oldOk := oldFiles[path]
+		newFile, newOk := newFiles[path]
+		if oldOk && newOk && bytes.Equal(oldFile.ContentHash, newFile.ContentHash) && oldFile.Executable == newFile.Executable {
+			continue
+		}
+
+		fd := &protos.FileDiff{
+			Path:           path,
+			OldContentHash: oldFile.ContentHash,
+			NewContentHash: newFile.ContentHash,
+			OldExecutable:  oldFile.Executable,
+			NewExecutable:  newFile.Executable,
+		}
+		if err := diffFile(repo, rendered, fd, req.Stat); err != nil {
+			http.Error(w, fmt.Sprintf("diff file %s: %s", path, err.Error()), http.StatusInternalServerError)
+			return
+		}
+		resp.Files = append(resp.Files, fd)
+	}
+
+	_ = protos.MarshalWrite(resp, w)
+}
+
+// matchesPaths reports whether the file is one of the paths or inside one of them.
+// No paths match every file.
+func matchesPaths(name string, paths []string) bool {
+	if len(paths) == 0 {
+		return true
+	}
+	for _, p := range paths {
+		p = strings.TrimSuffix(p, "/")
+		if p == "" || p == "." || name == p || strings.HasPrefix(name, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// diffFile fills the patch and the line counts of fd.
+func diffFile(repo repos.Repo, rendered renderedConflicts, fd *protos.FileDiff, stat bool) error {
+	if bytes.Equal(fd.OldContentHash, fd.NewContentHash) {
+		// only the executable bit changed
+		return nil
+	}
+
+	oldText, err := rendered.readText(repo, fd.OldContentHash)
+	if err != nil {
+		return errors.Join(errors.New("read old content"), err)
+	}
+	newText, err := rendered.readText(repo, fd.NewContentHash)
+	if err != nil {
+		return errors.Join(errors.New("read new content"), err)
+	}
+	if oldText == nil || newText == nil {
+		fd.Binary = true
+		return nil
+	}
+
+	oldName, newName := "a/"+fd.Path, "b/"+fd.Path
+	if len(fd.OldContentHash) == 0 {
+		oldName = "/dev/null"
+	}
+	if len(fd.NewContentHash) == 0 {
+		newName = "/dev/null"
+	}
+	patch, diffStat := text.UnifiedDiff(oldName, newName, oldText.Content(), newText.Content(), diffContextLines)
+	fd.Additions = int32(diffStat.Additions)
+	fd.Deletions = int32(diffStat.Deletions)
+	if !stat {
+		fd.Patch = patch
+	}
+	return nil
+}
+
+// readDiffText decodes the content of a file. A missing file is empty.
+// It returns nil if the content is binary.
+func readDiffText(repo repos.Repo, contentHash []byte) (*text.Text, error) {
+	if len(contentHash) == 0 {
+		return text.NewText(""), nil
+	}
+
+	fi, err := repo.GetFileInfo(contentHash)
+	if err != nil {
+		return nil, errors.Join(errors.New("get file info"), err)
+	}
+	if fi.Size > 1024*1024 {
+		// > 1MB, treat as binary like the merge does
+		return nil, nil
+	}
+
+	f, err := repo.GetFileContent(contentHash)
+	if err != nil {
+		return nil, errors.Join(errors.New("get file content"), err)
+	}
+	defer f.Close()
+
+	content, isText, err := text.IsTextReader(utils.Decompress(f))
+	if err != nil {
+		return nil, errors.Join(errors.New("detect text"), err)
+	}
+	if !isText {
+		return nil, nil
+	}
+	txt, err := text.ReadFrom(content)
+	if err != nil {
+		return nil, errors.Join(errors.New("read text"), err)
+	}
+	return txt, nil
+}
@@ -0,0 +1,193 @@
+package serve
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/tsukinoko-kun/pogo/db"
+	"github.com/tsukinoko-kun/pogo/protos"
+	"github.com/tsukinoko-kun/pogo/repos"
+	"github.com/tsukinoko-kun/pogo/serve/serveerrors"
+	"github.com/tsukinoko-kun/pogo/signedhttp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// handleEvolog lists the snapshots of a change, newest first.
+// Every logged operation that modified the change recorded its state afterwards.
+// The state before the first logged operation is listed too, so changes from before the operation log keep it.
+func (a *App) handleEvolog(w http.ResponseWriter, r *signedhttp.Request) {
+	repo, err := a.openRepo(r.PathValue("repo"))
+	if err != nil {
+		if errors.Is(err, repos.ErrRepoNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "open repository: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	req := new(protos.EvologRequest)
+	err = protos.Unmarshal(r.Body(), req)
+	if err != nil {
+		http.Error(w, "unmarshal evolog request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	changeId, err := revsetEnv(r, db.Q, repo).ResolveOne(req.Change)
+	if err != nil {
+		writeRevsetError(w, "find change "+req.Change, err)
+		return
+	}
+
+	resp := new(protos.EvologResponse)
+	if resp.Name, err = db.Q.GetChangeName(r.Context(), changeId, repo.ID()); err != nil {
+		http.Error(w, "get change name: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	snapshots, err := db.Q.ListChangeSnapshots(r.Context(), changeId)
+	if err != nil {
+		http.Error(w, "list snapshots: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for i, s := range snapshots {
+		snapshot := &protos.ChangeSnapshot{
+			Id:          s.ID,
+			CreatedAt:   timestamppb.New(s.CreatedAt.Time),
+			Description: s.Description,
+		}
+		if s.After {
+			snapshot.OperationId = s.OperationID
+			snapshot.Command = s.Command
+			snapshot.Username = s.Username
+			snapshot.Device = s.Device
+		} else if i > 0 {
+			// the state before an operation is the state after the previous one
+			continue
+		}
+		resp.Snapshots = append(resp.Snapshots, snapshot)
+	}
+	slices.Reverse(resp.Snapshots)
+
+	_ = protos.MarshalWrite(resp, w)
+}
+
+// handleRestoreSnapshot resets the files and the description of a change to one of its snapshots.
+// Descendants are rebased onto the restored state, like after a squash.
+func (a *App) handleRestoreSnapshot(w http.ResponseWriter, r *signedhttp.Request) {
+	repo, err := a.openRepo(r.PathValue("repo"))
+	if err != nil {
+		if errors.Is(err, repos.ErrRepoNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "open repository: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	req := new(protos.RestoreSnapshotRequest)
+	err = protos.Unmarshal(r.Body(), req)
+	if err != nil {
+		http.Error(w, "unmarshal restore snapshot request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Q.Begin(r.Context())
+	if err != nil {
+		http.Error(w, "begin transaction: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Close()
+
+	snapshotChange, err := tx.GetSnapshotChange(r.Context(), req.SnapshotId, repo.ID())
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, fmt.Sprintf("snapshot %d not found", req.SnapshotId), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "get snapshot: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	changeId := snapshotChange.ChangeID
+
+	if owner, err := tx.GetChangeOwner(r.Context(), changeId); err != nil {
+		http.Error(w, "get change owner: "+err.Error(), http.StatusInternalServerError)
+		return
+	} else if owner.Author != r.Username() || owner.Device != r.MachineID() {
+		http.Error(w, serveerrors.ErrRewriteChangeNotOwned.Error(), http.StatusBadRequest)
+		return
+	}
+
+	op, err := beginOperation(r, tx.Queries, repo, fmt.Sprintf("restore snapshot %d", req.SnapshotId))
+	if err != nil {
+		http.Error(w, "begin operation: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	files, err := listChangeFiles(r.Context(), tx.Queries, changeId)
+	if err != nil {
+		http.Error(w, "get change files: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := op.touch(changeId); err != nil {
+		http.Error(w, "snapshot change: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := restoreChangeSnapshot(r, tx.Queries, changeId, req.SnapshotId, aspectDescription|aspectFiles); err != nil {
+		http.Error(w, "restore snapshot: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	oldFiles := map[int64][]changeFile{changeId: files}
+	if err := rebaseDescendants(newDBRewriter(r, tx.Queries, repo, op), oldFiles, []int64{changeId}); err != nil {
+		writeRewriteError(w, "rebase descendants", err)
+		return
+	}
+
+	resp := &protos.RestoreSnapshotResponse{Name: snapshotChange.Name}
+	headId, err := tx.GetBookmark(r.Context(), repo.ID(), headBookmark(r))
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "get head: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for id := range oldFiles {
+		if err := checkChangeBookmarkRules(r.Context(), tx.Queries, repo, r.Username(), id); err != nil {
+			writeBookmarkRuleError(w, "check bookmark rules", err)
+			return
+		}
+		if id == headId {
+			resp.HeadChanged = true
+		}
+		name, err := tx.GetChangeName(r.Context(), id, repo.ID())
+		if err != nil {
+			http.Error(w, "get change name: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if id != changeId {
+			resp.Rebased = append(resp.Rebased, name)
+		}
+		if conflicts, err := tx.HasChangeConflicts(r.Context(), id); err != nil {
+			http.Error(w, "check change conflicts: "+err.Error(), http.StatusInternalServerError)
+			return
+		} else if conflicts {
+			resp.Conflicts = append(resp.Conflicts, name)
+		}
+	}
+	slices.Sort(resp.Rebased)
+	slices.Sort(resp.Conflicts)
+
+	if err := op.finish(); err != nil {
+		http.Error(w, "log operation: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		http.Error(w, "commit transaction: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_ = protos.MarshalWrite(resp, w)
+}
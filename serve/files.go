@@ -0,0 +1,179 @@
+package serve
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/tsukinoko-kun/pogo/db"
+	"github.com/tsukinoko-kun/pogo/protos"
+	"github.com/tsukinoko-kun/pogo/repos"
+	"github.com/tsukinoko-kun/pogo/signedhttp"
+	"github.com/tsukinoko-kun/pogo/utils"
+)
+
+// handleFiles lists the files of one change without sending their contents.
+func (a *App) handleFiles(w http.ResponseWriter, r *signedhttp.Request) {
+	repo, err := a.openRepo(r.PathValue("repo"))
+	if err != nil {
+		if errors.Is(err, repos.ErrRepoNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "open repository: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	req := new(protos.FilesRequest)
+	err = protos.Unmarshal(r.Body(), req)
+	if err != nil {
+		http.Error(w, "unmarshal files request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	changeId, err := revsetEnv(r, db.Q, repo).ResolveOne(req.Change)
+	if err != nil {
+		writeRevsetError(w, "find change "+req.Change, err)
+		return
+	}
+
+	resp := new(protos.FilesResponse)
+	if resp.ChangeName, err = db.Q.GetChangeName(r.Context(), changeId, repo.ID()); err != nil {
+		http.Error(w, "get change name: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	files, err := listChangeFiles(r.Context(), db.Q, changeId)
+	if err != nil {
+		http.Error(w, "list change files: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	rendered := make(renderedConflicts)
+	if err := rendered.render(repo, files); err != nil {
+		http.Error(w, "render conflicts: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	sizedFiles, err := db.Q.ListChangeFilesWithSize(r.Context(), changeId)
+	if err != nil {
+		http.Error(w, "list change files: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	sizes := make(map[string]*int64, len(sizedFiles))
+	for _, f := range sizedFiles {
+		sizes[f.Name] = f.Size
+	}
+
+	resp.Files = make([]*protos.ChangeFile, len(files))
+	for i, f := range files {
+		size := sizes[f.Name]
+		if txt, ok := rendered[string(f.ContentHash)]; ok {
+			n, err := io.Copy(io.Discard, txt.Reader())
+			if err != nil {
+				http.Error(w, "get size of "+f.Name+": "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			size = &n
+		} else if size == nil {
+			if size, err = fileSize(repo, f.ContentHash); err != nil {
+				http.Error(w, "get size of "+f.Name+": "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		resp.Files[i] = &protos.ChangeFile{
+			Name:        f.Name,
+			Executable:  f.Executable,
+			ContentHash: f.ContentHash,
+			Size:        *size,
+		}
+	}
+
+	_ = protos.MarshalWrite(resp, w)
+}
+
+// fileSize measures the uncompressed size of a blob, the blob store only knows the compressed size.
+// Sizes are recorded when the content is stored, only files pushed before that need to be measured
+// until "server fsck -repair" records their sizes.
+func fileSize(repo repos.Repo, contentHash []byte) (*int64, error) {
+	f, err := repo.GetFileContent(contentHash)
+	if err != nil {
+		return nil, errors.Join(errors.New("get file content"), err)
+	}
+	defer f.Close()
+
+	size, err := io.Copy(io.Discard, utils.Decompress(f))
+	if err != nil {
+		return nil, errors.Join(errors.New("decompress file content"), err)
+	}
+	return &size, nil
+}
+
+// handleCat streams the decompressed content of one file of a change.
+func (a *App) handleCat(w http.ResponseWriter, r *signedhttp.Request) {
+	repo, err := a.openRepo(r.PathValue("repo"))
+	if err != nil {
+		if errors.Is(err, repos.ErrRepoNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "open repository: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	req := new(protos.CatRequest)
+	err = protos.Unmarshal(r.Body(), req)
+	if err != nil {
+		http.Error(w, "unmarshal cat request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	changeId, err := revsetEnv(r, db.Q, repo).ResolveOne(req.Change)
+	if err != nil {
+		writeRevsetError(w, "find change "+req.Change, err)
+		return
+	}
+
+	file, err := db.Q.GetChangeFile(r.Context(), changeId, req.Path)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			catRenderedConflict(w, r, repo, changeId, req)
+			return
+		}
+		http.Error(w, "get change file: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	f, err := repo.GetFileContent(file.ContentHash)
+	if err != nil {
+		http.Error(w, "get file content: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	// the status is already sent once copying starts, a failure can only cut the body short
+	_, _ = io.Copy(w, utils.Decompress(f))
+}
+
+// catRenderedConflict writes the markers of a conflicted file that has no content of its own.
+func catRenderedConflict(w http.ResponseWriter, r *signedhttp.Request, repo repos.Repo, changeId int64, req *protos.CatRequest) {
+	files, err := listChangeFiles(r.Context(), db.Q, changeId)
+	if err != nil {
+		http.Error(w, "list change files: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	i := slices.IndexFunc(files, func(f changeFile) bool { return f.Name == req.Path })
+	if i < 0 {
+		http.Error(w, "file "+req.Path+" not found in change "+req.Change, http.StatusNotFound)
+		return
+	}
+	txt, err := renderConflict(repo, files[i].conflict)
+	if err != nil {
+		http.Error(w, "render conflict: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	_, _ = io.Copy(w, txt.Reader())
+}
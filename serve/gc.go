@@ -0,0 +1,75 @@
+package serve
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/tsukinoko-kun/pogo/db"
+	"github.com/tsukinoko-kun/pogo/gc"
+	"github.com/tsukinoko-kun/pogo/protos"
+)
+
+// SetGCGracePeriod sets how long file rows and blobs must stay unreferenced before the garbage collection deletes them.
+func (a *App) SetGCGracePeriod(gracePeriod time.Duration) {
+	a.gcGracePeriod = gracePeriod
+}
+
+// SetOperationRetention sets how long operations can be undone before the garbage collection deletes them, 0 keeps them forever.
+func (a *App) SetOperationRetention(retention time.Duration) {
+	a.operationRetention = retention
+}
+
+// SetEvologRetention sets how long operations that modified changes are kept for the evolution log
+// after their operation retention, 0 keeps them forever.
+func (a *App) SetEvologRetention(retention time.Duration) {
+	a.evologRetention = retention
+}
+
+// operationExpired reports whether an operation is older than the operation retention.
+// Expired operations might only be kept for the evolution log, they can't be undone anymore.
+func (a *App) operationExpired(operation db.Operation) bool {
+	return a.operationRetention > 0 && time.Since(operation.CreatedAt.Time) > a.operationRetention
+}
+
+func (a *App) handleGC(w http.ResponseWriter, httpReq *http.Request) {
+	defer httpReq.Body.Close()
+
+	r, ok := a.authenticateAdmin(w, httpReq)
+	if !ok {
+		return
+	}
+	defer r.Close()
+
+	req := new(protos.GCRequest)
+	if err := protos.Unmarshal(r.Body(), req); err != nil {
+		http.Error(w, "unmarshal gc request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report, err := gc.Run(r.Context(), gc.Options{
+		GracePeriod:        a.gcGracePeriod,
+		OperationRetention: a.operationRetention,
+		EvologRetention:    a.evologRetention,
+		DryRun:             req.DryRun,
+	})
+	if err != nil {
+		if errors.Is(err, gc.ErrAlreadyRunning) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, "garbage collection: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_ = protos.MarshalWrite(&protos.GCResponse{
+		DryRun:            report.DryRun,
+		OperationsDeleted: report.OperationsDeleted,
+		SnapshotsDeleted:  report.SnapshotsDeleted,
+		FilesMarked:       report.FilesMarked,
+		FilesDeleted:      report.FilesDeleted,
+		BlobsMarked:       report.BlobsMarked,
+		BlobsDeleted:      report.BlobsDeleted,
+		BytesFreed:        report.BytesFreed,
+	}, w)
+}
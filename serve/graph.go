@@ -0,0 +1,47 @@
+package serve
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/tsukinoko-kun/pogo/db"
+	"github.com/tsukinoko-kun/pogo/repos"
+)
+
+// updateDepths recomputes the depth of the roots and all their descendants after their parents changed.
+// Edges below the roots must be unchanged, so the old depths still order the descendants parents first.
+func updateDepths(ctx context.Context, q db.Querier, repo repos.Repo, op *operation, roots []int64) error {
+	ids, err := q.RevsetDescendants(ctx, roots, repo.ID())
+	if err != nil {
+		return errors.Join(errors.New("get descendants"), err)
+	}
+
+	oldDepths := make(map[int64]int64, len(ids))
+	for _, id := range ids {
+		if oldDepths[id], err = q.GetChangeDepth(ctx, id, repo.ID()); err != nil {
+			return errors.Join(errors.New("get change depth"), err)
+		}
+	}
+	slices.SortFunc(ids, func(a, b int64) int {
+		return cmp.Compare(oldDepths[a], oldDepths[b])
+	})
+
+	for _, id := range ids {
+		parentDepth, err := q.GetParentsMaxDepth(ctx, id)
+		if err != nil {
+			return errors.Join(errors.New("get parent depth"), err)
+		}
+		if parentDepth+1 == oldDepths[id] {
+			continue
+		}
+		if err := op.touch(id); err != nil {
+			return err
+		}
+		if err := q.SetChangeDepth(ctx, id, parentDepth+1); err != nil {
+			return errors.Join(errors.New("set change depth"), err)
+		}
+	}
+	return nil
+}
@@ -5,8 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"iter"
-	"os"
+	"maps"
+	"slices"
 	"strings"
 
 	"github.com/tsukinoko-kun/pogo/db"
@@ -22,463 +22,416 @@ type mergeParent struct {
 	changeName string
 }
 
+// Merge writes the files of any number of parents, merged against their merge base, into the target change.
 func (a *App) Merge(ctx context.Context, q db.Querier, repo repos.Repo, targetChangeId int64, mergeParents []mergeParent) error {
-	var lca int64
-	{
-		ancestries := make([][]db.GetAncestryOfChangeRow, len(mergeParents))
-		for i, head := range mergeParents {
-			ancestry, err := q.GetAncestryOfChange(ctx, head.changeID, 100, repo.ID())
-			if err != nil {
-				return errors.Join(errors.New("get ancestry of change"), err)
-			}
-			ancestries[i] = ancestry
-		}
-		var ok bool
-		lca, ok = getLCA(ancestries)
-		if !ok {
-			return errors.New("no common ancestor found")
-		}
-	}
-
-	mergeParentsOverlapChanges := make([]overlapChange, len(mergeParents))
+	heads := make([]int64, len(mergeParents))
 	for i, mergeParent := range mergeParents {
-		mergeParentsOverlapChanges[i] = overlapChange{mergeParent.changeName, mergeParent.changeID}
+		heads[i] = mergeParent.changeID
 	}
-	overlapRes, err := overlap(ctx, q, repo, overlapChange{changeID: lca, name: "LCA"}, mergeParentsOverlapChanges)
+	base, err := mergeBaseTree(ctx, q, repo, newDBChangeGraph(ctx, q, repo), heads)
 	if err != nil {
-		return errors.Join(errors.New("overlap"), err)
+		return err
 	}
 
-	for fileChange := range joinOverlappingChanges(overlapRes) {
-		if fileChange.IsText() {
-			if err := repo.SetFileContent(
-				fileChange.ContentHash(),
-				fileChange.TextContent().Reader(),
-			); err != nil {
-				return errors.Join(fmt.Errorf("set text file %s content", fileChange.FileName()), err)
-			}
-		}
-		fileId, err := db.UpsertFile(
-			q,
-			ctx,
-			fileChange.FileName(),
-			utils.Ptr(fileChange.Executable()),
-			fileChange.ContentHash(),
-			fileChange.Conflict(),
-		)
+	mergeParentsOverlapChanges := make([]overlapChange, len(mergeParents))
+	for i, mergeParent := range mergeParents {
+		parentFiles, err := listChangeFiles(ctx, q, mergeParent.changeID)
 		if err != nil {
-			return errors.Join(fmt.Errorf("upsert file %s", fileChange.FileName()), err)
-		}
-		if err := q.AddFileToChange(ctx, targetChangeId, fileId); err != nil {
-			return errors.Join(fmt.Errorf("add file %s to change %d", fileChange.FileName(), targetChangeId), err)
+			return errors.Join(fmt.Errorf("get parent files for change %s", mergeParent.changeName), err)
 		}
+		mergeParentsOverlapChanges[i] = overlapChange{mergeParent.changeName, parentFiles}
 	}
 
-	return nil
-}
-
-// getLCA calculates the lowest common ancestor of a set of commits.
-// It returns the change ID and a boolean indicating whether the LCA was found.
-func getLCA(ancestries [][]db.GetAncestryOfChangeRow) (int64, bool) {
-	if len(ancestries) == 0 {
-		return 0, false
-	}
-
-	if len(ancestries) == 1 {
-		if len(ancestries[0]) > 0 {
-			return ancestries[0][0].ID, true // return the tip itself
-		}
-		return 0, false
+	files, err := mergeFileTrees(ctx, q, repo, base, mergeParentsOverlapChanges)
+	if err != nil {
+		return err
 	}
+	return addChangeFiles(ctx, q, targetChangeId, files)
+}
 
-	// Initialize candidates with first ancestry (ID -> depth)
-	candidates := make(map[int64]int64)
-	for _, change := range ancestries[0] {
-		candidates[change.ID] = change.Depth
+// mergeFileTrees merges the files of the sides that changed relative to base.
+// The contents and file rows of the result are stored, so the returned files can be added to any change.
+// Conflicts are stored once the files are added to a change, see addChangeFiles.
+func mergeFileTrees(ctx context.Context, q db.Querier, repo repos.Repo, base overlapChange, sides []overlapChange) ([]changeFile, error) {
+	merged, err := mergeFiles(repo, base, sides)
+	if err != nil {
+		return nil, err
 	}
 
-	// Intersect with each subsequent ancestry
-	for i := 1; i < len(ancestries); i++ {
-		current := make(map[int64]int64)
-		for _, change := range ancestries[i] {
-			if _, exists := candidates[change.ID]; exists {
-				current[change.ID] = change.Depth
+	files := make([]changeFile, 0, len(merged))
+	for _, f := range merged {
+		// the versions of a conflict are kept as the conflict stores them
+		for _, txt := range f.rendered {
+			if _, err := repo.SetFileContent(utils.HashReader(txt.Reader()), txt.Reader()); err != nil {
+				return nil, errors.Join(fmt.Errorf("set rendered conflict %s content", f.Name), err)
 			}
 		}
-		candidates = current
-
-		if len(candidates) == 0 {
-			return 0, false // no common ancestors
+		if f.content != nil {
+			size, err := repo.SetFileContent(f.ContentHash, f.content.Reader())
+			if err != nil {
+				return nil, errors.Join(fmt.Errorf("set text file %s content", f.Name), err)
+			}
+			if _, err := db.UpsertFile(q, ctx, f.Name, utils.Ptr(f.Executable), f.ContentHash); err != nil {
+				return nil, errors.Join(fmt.Errorf("upsert file %s", f.Name), err)
+			}
+			if err := q.SetFileSize(ctx, f.ContentHash, &size); err != nil {
+				return nil, errors.Join(fmt.Errorf("set size of file %s", f.Name), err)
+			}
 		}
+		files = append(files, f.changeFile)
 	}
+	return files, nil
+}
 
-	// Find common ancestor with maximum depth (closest to tips)
-	var lcaID int64
-	var maxDepth int64 = -1
-	for id, depth := range candidates {
-		if depth > maxDepth {
-			maxDepth = depth
-			lcaID = id
-		}
-	}
+// conflictKind tells why a merged file is in conflict.
+type conflictKind uint8
+
+const (
+	noConflict conflictKind = iota
+	// contentConflict: the sides changed the same lines, or a binary file, differently
+	contentConflict
+	// modifyDeleteConflict: a side changed the file, another side deleted it
+	modifyDeleteConflict
+	// addAddConflict: the sides added the file with different contents
+	addAddConflict
+)
 
-	return lcaID, true
+func (k conflictKind) String() string {
+	switch k {
+	case contentConflict:
+		return "content"
+	case modifyDeleteConflict:
+		return "modify/delete"
+	case addAddConflict:
+		return "add/add"
+	default:
+		return ""
+	}
 }
 
 type (
 	overlapChange struct {
-		name     string
-		changeID int64
+		name  string
+		files []changeFile
 	}
 
-	textFileChange struct {
-		others         []textFileOtherChange
-		base           *text.Text
-		executable     bool
-		baseExecutable bool
-		baseExists     bool
+	// sideFile is the version of a file in one side of a merge, file is nil if the side doesn't have it.
+	sideFile struct {
+		changeName string
+		file       *changeFile
 	}
 
-	binaryFileChange struct {
-		baseHash       []byte
-		otherHashes    []binaryfileOtherChange
-		baseExists     bool
-		executable     bool
-		baseExecutable bool
-	}
-
-	binaryfileOtherChange struct {
-		contentHash []byte
-		changeName  string
+	// mergedFile is a file of a merge result.
+	// content is only set for text the merge produced, it still has to be stored.
+	// A conflicted text file has no content, it is rendered from its conflict. rendered holds the conflicts
+	// the file was merged from as text, they are versions of the new conflict and still have to be stored.
+	mergedFile struct {
+		changeFile
+		content  *text.Text
+		rendered []*text.Text
 	}
 
 	textFileOtherChange struct {
 		textContent *text.Text
 		changeName  string
 	}
-
-	overlapResult struct {
-		textFileChanges   map[string]*textFileChange
-		binaryFileChanges map[string]*binaryFileChange
-	}
 )
 
-func overlap(ctx context.Context, q db.Querier, repo repos.Repo, base overlapChange, parents []overlapChange) (overlapResult, error) {
-	textFileChanges := make(map[string]*textFileChange)
-	binaryFileChanges := make(map[string]*binaryFileChange)
-
-	baseFiles, err := q.ListChangeFiles(ctx, base.changeID)
-	if err != nil {
-		return overlapResult{}, errors.Join(fmt.Errorf("get base files for change %s", base.name), err)
+// mergeFiles merges every file of the sides against base, see mergeFile.
+func mergeFiles(repo repos.Repo, base overlapChange, sides []overlapChange) ([]mergedFile, error) {
+	baseTree := newFileTree(base.files)
+	sideTrees := make([]fileTree, len(sides))
+	names := make(map[string]struct{}, len(baseTree))
+	for name := range baseTree {
+		names[name] = struct{}{}
+	}
+	for i, side := range sides {
+		sideTrees[i] = newFileTree(side.files)
+		for name := range sideTrees[i] {
+			names[name] = struct{}{}
+		}
 	}
 
-	for _, baseFile := range baseFiles {
-		{
-			fi, err := repo.GetFileInfo(baseFile.ContentHash)
-			if err != nil {
-				return overlapResult{}, errors.Join(fmt.Errorf("get file info %s in change %s", baseFile.Name, base.name), err)
-			}
-			if fi.Size() > 1024*1024 {
-				// > 1MB, treat as binary
-				binaryFileChanges[baseFile.Name] = &binaryFileChange{
-					baseHash:   baseFile.ContentHash,
-					baseExists: true,
-				}
-				continue
-			}
+	var merged []mergedFile
+	for _, name := range slices.Sorted(maps.Keys(names)) {
+		baseFile := sideFile{changeName: base.name}
+		if f, ok := baseTree[name]; ok {
+			baseFile.file = &f
 		}
-		f, err := repo.GetFileContent(baseFile.ContentHash)
-		if err != nil {
-			return overlapResult{}, errors.Join(fmt.Errorf("get file content %s in change %s", baseFile.Name, base.name), err)
-		}
-		defer f.Close()
-		decompFile := utils.Decompress(f)
-		if f, isText, err := text.IsTextReader(decompFile); err != nil {
-			return overlapResult{}, errors.Join(fmt.Errorf("detect text from file %s in change %s", baseFile.Name, base.name), err)
-		} else if isText {
-			txt, err := text.ReadFrom(f)
-			if err != nil {
-				return overlapResult{}, errors.Join(fmt.Errorf("read text from file %s in change %s", baseFile.Name, base.name), err)
-			}
-			textFileChanges[baseFile.Name] = &textFileChange{
-				base:           txt,
-				baseExists:     true,
-				executable:     baseFile.Executable,
-				baseExecutable: baseFile.Executable,
-			}
-		} else {
-			// binary
-			binaryFileChanges[baseFile.Name] = &binaryFileChange{
-				baseHash:       baseFile.ContentHash,
-				baseExists:     true,
-				executable:     baseFile.Executable,
-				baseExecutable: baseFile.Executable,
+		versions := make([]sideFile, len(sides))
+		for i, side := range sides {
+			versions[i].changeName = side.name
+			if f, ok := sideTrees[i][name]; ok {
+				versions[i].file = &f
 			}
 		}
-	}
 
-	for _, parent := range parents {
-		parentFiles, err := q.ListChangeFiles(ctx, parent.changeID)
+		files, err := mergeFile(repo, name, baseFile, versions)
 		if err != nil {
-			return overlapResult{}, errors.Join(fmt.Errorf("get parent files for change %s", parent.name), err)
-		}
-		for _, parentFile := range parentFiles {
-			// look if base file is binary
-			if change, ok := binaryFileChanges[parentFile.Name]; ok {
-				change.otherHashes = append(change.otherHashes, binaryfileOtherChange{
-					parentFile.ContentHash,
-					parent.name,
-				})
-				if change.baseExecutable != parentFile.Executable {
-					change.executable = parentFile.Executable
-				}
-				continue
-			}
-			// look if base file is text
-			if change, ok := textFileChanges[parentFile.Name]; ok {
-				f, err := repo.GetFileContent(parentFile.ContentHash)
-				if err != nil {
-					return overlapResult{}, errors.Join(fmt.Errorf("get file content %s in change %s", parentFile.Name, parent.name), err)
-				}
-				defer f.Close()
-				decompFile := utils.Decompress(f)
-				txt, err := text.ReadFrom(decompFile)
-				if err != nil {
-					return overlapResult{}, errors.Join(fmt.Errorf("read text from file %s in change %s", parentFile.Name, parent.name), err)
-				}
-				change.others = append(change.others, textFileOtherChange{
-					textContent: txt,
-					changeName:  parent.name,
-				})
-				if change.baseExecutable != parentFile.Executable {
-					change.executable = parentFile.Executable
-				}
-				continue
-			}
-			// no base file found, add as new file
-			fi, err := repo.GetFileInfo(parentFile.ContentHash)
-			if err != nil {
-				return overlapResult{}, errors.Join(fmt.Errorf("get file info %s in change %s", parentFile.Name, parent.name), err)
-			}
-			if fi.Size() > 1024*1024 {
-				// > 1MB, treat as binary
-				binaryFileChanges[parentFile.Name] = &binaryFileChange{
-					otherHashes: []binaryfileOtherChange{{
-						parentFile.ContentHash,
-						parent.name,
-					}},
-					executable: parentFile.Executable,
-					baseExists: false,
-				}
-				continue
-			}
-			f, err := repo.GetFileContent(parentFile.ContentHash)
-			if err != nil {
-				return overlapResult{}, errors.Join(fmt.Errorf("get file content %s in change %s", parentFile.Name, parent.name), err)
-			}
-			defer f.Close()
-			if f, isText, err := text.IsTextReader(f); err != nil {
-				return overlapResult{}, errors.Join(fmt.Errorf("detect text from file %s in change %s", parentFile.Name, parent.name), err)
-			} else if isText {
-				txt, err := text.ReadFrom(f)
-				if err != nil {
-					return overlapResult{}, errors.Join(fmt.Errorf("read text from file %s in change %s", parentFile.Name, parent.name), err)
-				}
-				textFileChanges[parentFile.Name] = &textFileChange{
-					others:     []textFileOtherChange{{txt, parent.name}},
-					base:       nil,
-					baseExists: false,
-					executable: parentFile.Executable,
-				}
-			} else {
-				binaryFileChanges[parentFile.Name] = &binaryFileChange{
-					otherHashes: []binaryfileOtherChange{{
-						parentFile.ContentHash,
-						parent.name,
-					}},
-					baseExists: false,
-					executable: parentFile.Executable,
-				}
-			}
+			return nil, errors.Join(fmt.Errorf("merge file %s", name), err)
 		}
+		merged = append(merged, files...)
 	}
-
-	return overlapResult{
-		textFileChanges:   textFileChanges,
-		binaryFileChanges: binaryFileChanges,
-	}, nil
+	return merged, nil
 }
 
-type (
-	joinOverlapResult interface {
-		IsText() bool
-		FileName() string
-		TextContent() *text.Text
-		ContentHash() []byte
-		Conflict() bool
-		Executable() bool
+// mergeFile merges the versions of one file, the file of base is nil if the base doesn't have it.
+// Every side either keeps the file unchanged, modifies it (adding counts as modifying) or deletes it:
+//
+//   - nothing modified: the base is kept, unless a side deleted it
+//   - equal modifications: the modification is taken, equal additions are no conflict
+//   - different modifications of a text file: three-way merge, a missing base is an empty file
+//   - different modifications of a binary file: every side keeps its version as <file>.binconflict_<change>
+//   - modified and deleted: a modify/delete conflict, the merged modifications are kept in conflict markers
+//     against the deletion, so the file is not silently brought back
+//
+// The executable bit is merged on its own, a side that flipped it wins.
+// Files that are taken as they are keep their conflict. Conflicts that are merged again take part as their markers.
+func mergeFile(repo repos.Repo, name string, base sideFile, versions []sideFile) ([]mergedFile, error) {
+	modified, deleted := classifyVersions(base.file, versions)
+
+	if len(modified) == 0 {
+		if base.file == nil || len(deleted) > 0 {
+			return nil, nil
+		}
+		return []mergedFile{{changeFile: *base.file}}, nil
+	}
+
+	executable := mergeExecutable(base.file, modified)
+	equal := !slices.ContainsFunc(modified[1:], func(v sideFile) bool {
+		return !sameContent(*v.file, *modified[0].file)
+	})
+	if equal && len(deleted) == 0 {
+		f := *modified[0].file
+		if f.ContentHash == nil && f.Executable != executable {
+			// the executable bit of a rendered conflict is part of the conflict
+			conflict := *f.conflict
+			conflict.id = 0
+			conflict.executable = executable
+			f.conflict = &conflict
+		}
+		f.Executable = executable
+		return []mergedFile{{changeFile: f}}, nil
 	}
 
-	joinOverlapTextResult struct {
-		fileName   string
-		content    *text.Text
-		conflict   bool
-		executable bool
+	kind := contentConflict
+	switch {
+	case len(deleted) > 0:
+		kind = modifyDeleteConflict
+	case base.file == nil:
+		kind = addAddConflict
 	}
 
-	joinOverlapBinaryResult struct {
-		fileName   string
-		hash       []byte
-		conflict   bool
-		executable bool
+	base, versions, rendered, err := renderVersions(repo, base, versions)
+	if err != nil {
+		return nil, err
+	}
+	if modified, deleted = classifyVersions(base.file, versions); len(modified) == 0 {
+		// the conflicts have the content of the base
+		if len(deleted) > 0 {
+			return nil, nil
+		}
+		return []mergedFile{{changeFile: *base.file}}, nil
+	}
+	baseText, others, err := readVersionTexts(repo, base.file, modified)
+	if err != nil {
+		return nil, err
 	}
-)
-
-func (r joinOverlapTextResult) IsText() bool {
-	return true
-}
-
-func (r joinOverlapTextResult) FileName() string {
-	return r.fileName
-}
-
-func (r joinOverlapTextResult) TextContent() *text.Text {
-	return r.content
-}
-
-func (r joinOverlapTextResult) ContentHash() []byte {
-	return utils.HashReader(r.content.Reader())
-}
 
-func (r joinOverlapTextResult) Conflict() bool {
-	return r.conflict
-}
+	if others == nil {
+		// binary contents can't be merged, every side that has the file keeps its version next to it
+		conflict := &fileConflict{kind: kind, base: base, sides: versions}
+		var merged []mergedFile
+		for _, v := range versions {
+			if v.file == nil {
+				continue
+			}
+			merged = append(merged, mergedFile{changeFile: changeFile{
+				ListChangeFilesRow: db.ListChangeFilesRow{
+					Name:        name + ".binconflict_" + v.changeName,
+					Executable:  v.file.Executable,
+					ContentHash: v.file.ContentHash,
+				},
+				conflict: conflict,
+			}})
+		}
+		if len(merged) > 0 {
+			merged[0].rendered = rendered
+		}
+		return merged, nil
+	}
 
-func (r joinOverlapTextResult) Executable() bool {
-	return r.executable
+	content, conflict, err := mergeVersionTexts(baseText, others, deleted)
+	if err != nil {
+		return nil, err
+	}
+	if !conflict {
+		return []mergedFile{{
+			changeFile: changeFile{ListChangeFilesRow: db.ListChangeFilesRow{
+				Name:        name,
+				Executable:  executable,
+				ContentHash: utils.HashReader(content.Reader()),
+			}},
+			content: content,
+		}}, nil
+	}
+	// the markers are rendered from the versions whenever the file is read
+	return []mergedFile{{
+		changeFile: changeFile{
+			ListChangeFilesRow: db.ListChangeFilesRow{Name: name, Executable: executable},
+			conflict:           &fileConflict{kind: kind, executable: executable, base: base, sides: versions},
+		},
+		rendered: rendered,
+	}}, nil
 }
 
-func (r joinOverlapBinaryResult) IsText() bool {
-	return false
+// renderConflict renders a text conflict with the markers the merge of its versions produces.
+func renderConflict(repo repos.Repo, conflict *fileConflict) (*text.Text, error) {
+	if len(conflict.sides) == 0 {
+		return nil, errors.New("conflict without sides")
+	}
+	modified, deleted := classifyVersions(conflict.base.file, conflict.sides)
+	baseText, others, err := readVersionTexts(repo, conflict.base.file, modified)
+	if err != nil {
+		return nil, err
+	}
+	if others == nil {
+		return nil, errors.New("binary conflicts can't be rendered")
+	}
+	content, _, err := mergeVersionTexts(baseText, others, deleted)
+	return content, err
 }
 
-func (r joinOverlapBinaryResult) FileName() string {
-	return r.fileName
+// classifyVersions sorts out the versions that modified the file relative to the base and the ones that deleted it.
+func classifyVersions(baseFile *changeFile, versions []sideFile) (modified []sideFile, deleted []sideFile) {
+	for _, v := range versions {
+		switch {
+		case v.file == nil && baseFile == nil:
+		case v.file == nil:
+			deleted = append(deleted, v)
+		case baseFile != nil && sameFile(*v.file, *baseFile):
+		default:
+			modified = append(modified, v)
+		}
+	}
+	return modified, deleted
 }
 
-func (r joinOverlapBinaryResult) TextContent() *text.Text {
-	return nil
-}
+// renderVersions replaces the conflicted versions of a file by plain files with the content of their markers,
+// so they can be merged and stored as the versions of a new conflict. The rendered texts are returned to be stored.
+func renderVersions(repo repos.Repo, base sideFile, versions []sideFile) (sideFile, []sideFile, []*text.Text, error) {
+	var rendered []*text.Text
+	render := func(v sideFile) (sideFile, error) {
+		if v.file == nil || v.file.conflict == nil {
+			return v, nil
+		}
+		f := *v.file
+		f.conflict = nil
+		if f.ContentHash == nil {
+			txt, err := renderConflict(repo, v.file.conflict)
+			if err != nil {
+				return v, errors.Join(fmt.Errorf("render conflict of change %s", v.changeName), err)
+			}
+			f.ContentHash = utils.HashReader(txt.Reader())
+			rendered = append(rendered, txt)
+		}
+		return sideFile{changeName: v.changeName, file: &f}, nil
+	}
 
-func (r joinOverlapBinaryResult) ContentHash() []byte {
-	return r.hash
+	base, err := render(base)
+	if err != nil {
+		return sideFile{}, nil, nil, err
+	}
+	renderedVersions := make([]sideFile, len(versions))
+	for i, v := range versions {
+		if renderedVersions[i], err = render(v); err != nil {
+			return sideFile{}, nil, nil, err
+		}
+	}
+	return base, renderedVersions, rendered, nil
 }
 
-func (r joinOverlapBinaryResult) Conflict() bool {
-	return r.conflict
+// readVersionTexts reads the base and the modified versions of a file as text.
+// others is nil if any of them is binary.
+func readVersionTexts(repo repos.Repo, baseFile *changeFile, modified []sideFile) (*text.Text, []textFileOtherChange, error) {
+	var baseText *text.Text
+	if baseFile != nil {
+		var err error
+		if baseText, err = readDiffText(repo, baseFile.ContentHash); err != nil {
+			return nil, nil, errors.Join(errors.New("read base"), err)
+		}
+		if baseText == nil {
+			return nil, nil, nil
+		}
+	}
+	others := make([]textFileOtherChange, 0, len(modified))
+	for _, v := range modified {
+		txt, err := readDiffText(repo, v.file.ContentHash)
+		if err != nil {
+			return nil, nil, errors.Join(fmt.Errorf("read version of change %s", v.changeName), err)
+		}
+		if txt == nil {
+			return nil, nil, nil
+		}
+		others = append(others, textFileOtherChange{textContent: txt, changeName: v.changeName})
+	}
+	return baseText, others, nil
 }
 
-func (r joinOverlapBinaryResult) Executable() bool {
-	return r.executable
+// mergeVersionTexts merges the modified versions of a text file. If other versions deleted the file,
+// the merged modifications are wrapped in conflict markers against the deletion.
+func mergeVersionTexts(base *text.Text, others []textFileOtherChange, deleted []sideFile) (*text.Text, bool, error) {
+	content, conflict, err := mergeTextChanges(base, others)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(deleted) > 0 {
+		return modifyDeleteMarkers(content, others, deleted), true, nil
+	}
+	return content, conflict, nil
 }
 
-func joinOverlappingChanges(overlap overlapResult) iter.Seq[joinOverlapResult] {
-	return func(yield func(joinOverlapResult) bool) {
-		for textChange := range joinOverlappingTextChanges(overlap.textFileChanges) {
-			if !yield(textChange) {
-				return
-			}
-		}
-		for binaryChange := range joinOverlappingBinaryChanges(overlap.binaryFileChanges) {
-			if !yield(binaryChange) {
-				return
-			}
+// mergeExecutable merges the executable bit of the modified versions.
+// Files added by several sides are executable if any side added it as executable.
+func mergeExecutable(baseFile *changeFile, modified []sideFile) bool {
+	if baseFile == nil {
+		return slices.ContainsFunc(modified, func(v sideFile) bool { return v.file.Executable })
+	}
+	for _, v := range modified {
+		if v.file.Executable != baseFile.Executable {
+			return v.file.Executable
 		}
 	}
+	return baseFile.Executable
 }
 
-func joinOverlappingTextChanges(textFileChanges map[string]*textFileChange) iter.Seq[joinOverlapTextResult] {
-	return func(yield func(joinOverlapTextResult) bool) {
-		for fileName, textChange := range textFileChanges {
-			if textChange.baseExists && len(textChange.others) == 0 {
-				if !yield(joinOverlapTextResult{
-					fileName,
-					textChange.base,
-					len(textChange.others) > 1,
-					textChange.baseExecutable,
-				}) {
-					return
-				}
-				continue
-			}
-			if len(textChange.others) == 1 {
-				if !yield(joinOverlapTextResult{
-					fileName,
-					textChange.others[0].textContent,
-					false,
-					textChange.executable,
-				}) {
-					return
-				}
-			} else {
-				// more than one change
-				if mergedContent, conflict, err := mergeTextChanges(textChange.base, textChange.others); err != nil {
-					fmt.Fprintf(os.Stderr, "error merging text changes: %v\n", err)
-					continue
-				} else {
-					if !yield(joinOverlapTextResult{
-						fileName,
-						mergedContent,
-						conflict,
-						textChange.executable,
-					}) {
-						return
-					}
-				}
-			}
-		}
+// modifyDeleteMarkers wraps the merged modifications in conflict markers, the other side of the conflict is empty
+// and names the changes that deleted the file.
+func modifyDeleteMarkers(content *text.Text, modified []textFileOtherChange, deleted []sideFile) *text.Text {
+	modifiedBy := make([]string, len(modified))
+	for i, m := range modified {
+		modifiedBy[i] = m.changeName
+	}
+	deletedBy := make([]string, len(deleted))
+	for i, d := range deleted {
+		deletedBy[i] = d.changeName
 	}
-}
 
-func joinOverlappingBinaryChanges(binaryFileChanges map[string]*binaryFileChange) iter.Seq[joinOverlapBinaryResult] {
-	return func(yield func(joinOverlapBinaryResult) bool) {
-		for fileName, binaryChange := range binaryFileChanges {
-			if binaryChange.baseExists && len(binaryChange.otherHashes) != 1 {
-				if !yield(joinOverlapBinaryResult{
-					fileName,
-					binaryChange.baseHash,
-					false,
-					binaryChange.baseExecutable,
-				}) {
-					return
-				}
-			}
-			if len(binaryChange.otherHashes) == 1 {
-				otherChange := binaryChange.otherHashes[0]
-				if !yield(joinOverlapBinaryResult{
-					fileName,
-					otherChange.contentHash,
-					false,
-					binaryChange.executable,
-				}) {
-					return
-				}
-			} else {
-				for _, otherChange := range binaryChange.otherHashes {
-					if !yield(joinOverlapBinaryResult{
-						fileName + ".binconflict_" + otherChange.changeName,
-						otherChange.contentHash,
-						true,
-						binaryChange.executable,
-					}) {
-						return
-					}
-				}
-			}
-		}
+	var sb strings.Builder
+	sb.WriteString("<<<<<<<<< " + strings.Join(modifiedBy, ", ") + "\n")
+	sb.WriteString(content.String())
+	if content.String() != "" && !strings.HasSuffix(content.String(), "\n") {
+		sb.WriteString("\n")
 	}
+	sb.WriteString("=========\n")
+	sb.WriteString(">>>>>>>>> " + strings.Join(deletedBy, ", ") + " (deleted)\n")
+	return text.NewTextWithEncoding(sb.String(), content.Encoding())
 }
 
+// mergeTextChanges merges any number of changed versions of a text file.
+// Versions equal to the base are skipped, equal versions are merged into one. The rest is folded into the
+// result one by one with a three-way merge against the base.
+// Conflict markers name every change that contributed to their side.
 func mergeTextChanges(base *text.Text, others []textFileOtherChange) (*text.Text, bool, error) {
 	if len(others) <= 1 {
 		if len(others) == 1 {
@@ -490,39 +443,63 @@ func mergeTextChanges(base *text.Text, others []textFileOtherChange) (*text.Text
 		return text.NewText(""), false, nil
 	}
 
-	// Check if all changes are identical
-	firstContent := others[0].textContent.String()
-	allSame := true
-	for i := 1; i < len(others); i++ {
-		if others[i].textContent.String() != firstContent {
-			allSame = false
-			break
+	var sides []textFileOtherChange
+	for _, other := range others {
+		if base != nil && other.textContent.String() == base.String() {
+			continue
 		}
+		if i := slices.IndexFunc(sides, func(side textFileOtherChange) bool {
+			return side.textContent.String() == other.textContent.String()
+		}); i >= 0 {
+			sides[i].changeName += ", " + other.changeName
+			continue
+		}
+		sides = append(sides, other)
 	}
-
-	if allSame {
-		return others[0].textContent, false, nil
+	switch len(sides) {
+	case 0:
+		return base, false, nil
+	case 1:
+		return sides[0].textContent, false, nil
 	}
 
-	diff, err := diff3.Merge(
-		others[0].textContent.Utf8Reader(),
-		base.Utf8Reader(),
-		others[1].textContent.Utf8Reader(),
-		true,
-		others[0].changeName,
-		others[1].changeName,
-	)
-	if err != nil {
-		return nil, false, errors.Join(fmt.Errorf("merge text changes"), err)
-	}
-	diffBytes, err := io.ReadAll(diff.Result)
-	if err != nil {
-		return nil, false, errors.Join(fmt.Errorf("read diff bytes"), err)
+	encoding := sides[0].textContent.Encoding()
+	if base != nil {
+		encoding = base.Encoding()
+	} else {
+		// both sides added the file
+		base = text.NewText("")
+	}
+
+	merged := sides[0]
+	conflict := false
+	for _, side := range sides[1:] {
+		diff, err := diff3.Merge(
+			merged.textContent.Utf8Reader(),
+			base.Utf8Reader(),
+			side.textContent.Utf8Reader(),
+			true,
+			merged.changeName,
+			side.changeName,
+		)
+		if err != nil {
+			return nil, false, errors.Join(fmt.Errorf("merge text changes"), err)
+		}
+		diffBytes, err := io.ReadAll(diff.Result)
+		if err != nil {
+			return nil, false, errors.Join(fmt.Errorf("read diff bytes"), err)
+		}
+		conflict = conflict || diff.Conflicts
+		merged = textFileOtherChange{
+			textContent: text.NewTextWithEncoding(string(diffBytes), encoding),
+			changeName:  merged.changeName + ", " + side.changeName,
+		}
 	}
-	diffStr := string(diffBytes)
-	return text.NewTextWithEncoding(diffStr, base.Encoding()), diff.Conflicts, nil
+	return merged.textContent, conflict, nil
 }
 
+// isInConflict looks for conflict markers of any change.
+// Only used for conflicts that were stored before their sides were, see conflictUnresolved.
 func isInConflict(repo repos.Repo, name string, contentHash []byte) (bool, error) {
 	if strings.Contains(name, ".binconflict_") {
 		return true, nil
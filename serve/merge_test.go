@@ -1,8 +1,13 @@
 package serve
 
 import (
+	"bytes"
 	"fmt"
+	"github.com/tsukinoko-kun/pogo/blobstore"
+	"github.com/tsukinoko-kun/pogo/db"
+	"github.com/tsukinoko-kun/pogo/repos"
 	"github.com/tsukinoko-kun/pogo/text"
+	"github.com/tsukinoko-kun/pogo/utils"
 	"io"
 	"os"
 	"strings"
@@ -56,6 +61,48 @@ func TestMergeTextChangesConflict(t *testing.T) {
 	}
 }
 
+func TestMergeTextChangesOctopus(t *testing.T) {
+	base := text.NewText("a\nb\nc\nd\ne\n")
+	others := []textFileOtherChange{
+		{textContent: text.NewText("A\nb\nc\nd\ne\n"), changeName: "A"},
+		{textContent: text.NewText("a\nb\nc\nd\ne\n"), changeName: "unchanged"},
+		{textContent: text.NewText("a\nb\nC\nd\ne\n"), changeName: "C"},
+		{textContent: text.NewText("a\nb\nc\nd\nE\n"), changeName: "E"},
+	}
+	res, hasConflicts, err := mergeTextChanges(base, others)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hasConflicts {
+		t.Fatalf("merge should not have conflicts, got:\n%s", res.String())
+	}
+	// the diff3 library drops the trailing newline
+	if strings.TrimSuffix(res.String(), "\n") != "A\nb\nC\nd\nE" {
+		t.Fatalf("merge result should contain every change, got %q", res.String())
+	}
+}
+
+func TestMergeTextChangesOctopusConflict(t *testing.T) {
+	base := text.NewText("foo\nbar\nbaz\n")
+	others := []textFileOtherChange{
+		{textContent: text.NewText("foo\nA\nbaz\n"), changeName: "A"},
+		{textContent: text.NewText("foo\nB\nbaz\n"), changeName: "B"},
+		{textContent: text.NewText("foo\nC\nbaz\n"), changeName: "C"},
+	}
+	res, hasConflicts, err := mergeTextChanges(base, others)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasConflicts {
+		t.Fatal("merge should have conflicts")
+	}
+	for _, name := range []string{"A", "B", "C"} {
+		if !strings.Contains(res.String(), name) {
+			t.Fatalf("conflict should name change %s, got:\n%s", name, res.String())
+		}
+	}
+}
+
 func TestDiff3Library(t *testing.T) {
 	b, err := os.ReadFile("merge.go")
 	if err != nil {
@@ -114,3 +161,390 @@ func TestDiff3Library(t *testing.T) {
 		t.Fatalf("diff should be:\n%q\n\n\ngot:\n%q", expectedDiff.String(), diff)
 	}
 }
+
+// mergeTestRepo stores file contents in a temporary blob store.
+type mergeTestRepo struct {
+	repo     repos.Repo
+	contents map[string]string
+}
+
+func newMergeTestRepo(t *testing.T) *mergeTestRepo {
+	previous := repos.BlobStore()
+	repos.SetBlobStore(blobstore.NewFileSystem(t.TempDir()))
+	t.Cleanup(func() { repos.SetBlobStore(previous) })
+	return &mergeTestRepo{contents: make(map[string]string)}
+}
+
+func (r *mergeTestRepo) file(t *testing.T, name string, content string, executable bool) changeFile {
+	hash := utils.HashReader(strings.NewReader(content))
+	if _, err := r.repo.SetFileContent(hash, strings.NewReader(content)); err != nil {
+		t.Fatal(err)
+	}
+	r.contents[string(hash)] = content
+	return changeFile{ListChangeFilesRow: db.ListChangeFilesRow{Name: name, Executable: executable, ContentHash: hash}}
+}
+
+// store stores the contents a merge produced, like mergeFileTrees.
+func (r *mergeTestRepo) store(t *testing.T, f mergedFile) {
+	for _, txt := range append(f.rendered, f.content) {
+		if txt != nil {
+			r.file(t, f.Name, txt.String(), false)
+		}
+	}
+}
+
+// content returns the content of a merged file, conflicts without content of their own are rendered.
+func (r *mergeTestRepo) content(t *testing.T, f mergedFile) string {
+	if f.content != nil {
+		return f.content.String()
+	}
+	if f.ContentHash == nil {
+		r.store(t, f)
+		txt, err := renderConflict(r.repo, f.conflict)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return txt.String()
+	}
+	return r.contents[string(f.ContentHash)]
+}
+
+func TestMergeFiles(t *testing.T) {
+	r := newMergeTestRepo(t)
+	const png = "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01"
+
+	type want struct {
+		name       string
+		content    string
+		executable bool
+		conflict   conflictKind
+	}
+	tests := []struct {
+		name  string
+		base  []changeFile
+		sides [][]changeFile
+		// text with content or add/add conflicts must contain the wanted content, other contents must be equal
+		want []want
+	}{
+		{
+			name:  "one side modified",
+			base:  []changeFile{r.file(t, "a", "a\n", false)},
+			sides: [][]changeFile{{r.file(t, "a", "b\n", false)}, {r.file(t, "a", "a\n", false)}},
+			want:  []want{{"a", "b\n", false, noConflict}},
+		},
+		{
+			name:  "modify/delete",
+			base:  []changeFile{r.file(t, "a", "a\n", false)},
+			sides: [][]changeFile{{r.file(t, "a", "b\n", false)}, nil},
+			want:  []want{{"a", "<<<<<<<<< A\nb\n=========\n>>>>>>>>> B (deleted)\n", false, modifyDeleteConflict}},
+		},
+		{
+			name:  "delete/unchanged",
+			base:  []changeFile{r.file(t, "a", "a\n", false)},
+			sides: [][]changeFile{nil, {r.file(t, "a", "a\n", false)}},
+			want:  nil,
+		},
+		{
+			name:  "delete/delete",
+			base:  []changeFile{r.file(t, "a", "a\n", false), r.file(t, "b", "b\n", false)},
+			sides: [][]changeFile{{r.file(t, "b", "b\n", false)}, {r.file(t, "b", "b\n", false)}},
+			want:  []want{{"b", "b\n", false, noConflict}},
+		},
+		{
+			name:  "add/add same content",
+			sides: [][]changeFile{{r.file(t, "a", "new\n", false)}, {r.file(t, "a", "new\n", false)}},
+			want:  []want{{"a", "new\n", false, noConflict}},
+		},
+		{
+			name:  "add/add different content",
+			sides: [][]changeFile{{r.file(t, "a", "one\n", false)}, {r.file(t, "a", "two\n", false)}},
+			want:  []want{{"a", ">>>>>>>>> B", false, addAddConflict}},
+		},
+		{
+			name:  "add on one side",
+			base:  []changeFile{r.file(t, "a", "a\n", false)},
+			sides: [][]changeFile{{r.file(t, "a", "a\n", false), r.file(t, "b", "b\n", true)}, {r.file(t, "a", "a\n", false)}},
+			want:  []want{{"a", "a\n", false, noConflict}, {"b", "b\n", true, noConflict}},
+		},
+		{
+			name:  "exec bit only and content",
+			base:  []changeFile{r.file(t, "a", "a\n", false)},
+			sides: [][]changeFile{{r.file(t, "a", "a\n", true)}, {r.file(t, "a", "b\n", false)}},
+			want:  []want{{"a", "b\n", true, noConflict}},
+		},
+		{
+			name:  "exec bit removed on both sides",
+			base:  []changeFile{r.file(t, "a", "a\n", true)},
+			sides: [][]changeFile{{r.file(t, "a", "a\n", false)}, {r.file(t, "a", "a\n", false)}},
+			want:  []want{{"a", "a\n", false, noConflict}},
+		},
+		{
+			name:  "exec bit only/delete",
+			base:  []changeFile{r.file(t, "a", "a\n", false)},
+			sides: [][]changeFile{{r.file(t, "a", "a\n", true)}, nil},
+			want:  []want{{"a", "<<<<<<<<< A\na\n=========\n>>>>>>>>> B (deleted)\n", true, modifyDeleteConflict}},
+		},
+		{
+			name:  "text conflict",
+			base:  []changeFile{r.file(t, "a", "a\n", false)},
+			sides: [][]changeFile{{r.file(t, "a", "b\n", false)}, {r.file(t, "a", "c\n", false)}},
+			want:  []want{{"a", "<<<<<<<<< A", false, contentConflict}},
+		},
+		{
+			name:  "binary modified on one side",
+			base:  []changeFile{r.file(t, "img", png, false)},
+			sides: [][]changeFile{{r.file(t, "img", png+"A", false)}, {r.file(t, "img", png, false)}},
+			want:  []want{{"img", png + "A", false, noConflict}},
+		},
+		{
+			name:  "binary conflict",
+			base:  []changeFile{r.file(t, "img", png, false)},
+			sides: [][]changeFile{{r.file(t, "img", png+"A", false)}, {r.file(t, "img", png+"B", false)}},
+			want: []want{
+				{"img.binconflict_A", png + "A", false, contentConflict},
+				{"img.binconflict_B", png + "B", false, contentConflict},
+			},
+		},
+		{
+			name:  "binary modify/delete",
+			base:  []changeFile{r.file(t, "img", png, false)},
+			sides: [][]changeFile{nil, {r.file(t, "img", png+"B", false)}},
+			want:  []want{{"img.binconflict_B", png + "B", false, modifyDeleteConflict}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sides := make([]overlapChange, len(tt.sides))
+			for i, files := range tt.sides {
+				sides[i] = overlapChange{name: string(rune('A' + i)), files: files}
+			}
+			got, err := mergeFiles(r.repo, overlapChange{name: "base", files: tt.base}, sides)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %d files, got %d: %+v", len(tt.want), len(got), got)
+			}
+			for i, w := range tt.want {
+				f := got[i]
+				kind := noConflict
+				if f.conflict != nil {
+					kind = f.conflict.kind
+				}
+				if f.Name != w.name || f.Executable != w.executable || kind != w.conflict {
+					t.Errorf("expected %s (executable %v, conflict %d), got %s (executable %v, conflict %d)",
+						w.name, w.executable, w.conflict, f.Name, f.Executable, kind)
+				}
+				content := r.content(t, f)
+				textConflict := w.conflict == contentConflict || w.conflict == addAddConflict
+				if textConflict && !strings.Contains(w.name, ".binconflict_") {
+					if !strings.Contains(content, w.content) {
+						t.Errorf("%s should contain %q, got %q", f.Name, w.content, content)
+					}
+				} else if content != w.content {
+					t.Errorf("%s should be %q, got %q", f.Name, w.content, content)
+				}
+				if f.content != nil && !bytes.Equal(f.ContentHash, utils.HashReader(f.content.Reader())) {
+					t.Errorf("%s has a wrong content hash", f.Name)
+				}
+				if f.conflict != nil && (f.conflict.base.changeName != "base" || len(f.conflict.sides) != len(tt.sides)) {
+					t.Errorf("%s should keep the base and the %d sides of its conflict, got %s and %d sides",
+						f.Name, len(tt.sides), f.conflict.base.changeName, len(f.conflict.sides))
+				}
+				textFile := w.conflict != noConflict && !strings.Contains(w.name, ".binconflict_")
+				if textFile && (f.ContentHash != nil || f.content != nil) {
+					t.Errorf("%s should be rendered from its conflict instead of storing the markers", f.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestHasConflictRegion(t *testing.T) {
+	sides := conflictSideLines{
+		"kxwp": lineSet("a\nb\nd\n"),
+		"zmqt": lineSet("a\nc\nd\n"),
+		"abcd": lineSet("c\n"),
+	}
+	tests := []struct {
+		name    string
+		content string
+		want    bool
+	}{
+		{
+			name:    "unresolved",
+			content: "a\n<<<<<<<<< kxwp\nb\n=========\nc\n>>>>>>>>> zmqt\nd\n",
+			want:    true,
+		},
+		{
+			name:    "octopus labels",
+			content: "<<<<<<<<< kxwp, zmqt\nb\n=========\nc\n>>>>>>>>> abcd\n",
+			want:    true,
+		},
+		{
+			name:    "modify/delete",
+			content: "<<<<<<<<< kxwp\nb\n=========\n>>>>>>>>> zmqt (deleted)\n",
+			want:    true,
+		},
+		{
+			name:    "crlf",
+			content: "<<<<<<<<< kxwp\r\nb\r\n=========\r\nc\r\n>>>>>>>>> zmqt\r\n",
+			want:    true,
+		},
+		{
+			name:    "resolved",
+			content: "a\nb\nc\nd\n",
+			want:    false,
+		},
+		{
+			name:    "markers of other changes",
+			content: "example:\n<<<<<<<<< A\nb\n=========\nc\n>>>>>>>>> B\n",
+			want:    false,
+		},
+		{
+			name:    "one side left",
+			content: "<<<<<<<<< kxwp\nb\n=========\nc\n",
+			want:    false,
+		},
+		{
+			name:    "separator without start",
+			content: "b\n=========\nc\n>>>>>>>>> zmqt\n",
+			want:    false,
+		},
+		{
+			name:    "edited region",
+			content: "<<<<<<<<< kxwp\nb\n=========\nresolved\n>>>>>>>>> zmqt\n",
+			want:    false,
+		},
+		{
+			name:    "lines of the wrong side",
+			content: "<<<<<<<<< kxwp\nc\n=========\nb\n>>>>>>>>> zmqt\n",
+			want:    false,
+		},
+		{
+			name:    "deleted side with lines",
+			content: "<<<<<<<<< kxwp\nb\n=========\nc\n>>>>>>>>> zmqt, abcd (deleted)\n",
+			want:    true,
+		},
+		{
+			name:    "nested octopus region",
+			content: "<<<<<<<<< kxwp, zmqt\n<<<<<<<<< kxwp\nb\n=========\nc\n>>>>>>>>> zmqt\n=========\nc\n>>>>>>>>> abcd\n",
+			want:    true,
+		},
+		{
+			name:    "empty label",
+			content: "<<<<<<<<< \n=========\nc\n>>>>>>>>> zmqt\n",
+			want:    false,
+		},
+		{
+			name:    "label of only the deleted suffix",
+			content: "<<<<<<<<< kxwp\nb\n=========\n>>>>>>>>>  (deleted)\n",
+			want:    false,
+		},
+		{
+			name:    "empty name in the list",
+			content: "<<<<<<<<< kxwp, \nb\n=========\nc\n>>>>>>>>> zmqt\n",
+			want:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hasConflictRegion(tt.content, sides); got != tt.want {
+				t.Fatalf("hasConflictRegion(%q) should be %v, got %v", tt.content, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestHasConflictRegionOfMerge(t *testing.T) {
+	r := newMergeTestRepo(t)
+	base := overlapChange{name: "base", files: []changeFile{r.file(t, "a", "a\n", false)}}
+	sides := []overlapChange{
+		{name: "kxwp", files: []changeFile{r.file(t, "a", "b\n", false)}},
+		{name: "zmqt", files: []changeFile{r.file(t, "a", "c\n", false)}},
+	}
+	merged, err := mergeFiles(r.repo, base, sides)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(merged) != 1 || merged[0].conflict == nil || merged[0].conflict.kind != contentConflict {
+		t.Fatalf("expected one conflicted file, got %+v", merged)
+	}
+	content := r.content(t, merged[0])
+	if !hasConflictRegion(content, conflictSideLines{"kxwp": lineSet("b\n"), "zmqt": lineSet("c\n")}) {
+		t.Fatalf("the markers of the merge should be found in %q", content)
+	}
+	if hasConflictRegion(content, conflictSideLines{"kxwp": lineSet("b\n")}) {
+		t.Fatalf("markers of other sides should not be found in %q", content)
+	}
+	if hasConflictRegion(content, conflictSideLines{"kxwp": lineSet("b\n"), "zmqt": lineSet("d\n")}) {
+		t.Fatalf("lines that are not in the stored sides should not count as the region in %q", content)
+	}
+}
+
+func TestCarryConflict(t *testing.T) {
+	r := newMergeTestRepo(t)
+	base := overlapChange{name: "base", files: []changeFile{r.file(t, "a", "a\n", false)}}
+	sides := []overlapChange{
+		{name: "kxwp", files: []changeFile{r.file(t, "a", "b\n", false)}},
+		{name: "zmqt", files: []changeFile{r.file(t, "a", "c\n", false)}},
+	}
+	merged, err := mergeFiles(r.repo, base, sides)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(merged) != 1 || merged[0].ContentHash != nil {
+		t.Fatalf("expected one rendered conflict, got %+v", merged)
+	}
+	previous := merged[0].changeFile
+	rendered := r.content(t, merged[0])
+
+	tests := []struct {
+		name       string
+		content    string
+		executable bool
+		carry      bool
+		render     bool
+	}{
+		{name: "unchanged", content: rendered, carry: true, render: true},
+		{name: "executable", content: rendered, executable: true, carry: true},
+		{name: "edited", content: "x\n" + rendered, carry: true},
+		{name: "resolved", content: "b\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pushed := r.file(t, "a", tt.content, tt.executable)
+			carry, render, err := carryConflict(r.repo, previous, pushed.ContentHash, &tt.executable)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if carry != tt.carry || render != tt.render {
+				t.Fatalf("expected carry %v and render %v, got %v and %v", tt.carry, tt.render, carry, render)
+			}
+		})
+	}
+}
+
+func TestMergeKeepsConflict(t *testing.T) {
+	r := newMergeTestRepo(t)
+	conflicted := r.file(t, "a", "a\n", false)
+	conflicted.ContentHash = nil
+	conflicted.conflict = &fileConflict{id: 1, kind: contentConflict}
+	base := overlapChange{name: "base", files: []changeFile{r.file(t, "a", "a\n", false)}}
+	sides := []overlapChange{
+		{name: "kxwp", files: []changeFile{conflicted}},
+		{name: "zmqt", files: []changeFile{r.file(t, "a", "a\n", false), r.file(t, "b", "b\n", false)}},
+	}
+	merged, err := mergeFiles(r.repo, base, sides)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(merged) != 2 || merged[0].Name != "a" {
+		t.Fatalf("expected a and b, got %+v", merged)
+	}
+	if merged[0].ContentHash != nil || !sameConflict(merged[0].conflict, conflicted.conflict) {
+		t.Fatalf("a should keep its stored conflict without content, got %+v", merged[0])
+	}
+}
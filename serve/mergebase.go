@@ -0,0 +1,333 @@
+package serve
+
+import (
+	"cmp"
+	"container/heap"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/tsukinoko-kun/pogo/db"
+	"github.com/tsukinoko-kun/pogo/repos"
+)
+
+var errNoCommonAncestor = errors.New("no common ancestor found")
+
+// changeGraph is the part of the change graph the merge base search walks.
+// Depths are generation numbers, every change is deeper than all of its parents.
+type changeGraph interface {
+	depth(id int64) (int64, error)
+	parents(id int64) ([]int64, error)
+}
+
+// dbChangeGraph reads the change graph of a repository lazily and caches it.
+type dbChangeGraph struct {
+	ctx       context.Context
+	q         db.Querier
+	repo      repos.Repo
+	depths    map[int64]int64
+	parentIds map[int64][]int64
+}
+
+func newDBChangeGraph(ctx context.Context, q db.Querier, repo repos.Repo) *dbChangeGraph {
+	return &dbChangeGraph{
+		ctx:       ctx,
+		q:         q,
+		repo:      repo,
+		depths:    make(map[int64]int64),
+		parentIds: make(map[int64][]int64),
+	}
+}
+
+func (g *dbChangeGraph) depth(id int64) (int64, error) {
+	if depth, ok := g.depths[id]; ok {
+		return depth, nil
+	}
+	depth, err := g.q.GetChangeDepth(g.ctx, id, g.repo.ID())
+	if err != nil {
+		return 0, errors.Join(errors.New("get change depth"), err)
+	}
+	g.depths[id] = depth
+	return depth, nil
+}
+
+func (g *dbChangeGraph) parents(id int64) ([]int64, error) {
+	if parents, ok := g.parentIds[id]; ok {
+		return parents, nil
+	}
+	rows, err := g.q.ListParentDepths(g.ctx, id)
+	if err != nil {
+		return nil, errors.Join(errors.New("list parents"), err)
+	}
+	parents := make([]int64, len(rows))
+	for i, row := range rows {
+		parents[i] = row.ID
+		g.depths[row.ID] = row.Depth
+	}
+	g.parentIds[id] = parents
+	return parents, nil
+}
+
+// graphQueue pops the deepest change first, so a change is only visited after all of its queued descendants.
+type graphQueue struct {
+	ids    []int64
+	depths []int64
+}
+
+func (h *graphQueue) Len() int { return len(h.ids) }
+
+func (h *graphQueue) Less(i, j int) bool {
+	return cmp.Or(cmp.Compare(h.depths[j], h.depths[i]), cmp.Compare(h.ids[j], h.ids[i])) < 0
+}
+
+func (h *graphQueue) Swap(i, j int) {
+	h.ids[i], h.ids[j] = h.ids[j], h.ids[i]
+	h.depths[i], h.depths[j] = h.depths[j], h.depths[i]
+}
+
+func (h *graphQueue) Push(x any) {
+	e := x.([2]int64)
+	h.ids = append(h.ids, e[0])
+	h.depths = append(h.depths, e[1])
+}
+
+func (h *graphQueue) Pop() any {
+	n := len(h.ids) - 1
+	e := [2]int64{h.ids[n], h.depths[n]}
+	h.ids, h.depths = h.ids[:n], h.depths[:n]
+	return e
+}
+
+func (h *graphQueue) push(g changeGraph, id int64) error {
+	depth, err := g.depth(id)
+	if err != nil {
+		return err
+	}
+	heap.Push(h, [2]int64{id, depth})
+	return nil
+}
+
+func (h *graphQueue) pop() int64 {
+	return heap.Pop(h).([2]int64)[0]
+}
+
+// mergeBases returns the best common ancestors of all heads, deepest first.
+// A best common ancestor is no ancestor of another common ancestor, criss-cross histories have several.
+// The search is not limited, it stops once every remaining path is below a common ancestor.
+func mergeBases(g changeGraph, heads []int64) ([]int64, error) {
+	if len(heads) == 0 {
+		return nil, nil
+	}
+	// like git, the bases of all heads are the bases of the bases found so far and the next head
+	bases := []int64{heads[0]}
+	for _, head := range heads[1:] {
+		var next []int64
+		for _, base := range bases {
+			pairBases, err := pairMergeBases(g, base, head)
+			if err != nil {
+				return nil, err
+			}
+			for _, id := range pairBases {
+				if !slices.Contains(next, id) {
+					next = append(next, id)
+				}
+			}
+		}
+		var err error
+		if bases, err = removeRedundant(g, next); err != nil {
+			return nil, err
+		}
+		if len(bases) == 0 {
+			return nil, nil
+		}
+	}
+	return bases, nil
+}
+
+const (
+	flagFromA uint8 = 1 << iota
+	flagFromB
+	flagStale
+
+	flagFromBoth = flagFromA | flagFromB
+)
+
+// pairMergeBases paints the ancestors of a and b, deepest first.
+// A change reached from both sides is a common ancestor. Its ancestors are marked stale,
+// they are common ancestors too, but not the best ones.
+func pairMergeBases(g changeGraph, a, b int64) ([]int64, error) {
+	if a == b {
+		return []int64{a}, nil
+	}
+
+	flags := map[int64]uint8{a: flagFromA, b: flagFromB}
+	queue := new(graphQueue)
+	if err := queue.push(g, a); err != nil {
+		return nil, err
+	}
+	if err := queue.push(g, b); err != nil {
+		return nil, err
+	}
+
+	var result []int64
+	visited := make(map[int64]bool)
+	for queueHasNonStale(queue, flags) {
+		id := queue.pop()
+		if visited[id] {
+			continue
+		}
+		visited[id] = true
+
+		f := flags[id]
+		if f&flagFromBoth == flagFromBoth {
+			if f&flagStale == 0 {
+				result = append(result, id)
+			}
+			f |= flagStale
+		}
+
+		parents, err := g.parents(id)
+		if err != nil {
+			return nil, err
+		}
+		for _, parent := range parents {
+			if flags[parent]&f == f {
+				continue
+			}
+			flags[parent] |= f
+			if err := queue.push(g, parent); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return removeRedundant(g, result)
+}
+
+func queueHasNonStale(queue *graphQueue, flags map[int64]uint8) bool {
+	for _, id := range queue.ids {
+		if flags[id]&flagStale == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// removeRedundant drops every change that is an ancestor of another one and sorts the rest deepest first.
+func removeRedundant(g changeGraph, ids []int64) ([]int64, error) {
+	var result []int64
+	for _, id := range ids {
+		redundant := false
+		for _, other := range ids {
+			if other == id {
+				continue
+			}
+			ancestor, err := isGraphAncestor(g, id, other)
+			if err != nil {
+				return nil, err
+			}
+			if ancestor {
+				redundant = true
+				break
+			}
+		}
+		if !redundant {
+			result = append(result, id)
+		}
+	}
+
+	depths := make(map[int64]int64, len(result))
+	for _, id := range result {
+		depth, err := g.depth(id)
+		if err != nil {
+			return nil, err
+		}
+		depths[id] = depth
+	}
+	slices.SortFunc(result, func(a, b int64) int {
+		return cmp.Or(cmp.Compare(depths[b], depths[a]), cmp.Compare(a, b))
+	})
+	return result, nil
+}
+
+// isGraphAncestor reports whether ancestor is a strict ancestor of descendant.
+// Changes that aren't deeper than the ancestor can't lead to it and are skipped.
+func isGraphAncestor(g changeGraph, ancestor, descendant int64) (bool, error) {
+	if ancestor == descendant {
+		return false, nil
+	}
+	minDepth, err := g.depth(ancestor)
+	if err != nil {
+		return false, err
+	}
+
+	visited := map[int64]bool{descendant: true}
+	stack := []int64{descendant}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		parents, err := g.parents(id)
+		if err != nil {
+			return false, err
+		}
+		for _, parent := range parents {
+			if parent == ancestor {
+				return true, nil
+			}
+			if visited[parent] {
+				continue
+			}
+			visited[parent] = true
+			depth, err := g.depth(parent)
+			if err != nil {
+				return false, err
+			}
+			if depth > minDepth {
+				stack = append(stack, parent)
+			}
+		}
+	}
+	return false, nil
+}
+
+// mergeBaseTree returns the files to merge the heads against.
+// With several best common ancestors, like after criss-cross merges, they are merged into a virtual base first,
+// recursively against their own merge bases. Conflicts in the virtual base are kept as they are.
+func mergeBaseTree(ctx context.Context, q db.Querier, repo repos.Repo, g changeGraph, heads []int64) (overlapChange, error) {
+	bases, err := mergeBases(g, heads)
+	if err != nil {
+		return overlapChange{}, errors.Join(errors.New("find merge bases"), err)
+	}
+
+	sides := make([]overlapChange, len(bases))
+	names := make([]string, len(bases))
+	for i, base := range bases {
+		if names[i], err = q.GetChangeName(ctx, base, repo.ID()); err != nil {
+			return overlapChange{}, errors.Join(errors.New("get merge base name"), err)
+		}
+		files, err := listChangeFiles(ctx, q, base)
+		if err != nil {
+			return overlapChange{}, errors.Join(errors.New("get merge base files"), err)
+		}
+		sides[i] = overlapChange{name: names[i], files: files}
+	}
+
+	switch len(bases) {
+	case 0:
+		return overlapChange{}, errNoCommonAncestor
+	case 1:
+		return sides[0], nil
+	}
+
+	virtualBase, err := mergeBaseTree(ctx, q, repo, g, bases)
+	if err != nil {
+		return overlapChange{}, err
+	}
+	files, err := mergeFileTrees(ctx, q, repo, virtualBase, sides)
+	if err != nil {
+		return overlapChange{}, errors.Join(errors.New("merge virtual base"), err)
+	}
+	return overlapChange{name: "merge of " + strings.Join(names, ", "), files: files}, nil
+}
@@ -0,0 +1,160 @@
+package serve
+
+import (
+	"slices"
+	"testing"
+)
+
+// testGraph is a synthetic change graph, it maps every change to its parents.
+type testGraph map[int64][]int64
+
+func (g testGraph) depth(id int64) (int64, error) {
+	var depth int64
+	for _, parent := range g[id] {
+		parentDepth, _ := g.depth(parent)
+		depth = max(depth, parentDepth+1)
+	}
+	return depth, nil
+}
+
+func (g testGraph) parents(id int64) ([]int64, error) {
+	return g[id], nil
+}
+
+// chain appends n changes on top of from and returns the last one. Ids start at next.
+func (g testGraph) chain(from int64, next int64, n int) int64 {
+	for i := range n {
+		id := next + int64(i)
+		g[id] = []int64{from}
+		from = id
+	}
+	return from
+}
+
+func TestMergeBases(t *testing.T) {
+	tests := []struct {
+		name  string
+		graph testGraph
+		heads []int64
+		want  []int64
+	}{
+		{
+			name:  "same change",
+			graph: testGraph{1: nil, 2: {1}},
+			heads: []int64{2, 2},
+			want:  []int64{2},
+		},
+		{
+			name:  "ancestor",
+			graph: testGraph{1: nil, 2: {1}, 3: {2}},
+			heads: []int64{3, 2},
+			want:  []int64{2},
+		},
+		{
+			name:  "fork",
+			graph: testGraph{1: nil, 2: {1}, 3: {2}, 4: {2}},
+			heads: []int64{3, 4},
+			want:  []int64{2},
+		},
+		{
+			name: "uneven fork",
+			// 1 - 2 - 3 - 4 - 5
+			//      \
+			//       6
+			graph: testGraph{1: nil, 2: {1}, 3: {2}, 4: {3}, 5: {4}, 6: {2}},
+			heads: []int64{5, 6},
+			want:  []int64{2},
+		},
+		{
+			name: "merged before",
+			// 1 - 2 - 3 - 5 - 6
+			//      \     /
+			//       4 ---- 7
+			graph: testGraph{1: nil, 2: {1}, 3: {2}, 4: {2}, 5: {3, 4}, 6: {5}, 7: {4}},
+			heads: []int64{6, 7},
+			want:  []int64{4},
+		},
+		{
+			name: "criss-cross",
+			// 1 - 2 - 4 - 6
+			//   \   X
+			//     3 - 5 - 7
+			graph: testGraph{1: nil, 2: {1}, 3: {1}, 4: {2, 3}, 5: {3, 2}, 6: {4}, 7: {5}},
+			heads: []int64{6, 7},
+			want:  []int64{2, 3},
+		},
+		{
+			name:  "octopus",
+			graph: testGraph{1: nil, 2: {1}, 3: {2}, 4: {2}, 5: {2}},
+			heads: []int64{3, 4, 5},
+			want:  []int64{2},
+		},
+		{
+			name: "octopus with nested forks",
+			// 1 - 2 - 3 - 4
+			//      \   \
+			//       6   5
+			graph: testGraph{1: nil, 2: {1}, 3: {2}, 4: {3}, 5: {3}, 6: {2}},
+			heads: []int64{4, 5, 6},
+			want:  []int64{2},
+		},
+		{
+			name:  "unrelated",
+			graph: testGraph{1: nil, 2: {1}, 3: nil, 4: {3}},
+			heads: []int64{2, 4},
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mergeBases(tt.graph, tt.heads)
+			if err != nil {
+				t.Fatal(err)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("merge bases of %v should be %v, got %v", tt.heads, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMergeBasesDeepHistory(t *testing.T) {
+	g := testGraph{1: nil}
+	fork := g.chain(1, 2, 50)
+	a := g.chain(fork, 1000, 500)
+	b := g.chain(fork, 2000, 300)
+
+	got, err := mergeBases(g, []int64{a, b})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got, []int64{fork}) {
+		t.Fatalf("merge base should be %d, got %v", fork, got)
+	}
+}
+
+func TestMergeBasesRecursiveCrissCross(t *testing.T) {
+	// two criss-cross merges in a row, the merge bases of the heads have several merge bases themselves
+	g := testGraph{
+		1: nil,
+		2: {1}, 3: {1},
+		4: {2, 3}, 5: {3, 2},
+		6: {4, 5}, 7: {5, 4},
+		8: {6}, 9: {7},
+	}
+
+	heads := []int64{8, 9}
+	for _, want := range [][]int64{{4, 5}, {2, 3}, {1}} {
+		got, err := mergeBases(g, heads)
+		if err != nil {
+			t.Fatal(err)
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, want) {
+			t.Fatalf("merge bases of %v should be %v, got %v", heads, want, got)
+		}
+		heads = got
+	}
+}
@@ -0,0 +1,655 @@
+package serve
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/tsukinoko-kun/pogo/db"
+	"github.com/tsukinoko-kun/pogo/protos"
+	"github.com/tsukinoko-kun/pogo/repos"
+	"github.com/tsukinoko-kun/pogo/serve/serveerrors"
+	"github.com/tsukinoko-kun/pogo/signedhttp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// operation records what a mutating RPC changes, so it can be undone.
+// It remembers all bookmarks when it begins and snapshots every change before it is modified.
+// touch must be called before a change is modified, created after a new change was created.
+// Head moves by set_head are not operations, they only follow the working copies.
+type operation struct {
+	r         *signedhttp.Request
+	q         db.Querier
+	repo      repos.Repo
+	command   string
+	bookmarks map[string]int64
+	// before maps the touched changes to their snapshot before the operation, nil for created changes
+	before  map[int64]*int64
+	changes []int64
+}
+
+func beginOperation(r *signedhttp.Request, q db.Querier, repo repos.Repo, command string) (*operation, error) {
+	bookmarks, err := q.ListBookmarkTargets(r.Context(), repo.ID())
+	if err != nil {
+		return nil, errors.Join(errors.New("list bookmarks"), err)
+	}
+	op := &operation{
+		r:         r,
+		q:         q,
+		repo:      repo,
+		command:   command,
+		bookmarks: make(map[string]int64, len(bookmarks)),
+		before:    make(map[int64]*int64),
+	}
+	for _, b := range bookmarks {
+		op.bookmarks[b.Name] = b.ChangeID
+	}
+	return op, nil
+}
+
+// touch snapshots the change if the operation didn't touch it yet.
+func (op *operation) touch(changeId int64) error {
+	if _, ok := op.before[changeId]; ok {
+		return nil
+	}
+	snapshotId, err := createChangeSnapshot(op.r, op.q, changeId)
+	if err != nil {
+		return err
+	}
+	op.before[changeId] = &snapshotId
+	op.changes = append(op.changes, changeId)
+	return nil
+}
+
+func (op *operation) created(changeId int64) {
+	op.before[changeId] = nil
+	op.changes = append(op.changes, changeId)
+}
+
+// finish writes the operation to the log. Changes and bookmarks that ended up unchanged are left out,
+// nothing is written if the operation didn't change anything.
+func (op *operation) finish() error {
+	ctx := op.r.Context()
+
+	var changes []int64
+	for _, changeId := range op.changes {
+		if before := op.before[changeId]; before != nil {
+			unchanged, err := op.q.ChangeMatchesSnapshot(ctx, *before)
+			if err != nil {
+				return errors.Join(errors.New("compare change to snapshot"), err)
+			}
+			if unchanged {
+				if err := op.q.DeleteChangeSnapshot(ctx, *before); err != nil {
+					return errors.Join(errors.New("delete snapshot"), err)
+				}
+				continue
+			}
+		}
+		changes = append(changes, changeId)
+	}
+
+	type bookmarkMove struct {
+		name          string
+		before, after *int64
+	}
+	var bookmarkMoves []bookmarkMove
+	bookmarks, err := op.q.ListBookmarkTargets(ctx, op.repo.ID())
+	if err != nil {
+		return errors.Join(errors.New("list bookmarks"), err)
+	}
+	for _, b := range bookmarks {
+		if before, ok := op.bookmarks[b.Name]; !ok {
+			bookmarkMoves = append(bookmarkMoves, bookmarkMove{b.Name, nil, &b.ChangeID})
+		} else if before != b.ChangeID {
+			bookmarkMoves = append(bookmarkMoves, bookmarkMove{b.Name, &before, &b.ChangeID})
+		}
+	}
+	for name, before := range op.bookmarks {
+		if !slices.ContainsFunc(bookmarks, func(b db.ListBookmarkTargetsRow) bool { return b.Name == name }) {
+			bookmarkMoves = append(bookmarkMoves, bookmarkMove{name, &before, nil})
+		}
+	}
+
+	if len(changes) == 0 && len(bookmarkMoves) == 0 {
+		return nil
+	}
+
+	operationId, err := op.q.CreateOperation(ctx, op.repo.ID(), op.command, op.r.Username(), op.r.MachineID())
+	if err != nil {
+		return errors.Join(errors.New("create operation"), err)
+	}
+	for _, changeId := range changes {
+		after, err := createChangeSnapshot(op.r, op.q, changeId)
+		if err != nil {
+			return err
+		}
+		if err := op.q.AddOperationChange(ctx, operationId, changeId, op.before[changeId], after); err != nil {
+			return errors.Join(errors.New("add operation change"), err)
+		}
+	}
+	for _, move := range bookmarkMoves {
+		if err := op.q.AddOperationBookmark(ctx, operationId, move.name, move.before, move.after); err != nil {
+			return errors.Join(errors.New("add operation bookmark"), err)
+		}
+	}
+	return nil
+}
+
+func createChangeSnapshot(r *signedhttp.Request, q db.Querier, changeId int64) (int64, error) {
+	snapshotId, err := q.CreateChangeSnapshot(r.Context(), changeId)
+	if err != nil {
+		return 0, errors.Join(errors.New("create change snapshot"), err)
+	}
+	if err := q.AddChangeSnapshotFiles(r.Context(), snapshotId, changeId); err != nil {
+		return 0, errors.Join(errors.New("add change snapshot files"), err)
+	}
+	return snapshotId, nil
+}
+
+// changeAspects are the independently revertible parts of a change.
+type changeAspects uint8
+
+const (
+	// aspectDescription covers the description, author and device
+	aspectDescription changeAspects = 1 << iota
+	aspectParents
+	aspectFiles
+	aspectAbandoned
+
+	allAspects = aspectDescription | aspectParents | aspectFiles | aspectAbandoned
+)
+
+// changedAspects compares two snapshots of a change. Depths are left out, they follow the parents.
+func changedAspects(r *signedhttp.Request, q db.Querier, before *int64, after int64) (changeAspects, error) {
+	if before == nil {
+		return allAspects, nil
+	}
+	a, err := q.GetChangeSnapshot(r.Context(), *before)
+	if err != nil {
+		return 0, errors.Join(errors.New("get snapshot"), err)
+	}
+	b, err := q.GetChangeSnapshot(r.Context(), after)
+	if err != nil {
+		return 0, errors.Join(errors.New("get snapshot"), err)
+	}
+
+	var aspects changeAspects
+	if !equalStrings(a.Description, b.Description) || a.Author != b.Author || a.Device != b.Device {
+		aspects |= aspectDescription
+	}
+	if !slices.Equal(a.ParentIds, b.ParentIds) {
+		aspects |= aspectParents
+	}
+	if a.AbandonedAt.Valid != b.AbandonedAt.Valid || !a.AbandonedAt.Time.Equal(b.AbandonedAt.Time) {
+		aspects |= aspectAbandoned
+	}
+	if same, err := q.SnapshotsHaveSameFiles(r.Context(), *before, after); err != nil {
+		return 0, errors.Join(errors.New("compare snapshot files"), err)
+	} else if !same {
+		aspects |= aspectFiles
+	}
+	return aspects, nil
+}
+
+// restoreChangeSnapshot resets the given aspects of a change to a snapshot.
+func restoreChangeSnapshot(r *signedhttp.Request, q db.Querier, changeId int64, snapshotId int64, aspects changeAspects) error {
+	if aspects&aspectDescription != 0 {
+		if err := q.RestoreChangeSnapshotDescription(r.Context(), snapshotId); err != nil {
+			return errors.Join(errors.New("restore description"), err)
+		}
+	}
+	if aspects&aspectAbandoned != 0 {
+		if err := q.RestoreChangeSnapshotAbandoned(r.Context(), snapshotId); err != nil {
+			return errors.Join(errors.New("restore abandoned state"), err)
+		}
+	}
+	if aspects&aspectParents != 0 {
+		if err := q.ClearChangeParents(r.Context(), changeId); err != nil {
+			return errors.Join(errors.New("clear parents"), err)
+		}
+		if err := q.RestoreChangeSnapshotParents(r.Context(), snapshotId); err != nil {
+			return errors.Join(errors.New("restore parents"), err)
+		}
+	}
+	if aspects&aspectFiles != 0 {
+		if err := q.ClearChange(r.Context(), changeId); err != nil {
+			return errors.Join(errors.New("clear change"), err)
+		}
+		if err := q.RestoreChangeSnapshotFiles(r.Context(), snapshotId); err != nil {
+			return errors.Join(errors.New("restore files"), err)
+		}
+	}
+	return nil
+}
+
+// revertOperation resets everything the logged operation modified to its state before the operation.
+// Only the aspects of a change the operation modified are reset, so later edits of other aspects are kept.
+// Changes it created are abandoned. Head bookmarks are only moved back if they weren't moved since,
+// so working copies that moved on are kept.
+// If a bookmark rule forbids moving a bookmark back or the restored state of a change, a *bookmarkRuleError is returned.
+func revertOperation(op *operation, operationId int64) error {
+	ctx := op.r.Context()
+
+	changes, err := op.q.ListOperationChanges(ctx, operationId)
+	if err != nil {
+		return errors.Join(errors.New("list operation changes"), err)
+	}
+	bookmarks, err := op.q.ListOperationBookmarks(ctx, operationId)
+	if err != nil {
+		return errors.Join(errors.New("list operation bookmarks"), err)
+	}
+
+	var restored, created []int64
+	for _, c := range changes {
+		if c.BeforeSnapshotID == nil {
+			created = append(created, c.ChangeID)
+			continue
+		}
+		aspects, err := changedAspects(op.r, op.q, c.BeforeSnapshotID, c.AfterSnapshotID)
+		if err != nil {
+			return err
+		}
+		if aspects == 0 {
+			continue
+		}
+		if err := op.touch(c.ChangeID); err != nil {
+			return err
+		}
+		if err := restoreChangeSnapshot(op.r, op.q, c.ChangeID, *c.BeforeSnapshotID, aspects); err != nil {
+			return errors.Join(fmt.Errorf("restore change %s", c.Name), err)
+		}
+		restored = append(restored, c.ChangeID)
+	}
+
+	for _, b := range bookmarks {
+		var current *int64
+		if currentId, err := op.q.GetBookmark(ctx, op.repo.ID(), b.Name); err == nil {
+			current = &currentId
+		} else if !errors.Is(err, pgx.ErrNoRows) {
+			return errors.Join(errors.New("get bookmark"), err)
+		}
+
+		if isHeadBookmark(b.Name) && !equalIds(current, b.AfterChangeID) {
+			continue
+		}
+		if b.BeforeChangeID == nil {
+			if current == nil {
+				continue
+			}
+			if protected, err := isProtectedBookmark(ctx, op.q, op.repo, b.Name); err != nil {
+				return errors.Join(errors.New("get bookmark rule"), err)
+			} else if protected {
+				return &bookmarkRuleError{b.Name, "it can't be deleted"}
+			}
+			if _, err := op.q.DeleteBookmark(ctx, op.repo.ID(), b.Name); err != nil {
+				return errors.Join(errors.New("delete bookmark"), err)
+			}
+			continue
+		}
+		if err := checkBookmarkRule(ctx, op.q, op.repo, op.r.Username(), b.Name, current, *b.BeforeChangeID); err != nil {
+			return err
+		}
+		if err := op.q.SetBookmark(ctx, op.repo.ID(), b.Name, *b.BeforeChangeID); err != nil {
+			return errors.Join(errors.New("set bookmark"), err)
+		}
+	}
+
+	for _, changeId := range created {
+		if abandoned, err := op.q.IsChangeAbandoned(ctx, changeId); err != nil {
+			return errors.Join(errors.New("check if change is abandoned"), err)
+		} else if abandoned {
+			continue
+		}
+		if _, err := abandonChange(op.r, op.q, op.repo, op, changeId); err != nil {
+			return err
+		}
+	}
+
+	// changes created after the operation keep their parents, their depths might be outdated
+	if len(restored) > 0 {
+		if err := updateDepths(ctx, op.q, op.repo, op, restored); err != nil {
+			return errors.Join(errors.New("update depths"), err)
+		}
+	}
+	// restoring a change rewrites it like a describe or push does
+	for _, changeId := range restored {
+		if err := checkChangeBookmarkRules(ctx, op.q, op.repo, op.r.Username(), changeId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkOperationOwner refuses to undo operations of other users and devices, they might rewrite changes of others.
+// Admins can restore an earlier operation instead.
+func checkOperationOwner(r *signedhttp.Request, operation db.Operation) error {
+	if operation.Username == r.Username() && operation.Device == r.MachineID() {
+		return nil
+	}
+	return fmt.Errorf("%w: %d", serveerrors.ErrUndoOperationNotOwned, operation.ID)
+}
+
+func equalIds(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func equalStrings(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// laterConflictingOperation returns the first operation after target that modified the same aspects of a change
+// or the same bookmarks as target, or 0 if there is none.
+// Heads are only moved back if they weren't moved since, they don't block an undo.
+func laterConflictingOperation(r *signedhttp.Request, q db.Querier, repo repos.Repo, target int64) (int64, error) {
+	targetAspects, err := operationAspects(r, q, target)
+	if err != nil {
+		return 0, err
+	}
+	targetBookmarks, err := q.ListOperationBookmarks(r.Context(), target)
+	if err != nil {
+		return 0, errors.Join(errors.New("list operation bookmarks"), err)
+	}
+
+	laterIds, err := q.ListOperationsAfter(r.Context(), repo.ID(), target)
+	if err != nil {
+		return 0, errors.Join(errors.New("list later operations"), err)
+	}
+	// oldest first, so the operation to undo next is reported
+	slices.Reverse(laterIds)
+	for _, laterId := range laterIds {
+		laterAspects, err := operationAspects(r, q, laterId)
+		if err != nil {
+			return 0, err
+		}
+		for changeId, aspects := range laterAspects {
+			if targetAspects[changeId]&aspects != 0 {
+				return laterId, nil
+			}
+		}
+		laterBookmarks, err := q.ListOperationBookmarks(r.Context(), laterId)
+		if err != nil {
+			return 0, errors.Join(errors.New("list operation bookmarks"), err)
+		}
+		for _, b := range laterBookmarks {
+			if isHeadBookmark(b.Name) {
+				continue
+			}
+			if slices.ContainsFunc(targetBookmarks, func(t db.OperationBookmark) bool { return t.Name == b.Name }) {
+				return laterId, nil
+			}
+		}
+	}
+	return 0, nil
+}
+
+// operationAspects maps the changes of a logged operation to the aspects it modified.
+func operationAspects(r *signedhttp.Request, q db.Querier, operationId int64) (map[int64]changeAspects, error) {
+	changes, err := q.ListOperationChanges(r.Context(), operationId)
+	if err != nil {
+		return nil, errors.Join(errors.New("list operation changes"), err)
+	}
+	aspects := make(map[int64]changeAspects, len(changes))
+	for _, c := range changes {
+		if aspects[c.ChangeID], err = changedAspects(r, q, c.BeforeSnapshotID, c.AfterSnapshotID); err != nil {
+			return nil, err
+		}
+	}
+	return aspects, nil
+}
+
+// defaultOperationLogLimit is used if the client doesn't limit the operation log.
+const defaultOperationLogLimit = 20
+
+// operationToProto lists the changes and bookmarks of a logged operation.
+func operationToProto(r *signedhttp.Request, q *db.Queries, operation db.Operation) (*protos.Operation, error) {
+	o := &protos.Operation{
+		Id:        operation.ID,
+		Command:   operation.Command,
+		Username:  operation.Username,
+		Device:    operation.Device,
+		CreatedAt: timestamppb.New(operation.CreatedAt.Time),
+	}
+	changes, err := q.ListOperationChanges(r.Context(), operation.ID)
+	if err != nil {
+		return nil, errors.Join(errors.New("list operation changes"), err)
+	}
+	for _, c := range changes {
+		o.Changes = append(o.Changes, c.Name)
+	}
+	bookmarks, err := q.ListOperationBookmarks(r.Context(), operation.ID)
+	if err != nil {
+		return nil, errors.Join(errors.New("list operation bookmarks"), err)
+	}
+	for _, b := range bookmarks {
+		if !isHeadBookmark(b.Name) {
+			o.Bookmarks = append(o.Bookmarks, b.Name)
+		}
+	}
+	return o, nil
+}
+
+func (a *App) handleOperationLog(w http.ResponseWriter, r *signedhttp.Request) {
+	repo, err := a.openRepo(r.PathValue("repo"))
+	if err != nil {
+		if errors.Is(err, repos.ErrRepoNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "open repository: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	req := new(protos.OperationLogRequest)
+	err = protos.Unmarshal(r.Body(), req)
+	if err != nil {
+		http.Error(w, "unmarshal operation log request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultOperationLogLimit
+	}
+
+	operations, err := db.Q.ListOperations(r.Context(), repo.ID(), limit)
+	if err != nil {
+		http.Error(w, "list operations: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := &protos.OperationLogResponse{Operations: make([]*protos.Operation, len(operations))}
+	for i, operation := range operations {
+		if resp.Operations[i], err = operationToProto(r, db.Q, operation); err != nil {
+			http.Error(w, "describe operation: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	_ = protos.MarshalWrite(resp, w)
+}
+
+// handleUndo reverts one operation of the requester, defaults to the latest one that isn't a push.
+// Pushes are skipped because every command pushes the working copy first. Operations that modified the same changes or bookmarks later must be
+// undone first, restoring an earlier operation reverts all of them at once.
+func (a *App) handleUndo(w http.ResponseWriter, r *signedhttp.Request) {
+	repo, err := a.openRepo(r.PathValue("repo"))
+	if err != nil {
+		if errors.Is(err, repos.ErrRepoNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "open repository: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	req := new(protos.UndoRequest)
+	err = protos.Unmarshal(r.Body(), req)
+	if err != nil {
+		http.Error(w, "unmarshal undo request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Q.Begin(r.Context())
+	if err != nil {
+		http.Error(w, "begin transaction: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Close()
+
+	var target db.Operation
+	if req.OperationId != nil {
+		target, err = tx.GetOperation(r.Context(), repo.ID(), *req.OperationId)
+	} else {
+		target, err = tx.GetLatestOperation(r.Context(), repo.ID(), r.Username(), r.MachineID())
+	}
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, "no operation to undo", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "get operation: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := checkOperationOwner(r, target); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if a.operationExpired(target) {
+		http.Error(w, fmt.Sprintf("operation %d expired, it can't be undone anymore", target.ID), http.StatusGone)
+		return
+	}
+
+	resp := new(protos.UndoResponse)
+	if resp.Undone, err = operationToProto(r, tx.Queries, target); err != nil {
+		http.Error(w, "describe operation: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if laterId, err := laterConflictingOperation(r, tx.Queries, repo, target.ID); err != nil {
+		http.Error(w, "check later operations: "+err.Error(), http.StatusInternalServerError)
+		return
+	} else if laterId != 0 {
+		http.Error(
+			w,
+			fmt.Sprintf("operation %d modified the same changes or bookmarks after operation %d, undo it first or restore an earlier operation", laterId, target.ID),
+			http.StatusConflict,
+		)
+		return
+	}
+
+	op, err := beginOperation(r, tx.Queries, repo, fmt.Sprintf("undo %d", target.ID))
+	if err != nil {
+		http.Error(w, "begin operation: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := revertOperation(op, target.ID); err != nil {
+		writeBookmarkRuleError(w, "revert operation", err)
+		return
+	}
+
+	if err := op.finish(); err != nil {
+		http.Error(w, "log operation: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		http.Error(w, "commit transaction: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_ = protos.MarshalWrite(resp, w)
+}
+
+// handleRestoreOperation reverts all operations after the given one, newest first,
+// so everything they modified is back in the state right after the given operation.
+func (a *App) handleRestoreOperation(w http.ResponseWriter, r *signedhttp.Request) {
+	repo, err := a.openRepo(r.PathValue("repo"))
+	if err != nil {
+		if errors.Is(err, repos.ErrRepoNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "open repository: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	req := new(protos.RestoreOperationRequest)
+	err = protos.Unmarshal(r.Body(), req)
+	if err != nil {
+		http.Error(w, "unmarshal restore operation request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Q.Begin(r.Context())
+	if err != nil {
+		http.Error(w, "begin transaction: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Close()
+
+	if _, err := tx.GetOperation(r.Context(), repo.ID(), req.OperationId); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, fmt.Sprintf("operation %d not found", req.OperationId), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "get operation: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	laterIds, err := tx.ListOperationsAfter(r.Context(), repo.ID(), req.OperationId)
+	if err != nil {
+		http.Error(w, "list later operations: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	op, err := beginOperation(r, tx.Queries, repo, fmt.Sprintf("restore %d", req.OperationId))
+	if err != nil {
+		http.Error(w, "begin operation: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := new(protos.RestoreOperationResponse)
+	for _, laterId := range laterIds {
+		later, err := tx.GetOperation(r.Context(), repo.ID(), laterId)
+		if err != nil {
+			http.Error(w, "get operation: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if a.operationExpired(later) {
+			http.Error(w, fmt.Sprintf("operation %d expired, it can't be reverted anymore", later.ID), http.StatusGone)
+			return
+		}
+		reverted, err := operationToProto(r, tx.Queries, later)
+		if err != nil {
+			http.Error(w, "describe operation: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		resp.Reverted = append(resp.Reverted, reverted)
+
+		if err := revertOperation(op, laterId); err != nil {
+			writeBookmarkRuleError(w, fmt.Sprintf("revert operation %d", laterId), err)
+			return
+		}
+	}
+
+	if err := op.finish(); err != nil {
+		http.Error(w, "log operation: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		http.Error(w, "commit transaction: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_ = protos.MarshalWrite(resp, w)
+}
@@ -0,0 +1,643 @@
+package serve
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/tsukinoko-kun/pogo/db"
+	"github.com/tsukinoko-kun/pogo/repos"
+	"github.com/tsukinoko-kun/pogo/serve/serveerrors"
+	"github.com/tsukinoko-kun/pogo/signedhttp"
+	"github.com/tsukinoko-kun/pogo/utils"
+)
+
+type memChange struct {
+	description *string
+	author      string
+	device      string
+	depth       int64
+	parents     []int64
+	files       []int64
+	abandonedAt pgtype.Timestamptz
+}
+
+type memSnapshot struct {
+	db.ChangeSnapshot
+	files []int64
+}
+
+// opQuerier keeps the changes, bookmarks and operation log of one repository in memory.
+type opQuerier struct {
+	db.Querier
+	changes     map[int64]*memChange
+	bookmarks   map[string]int64
+	rules       map[string]db.BookmarkRule
+	snapshots   map[int64]*memSnapshot
+	snapshotIds int64
+	operations  []int64
+	opChanges   map[int64][]db.ListOperationChangesRow
+	opBookmarks map[int64][]db.OperationBookmark
+}
+
+func newOpQuerier() *opQuerier {
+	return &opQuerier{
+		changes:     make(map[int64]*memChange),
+		bookmarks:   make(map[string]int64),
+		rules:       make(map[string]db.BookmarkRule),
+		snapshots:   make(map[int64]*memSnapshot),
+		opChanges:   make(map[int64][]db.ListOperationChangesRow),
+		opBookmarks: make(map[int64][]db.OperationBookmark),
+	}
+}
+
+// addChange creates a change with the description and files on top of the parents.
+func (q *opQuerier) addChange(id int64, description string, files []int64, parents ...int64) {
+	c := &memChange{description: &description, author: "alice", device: "laptop", files: files, parents: parents}
+	q.changes[id] = c
+	c.depth = q.parentsMaxDepth(id) + 1
+}
+
+func (q *opQuerier) parentsMaxDepth(id int64) int64 {
+	depth := int64(-1)
+	for _, parent := range q.changes[id].parents {
+		depth = max(depth, q.changes[parent].depth)
+	}
+	return depth
+}
+
+func changeName(id int64) string {
+	return fmt.Sprintf("change%d", id)
+}
+
+func (q *opQuerier) ListBookmarkTargets(context.Context, int32) ([]db.ListBookmarkTargetsRow, error) {
+	var targets []db.ListBookmarkTargetsRow
+	for _, name := range slices.Sorted(maps.Keys(q.bookmarks)) {
+		targets = append(targets, db.ListBookmarkTargetsRow{Name: name, ChangeID: q.bookmarks[name]})
+	}
+	return targets, nil
+}
+
+func (q *opQuerier) ListChangeBookmarks(_ context.Context, _ int32, changeId int64) ([]string, error) {
+	var names []string
+	for _, name := range slices.Sorted(maps.Keys(q.bookmarks)) {
+		if q.bookmarks[name] == changeId {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+func (q *opQuerier) GetBookmark(_ context.Context, _ int32, name string) (int64, error) {
+	changeId, ok := q.bookmarks[name]
+	if !ok {
+		return 0, pgx.ErrNoRows
+	}
+	return changeId, nil
+}
+
+func (q *opQuerier) SetBookmark(_ context.Context, _ int32, name string, changeId int64) error {
+	q.bookmarks[name] = changeId
+	return nil
+}
+
+func (q *opQuerier) DeleteBookmark(_ context.Context, _ int32, name string) (int64, error) {
+	if _, ok := q.bookmarks[name]; !ok {
+		return 0, nil
+	}
+	delete(q.bookmarks, name)
+	return 1, nil
+}
+
+func (q *opQuerier) GetBookmarkRule(_ context.Context, _ int32, bookmark string) (db.BookmarkRule, error) {
+	rule, ok := q.rules[bookmark]
+	if !ok {
+		return db.BookmarkRule{}, pgx.ErrNoRows
+	}
+	return rule, nil
+}
+
+func (q *opQuerier) IsAncestor(_ context.Context, descendant int64, ancestor int64) (bool, error) {
+	if descendant == ancestor {
+		return true, nil
+	}
+	for _, parent := range q.changes[descendant].parents {
+		if ok, _ := q.IsAncestor(context.Background(), parent, ancestor); ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (q *opQuerier) HasChangeConflicts(context.Context, int64) (bool, error) {
+	return false, nil
+}
+
+func (q *opQuerier) GetChangeDescription(_ context.Context, id int64) (*string, error) {
+	return q.changes[id].description, nil
+}
+
+func (q *opQuerier) GetChangeName(_ context.Context, id int64, _ int32) (string, error) {
+	return changeName(id), nil
+}
+
+func (q *opQuerier) IsChangeAbandoned(_ context.Context, id int64) (bool, error) {
+	return q.changes[id].abandonedAt.Valid, nil
+}
+
+func (q *opQuerier) AbandonChange(_ context.Context, id int64) error {
+	q.changes[id].abandonedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	return nil
+}
+
+func (q *opQuerier) RevsetParents(_ context.Context, ids []int64, _ int32) ([]int64, error) {
+	var parents []int64
+	for _, id := range ids {
+		for _, parent := range q.changes[id].parents {
+			if !q.changes[parent].abandonedAt.Valid && !slices.Contains(parents, parent) {
+				parents = append(parents, parent)
+			}
+		}
+	}
+	return parents, nil
+}
+
+func (q *opQuerier) GetChildren(_ context.Context, parentId *int64) ([]int64, error) {
+	var children []int64
+	for _, id := range slices.Sorted(maps.Keys(q.changes)) {
+		if !q.changes[id].abandonedAt.Valid && slices.Contains(q.changes[id].parents, *parentId) {
+			children = append(children, id)
+		}
+	}
+	return children, nil
+}
+
+func (q *opQuerier) RevsetDescendants(_ context.Context, ids []int64, _ int32) ([]int64, error) {
+	descendants := slices.Clone(ids)
+	for i := 0; i < len(descendants); i++ {
+		children, _ := q.GetChildren(context.Background(), &descendants[i])
+		for _, child := range children {
+			if !slices.Contains(descendants, child) {
+				descendants = append(descendants, child)
+			}
+		}
+	}
+	return descendants, nil
+}
+
+func (q *opQuerier) RemoveChangeParent(_ context.Context, changeId int64, parentId *int64) error {
+	c := q.changes[changeId]
+	c.parents = slices.DeleteFunc(c.parents, func(p int64) bool { return p == *parentId })
+	return nil
+}
+
+func (q *opQuerier) SetChangeParent(_ context.Context, changeId int64, parentId *int64) error {
+	c := q.changes[changeId]
+	if !slices.Contains(c.parents, *parentId) {
+		c.parents = append(c.parents, *parentId)
+	}
+	return nil
+}
+
+func (q *opQuerier) ClearChangeParents(_ context.Context, changeId int64) error {
+	q.changes[changeId].parents = nil
+	return nil
+}
+
+func (q *opQuerier) ClearChange(_ context.Context, changeId int64) error {
+	q.changes[changeId].files = nil
+	return nil
+}
+
+func (q *opQuerier) GetChangeDepth(_ context.Context, id int64, _ int32) (int64, error) {
+	return q.changes[id].depth, nil
+}
+
+func (q *opQuerier) GetParentsMaxDepth(_ context.Context, changeId int64) (int64, error) {
+	return q.parentsMaxDepth(changeId), nil
+}
+
+func (q *opQuerier) SetChangeDepth(_ context.Context, id int64, depth int64) error {
+	q.changes[id].depth = depth
+	return nil
+}
+
+func (q *opQuerier) CreateChangeSnapshot(_ context.Context, changeId int64) (int64, error) {
+	c := q.changes[changeId]
+	q.snapshotIds++
+	id := q.snapshotIds
+	q.snapshots[id] = &memSnapshot{ChangeSnapshot: db.ChangeSnapshot{
+		ID:          id,
+		ChangeID:    changeId,
+		Description: c.description,
+		Author:      c.author,
+		Device:      c.device,
+		Depth:       c.depth,
+		ParentIds:   slices.Sorted(slices.Values(c.parents)),
+		AbandonedAt: c.abandonedAt,
+	}}
+	return id, nil
+}
+
+func (q *opQuerier) AddChangeSnapshotFiles(_ context.Context, snapshotId int64, changeId int64) error {
+	q.snapshots[snapshotId].files = slices.Clone(q.changes[changeId].files)
+	return nil
+}
+
+func sameFiles(a, b []int64) bool {
+	return slices.Equal(slices.Sorted(slices.Values(a)), slices.Sorted(slices.Values(b)))
+}
+
+func (q *opQuerier) ChangeMatchesSnapshot(_ context.Context, id int64) (bool, error) {
+	s := q.snapshots[id]
+	c := q.changes[s.ChangeID]
+	return equalStrings(s.Description, c.description) &&
+		s.Author == c.author &&
+		s.Device == c.device &&
+		s.Depth == c.depth &&
+		s.AbandonedAt == c.abandonedAt &&
+		slices.Equal(s.ParentIds, slices.Sorted(slices.Values(c.parents))) &&
+		sameFiles(s.files, c.files), nil
+}
+
+func (q *opQuerier) DeleteChangeSnapshot(_ context.Context, id int64) error {
+	delete(q.snapshots, id)
+	return nil
+}
+
+func (q *opQuerier) GetChangeSnapshot(_ context.Context, id int64) (db.ChangeSnapshot, error) {
+	s, ok := q.snapshots[id]
+	if !ok {
+		return db.ChangeSnapshot{}, pgx.ErrNoRows
+	}
+	return s.ChangeSnapshot, nil
+}
+
+func (q *opQuerier) SnapshotsHaveSameFiles(_ context.Context, a int64, b int64) (bool, error) {
+	return sameFiles(q.snapshots[a].files, q.snapshots[b].files), nil
+}
+
+func (q *opQuerier) RestoreChangeSnapshotDescription(_ context.Context, id int64) error {
+	s := q.snapshots[id]
+	c := q.changes[s.ChangeID]
+	c.description, c.author, c.device = s.Description, s.Author, s.Device
+	return nil
+}
+
+func (q *opQuerier) RestoreChangeSnapshotAbandoned(_ context.Context, id int64) error {
+	s := q.snapshots[id]
+	q.changes[s.ChangeID].abandonedAt = s.AbandonedAt
+	return nil
+}
+
+func (q *opQuerier) RestoreChangeSnapshotParents(_ context.Context, id int64) error {
+	s := q.snapshots[id]
+	c := q.changes[s.ChangeID]
+	c.parents = append(c.parents, s.ParentIds...)
+	return nil
+}
+
+func (q *opQuerier) RestoreChangeSnapshotFiles(_ context.Context, snapshotId int64) error {
+	s := q.snapshots[snapshotId]
+	c := q.changes[s.ChangeID]
+	c.files = append(c.files, s.files...)
+	return nil
+}
+
+func (q *opQuerier) CreateOperation(context.Context, int32, string, string, string) (int64, error) {
+	id := int64(len(q.operations) + 1)
+	q.operations = append(q.operations, id)
+	return id, nil
+}
+
+func (q *opQuerier) AddOperationChange(_ context.Context, operationId int64, changeId int64, beforeSnapshotId *int64, afterSnapshotId int64) error {
+	q.opChanges[operationId] = append(q.opChanges[operationId], db.ListOperationChangesRow{
+		ChangeID:         changeId,
+		BeforeSnapshotID: beforeSnapshotId,
+		AfterSnapshotID:  afterSnapshotId,
+		Name:             changeName(changeId),
+	})
+	return nil
+}
+
+func (q *opQuerier) AddOperationBookmark(_ context.Context, operationId int64, name string, beforeChangeId *int64, afterChangeId *int64) error {
+	q.opBookmarks[operationId] = append(q.opBookmarks[operationId], db.OperationBookmark{
+		OperationID:    operationId,
+		Name:           name,
+		BeforeChangeID: beforeChangeId,
+		AfterChangeID:  afterChangeId,
+	})
+	return nil
+}
+
+func (q *opQuerier) ListOperationChanges(_ context.Context, operationId int64) ([]db.ListOperationChangesRow, error) {
+	return q.opChanges[operationId], nil
+}
+
+func (q *opQuerier) ListOperationBookmarks(_ context.Context, operationId int64) ([]db.OperationBookmark, error) {
+	bookmarks := slices.Clone(q.opBookmarks[operationId])
+	slices.SortFunc(bookmarks, func(a, b db.OperationBookmark) int { return strings.Compare(a.Name, b.Name) })
+	return bookmarks, nil
+}
+
+func (q *opQuerier) ListOperationsAfter(_ context.Context, _ int32, id int64) ([]int64, error) {
+	var later []int64
+	for _, operationId := range slices.Backward(q.operations) {
+		if operationId > id {
+			later = append(later, operationId)
+		}
+	}
+	return later, nil
+}
+
+func newTestRequest(t *testing.T) *signedhttp.Request {
+	t.Helper()
+	return newUserTestRequest(t, "alice", "laptop")
+}
+
+func newUserTestRequest(t *testing.T, username string, machineId string) *signedhttp.Request {
+	t.Helper()
+	httpReq := httptest.NewRequest("POST", "/rpc/undo", nil)
+	httpReq.Header.Set(signedhttp.HeaderUsername, username)
+	httpReq.Header.Set(signedhttp.HeaderMachineID, machineId)
+	httpReq.Header.Set(signedhttp.HeaderPublicKey, base64.URLEncoding.EncodeToString([]byte("key")))
+	r, err := signedhttp.NewRequest(httpReq, signedhttp.NewMemoryNonceStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// record runs modify as a logged operation and returns its id.
+func record(t *testing.T, r *signedhttp.Request, q *opQuerier, command string, modify func(op *operation)) int64 {
+	t.Helper()
+	op, err := beginOperation(r, q, repos.Repo(1), command)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modify(op)
+	if err := op.finish(); err != nil {
+		t.Fatal(err)
+	}
+	return q.operations[len(q.operations)-1]
+}
+
+func touch(t *testing.T, op *operation, changeId int64) {
+	t.Helper()
+	if err := op.touch(changeId); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// revert reverts the operation in an undo operation.
+func revert(t *testing.T, r *signedhttp.Request, q *opQuerier, operationId int64) error {
+	t.Helper()
+	op, err := beginOperation(r, q, repos.Repo(1), fmt.Sprintf("undo %d", operationId))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := revertOperation(op, operationId); err != nil {
+		return err
+	}
+	if err := op.finish(); err != nil {
+		t.Fatal(err)
+	}
+	return nil
+}
+
+func laterConflict(t *testing.T, r *signedhttp.Request, q *opQuerier, operationId int64) int64 {
+	t.Helper()
+	laterId, err := laterConflictingOperation(r, q, repos.Repo(1), operationId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return laterId
+}
+
+func TestRevertOperationKeepsLaterEdits(t *testing.T) {
+	r := newTestRequest(t)
+	q := newOpQuerier()
+	q.addChange(1, "root", nil)
+	q.addChange(2, "old", []int64{10}, 1)
+
+	describe := record(t, r, q, "describe", func(op *operation) {
+		touch(t, op, 2)
+		q.changes[2].description = utils.Ptr("new")
+	})
+	push := record(t, r, q, "push", func(op *operation) {
+		touch(t, op, 2)
+		q.changes[2].files = []int64{11}
+	})
+
+	if laterId := laterConflict(t, r, q, describe); laterId != 0 {
+		t.Fatalf("a push of other files should not block undoing the describe, got operation %d", laterId)
+	}
+	if err := revert(t, r, q, describe); err != nil {
+		t.Fatal(err)
+	}
+	if got := *q.changes[2].description; got != "old" {
+		t.Fatalf("the description should be reverted, got %q", got)
+	}
+	if !slices.Equal(q.changes[2].files, []int64{11}) {
+		t.Fatalf("the files of the later push should be kept, got %v", q.changes[2].files)
+	}
+	undo := q.operations[len(q.operations)-1]
+	if undo == push {
+		t.Fatal("the undo should be logged as a new operation")
+	}
+
+	// the undo modified the description again
+	if laterId := laterConflict(t, r, q, describe); laterId != undo {
+		t.Fatalf("the undo should block undoing the describe again, got operation %d", laterId)
+	}
+	if laterId := laterConflict(t, r, q, push); laterId != 0 {
+		t.Fatalf("the undo of the describe should not block undoing the push, got operation %d", laterId)
+	}
+}
+
+func TestRevertOperationParents(t *testing.T) {
+	r := newTestRequest(t)
+	q := newOpQuerier()
+	q.addChange(1, "root", nil)
+	q.addChange(2, "side", nil, 1)
+	q.addChange(3, "moved", nil, 2)
+	q.addChange(4, "child", nil, 3)
+
+	rebase := record(t, r, q, "rebase", func(op *operation) {
+		touch(t, op, 3)
+		q.changes[3].parents = []int64{1}
+		if err := updateDepths(context.Background(), q, repos.Repo(1), op, []int64{3}); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if q.changes[4].depth != 2 {
+		t.Fatalf("the rebase should update the depth of the child, got %d", q.changes[4].depth)
+	}
+
+	if err := revert(t, r, q, rebase); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(q.changes[3].parents, []int64{2}) {
+		t.Fatalf("the parents should be reverted, got %v", q.changes[3].parents)
+	}
+	if q.changes[3].depth != 2 || q.changes[4].depth != 3 {
+		t.Fatalf("the depths should follow the parents, got %d and %d", q.changes[3].depth, q.changes[4].depth)
+	}
+}
+
+func TestRevertOperationCreatedChangesAndBookmarks(t *testing.T) {
+	r := newTestRequest(t)
+	q := newOpQuerier()
+	head := headBookmark(r)
+	q.addChange(1, "root", nil)
+	q.addChange(2, "base", nil, 1)
+	q.bookmarks[head] = 2
+	q.bookmarks["feature"] = 1
+
+	newChange := record(t, r, q, "new", func(op *operation) {
+		q.addChange(3, "", nil, 2)
+		op.created(3)
+		q.bookmarks[head] = 3
+		q.bookmarks["feature"] = 3
+		q.bookmarks["topic"] = 3
+	})
+
+	if err := revert(t, r, q, newChange); err != nil {
+		t.Fatal(err)
+	}
+	if !q.changes[3].abandonedAt.Valid {
+		t.Fatal("the created change should be abandoned")
+	}
+	if q.bookmarks[head] != 2 || q.bookmarks["feature"] != 1 {
+		t.Fatalf("the bookmarks should be moved back, got %v", q.bookmarks)
+	}
+	if _, ok := q.bookmarks["topic"]; ok {
+		t.Fatal("the created bookmark should be deleted")
+	}
+}
+
+func TestRevertOperationKeepsMovedHead(t *testing.T) {
+	r := newTestRequest(t)
+	q := newOpQuerier()
+	head := headBookmark(r)
+	q.addChange(1, "root", nil)
+	q.addChange(2, "base", nil, 1)
+	q.addChange(3, "other", nil, 1)
+	q.bookmarks[head] = 1
+
+	edit := record(t, r, q, "edit", func(op *operation) {
+		q.bookmarks[head] = 2
+	})
+	moveHead := record(t, r, q, "edit", func(op *operation) {
+		q.bookmarks[head] = 3
+	})
+
+	if laterId := laterConflict(t, r, q, edit); laterId != 0 {
+		t.Fatalf("head moves should not block an undo, got operation %d", laterId)
+	}
+	if err := revert(t, r, q, edit); err != nil {
+		t.Fatal(err)
+	}
+	if q.bookmarks[head] != 3 {
+		t.Fatalf("a head that moved on should be kept, got change %d", q.bookmarks[head])
+	}
+	if q.operations[len(q.operations)-1] != moveHead {
+		t.Fatal("an undo that didn't change anything should not be logged")
+	}
+}
+
+func TestRevertOperationBookmarkRule(t *testing.T) {
+	r := newTestRequest(t)
+	q := newOpQuerier()
+	q.addChange(1, "root", nil)
+	q.addChange(2, "base", nil, 1)
+	q.rules["main"] = db.BookmarkRule{Bookmark: "main", DescendantsOnly: true}
+	q.rules["release"] = db.BookmarkRule{Bookmark: "release"}
+	q.bookmarks["main"] = 1
+
+	moveMain := record(t, r, q, "bookmark set", func(op *operation) {
+		q.bookmarks["main"] = 2
+	})
+	createRelease := record(t, r, q, "bookmark set", func(op *operation) {
+		q.bookmarks["release"] = 2
+	})
+
+	var ruleErr *bookmarkRuleError
+	if err := revert(t, r, q, moveMain); !errors.As(err, &ruleErr) || ruleErr.Bookmark != "main" {
+		t.Fatalf("moving main backwards should be forbidden, got %v", err)
+	}
+	if err := revert(t, r, q, createRelease); !errors.As(err, &ruleErr) || ruleErr.Bookmark != "release" {
+		t.Fatalf("deleting a protected bookmark should be forbidden, got %v", err)
+	}
+}
+
+func TestRevertOperationChangeBookmarkRule(t *testing.T) {
+	r := newTestRequest(t)
+	q := newOpQuerier()
+	q.addChange(1, "root", nil)
+	q.addChange(2, "", nil, 1)
+	q.rules["main"] = db.BookmarkRule{Bookmark: "main", RequireDescription: true}
+
+	describe := record(t, r, q, "describe", func(op *operation) {
+		touch(t, op, 2)
+		q.changes[2].description = utils.Ptr("feature")
+	})
+	record(t, r, q, "bookmark set", func(op *operation) {
+		q.bookmarks["main"] = 2
+	})
+
+	var ruleErr *bookmarkRuleError
+	if err := revert(t, r, q, describe); !errors.As(err, &ruleErr) || ruleErr.Bookmark != "main" {
+		t.Fatalf("removing the description of main should be forbidden, got %v", err)
+	}
+}
+
+func TestCheckOperationOwner(t *testing.T) {
+	push := db.Operation{ID: 1, Command: "push", Username: "alice", Device: "laptop"}
+
+	if err := checkOperationOwner(newTestRequest(t), push); err != nil {
+		t.Fatalf("the push should be undone by its owner, got %v", err)
+	}
+	for _, r := range []*signedhttp.Request{newUserTestRequest(t, "bob", "laptop"), newUserTestRequest(t, "alice", "desktop")} {
+		if err := checkOperationOwner(r, push); !errors.Is(err, serveerrors.ErrUndoOperationNotOwned) {
+			t.Fatalf("%s on %s should not undo the push of alice on laptop, got %v", r.Username(), r.MachineID(), err)
+		}
+	}
+}
+
+func TestLaterConflictingOperationBookmarks(t *testing.T) {
+	r := newTestRequest(t)
+	q := newOpQuerier()
+	q.addChange(1, "root", nil)
+	q.addChange(2, "base", nil, 1)
+	q.addChange(3, "other", nil, 1)
+
+	setFeature := record(t, r, q, "bookmark set", func(op *operation) {
+		q.bookmarks["feature"] = 2
+	})
+	record(t, r, q, "bookmark set", func(op *operation) {
+		q.bookmarks["topic"] = 3
+	})
+	if laterId := laterConflict(t, r, q, setFeature); laterId != 0 {
+		t.Fatalf("other bookmarks should not block an undo, got operation %d", laterId)
+	}
+
+	moveFeature := record(t, r, q, "bookmark set", func(op *operation) {
+		q.bookmarks["feature"] = 3
+	})
+	record(t, r, q, "bookmark delete", func(op *operation) {
+		delete(q.bookmarks, "feature")
+	})
+	if laterId := laterConflict(t, r, q, setFeature); laterId != moveFeature {
+		t.Fatalf("the first later move of the bookmark should block an undo, got operation %d, want %d", laterId, moveFeature)
+	}
+}
@@ -2,6 +2,7 @@ package serve
 
 import (
 	"archive/tar"
+	"context"
 	"encoding/base64"
 	"errors"
 	"fmt"
@@ -10,7 +11,6 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/tsukinoko-kun/pogo/db"
 	"github.com/tsukinoko-kun/pogo/protos"
@@ -19,26 +19,50 @@ import (
 	"github.com/tsukinoko-kun/pogo/signedhttp"
 	"github.com/tsukinoko-kun/pogo/utils"
 
+	"github.com/jackc/pgx/v5"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// streamingRpcs are the functions whose request body is streamed instead of buffered.
+// Their handlers must verify the signature before committing anything.
+var streamingRpcs = map[string]bool{
+	"push": true,
+}
+
 func (a *App) handleRpc(w http.ResponseWriter, httpReq *http.Request) {
 	defer httpReq.Body.Close()
 
-	r, err := signedhttp.NewRequest(httpReq)
+	funcName := httpReq.PathValue("func")
+	requiredRole, ok := rpcRoles[funcName]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	authenticate := a.authenticate
+	if streamingRpcs[funcName] {
+		authenticate = a.authenticateStream
+	}
+	r, ok := authenticate(w, httpReq)
+	if !ok {
+		return
+	}
+	defer r.Close()
+
+	repo, err := a.openRepo(r.PathValue("repo"))
 	if err != nil {
-		if errors.Is(err, signedhttp.ErrMissingSignature) ||
-			errors.Is(err, signedhttp.ErrInvalidSignature) ||
-			errors.Is(err, signedhttp.ErrSignatureVerificationFailed) {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+		if errors.Is(err, repos.ErrRepoNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
 			return
 		}
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, "open repository: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !a.authorize(w, r, repo, requiredRole) {
 		return
 	}
-	defer r.Close()
 
-	funcName := r.PathValue("func")
 	switch funcName {
 	case "push":
 		a.handlePush(w, r)
@@ -50,49 +74,97 @@ func (a *App) handleRpc(w http.ResponseWriter, httpReq *http.Request) {
 		a.handleCheckout(w, r)
 	case "set_bookmark":
 		a.handleSetBookmark(w, r)
+	case "delete_bookmark":
+		a.handleDeleteBookmark(w, r)
+	case "rename_bookmark":
+		a.handleRenameBookmark(w, r)
+	case "abandon":
+		a.handleAbandon(w, r)
+	case "squash":
+		a.handleSquash(w, r)
+	case "rebase":
+		a.handleRebase(w, r)
+	case "op_log":
+		a.handleOperationLog(w, r)
+	case "undo":
+		a.handleUndo(w, r)
+	case "restore_operation":
+		a.handleRestoreOperation(w, r)
+	case "evolog":
+		a.handleEvolog(w, r)
+	case "restore_snapshot":
+		a.handleRestoreSnapshot(w, r)
+	case "list_bookmark_rules":
+		a.handleListBookmarkRules(w, r)
+	case "set_bookmark_rule":
+		a.handleSetBookmarkRule(w, r)
+	case "remove_bookmark_rule":
+		a.handleRemoveBookmarkRule(w, r)
+	case "set_head":
+		a.handleSetHead(w, r)
 	case "log":
 		a.handleLog(w, r)
+	case "status":
+		a.handleStatus(w, r)
 	case "conflicts":
 		a.handleConflicts(w, r)
+	case "diff":
+		a.handleDiff(w, r)
+	case "files":
+		a.handleFiles(w, r)
+	case "cat":
+		a.handleCat(w, r)
 	case "find_change":
 		a.handleFindChange(w, r)
 	case "describe":
 		a.handleDescribe(w, r)
 	case "list_bookmarks":
 		a.handleListBookmarks(w, r)
+	case "list_members":
+		a.handleListMembers(w, r)
+	case "set_member":
+		a.handleSetMember(w, r)
+	case "remove_member":
+		a.handleRemoveMember(w, r)
 	default:
 		w.WriteHeader(http.StatusNotFound)
 	}
 }
 
+// openRepo opens a repository by its id or name and returns repos.ErrRepoNotFound if it doesn't exist.
 func (a *App) openRepo(idStr string) (repos.Repo, error) {
 	if id, err := strconv.ParseInt(idStr, 10, 32); err == nil {
-		return repos.Open(int32(id)), nil
+		return repos.OpenByID(int32(id))
 	}
 	// try by name as fallback
 	return repos.OpenByName(idStr)
 }
 
+// changeInRepo reports whether a change id from a request belongs to the repository.
+// Change ids are unique across repositories, a change of another repository is treated as unknown.
+func changeInRepo(ctx context.Context, q db.Querier, repo repos.Repo, changeId int64) (bool, error) {
+	if _, err := q.GetChangeName(ctx, changeId, repo.ID()); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
 var repoNamingRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_-]*$`)
 
 func (a *App) handleInit(w http.ResponseWriter, httpReq *http.Request) {
 	defer httpReq.Body.Close()
 
-	r, err := signedhttp.NewRequest(httpReq)
-	if err != nil {
-		if errors.Is(err, signedhttp.ErrMissingSignature) ||
-			errors.Is(err, signedhttp.ErrInvalidSignature) ||
-			errors.Is(err, signedhttp.ErrSignatureVerificationFailed) {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
-			return
-		}
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	r, ok := a.authenticate(w, httpReq)
+	if !ok {
 		return
 	}
 	defer r.Close()
 
 	req := new(protos.InitRequest)
-	err = protos.Unmarshal(r.Body(), req)
+	err := protos.Unmarshal(r.Body(), req)
 	if err != nil {
 		http.Error(w, "unmarshal init request: "+err.Error(), http.StatusBadRequest)
 		return
@@ -116,6 +188,16 @@ func (a *App) handleInit(w http.ResponseWriter, httpReq *http.Request) {
 		return
 	}
 
+	user, err := tx.GetUserByName(r.Context(), r.Username())
+	if err != nil {
+		http.Error(w, "get user: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err = tx.SetRepoMember(r.Context(), repo.ID(), user.ID, string(repos.RoleAdmin)); err != nil {
+		http.Error(w, "add repository admin: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	changeName, err := tx.GenerateChangeName(r.Context(), repo.ID())
 	if err != nil {
 		http.Error(w, "generate change name: "+err.Error(), http.StatusInternalServerError)
@@ -160,9 +242,97 @@ func (a *App) handleInit(w http.ResponseWriter, httpReq *http.Request) {
 	_ = protos.MarshalWrite(initResp, w)
 }
 
+func (a *App) handleClone(w http.ResponseWriter, httpReq *http.Request) {
+	defer httpReq.Body.Close()
+
+	r, ok := a.authenticate(w, httpReq)
+	if !ok {
+		return
+	}
+	defer r.Close()
+
+	req := new(protos.CloneRequest)
+	err := protos.Unmarshal(r.Body(), req)
+	if err != nil {
+		http.Error(w, "unmarshal clone request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repo, err := repos.OpenByName(req.Repo)
+	if errors.Is(err, repos.ErrRepoNotFound) {
+		// try by id as fallback
+		if id, parseErr := strconv.ParseInt(req.Repo, 10, 32); parseErr == nil {
+			repo, err = repos.OpenByID(int32(id))
+		}
+	}
+	if err != nil {
+		if errors.Is(err, repos.ErrRepoNotFound) {
+			http.Error(w, "repository '"+req.Repo+"' not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "open repository: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if !a.authorize(w, r, repo, repos.RoleRead) {
+		return
+	}
+
+	bookmark := req.Bookmark
+	if bookmark == "" {
+		bookmark = "main"
+	}
+	if strings.HasPrefix(bookmark, "__head-") {
+		http.Error(w, "cannot clone from head bookmark '"+bookmark+"'", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Q.Begin(r.Context())
+	if err != nil {
+		http.Error(w, "begin transaction: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Close()
+
+	changeId, err := tx.GetBookmark(r.Context(), repo.ID(), bookmark)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, fmt.Sprintf("bookmark '%s' not found in repository '%s'", bookmark, req.Repo), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "get bookmark: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	headName := headBookmark(r)
+	if err = tx.SetBookmark(r.Context(), repo.ID(), headName, changeId); err != nil {
+		http.Error(
+			w,
+			fmt.Sprintf("set bookmark '%s' to change %d: %s", headName, changeId, err.Error()),
+			http.StatusInternalServerError,
+		)
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		http.Error(w, "commit transaction: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	cloneResp := &protos.CloneResponse{
+		RepoID:   repo.ID(),
+		ChangeId: changeId,
+	}
+	_ = protos.MarshalWrite(cloneResp, w)
+}
+
 func (a *App) handlePush(w http.ResponseWriter, r *signedhttp.Request) {
 	repo, err := a.openRepo(r.PathValue("repo"))
 	if err != nil {
+		if errors.Is(err, repos.ErrRepoNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
 		http.Error(w, "open repository: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -198,11 +368,48 @@ func (a *App) handlePush(w http.ResponseWriter, r *signedhttp.Request) {
 		return
 	}
 
+	op, err := beginOperation(r, tx.Queries, repo, "push")
+	if err != nil {
+		http.Error(w, "begin operation: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := op.touch(changeId); err != nil {
+		http.Error(w, "snapshot change: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// pushed files can only stay in the conflicts the change already had
+	previousFiles, err := listChangeFiles(r.Context(), tx.Queries, changeId)
+	if err != nil {
+		http.Error(w, "list change files: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	previousConflicts := make(map[string]changeFile)
+	for _, f := range previousFiles {
+		if f.conflict != nil {
+			previousConflicts[f.Name] = f
+		}
+	}
+
 	if err = tx.ClearChange(r.Context(), changeId); err != nil {
 		http.Error(w, "clear change: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	// blobs are written to the content store while the body is streamed,
+	// the new ones are removed again if the signature of the body is never verified.
+	// Once the push is verified, the garbage collection removes the blobs of a failed push.
+	var newBlobs [][]byte
+	verified := false
+	defer func() {
+		if verified {
+			return
+		}
+		for _, contentHash := range newBlobs {
+			_ = repo.RemoveFileContent(contentHash)
+		}
+	}()
+
 	tarReader := tar.NewReader(r.Body())
 
 	for {
@@ -211,6 +418,10 @@ func (a *App) handlePush(w http.ResponseWriter, r *signedhttp.Request) {
 			if err == io.EOF {
 				break
 			}
+			if isSignatureError(err) {
+				writeSignatureError(w, err)
+				return
+			}
 			http.Error(w, "read tar: "+err.Error(), http.StatusBadRequest)
 			return
 		}
@@ -225,17 +436,45 @@ func (a *App) handlePush(w http.ResponseWriter, r *signedhttp.Request) {
 			return
 		}
 
+		var size int64
 		if pfi.ContainsContent {
-			if err := repo.SetFileContent(pfi.ContentHash, tarReader); err != nil {
+			// a mark from an earlier garbage collection must not delete the new blob
+			if err := db.Q.UnmarkBlob(r.Context(), pfi.ContentHash); err != nil {
+				http.Error(w, "unmark blob: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if exists, err := repo.FileExists(pfi.ContentHash); err != nil {
+				http.Error(w, "check file exists: "+err.Error(), http.StatusInternalServerError)
+				return
+			} else if !exists {
+				newBlobs = append(newBlobs, pfi.ContentHash)
+			}
+			if size, err = repo.SetFileContent(pfi.ContentHash, tarReader); err != nil {
+				if errors.Is(err, repos.ErrContentHashMismatch) {
+					http.Error(w, "file '"+pfi.Name+"': "+err.Error(), http.StatusBadRequest)
+					return
+				}
 				http.Error(w, "set file content: "+err.Error(), http.StatusInternalServerError)
 				return
 			}
 		}
 
-		inConflict, err := isInConflict(repo, pfi.Name, pfi.ContentHash)
-		if err != nil {
-			http.Error(w, "check if file is in conflict: "+err.Error(), http.StatusInternalServerError)
-			return
+		var carry, render bool
+		if previous, ok := previousConflicts[pfi.Name]; ok {
+			if carry, render, err = carryConflict(repo, previous, pfi.ContentHash, pfi.Executable); err != nil {
+				http.Error(w, "check if file is in conflict: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if carry {
+				if err := addChangeConflict(r.Context(), tx.Queries, changeId, pfi.Name, previous.conflict); err != nil {
+					http.Error(w, "keep file conflict: "+err.Error(), http.StatusInternalServerError)
+					return
+				}
+			}
+		}
+		if render {
+			// the file still is the rendered conflict, it keeps being rendered from the versions
+			continue
 		}
 
 		fileId, err := db.UpsertFile(
@@ -244,18 +483,42 @@ func (a *App) handlePush(w http.ResponseWriter, r *signedhttp.Request) {
 			pfi.Name,
 			pfi.Executable,
 			pfi.ContentHash,
-			inConflict,
 		)
 		if err != nil {
 			http.Error(w, "upsert file: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
+		// the blob store only knows the compressed size, the size of the content is recorded once it is stored
+		if pfi.ContainsContent {
+			if err := tx.SetFileSize(r.Context(), pfi.ContentHash, &size); err != nil {
+				http.Error(w, "set file size: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
 		if err := tx.AddFileToChange(r.Context(), changeId, fileId); err != nil {
 			http.Error(w, "add file to change: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
 	}
 
+	// a push rewrites the files of the change in place, the bookmarks on it must allow that
+	if err := checkChangeBookmarkRules(r.Context(), tx.Queries, repo, r.Username(), changeId); err != nil {
+		writeBookmarkRuleError(w, "check bookmark rules", err)
+		return
+	}
+
+	// nothing may be committed before the signature of the whole body is verified
+	if err = r.Verify(); err != nil {
+		writeSignatureError(w, err)
+		return
+	}
+	verified = true
+
+	if err := op.finish(); err != nil {
+		http.Error(w, "log operation: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	if err = tx.Commit(); err != nil {
 		http.Error(w, "commit transaction: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -265,6 +528,10 @@ func (a *App) handlePush(w http.ResponseWriter, r *signedhttp.Request) {
 func (a *App) handleCheckFilesExists(w http.ResponseWriter, r *signedhttp.Request) {
 	repo, err := a.openRepo(r.PathValue("repo"))
 	if err != nil {
+		if errors.Is(err, repos.ErrRepoNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
 		http.Error(w, "open repository: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -276,15 +543,24 @@ func (a *App) handleCheckFilesExists(w http.ResponseWriter, r *signedhttp.Reques
 		return
 	}
 
-	resp := new(protos.CheckFilesExistsResponse)
+	// the client won't upload the blobs that exist, so the garbage collection must not delete them
+	// while the push is in flight. They are unmarked before they are checked: an unmark waits for a
+	// garbage collection that is deleting the blob, so the check afterwards sees it gone.
+	if len(req.ContentHash) > 0 {
+		if err := db.Q.UnmarkBlobs(r.Context(), req.ContentHash); err != nil {
+			http.Error(w, "unmark blobs: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
 
+	resp := new(protos.CheckFilesExistsResponse)
 	for _, hash := range req.ContentHash {
-		if exists, err := repo.FileExists(hash); err != nil {
+		exists, err := repo.FileExists(hash)
+		if err != nil {
 			http.Error(w, "check file exists: "+err.Error(), http.StatusInternalServerError)
 			return
-		} else {
-			resp.Exists = append(resp.Exists, exists)
 		}
+		resp.Exists = append(resp.Exists, exists)
 	}
 
 	_ = protos.MarshalWrite(resp, w)
@@ -293,6 +569,10 @@ func (a *App) handleCheckFilesExists(w http.ResponseWriter, r *signedhttp.Reques
 func (a *App) handleNewChange(w http.ResponseWriter, r *signedhttp.Request) {
 	repo, err := a.openRepo(r.PathValue("repo"))
 	if err != nil {
+		if errors.Is(err, repos.ErrRepoNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
 		http.Error(w, "open repository: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -311,6 +591,12 @@ func (a *App) handleNewChange(w http.ResponseWriter, r *signedhttp.Request) {
 	}
 	defer tx.Close()
 
+	op, err := beginOperation(r, tx.Queries, repo, "new")
+	if err != nil {
+		http.Error(w, "begin operation: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	changeName, err := tx.GenerateChangeName(r.Context(), repo.ID())
 	if err != nil {
 		http.Error(w, "generate change name: "+err.Error(), http.StatusInternalServerError)
@@ -330,10 +616,11 @@ func (a *App) handleNewChange(w http.ResponseWriter, r *signedhttp.Request) {
 		http.Error(w, "create change: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	op.created(changeId)
 
 	for _, bookmark := range newChangeRequest.GetSetBookmarks() {
-		if err := tx.SetBookmark(r.Context(), repo.ID(), bookmark, changeId); err != nil {
-			http.Error(w, "set bookmark: "+err.Error(), http.StatusInternalServerError)
+		if err := validateBookmarkName(bookmark); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 	}
@@ -343,9 +630,9 @@ func (a *App) handleNewChange(w http.ResponseWriter, r *signedhttp.Request) {
 
 	// set parents
 	for i, parent := range newChangeRequest.Parents {
-		parentChangeId, err := tx.FindChange(r.Context(), repo.ID(), parent)
+		parentChangeId, err := revsetEnv(r, tx.Queries, repo).ResolveOne(parent)
 		if err != nil {
-			http.Error(w, "get parent change: "+err.Error(), http.StatusInternalServerError)
+			writeRevsetError(w, "get parent change", err)
 			return
 		}
 		parentChangeName, err := tx.GetChangeName(r.Context(), parentChangeId, repo.ID())
@@ -394,14 +681,43 @@ func (a *App) handleNewChange(w http.ResponseWriter, r *signedhttp.Request) {
 			http.Error(w, "new change must have the same file count as its parent", http.StatusBadRequest)
 			return
 		}
-	case 2:
+	default:
 		// more complex case, merge algorithm is required
 		if err := a.Merge(r.Context(), tx, repo, changeId, mergeParents); err != nil {
 			http.Error(w, "merge: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
-	default:
-		http.Error(w, "new change must have one or two parents", http.StatusBadRequest)
+	}
+
+	// bookmarks are set last, protection rules check the merged change
+	for _, bookmark := range newChangeRequest.GetSetBookmarks() {
+		var current *int64
+		if currentId, err := tx.GetBookmark(r.Context(), repo.ID(), bookmark); err == nil {
+			current = &currentId
+		} else if !errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, "get bookmark: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := checkBookmarkRule(r.Context(), tx.Queries, repo, r.Username(), bookmark, current, changeId); err != nil {
+			writeBookmarkRuleError(w, "check bookmark rule", err)
+			return
+		}
+		// the new change is a child of its parents, a bookmark on one of their ancestors moves forward
+		if forward, err := movesForward(r.Context(), tx.Queries, current, changeId); err != nil {
+			http.Error(w, "check bookmark direction: "+err.Error(), http.StatusInternalServerError)
+			return
+		} else if !forward {
+			writeBookmarkBackwardsError(w, bookmark)
+			return
+		}
+		if err := tx.SetBookmark(r.Context(), repo.ID(), bookmark, changeId); err != nil {
+			http.Error(w, "set bookmark: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := op.finish(); err != nil {
+		http.Error(w, "log operation: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
@@ -421,6 +737,10 @@ func (a *App) handleNewChange(w http.ResponseWriter, r *signedhttp.Request) {
 func (a *App) handleCheckout(w http.ResponseWriter, r *signedhttp.Request) {
 	repo, err := a.openRepo(r.PathValue("repo"))
 	if err != nil {
+		if errors.Is(err, repos.ErrRepoNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
 		http.Error(w, "open repository: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -432,12 +752,26 @@ func (a *App) handleCheckout(w http.ResponseWriter, r *signedhttp.Request) {
 		return
 	}
 
-	// send the whole change via one tar stream
-	files, err := db.Q.ListChangeFiles(r.Context(), checkoutReq.ChangeId)
+	if ok, err := changeInRepo(r.Context(), db.Q, repo, checkoutReq.ChangeId); err != nil {
+		http.Error(w, "find change: "+err.Error(), http.StatusInternalServerError)
+		return
+	} else if !ok {
+		http.Error(w, fmt.Sprintf("change %d not found", checkoutReq.ChangeId), http.StatusNotFound)
+		return
+	}
+
+	// send the whole change via one tar stream,
+	// conflicted files without content of their own are rendered with conflict markers from their versions
+	files, err := listChangeFiles(r.Context(), db.Q, checkoutReq.ChangeId)
 	if err != nil {
 		http.Error(w, "list change files: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	rendered := make(renderedConflicts)
+	if err := rendered.render(repo, files); err != nil {
+		http.Error(w, "render conflicts: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
 	tarWriter := tar.NewWriter(w)
 	defer tarWriter.Close()
 	for _, fileInfo := range files {
@@ -450,12 +784,30 @@ func (a *App) handleCheckout(w http.ResponseWriter, r *signedhttp.Request) {
 		} else {
 			header.Mode = 0644
 		}
+		if txt, ok := rendered[string(fileInfo.ContentHash)]; ok {
+			// the tar carries compressed contents like the blob store has them
+			compressed, err := io.ReadAll(utils.Compress(txt.Reader()))
+			if err != nil {
+				http.Error(w, "compress rendered conflict: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			header.Size = int64(len(compressed))
+			if err := tarWriter.WriteHeader(header); err != nil {
+				http.Error(w, "write tar header: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if _, err := tarWriter.Write(compressed); err != nil {
+				http.Error(w, "write rendered conflict: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			continue
+		}
 		stat, err := repo.GetFileInfo(fileInfo.ContentHash)
 		if err != nil {
 			http.Error(w, "get file info: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
-		header.Size = stat.Size()
+		header.Size = stat.Size
 		if err := tarWriter.WriteHeader(header); err != nil {
 			http.Error(w, "write tar header: "+err.Error(), http.StatusInternalServerError)
 			return
@@ -475,22 +827,37 @@ func (a *App) handleCheckout(w http.ResponseWriter, r *signedhttp.Request) {
 	}
 }
 
-func (a *App) handleSetBookmark(w http.ResponseWriter, r *signedhttp.Request) {
+// handleSetHead moves the head bookmark of the requesting user and machine.
+// Unlike set_bookmark, this only requires read access because the head is private to the caller.
+func (a *App) handleSetHead(w http.ResponseWriter, r *signedhttp.Request) {
 	repo, err := a.openRepo(r.PathValue("repo"))
 	if err != nil {
+		if errors.Is(err, repos.ErrRepoNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
 		http.Error(w, "open repository: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	req := new(protos.SetBookmarkRequest)
+	req := new(protos.SetHeadRequest)
 	err = protos.Unmarshal(r.Body(), req)
 	if err != nil {
-		http.Error(w, "unmarshal set bookmark request: "+err.Error(), http.StatusBadRequest)
+		http.Error(w, "unmarshal set head request: "+err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	if err = db.Q.SetBookmark(r.Context(), repo.ID(), req.Bookmark, req.ChangeId); err != nil {
-		http.Error(w, "set bookmark: "+err.Error(), http.StatusInternalServerError)
+	if ok, err := changeInRepo(r.Context(), db.Q, repo, req.ChangeId); err != nil {
+		http.Error(w, "find change: "+err.Error(), http.StatusInternalServerError)
+		return
+	} else if !ok {
+		http.Error(w, fmt.Sprintf("change %d not found", req.ChangeId), http.StatusNotFound)
+		return
+	}
+
+	headName := headBookmark(r)
+	if err = db.Q.SetBookmark(r.Context(), repo.ID(), headName, req.ChangeId); err != nil {
+		http.Error(w, "set head: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
@@ -500,6 +867,10 @@ func (a *App) handleSetBookmark(w http.ResponseWriter, r *signedhttp.Request) {
 func (a *App) handleLog(w http.ResponseWriter, r *signedhttp.Request) {
 	repo, err := a.openRepo(r.PathValue("repo"))
 	if err != nil {
+		if errors.Is(err, repos.ErrRepoNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
 		http.Error(w, "open repository: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -511,43 +882,68 @@ func (a *App) handleLog(w http.ResponseWriter, r *signedhttp.Request) {
 		return
 	}
 
-	head, err := db.Q.FindChange(r.Context(), repo.ID(), req.Head)
+	env := revsetEnv(r, db.Q, repo)
+
+	head, err := env.ResolveOne(req.Head)
 	if err != nil {
-		http.Error(w, "find change: "+err.Error(), http.StatusInternalServerError)
+		writeRevsetError(w, "find change", err)
 		return
 	}
 
-	sb := &strings.Builder{}
-
-	var tz *time.Location
-	if req.TimeZone == "" {
-		tz = time.UTC
-	} else {
-		tz, err = time.LoadLocation(req.TimeZone)
-		if err != nil {
-			http.Error(w, "load time zone: "+err.Error(), http.StatusInternalServerError)
+	var selected []int64
+	if req.Revset != "" {
+		if selected, err = env.Resolve(req.Revset); err != nil {
+			writeRevsetError(w, "resolve revset", err)
 			return
 		}
+		if selected == nil {
+			selected = []int64{}
+		}
 	}
 
-	err = repo.PrintLog(repos.LogOptions{
-		r.Context(),
-		sb,
-		req.Limit,
-		tz,
-		head,
+	changes, edges, err := repo.Log(repos.LogOptions{
+		Ctx:     r.Context(),
+		Limit:   req.Limit,
+		Head:    head,
+		Changes: selected,
 	})
 	if err != nil {
-		http.Error(w, "print log: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "log: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	_ = protos.MarshalWrite(&protos.LogResponse{Log: sb.String()}, w)
+	resp := &protos.LogResponse{Head: head}
+	for _, change := range changes {
+		resp.Changes = append(resp.Changes, &protos.LogChange{
+			Id:           change.ID,
+			Name:         change.Name,
+			Prefix:       change.Prefix,
+			Description:  change.Description,
+			Author:       change.Author,
+			Device:       change.Device,
+			Depth:        change.Depth,
+			CreatedAt:    timestamppb.New(change.CreatedAt),
+			UpdatedAt:    timestamppb.New(change.UpdatedAt),
+			HasConflicts: change.HasConflicts,
+		})
+	}
+	for _, edge := range edges {
+		resp.Edges = append(resp.Edges, &protos.LogEdge{
+			ChangeId: edge.ChangeID,
+			ParentId: edge.ParentID,
+		})
+	}
+
+	_ = protos.MarshalWrite(resp, w)
 }
 
 func (a *App) handleConflicts(w http.ResponseWriter, r *signedhttp.Request) {
 	repo, err := a.openRepo(r.PathValue("repo"))
 	if err != nil {
+		if errors.Is(err, repos.ErrRepoNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
 		http.Error(w, "open repository: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -559,24 +955,37 @@ func (a *App) handleConflicts(w http.ResponseWriter, r *signedhttp.Request) {
 		return
 	}
 
-	changeId, err := db.Q.FindChange(r.Context(), repo.ID(), req.Change)
+	changeId, err := revsetEnv(r, db.Q, repo).ResolveOne(req.Change)
 	if err != nil {
-		http.Error(w, "find change: "+err.Error(), http.StatusInternalServerError)
+		writeRevsetError(w, "find change", err)
 		return
 	}
 
-	conflicts, err := db.Q.GetChangeConflicts(r.Context(), changeId)
+	files, err := listChangeFiles(r.Context(), db.Q, changeId)
 	if err != nil {
 		http.Error(w, "get change conflicts: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	_ = protos.MarshalWrite(&protos.ConflictsResponse{Conflicts: conflicts}, w)
+	resp := new(protos.ConflictsResponse)
+	for _, f := range files {
+		if f.conflict == nil {
+			continue
+		}
+		resp.Conflicts = append(resp.Conflicts, f.Name)
+		resp.Files = append(resp.Files, protoConflict(f.Name, f.conflict))
+	}
+
+	_ = protos.MarshalWrite(resp, w)
 }
 
 func (a *App) handleFindChange(w http.ResponseWriter, r *signedhttp.Request) {
 	repo, err := a.openRepo(r.PathValue("repo"))
 	if err != nil {
+		if errors.Is(err, repos.ErrRepoNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
 		http.Error(w, "open repository: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -588,9 +997,9 @@ func (a *App) handleFindChange(w http.ResponseWriter, r *signedhttp.Request) {
 		return
 	}
 
-	changeId, err := db.Q.FindChange(r.Context(), repo.ID(), req.Name)
+	changeId, err := revsetEnv(r, db.Q, repo).ResolveOne(req.Name)
 	if err != nil {
-		http.Error(w, "find change: "+err.Error(), http.StatusNotFound)
+		writeRevsetError(w, "find change", err)
 		return
 	}
 
@@ -605,6 +1014,10 @@ func (a *App) handleFindChange(w http.ResponseWriter, r *signedhttp.Request) {
 func (a *App) handleDescribe(w http.ResponseWriter, r *signedhttp.Request) {
 	repo, err := a.openRepo(r.PathValue("repo"))
 	if err != nil {
+		if errors.Is(err, repos.ErrRepoNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
 		http.Error(w, "open repository: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -616,13 +1029,30 @@ func (a *App) handleDescribe(w http.ResponseWriter, r *signedhttp.Request) {
 		return
 	}
 
-	changeId, err := db.Q.FindChange(r.Context(), repo.ID(), req.Change)
+	tx, err := db.Q.Begin(r.Context())
 	if err != nil {
-		http.Error(w, fmt.Sprintf("find change %s: %s", req.Change, err.Error()), http.StatusInternalServerError)
+		http.Error(w, "begin transaction: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	defer tx.Close()
 
-	if err = db.Q.SetChangeDescription(
+	changeId, err := revsetEnv(r, tx.Queries, repo).ResolveOne(req.Change)
+	if err != nil {
+		writeRevsetError(w, "find change "+req.Change, err)
+		return
+	}
+
+	op, err := beginOperation(r, tx.Queries, repo, "describe")
+	if err != nil {
+		http.Error(w, "begin operation: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := op.touch(changeId); err != nil {
+		http.Error(w, "snapshot change: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err = tx.SetChangeDescription(
 		r.Context(),
 		changeId,
 		utils.Ptr(req.Description),
@@ -632,6 +1062,20 @@ func (a *App) handleDescribe(w http.ResponseWriter, r *signedhttp.Request) {
 		http.Error(w, "set change description: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if err := checkChangeBookmarkRules(r.Context(), tx.Queries, repo, r.Username(), changeId); err != nil {
+		writeBookmarkRuleError(w, "check bookmark rules", err)
+		return
+	}
+
+	if err := op.finish(); err != nil {
+		http.Error(w, "log operation: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		http.Error(w, "commit transaction: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
 
 	w.WriteHeader(http.StatusOK)
 }
@@ -639,6 +1083,10 @@ func (a *App) handleDescribe(w http.ResponseWriter, r *signedhttp.Request) {
 func (a *App) handleListBookmarks(w http.ResponseWriter, r *signedhttp.Request) {
 	repo, err := a.openRepo(r.PathValue("repo"))
 	if err != nil {
+		if errors.Is(err, repos.ErrRepoNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
 		http.Error(w, "open repository: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
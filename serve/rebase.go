@@ -0,0 +1,231 @@
+package serve
+
+import (
+	"cmp"
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/tsukinoko-kun/pogo/db"
+	"github.com/tsukinoko-kun/pogo/protos"
+	"github.com/tsukinoko-kun/pogo/repos"
+	"github.com/tsukinoko-kun/pogo/signedhttp"
+)
+
+// handleRebase moves a change and its descendants onto a new parent.
+// The diff of the change against its old parent is replayed onto the new parent with a three-way merge,
+// descendants are replayed onto their rebased parents the same way. Overlapping edits become conflicts.
+// Bookmarks keep pointing at the rebased changes. The source and all of its descendants must be owned by the user and machine.
+func (a *App) handleRebase(w http.ResponseWriter, r *signedhttp.Request) {
+	repo, err := a.openRepo(r.PathValue("repo"))
+	if err != nil {
+		if errors.Is(err, repos.ErrRepoNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "open repository: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	req := new(protos.RebaseRequest)
+	err = protos.Unmarshal(r.Body(), req)
+	if err != nil {
+		http.Error(w, "unmarshal rebase request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Q.Begin(r.Context())
+	if err != nil {
+		http.Error(w, "begin transaction: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Close()
+
+	env := revsetEnv(r, tx.Queries, repo)
+	sourceId, err := env.ResolveOne(req.Source)
+	if err != nil {
+		writeRevsetError(w, "find change "+req.Source, err)
+		return
+	}
+	destId, err := env.ResolveOne(req.Destination)
+	if err != nil {
+		writeRevsetError(w, "find change "+req.Destination, err)
+		return
+	}
+
+	resp := new(protos.RebaseResponse)
+	if resp.SourceName, err = tx.GetChangeName(r.Context(), sourceId, repo.ID()); err != nil {
+		http.Error(w, "get change name: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if resp.DestinationName, err = tx.GetChangeName(r.Context(), destId, repo.ID()); err != nil {
+		http.Error(w, "get change name: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if destDescendant, err := tx.IsAncestor(r.Context(), destId, sourceId); err != nil {
+		http.Error(w, "check destination: "+err.Error(), http.StatusInternalServerError)
+		return
+	} else if destDescendant {
+		http.Error(w, "cannot rebase "+resp.SourceName+" onto itself or its descendant "+resp.DestinationName, http.StatusBadRequest)
+		return
+	}
+
+	// all descendants move with the source, even those whose files don't change.
+	// Like a push, only the owner may rewrite them, the next push of the working copy of another user
+	// or machine would silently revert the rebase otherwise.
+	moved, err := tx.RevsetDescendants(r.Context(), []int64{sourceId}, repo.ID())
+	if err != nil {
+		http.Error(w, "get descendants: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for _, id := range moved {
+		if err := checkChangeOwner(r, tx.Queries, repo, id); err != nil {
+			writeRewriteError(w, "check change owner", err)
+			return
+		}
+	}
+
+	op, err := beginOperation(r, tx.Queries, repo, "rebase")
+	if err != nil {
+		http.Error(w, "begin operation: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	parents, err := tx.RevsetParents(r.Context(), []int64{sourceId}, repo.ID())
+	if err != nil {
+		http.Error(w, "get parents: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(parents) != 1 {
+		http.Error(w, "can only rebase changes with exactly one parent", http.StatusBadRequest)
+		return
+	}
+	parentId := parents[0]
+
+	// the bookmarks on the moved changes move in the history with them
+	for _, id := range moved {
+		if err := checkRebaseBookmarkRules(r.Context(), tx.Queries, repo, id, parentId, destId); err != nil {
+			writeBookmarkRuleError(w, "check bookmark rules", err)
+			return
+		}
+	}
+
+	sourceFiles, err := listChangeFiles(r.Context(), tx.Queries, sourceId)
+	if err != nil {
+		http.Error(w, "get source files: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	parentFiles, err := listChangeFiles(r.Context(), tx.Queries, parentId)
+	if err != nil {
+		http.Error(w, "get parent files: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	destFiles, err := listChangeFiles(r.Context(), tx.Queries, destId)
+	if err != nil {
+		http.Error(w, "get destination files: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	parentName, err := tx.GetChangeName(r.Context(), parentId, repo.ID())
+	if err != nil {
+		http.Error(w, "get change name: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rebasedFiles, err := mergeFileTrees(
+		r.Context(), tx.Queries, repo,
+		overlapChange{name: parentName, files: parentFiles},
+		[]overlapChange{{name: resp.DestinationName, files: destFiles}, {name: resp.SourceName, files: sourceFiles}},
+	)
+	if err != nil {
+		http.Error(w, "merge source onto destination: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := op.touch(sourceId); err != nil {
+		http.Error(w, "snapshot change: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := tx.RemoveChangeParent(r.Context(), sourceId, &parentId); err != nil {
+		http.Error(w, "remove parent: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := tx.SetChangeParent(r.Context(), sourceId, &destId); err != nil {
+		http.Error(w, "set parent change: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := updateDepths(r.Context(), tx.Queries, repo, op, []int64{sourceId}); err != nil {
+		http.Error(w, "update depths: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := setChangeFiles(r.Context(), tx.Queries, op, sourceId, rebasedFiles); err != nil {
+		http.Error(w, "set source files: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	oldFiles := map[int64][]changeFile{sourceId: sourceFiles}
+	if err := rebaseDescendants(newDBRewriter(r, tx.Queries, repo, op), oldFiles, []int64{sourceId}); err != nil {
+		writeRewriteError(w, "rebase descendants", err)
+		return
+	}
+
+	rebased := moved
+	depths := make(map[int64]int64, len(rebased))
+	for _, id := range rebased {
+		if depths[id], err = tx.GetChangeDepth(r.Context(), id, repo.ID()); err != nil {
+			http.Error(w, "get change depth: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	slices.SortFunc(rebased, func(a, b int64) int {
+		return cmp.Or(cmp.Compare(depths[a], depths[b]), cmp.Compare(a, b))
+	})
+
+	headName := headBookmark(r)
+	for _, id := range rebased {
+		name, err := tx.GetChangeName(r.Context(), id, repo.ID())
+		if err != nil {
+			http.Error(w, "get change name: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		resp.Rebased = append(resp.Rebased, name)
+
+		if conflicts, err := tx.HasChangeConflicts(r.Context(), id); err != nil {
+			http.Error(w, "check change conflicts: "+err.Error(), http.StatusInternalServerError)
+			return
+		} else if conflicts {
+			resp.Conflicts = append(resp.Conflicts, name)
+		}
+
+		// the bookmarks stay on the rewritten change, which must still satisfy their rules
+		if err := checkChangeBookmarkRules(r.Context(), tx.Queries, repo, r.Username(), id); err != nil {
+			writeBookmarkRuleError(w, "check bookmark rules", err)
+			return
+		}
+		bookmarks, err := tx.ListChangeBookmarks(r.Context(), repo.ID(), id)
+		if err != nil {
+			http.Error(w, "list bookmarks: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, bookmark := range bookmarks {
+			switch {
+			case bookmark == headName:
+				resp.HeadRebased = true
+			case !isHeadBookmark(bookmark):
+				resp.Bookmarks = append(resp.Bookmarks, bookmark)
+			}
+		}
+	}
+
+	if err := op.finish(); err != nil {
+		http.Error(w, "log operation: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		http.Error(w, "commit transaction: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_ = protos.MarshalWrite(resp, w)
+}
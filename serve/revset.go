@@ -0,0 +1,51 @@
+package serve
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/tsukinoko-kun/pogo/db"
+	"github.com/tsukinoko-kun/pogo/repos"
+	"github.com/tsukinoko-kun/pogo/revset"
+	"github.com/tsukinoko-kun/pogo/signedhttp"
+)
+
+// headBookmark is the name of the bookmark that tracks the working copy of the requesting user and machine.
+func headBookmark(r *signedhttp.Request) string {
+	return fmt.Sprintf("__head-%s-%s", r.Username(), r.MachineID())
+}
+
+// isHeadBookmark reports whether the bookmark tracks the working copy of any user and machine.
+func isHeadBookmark(name string) bool {
+	return strings.HasPrefix(name, "__head-")
+}
+
+// revsetEnv evaluates revsets of the request against the repository.
+func revsetEnv(r *signedhttp.Request, q *db.Queries, repo repos.Repo) revset.Env {
+	return revset.Env{
+		Ctx:          r.Context(),
+		Q:            q,
+		RepositoryID: repo.ID(),
+		Head:         headBookmark(r),
+	}
+}
+
+// writeRevsetError writes an error response for a revset that could not be resolved.
+// Mistakes in the revset are client errors, everything else is an internal error.
+func writeRevsetError(w http.ResponseWriter, context string, err error) {
+	var syntaxErr *revset.SyntaxError
+	switch {
+	case errors.As(err, &syntaxErr),
+		errors.Is(err, revset.ErrMultipleChanges),
+		errors.Is(err, revset.ErrInvalidPattern),
+		errors.Is(err, db.ErrAmbiguousChange):
+		http.Error(w, context+": "+err.Error(), http.StatusBadRequest)
+	case errors.Is(err, revset.ErrUnknownRevision),
+		errors.Is(err, revset.ErrNoChange):
+		http.Error(w, context+": "+err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, context+": "+err.Error(), http.StatusInternalServerError)
+	}
+}
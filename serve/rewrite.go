@@ -0,0 +1,371 @@
+package serve
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/tsukinoko-kun/pogo/db"
+	"github.com/tsukinoko-kun/pogo/repos"
+	"github.com/tsukinoko-kun/pogo/serve/serveerrors"
+	"github.com/tsukinoko-kun/pogo/signedhttp"
+	"github.com/tsukinoko-kun/pogo/utils"
+)
+
+// changeFile is a file of a change. A conflicted path carries its conflict,
+// a path without content hash has no content of its own and is rendered from the conflict.
+type changeFile struct {
+	db.ListChangeFilesRow
+	conflict *fileConflict
+}
+
+// fileTree maps the file names of a change to its files.
+type fileTree map[string]changeFile
+
+func newFileTree(files []changeFile) fileTree {
+	s := make(fileTree, len(files))
+	for _, f := range files {
+		s[f.Name] = f
+	}
+	return s
+}
+
+func (s fileTree) files() []changeFile {
+	files := make([]changeFile, 0, len(s))
+	for _, f := range s {
+		files = append(files, f)
+	}
+	slices.SortFunc(files, func(a, b changeFile) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return files
+}
+
+func sameFile(a, b changeFile) bool {
+	return a.Executable == b.Executable && sameContent(a, b)
+}
+
+func sameContent(a, b changeFile) bool {
+	return bytes.Equal(a.ContentHash, b.ContentHash) && sameConflict(a.conflict, b.conflict)
+}
+
+// changedPaths returns the names of all files that differ between the trees.
+func changedPaths(a, b fileTree) []string {
+	var names []string
+	for name, fa := range a {
+		if fb, ok := b[name]; !ok || !sameFile(fa, fb) {
+			names = append(names, name)
+		}
+	}
+	for name := range b {
+		if _, ok := a[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// withPaths returns a copy of s where the given paths have the state they have in from.
+func (s fileTree) withPaths(from fileTree, paths []string) fileTree {
+	res := make(fileTree, len(s))
+	for name, f := range s {
+		res[name] = f
+	}
+	for _, name := range paths {
+		if f, ok := from[name]; ok {
+			res[name] = f
+		} else {
+			delete(res, name)
+		}
+	}
+	return res
+}
+
+// setChangeFiles replaces the files of a change.
+func setChangeFiles(ctx context.Context, q db.Querier, op *operation, changeId int64, files []changeFile) error {
+	if err := op.touch(changeId); err != nil {
+		return err
+	}
+	if err := q.ClearChange(ctx, changeId); err != nil {
+		return errors.Join(errors.New("clear change"), err)
+	}
+	return addChangeFiles(ctx, q, changeId, files)
+}
+
+// addChangeFiles adds files to a change, together with their conflicts.
+func addChangeFiles(ctx context.Context, q db.Querier, changeId int64, files []changeFile) error {
+	for _, f := range files {
+		// rendered conflicts have no file row
+		if f.ContentHash != nil {
+			fileId, err := db.UpsertFile(q, ctx, f.Name, utils.Ptr(f.Executable), f.ContentHash)
+			if err != nil {
+				return errors.Join(fmt.Errorf("upsert file %s", f.Name), err)
+			}
+			if err := q.AddFileToChange(ctx, changeId, fileId); err != nil {
+				return errors.Join(fmt.Errorf("add file %s to change %d", f.Name, changeId), err)
+			}
+		}
+		if f.conflict != nil {
+			if err := addChangeConflict(ctx, q, changeId, f.Name, f.conflict); err != nil {
+				return errors.Join(fmt.Errorf("add conflict of file %s to change %d", f.Name, changeId), err)
+			}
+		}
+	}
+	return nil
+}
+
+// changeRewriter is the part of a repository that rewriting changes reads and writes.
+type changeRewriter interface {
+	// descendants returns the descendants of roots including the roots, parents before their children.
+	descendants(roots []int64) ([]int64, error)
+	parents(id int64) ([]int64, error)
+	name(id int64) (string, error)
+	files(id int64) ([]changeFile, error)
+	// merge merges the sides that changed relative to base, like mergeFileTrees.
+	merge(base overlapChange, sides []overlapChange) ([]changeFile, error)
+	// rewrite replaces the files of a change.
+	// It fails with serveerrors.ErrRewriteChangeNotOwned if the change may not be rewritten.
+	rewrite(id int64, files []changeFile) error
+}
+
+// dbRewriter rewrites the changes of a repository for the user and machine of a request.
+// It remembers the rewritten changes, so the rules of their bookmarks can be checked once the rewrite is done.
+type dbRewriter struct {
+	r         *signedhttp.Request
+	q         *db.Queries
+	repo      repos.Repo
+	op        *operation
+	rewritten []int64
+}
+
+func newDBRewriter(r *signedhttp.Request, q *db.Queries, repo repos.Repo, op *operation) *dbRewriter {
+	return &dbRewriter{r: r, q: q, repo: repo, op: op}
+}
+
+func (d *dbRewriter) descendants(roots []int64) ([]int64, error) {
+	ids, err := d.q.RevsetDescendants(d.r.Context(), roots, d.repo.ID())
+	if err != nil {
+		return nil, errors.Join(errors.New("get descendants"), err)
+	}
+	depths := make(map[int64]int64, len(ids))
+	for _, id := range ids {
+		if depths[id], err = d.q.GetChangeDepth(d.r.Context(), id, d.repo.ID()); err != nil {
+			return nil, errors.Join(errors.New("get change depth"), err)
+		}
+	}
+	slices.SortFunc(ids, func(a, b int64) int {
+		return cmp.Compare(depths[a], depths[b])
+	})
+	return ids, nil
+}
+
+func (d *dbRewriter) parents(id int64) ([]int64, error) {
+	parents, err := d.q.RevsetParents(d.r.Context(), []int64{id}, d.repo.ID())
+	if err != nil {
+		return nil, errors.Join(errors.New("get parents"), err)
+	}
+	return parents, nil
+}
+
+func (d *dbRewriter) name(id int64) (string, error) {
+	name, err := d.q.GetChangeName(d.r.Context(), id, d.repo.ID())
+	if err != nil {
+		return "", errors.Join(errors.New("get change name"), err)
+	}
+	return name, nil
+}
+
+func (d *dbRewriter) files(id int64) ([]changeFile, error) {
+	return listChangeFiles(d.r.Context(), d.q, id)
+}
+
+func (d *dbRewriter) merge(base overlapChange, sides []overlapChange) ([]changeFile, error) {
+	return mergeFileTrees(d.r.Context(), d.q, d.repo, base, sides)
+}
+
+func (d *dbRewriter) rewrite(id int64, files []changeFile) error {
+	if err := checkChangeOwner(d.r, d.q, d.repo, id); err != nil {
+		return err
+	}
+	if err := setChangeFiles(d.r.Context(), d.q, d.op, id, files); err != nil {
+		return err
+	}
+	if !slices.Contains(d.rewritten, id) {
+		d.rewritten = append(d.rewritten, id)
+	}
+	return nil
+}
+
+// checkBookmarkRules checks the rules of the bookmarks on all rewritten changes.
+func (d *dbRewriter) checkBookmarkRules() error {
+	for _, id := range d.rewritten {
+		if err := checkChangeBookmarkRules(d.r.Context(), d.q, d.repo, d.r.Username(), id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkChangeOwner fails with serveerrors.ErrRewriteChangeNotOwned if the change was created by another user
+// or on another machine than the request. Like a push, only the owner may rewrite a change,
+// the next push of the owner would silently revert the rewrite otherwise.
+func checkChangeOwner(r *signedhttp.Request, q db.Querier, repo repos.Repo, changeId int64) error {
+	owner, err := q.GetChangeOwner(r.Context(), changeId)
+	if err != nil {
+		return errors.Join(errors.New("get change owner"), err)
+	}
+	if owner.Author == r.Username() && owner.Device == r.MachineID() {
+		return nil
+	}
+	name, err := q.GetChangeName(r.Context(), changeId, repo.ID())
+	if err != nil {
+		return errors.Join(errors.New("get change name"), err)
+	}
+	return fmt.Errorf("%w: %s", serveerrors.ErrRewriteChangeNotOwned, name)
+}
+
+// writeRewriteError writes an error response for a failed rewrite.
+// Changes of others can't be rewritten, violated bookmark rules are forbidden and everything else is an internal error.
+func writeRewriteError(w http.ResponseWriter, context string, err error) {
+	if errors.Is(err, serveerrors.ErrRewriteChangeNotOwned) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeBookmarkRuleError(w, context, err)
+}
+
+// rebaseDescendants replays the descendants of rewritten changes onto their new file states.
+// oldFiles holds the files every rewritten change had before it was rewritten. It must contain the roots and
+// is extended with every descendant that changes.
+// Each descendant keeps its diff against the old state of its rewritten parents, overlapping edits become conflicts.
+// Descendants that are roots themselves are rebased too, so a root can already be rewritten relative to its
+// old parent.
+func rebaseDescendants(rw changeRewriter, oldFiles map[int64][]changeFile, roots []int64) error {
+	ids, err := rw.descendants(roots)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		parents, err := rw.parents(id)
+		if err != nil {
+			return err
+		}
+		slices.Sort(parents)
+
+		files, err := rw.files(id)
+		if err != nil {
+			return err
+		}
+		name, err := rw.name(id)
+		if err != nil {
+			return err
+		}
+
+		rebased := files
+		for _, parent := range parents {
+			parentOldFiles, ok := oldFiles[parent]
+			if !ok {
+				continue
+			}
+			parentFiles, err := rw.files(parent)
+			if err != nil {
+				return err
+			}
+			parentName, err := rw.name(parent)
+			if err != nil {
+				return err
+			}
+			rebased, err = rw.merge(
+				overlapChange{name: parentName + " (before rewrite)", files: parentOldFiles},
+				[]overlapChange{{name: parentName, files: parentFiles}, {name: name, files: rebased}},
+			)
+			if err != nil {
+				return errors.Join(fmt.Errorf("rebase change %s", name), err)
+			}
+		}
+
+		if len(changedPaths(newFileTree(files), newFileTree(rebased))) == 0 {
+			continue
+		}
+		if _, ok := oldFiles[id]; !ok {
+			oldFiles[id] = files
+		}
+		if err := rw.rewrite(id, rebased); err != nil {
+			return errors.Join(fmt.Errorf("set files of change %s", name), err)
+		}
+	}
+	return nil
+}
+
+// squashChanges moves the diff of source against its parent into into, only the given paths if there are any.
+// into is the parent or a change that is no descendant of source. The descendants of both are rebased.
+// It returns the files every rewritten change had before.
+func squashChanges(rw changeRewriter, sourceId int64, parentId int64, intoId int64, paths []string) (map[int64][]changeFile, error) {
+	sourceFiles, err := rw.files(sourceId)
+	if err != nil {
+		return nil, err
+	}
+	parentFiles, err := rw.files(parentId)
+	if err != nil {
+		return nil, err
+	}
+	intoFiles, err := rw.files(intoId)
+	if err != nil {
+		return nil, err
+	}
+
+	source := newFileTree(sourceFiles)
+	parent := newFileTree(parentFiles)
+	var selectedPaths []string
+	for _, name := range changedPaths(parent, source) {
+		if matchesPaths(name, paths) {
+			selectedPaths = append(selectedPaths, name)
+		}
+	}
+
+	// the selected diff applied to the parent, and the source without it
+	selected := parent.withPaths(source, selectedPaths).files()
+	rest := source.withPaths(parent, selectedPaths).files()
+
+	newIntoFiles := selected
+	if intoId != parentId {
+		sourceName, err := rw.name(sourceId)
+		if err != nil {
+			return nil, err
+		}
+		intoName, err := rw.name(intoId)
+		if err != nil {
+			return nil, err
+		}
+		newIntoFiles, err = rw.merge(
+			overlapChange{name: "parent of " + sourceName, files: parentFiles},
+			[]overlapChange{{name: intoName, files: intoFiles}, {name: sourceName, files: selected}},
+		)
+		if err != nil {
+			return nil, errors.Join(errors.New("merge into target"), err)
+		}
+	}
+
+	if err := rw.rewrite(intoId, newIntoFiles); err != nil {
+		return nil, errors.Join(errors.New("set target files"), err)
+	}
+	if err := rw.rewrite(sourceId, rest); err != nil {
+		return nil, errors.Join(errors.New("set source files"), err)
+	}
+
+	// the source is rebased too if the target is one of its ancestors
+	oldFiles := map[int64][]changeFile{
+		intoId:   intoFiles,
+		sourceId: sourceFiles,
+	}
+	if err := rebaseDescendants(rw, oldFiles, []int64{intoId, sourceId}); err != nil {
+		return nil, errors.Join(errors.New("rebase descendants"), err)
+	}
+	return oldFiles, nil
+}
@@ -0,0 +1,274 @@
+package serve
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/tsukinoko-kun/pogo/serve/serveerrors"
+)
+
+// testRewriter keeps a change graph and the files of its changes in memory, contents are stored in a mergeTestRepo.
+type testRewriter struct {
+	t           *testing.T
+	r           *mergeTestRepo
+	graph       testGraph
+	changeFiles map[int64][]changeFile
+	notOwned    map[int64]bool
+	rewritten   []int64
+}
+
+func newTestRewriter(t *testing.T) *testRewriter {
+	return &testRewriter{
+		t:           t,
+		r:           newMergeTestRepo(t),
+		graph:       make(testGraph),
+		changeFiles: make(map[int64][]changeFile),
+		notOwned:    make(map[int64]bool),
+	}
+}
+
+// change adds a change with the given parents and files, which map names to contents.
+func (rw *testRewriter) change(id int64, parents []int64, files map[string]string) {
+	rw.graph[id] = parents
+	rows := make([]changeFile, 0, len(files))
+	for name, content := range files {
+		rows = append(rows, rw.r.file(rw.t, name, content, false))
+	}
+	rw.changeFiles[id] = newFileTree(rows).files()
+}
+
+// contents returns the names and contents of the files of a change.
+func (rw *testRewriter) contents(id int64) map[string]string {
+	contents := make(map[string]string, len(rw.changeFiles[id]))
+	for _, f := range rw.changeFiles[id] {
+		if f.ContentHash == nil {
+			txt, err := renderConflict(rw.r.repo, f.conflict)
+			if err != nil {
+				rw.t.Fatal(err)
+			}
+			contents[f.Name] = txt.String()
+			continue
+		}
+		contents[f.Name] = rw.r.contents[string(f.ContentHash)]
+	}
+	return contents
+}
+
+func (rw *testRewriter) descendants(roots []int64) ([]int64, error) {
+	var ids []int64
+	for id := range rw.graph {
+		for _, root := range roots {
+			if id == root || rw.isAncestor(root, id) {
+				ids = append(ids, id)
+				break
+			}
+		}
+	}
+	slices.SortFunc(ids, func(a, b int64) int {
+		depthA, _ := rw.graph.depth(a)
+		depthB, _ := rw.graph.depth(b)
+		return cmp.Or(cmp.Compare(depthA, depthB), cmp.Compare(a, b))
+	})
+	return ids, nil
+}
+
+func (rw *testRewriter) isAncestor(ancestor int64, id int64) bool {
+	for _, parent := range rw.graph[id] {
+		if parent == ancestor || rw.isAncestor(ancestor, parent) {
+			return true
+		}
+	}
+	return false
+}
+
+func (rw *testRewriter) parents(id int64) ([]int64, error) {
+	return rw.graph[id], nil
+}
+
+func (rw *testRewriter) name(id int64) (string, error) {
+	return fmt.Sprintf("change%d", id), nil
+}
+
+func (rw *testRewriter) files(id int64) ([]changeFile, error) {
+	return rw.changeFiles[id], nil
+}
+
+func (rw *testRewriter) merge(base overlapChange, sides []overlapChange) ([]changeFile, error) {
+	merged, err := mergeFiles(rw.r.repo, base, sides)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]changeFile, 0, len(merged))
+	for _, f := range merged {
+		rw.r.store(rw.t, f)
+		files = append(files, f.changeFile)
+	}
+	return files, nil
+}
+
+func (rw *testRewriter) rewrite(id int64, files []changeFile) error {
+	if rw.notOwned[id] {
+		return serveerrors.ErrRewriteChangeNotOwned
+	}
+	rw.changeFiles[id] = files
+	rw.rewritten = append(rw.rewritten, id)
+	return nil
+}
+
+func assertContents(t *testing.T, rw *testRewriter, id int64, want map[string]string) {
+	t.Helper()
+	got := rw.contents(id)
+	if len(got) != len(want) {
+		t.Fatalf("change %d should have files %v, got %v", id, want, got)
+	}
+	for name, content := range want {
+		if got[name] != content {
+			t.Fatalf("change %d: %s should be %q, got %q", id, name, content, got[name])
+		}
+	}
+}
+
+func TestRebaseDescendantsSiblings(t *testing.T) {
+	rw := newTestRewriter(t)
+	rw.change(1, nil, map[string]string{"a.txt": "1\n2\n3\n4\n5"})
+	rw.change(2, []int64{1}, map[string]string{"a.txt": "one\n2\n3\n4\n5"})
+	rw.change(3, []int64{1}, map[string]string{"a.txt": "1\n2\n3\n4\nfive"})
+	rw.change(4, []int64{1}, map[string]string{"a.txt": "1\n2\n3\n4\n5", "b.txt": "b\n"})
+
+	oldFiles := map[int64][]changeFile{1: rw.changeFiles[1]}
+	rw.change(1, nil, map[string]string{"a.txt": "1\n2\nthree\n4\n5"})
+	if err := rebaseDescendants(rw, oldFiles, []int64{1}); err != nil {
+		t.Fatal(err)
+	}
+
+	assertContents(t, rw, 2, map[string]string{"a.txt": "one\n2\nthree\n4\n5"})
+	assertContents(t, rw, 3, map[string]string{"a.txt": "1\n2\nthree\n4\nfive"})
+	assertContents(t, rw, 4, map[string]string{"a.txt": "1\n2\nthree\n4\n5", "b.txt": "b\n"})
+	for _, id := range []int64{2, 3, 4} {
+		if _, ok := oldFiles[id]; !ok {
+			t.Fatalf("the old files of rebased change %d should be remembered", id)
+		}
+	}
+}
+
+func TestRebaseDescendantsUnchanged(t *testing.T) {
+	rw := newTestRewriter(t)
+	rw.change(1, nil, map[string]string{"a.txt": "a\n"})
+	rw.change(2, []int64{1}, map[string]string{"a.txt": "b\n"})
+	rw.notOwned[2] = true
+
+	// the descendant replaced the whole file, the rewrite of the parent doesn't change it
+	oldFiles := map[int64][]changeFile{1: rw.changeFiles[1]}
+	rw.change(1, nil, map[string]string{"a.txt": "b\n"})
+	if err := rebaseDescendants(rw, oldFiles, []int64{1}); err != nil {
+		t.Fatalf("a descendant that doesn't change must not be rewritten, got %v", err)
+	}
+	if len(rw.rewritten) != 0 {
+		t.Fatalf("no change should be rewritten, got %v", rw.rewritten)
+	}
+}
+
+func TestRebaseDescendantsNotOwned(t *testing.T) {
+	rw := newTestRewriter(t)
+	rw.change(1, nil, map[string]string{"a.txt": "1\n2\n3\n"})
+	rw.change(2, []int64{1}, map[string]string{"a.txt": "1\n2\n3\n", "b.txt": "b\n"})
+	rw.change(3, []int64{2}, map[string]string{"a.txt": "1\n2\nthree\n", "b.txt": "b\n"})
+	// like the working copy of another user on top of the rewritten change
+	rw.notOwned[3] = true
+
+	oldFiles := map[int64][]changeFile{1: rw.changeFiles[1]}
+	rw.change(1, nil, map[string]string{"a.txt": "one\n2\n3\n"})
+	err := rebaseDescendants(rw, oldFiles, []int64{1})
+	if !errors.Is(err, serveerrors.ErrRewriteChangeNotOwned) {
+		t.Fatalf("rebasing a change of another user should fail, got %v", err)
+	}
+}
+
+func TestSquashChangesPaths(t *testing.T) {
+	rw := newTestRewriter(t)
+	rw.change(1, nil, map[string]string{"a.txt": "a\n", "dir/b.txt": "b\n", "c.txt": "c\n"})
+	rw.change(2, []int64{1}, map[string]string{"a.txt": "a2\n", "dir/b.txt": "b2\n", "dir/new.txt": "new\n"})
+	rw.change(3, []int64{2}, map[string]string{"a.txt": "a2\n", "dir/b.txt": "b2\n", "dir/new.txt": "new\n", "d.txt": "d\n"})
+
+	if _, err := squashChanges(rw, 2, 1, 1, []string{"dir/"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// only the diff of dir moves into the parent, the deletion of c.txt and the edit of a.txt stay
+	assertContents(t, rw, 1, map[string]string{"a.txt": "a\n", "dir/b.txt": "b2\n", "dir/new.txt": "new\n", "c.txt": "c\n"})
+	assertContents(t, rw, 2, map[string]string{"a.txt": "a2\n", "dir/b.txt": "b2\n", "dir/new.txt": "new\n"})
+	assertContents(t, rw, 3, map[string]string{"a.txt": "a2\n", "dir/b.txt": "b2\n", "dir/new.txt": "new\n", "d.txt": "d\n"})
+}
+
+func TestSquashChangesAll(t *testing.T) {
+	rw := newTestRewriter(t)
+	rw.change(1, nil, map[string]string{"a.txt": "a\n"})
+	rw.change(2, []int64{1}, map[string]string{"a.txt": "a2\n", "b.txt": "b\n"})
+
+	if _, err := squashChanges(rw, 2, 1, 1, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	assertContents(t, rw, 1, map[string]string{"a.txt": "a2\n", "b.txt": "b\n"})
+	// the source ends up empty, the handler abandons it
+	assertContents(t, rw, 2, map[string]string{"a.txt": "a2\n", "b.txt": "b\n"})
+}
+
+func TestSquashChangesIntoSibling(t *testing.T) {
+	rw := newTestRewriter(t)
+	rw.change(1, nil, map[string]string{"a.txt": "1\n2\n3\n4\n5"})
+	rw.change(2, []int64{1}, map[string]string{"a.txt": "1\n2\n3\n4\nfive", "b.txt": "b\n"})
+	rw.change(3, []int64{1}, map[string]string{"a.txt": "one\n2\n3\n4\n5"})
+	rw.change(4, []int64{3}, map[string]string{"a.txt": "one\n2\nthree\n4\n5"})
+
+	oldFiles, err := squashChanges(rw, 2, 1, 3, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the diff of the source is merged into the target and replayed onto its descendants
+	assertContents(t, rw, 3, map[string]string{"a.txt": "one\n2\n3\n4\nfive", "b.txt": "b\n"})
+	assertContents(t, rw, 4, map[string]string{"a.txt": "one\n2\nthree\n4\nfive", "b.txt": "b\n"})
+	assertContents(t, rw, 2, map[string]string{"a.txt": "1\n2\n3\n4\n5"})
+	assertContents(t, rw, 1, map[string]string{"a.txt": "1\n2\n3\n4\n5"})
+	for _, id := range []int64{2, 3, 4} {
+		if _, ok := oldFiles[id]; !ok {
+			t.Fatalf("the old files of rewritten change %d should be returned", id)
+		}
+	}
+	if _, ok := oldFiles[1]; ok {
+		t.Fatal("the parent of the source is not rewritten")
+	}
+}
+
+func TestSquashChangesIntoAncestor(t *testing.T) {
+	rw := newTestRewriter(t)
+	rw.change(1, nil, map[string]string{"a.txt": "1\n2\n3\n"})
+	rw.change(2, []int64{1}, map[string]string{"a.txt": "1\n2\n3\n", "b.txt": "b\n"})
+	rw.change(3, []int64{2}, map[string]string{"a.txt": "1\n2\nthree\n", "b.txt": "b\n"})
+
+	if _, err := squashChanges(rw, 3, 2, 1, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	assertContents(t, rw, 1, map[string]string{"a.txt": "1\n2\nthree\n"})
+	assertContents(t, rw, 2, map[string]string{"a.txt": "1\n2\nthree\n", "b.txt": "b\n"})
+	// the source is rebased onto its rewritten parent and ends up empty
+	assertContents(t, rw, 3, map[string]string{"a.txt": "1\n2\nthree\n", "b.txt": "b\n"})
+}
+
+func TestSquashChangesNotOwned(t *testing.T) {
+	rw := newTestRewriter(t)
+	rw.change(1, nil, map[string]string{"a.txt": "1\n2\n3\n"})
+	rw.change(2, []int64{1}, map[string]string{"a.txt": "1\n2\n3\n", "b.txt": "b\n"})
+	rw.change(3, []int64{2}, map[string]string{"a.txt": "1\n2\nthree\n", "b.txt": "b\n"})
+	rw.notOwned[2] = true
+
+	// the change between source and target belongs to someone else
+	if _, err := squashChanges(rw, 3, 2, 1, nil); !errors.Is(err, serveerrors.ErrRewriteChangeNotOwned) {
+		t.Fatalf("squashing over a change of another user should fail, got %v", err)
+	}
+}
@@ -6,18 +6,36 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"time"
+
+	"github.com/tsukinoko-kun/pogo/gc"
+	"github.com/tsukinoko-kun/pogo/signedhttp"
 )
 
 type App struct {
-	server *http.Server
-	mux    *http.ServeMux
+	server             *http.Server
+	mux                *http.ServeMux
+	registrationMode   RegistrationMode
+	nonces             signedhttp.NonceStore
+	gcGracePeriod      time.Duration
+	operationRetention time.Duration
+	evologRetention    time.Duration
 }
 
 func NewApp() *App {
 	a := &App{
-		mux: http.NewServeMux(),
+		mux:                http.NewServeMux(),
+		registrationMode:   RegistrationApproval,
+		nonces:             signedhttp.NewMemoryNonceStore(),
+		gcGracePeriod:      gc.DefaultGracePeriod,
+		operationRetention: gc.DefaultOperationRetention,
 	}
 	a.mux.HandleFunc("/rpc/init", a.handleInit)
+	a.mux.HandleFunc("/rpc/clone", a.handleClone)
+	a.mux.HandleFunc("/rpc/register", a.handleRegister)
+	a.mux.HandleFunc("/rpc/pending_keys", a.handleListPendingKeys)
+	a.mux.HandleFunc("/rpc/approve_key", a.handleApproveKey)
+	a.mux.HandleFunc("/rpc/gc", a.handleGC)
 	a.mux.HandleFunc("/rpc/{repo}/{func}", a.handleRpc)
 	return a
 }
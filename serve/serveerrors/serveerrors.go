@@ -5,4 +5,10 @@ import "errors"
 var (
 	ErrPushToChangeWithChild = errors.New("pushing to a change that has children is not allowed")
 	ErrPushToChangeNotOwned  = errors.New("pushing to a change that was created by another user or device is not allowed")
+	ErrRewriteChangeNotOwned = errors.New("rewriting a change that was created by another user or device is not allowed")
+	ErrUndoOperationNotOwned = errors.New("undoing an operation of another user or device is not allowed")
+	ErrKeyNotRegistered      = errors.New("public key is not registered for this user, run 'pogo auth register'")
+	ErrKeyNotApproved        = errors.New("public key is waiting for approval by an admin")
+	ErrKeyOwnedByOtherUser   = errors.New("public key is already registered for another user")
+	ErrAdminRequired         = errors.New("this action requires admin rights")
 )
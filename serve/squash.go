@@ -0,0 +1,167 @@
+package serve
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/tsukinoko-kun/pogo/db"
+	"github.com/tsukinoko-kun/pogo/protos"
+	"github.com/tsukinoko-kun/pogo/repos"
+	"github.com/tsukinoko-kun/pogo/signedhttp"
+	"github.com/tsukinoko-kun/pogo/utils"
+)
+
+// handleSquash moves the diff of a change against its parent, or only the selected paths of it, into another change.
+// The target defaults to the parent. Descendants of both changes are rebased onto their new state
+// and the source is abandoned if it ends up empty.
+func (a *App) handleSquash(w http.ResponseWriter, r *signedhttp.Request) {
+	repo, err := a.openRepo(r.PathValue("repo"))
+	if err != nil {
+		if errors.Is(err, repos.ErrRepoNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "open repository: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	req := new(protos.SquashRequest)
+	err = protos.Unmarshal(r.Body(), req)
+	if err != nil {
+		http.Error(w, "unmarshal squash request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Q.Begin(r.Context())
+	if err != nil {
+		http.Error(w, "begin transaction: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Close()
+
+	env := revsetEnv(r, tx.Queries, repo)
+	sourceId, err := env.ResolveOne(req.Source)
+	if err != nil {
+		writeRevsetError(w, "find change "+req.Source, err)
+		return
+	}
+
+	resp := new(protos.SquashResponse)
+	if resp.SourceName, err = tx.GetChangeName(r.Context(), sourceId, repo.ID()); err != nil {
+		http.Error(w, "get change name: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	parents, err := tx.RevsetParents(r.Context(), []int64{sourceId}, repo.ID())
+	if err != nil {
+		http.Error(w, "get parents: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(parents) != 1 {
+		http.Error(w, "can only squash changes with exactly one parent, "+resp.SourceName+" has "+strconv.Itoa(len(parents)), http.StatusBadRequest)
+		return
+	}
+	parentId := parents[0]
+
+	intoId := parentId
+	if req.Into != "" {
+		if intoId, err = env.ResolveOne(req.Into); err != nil {
+			writeRevsetError(w, "find change "+req.Into, err)
+			return
+		}
+	}
+	if resp.IntoName, err = tx.GetChangeName(r.Context(), intoId, repo.ID()); err != nil {
+		http.Error(w, "get change name: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if intoId == sourceId {
+		http.Error(w, "cannot squash a change into itself", http.StatusBadRequest)
+		return
+	}
+	if intoDescendant, err := tx.IsAncestor(r.Context(), intoId, sourceId); err != nil {
+		http.Error(w, "check target: "+err.Error(), http.StatusInternalServerError)
+		return
+	} else if intoDescendant {
+		http.Error(w, "cannot squash "+resp.SourceName+" into its descendant "+resp.IntoName, http.StatusBadRequest)
+		return
+	}
+
+	op, err := beginOperation(r, tx.Queries, repo, "squash")
+	if err != nil {
+		http.Error(w, "begin operation: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rw := newDBRewriter(r, tx.Queries, repo, op)
+	if _, err := squashChanges(rw, sourceId, parentId, intoId, req.Paths); err != nil {
+		writeRewriteError(w, "squash", err)
+		return
+	}
+
+	sourceFiles, err := listChangeFiles(r.Context(), tx.Queries, sourceId)
+	if err != nil {
+		http.Error(w, "get source files: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	parentFiles, err := listChangeFiles(r.Context(), tx.Queries, parentId)
+	if err != nil {
+		http.Error(w, "get parent files: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	empty := len(changedPaths(newFileTree(parentFiles), newFileTree(sourceFiles))) == 0
+
+	description := req.Description
+	if description == nil && empty {
+		var descriptions []string
+		for _, id := range []int64{intoId, sourceId} {
+			d, err := tx.GetChangeDescription(r.Context(), id)
+			if err != nil {
+				http.Error(w, "get change description: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if d != nil && strings.TrimSpace(*d) != "" {
+				descriptions = append(descriptions, strings.TrimSpace(*d))
+			}
+		}
+		if len(descriptions) > 0 {
+			description = utils.Ptr(strings.Join(descriptions, "\n\n"))
+		}
+	}
+	if description != nil {
+		if err := op.touch(intoId); err != nil {
+			http.Error(w, "snapshot change: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := tx.SetChangeDescription(r.Context(), intoId, description, r.Username(), r.MachineID()); err != nil {
+			http.Error(w, "set description: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if empty {
+		if resp.Abandoned, err = abandonChange(r, tx.Queries, repo, op, sourceId); err != nil {
+			writeBookmarkRuleError(w, "abandon source", err)
+			return
+		}
+	}
+
+	// the bookmarks of an abandoned source were already moved and checked
+	if err := rw.checkBookmarkRules(); err != nil {
+		writeBookmarkRuleError(w, "check bookmark rules", err)
+		return
+	}
+
+	if err := op.finish(); err != nil {
+		http.Error(w, "log operation: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		http.Error(w, "commit transaction: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_ = protos.MarshalWrite(resp, w)
+}
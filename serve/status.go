@@ -0,0 +1,85 @@
+package serve
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/tsukinoko-kun/pogo/db"
+	"github.com/tsukinoko-kun/pogo/protos"
+	"github.com/tsukinoko-kun/pogo/repos"
+	"github.com/tsukinoko-kun/pogo/signedhttp"
+	"github.com/tsukinoko-kun/pogo/utils"
+)
+
+// handleStatus describes the change the head of the requesting user and machine points at.
+// It changes nothing, so clients can compare their working copy without pushing.
+func (a *App) handleStatus(w http.ResponseWriter, r *signedhttp.Request) {
+	repo, err := a.openRepo(r.PathValue("repo"))
+	if err != nil {
+		if errors.Is(err, repos.ErrRepoNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "open repository: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	changeId, err := db.Q.GetBookmark(r.Context(), repo.ID(), headBookmark(r))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, "no change checked out", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "get head: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := &protos.StatusResponse{ChangeId: changeId}
+
+	if resp.ChangeName, err = db.Q.GetChangeName(r.Context(), changeId, repo.ID()); err != nil {
+		http.Error(w, "get change name: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if resp.ChangePrefix, err = db.Q.GetChangePrefix(r.Context(), changeId, repo.ID()); err != nil {
+		http.Error(w, "get change prefix: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if resp.Description, err = db.Q.GetChangeDescription(r.Context(), changeId); err != nil {
+		http.Error(w, "get change description: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	owner, err := db.Q.GetChangeOwner(r.Context(), changeId)
+	if err != nil {
+		http.Error(w, "get change owner: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	resp.Author = owner.Author
+	resp.Device = owner.Device
+
+	if resp.HasChildren, err = db.Q.HasChangeChild(r.Context(), utils.Ptr(changeId)); err != nil {
+		http.Error(w, "check if change has child: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	files, err := listChangeFiles(r.Context(), db.Q, changeId)
+	if err != nil {
+		http.Error(w, "list change files: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// a checkout writes the markers of rendered conflicts, the working copy is compared with them
+	if err := make(renderedConflicts).render(repo, files); err != nil {
+		http.Error(w, "render conflicts: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for _, f := range files {
+		resp.Files = append(resp.Files, &protos.StatusFile{
+			Name:        f.Name,
+			Executable:  f.Executable,
+			ContentHash: f.ContentHash,
+		})
+	}
+
+	_ = protos.MarshalWrite(resp, w)
+}
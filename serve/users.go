@@ -0,0 +1,337 @@
+package serve
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/tsukinoko-kun/pogo/db"
+	"github.com/tsukinoko-kun/pogo/protos"
+	"github.com/tsukinoko-kun/pogo/serve/serveerrors"
+	"github.com/tsukinoko-kun/pogo/signedhttp"
+
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/crypto/ssh"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// RegistrationMode controls whether newly registered users can use the server right away.
+type RegistrationMode string
+
+const (
+	// RegistrationApproval requires an admin to approve every new user and every new key.
+	RegistrationApproval RegistrationMode = "approval"
+	// RegistrationTOFU trusts the first key registered for a username (trust on first use).
+	// Additional keys for an existing user still require approval by an admin.
+	RegistrationTOFU RegistrationMode = "tofu"
+)
+
+func ParseRegistrationMode(s string) (RegistrationMode, error) {
+	switch RegistrationMode(s) {
+	case RegistrationApproval, RegistrationTOFU:
+		return RegistrationMode(s), nil
+	case "":
+		return RegistrationApproval, nil
+	default:
+		return "", fmt.Errorf("unknown registration mode '%s', expected '%s' or '%s'", s, RegistrationApproval, RegistrationTOFU)
+	}
+}
+
+func (a *App) SetRegistrationMode(mode RegistrationMode) {
+	a.registrationMode = mode
+}
+
+// SetNonceStore sets where the nonces of accepted requests are recorded for replay protection.
+// Use a shared store (like db.NonceStore) if multiple server instances serve the same database.
+func (a *App) SetNonceStore(nonces signedhttp.NonceStore) {
+	a.nonces = nonces
+}
+
+// signedRequest wraps httpReq in a signedhttp.Request without reading the body.
+// The identity of the returned request is only claimed, not verified.
+// If the request is malformed, an error response is written and false is returned.
+func (a *App) signedRequest(w http.ResponseWriter, httpReq *http.Request) (*signedhttp.Request, bool) {
+	r, err := signedhttp.NewRequest(httpReq, a.nonces)
+	if err != nil {
+		writeSignatureError(w, err)
+		return nil, false
+	}
+	return r, true
+}
+
+// verifySignature wraps httpReq in a signedhttp.Request, buffers the body and verifies the signature.
+// If the signature is invalid, an error response is written and false is returned.
+func (a *App) verifySignature(w http.ResponseWriter, httpReq *http.Request) (*signedhttp.Request, bool) {
+	r, ok := a.signedRequest(w, httpReq)
+	if !ok {
+		return nil, false
+	}
+	if err := r.Buffer(); err != nil {
+		_ = r.Close()
+		writeSignatureError(w, err)
+		return nil, false
+	}
+	return r, true
+}
+
+// writeSignatureError writes the response for an error returned by signedhttp.
+func writeSignatureError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, signedhttp.ErrReplayedRequest):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, signedhttp.ErrMissingSignature),
+		errors.Is(err, signedhttp.ErrInvalidSignature),
+		errors.Is(err, signedhttp.ErrSignatureVerificationFailed):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// isSignatureError reports whether err was caused by a failed signature verification.
+func isSignatureError(err error) bool {
+	return errors.Is(err, signedhttp.ErrReplayedRequest) ||
+		errors.Is(err, signedhttp.ErrMissingSignature) ||
+		errors.Is(err, signedhttp.ErrInvalidSignature) ||
+		errors.Is(err, signedhttp.ErrSignatureVerificationFailed)
+}
+
+// authenticate verifies the signature of httpReq and checks that the signing key is registered and approved for the claimed username.
+// If the request must not be processed, an error response is written and false is returned.
+func (a *App) authenticate(w http.ResponseWriter, httpReq *http.Request) (*signedhttp.Request, bool) {
+	r, ok := a.verifySignature(w, httpReq)
+	if !ok {
+		return nil, false
+	}
+	if !a.checkKey(w, r) {
+		_ = r.Close()
+		return nil, false
+	}
+	return r, true
+}
+
+// authenticateStream works like authenticate but leaves the body unread, so it can be streamed.
+// The signature is verified at the end of the body.
+// Handlers must call Verify on the returned request before any effect becomes permanent.
+func (a *App) authenticateStream(w http.ResponseWriter, httpReq *http.Request) (*signedhttp.Request, bool) {
+	r, ok := a.signedRequest(w, httpReq)
+	if !ok {
+		return nil, false
+	}
+	if !a.checkKey(w, r) {
+		_ = r.Close()
+		return nil, false
+	}
+	return r, true
+}
+
+// checkKey checks that the signing key of r is registered and approved for its username.
+// If not, an error response is written and false is returned.
+func (a *App) checkKey(w http.ResponseWriter, r *signedhttp.Request) bool {
+	status, err := db.Q.GetUserKeyStatus(r.Context(), r.Username(), r.PublicKey())
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, serveerrors.ErrKeyNotRegistered.Error(), http.StatusUnauthorized)
+			return false
+		}
+		http.Error(w, "get user key status: "+err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	if !status.Approved {
+		http.Error(w, serveerrors.ErrKeyNotApproved.Error(), http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// authenticateAdmin works like authenticate but additionally requires the user to be an admin.
+func (a *App) authenticateAdmin(w http.ResponseWriter, httpReq *http.Request) (*signedhttp.Request, bool) {
+	r, ok := a.authenticate(w, httpReq)
+	if !ok {
+		return nil, false
+	}
+	user, err := db.Q.GetUserByName(r.Context(), r.Username())
+	if err != nil {
+		_ = r.Close()
+		http.Error(w, "get user: "+err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if !user.Admin {
+		_ = r.Close()
+		http.Error(w, serveerrors.ErrAdminRequired.Error(), http.StatusForbidden)
+		return nil, false
+	}
+	return r, true
+}
+
+func (a *App) handleRegister(w http.ResponseWriter, httpReq *http.Request) {
+	defer httpReq.Body.Close()
+
+	r, ok := a.verifySignature(w, httpReq)
+	if !ok {
+		return
+	}
+	defer r.Close()
+
+	if r.Username() == "" {
+		http.Error(w, "username must not be empty", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Q.Begin(r.Context())
+	if err != nil {
+		http.Error(w, "begin transaction: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Close()
+
+	// concurrent registrations must not both see an unknown key, an unknown user or an empty server
+	if err := tx.LockUserRegistration(r.Context()); err != nil {
+		http.Error(w, "lock user registration: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if owner, err := tx.GetKeyOwner(r.Context(), r.PublicKey()); err == nil {
+		if owner != r.Username() {
+			http.Error(w, serveerrors.ErrKeyOwnedByOtherUser.Error(), http.StatusConflict)
+			return
+		}
+		// already registered, just report the current status
+		status, err := tx.GetUserKeyStatus(r.Context(), r.Username(), r.PublicKey())
+		if err != nil {
+			http.Error(w, "get user key status: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_ = protos.MarshalWrite(&protos.RegisterResponse{Approved: status.Approved, Admin: status.Admin}, w)
+		return
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "get key owner: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := new(protos.RegisterResponse)
+
+	user, err := tx.GetUserByName(r.Context(), r.Username())
+	switch {
+	case err == nil:
+		// new key for an existing user, an admin has to approve it
+		resp.Admin = user.Admin
+		if err = tx.AddUserKey(r.Context(), user.ID, r.PublicKey(), false); err != nil {
+			http.Error(w, "add user key: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	case errors.Is(err, pgx.ErrNoRows):
+		userCount, err := tx.CountUsers(r.Context())
+		if err != nil {
+			http.Error(w, "count users: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		// the first user is the admin of the server
+		resp.Admin = userCount == 0
+		resp.Approved = resp.Admin || a.registrationMode == RegistrationTOFU
+		userId, err := tx.CreateUser(r.Context(), r.Username(), resp.Admin)
+		if err != nil {
+			http.Error(w, "create user: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err = tx.AddUserKey(r.Context(), userId, r.PublicKey(), resp.Approved); err != nil {
+			http.Error(w, "add user key: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	default:
+		http.Error(w, "get user: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		http.Error(w, "commit transaction: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	_ = protos.MarshalWrite(resp, w)
+}
+
+func (a *App) handleListPendingKeys(w http.ResponseWriter, httpReq *http.Request) {
+	defer httpReq.Body.Close()
+
+	r, ok := a.authenticateAdmin(w, httpReq)
+	if !ok {
+		return
+	}
+	defer r.Close()
+
+	pendingKeys, err := db.Q.ListPendingUserKeys(r.Context())
+	if err != nil {
+		http.Error(w, "list pending user keys: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := new(protos.ListPendingKeysResponse)
+	for _, pendingKey := range pendingKeys {
+		fingerprint, err := keyFingerprint(pendingKey.PublicKey)
+		if err != nil {
+			http.Error(w, "fingerprint key of user "+pendingKey.Name+": "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		resp.Keys = append(resp.Keys, &protos.PendingKey{
+			Username:    pendingKey.Name,
+			Fingerprint: fingerprint,
+			CreatedAt:   timestamppb.New(pendingKey.CreatedAt.Time),
+		})
+	}
+
+	_ = protos.MarshalWrite(resp, w)
+}
+
+func (a *App) handleApproveKey(w http.ResponseWriter, httpReq *http.Request) {
+	defer httpReq.Body.Close()
+
+	r, ok := a.authenticateAdmin(w, httpReq)
+	if !ok {
+		return
+	}
+	defer r.Close()
+
+	req := new(protos.ApproveKeyRequest)
+	if err := protos.Unmarshal(r.Body(), req); err != nil {
+		http.Error(w, "unmarshal approve key request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	pendingKeys, err := db.Q.ListPendingUserKeys(r.Context())
+	if err != nil {
+		http.Error(w, "list pending user keys: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	for _, pendingKey := range pendingKeys {
+		if pendingKey.Name != req.Username {
+			continue
+		}
+		fingerprint, err := keyFingerprint(pendingKey.PublicKey)
+		if err != nil {
+			http.Error(w, "fingerprint key of user "+pendingKey.Name+": "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if fingerprint != req.Fingerprint {
+			continue
+		}
+		if err = db.Q.ApproveUserKey(r.Context(), pendingKey.ID); err != nil {
+			http.Error(w, "approve user key: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	http.Error(w, fmt.Sprintf("no pending key %s found for user %s", req.Fingerprint, req.Username), http.StatusNotFound)
+}
+
+func keyFingerprint(publicKey []byte) (string, error) {
+	key, err := ssh.ParsePublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+	return ssh.FingerprintSHA256(key), nil
+}
@@ -0,0 +1,51 @@
+package signedhttp
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// NonceStore remembers the nonces of accepted requests until their signatures expire.
+type NonceStore interface {
+	// Use records the nonce until expiresAt.
+	// It returns false if the nonce was already recorded before.
+	Use(ctx context.Context, nonce []byte, expiresAt time.Time) (bool, error)
+}
+
+// MemoryNonceStore is a NonceStore that keeps the nonces in memory.
+// It is only suitable if a single server instance handles all requests.
+type MemoryNonceStore struct {
+	mu          sync.Mutex
+	nonces      map[string]time.Time
+	lastCleanup time.Time
+}
+
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{
+		nonces:      make(map[string]time.Time),
+		lastCleanup: time.Now(),
+	}
+}
+
+func (s *MemoryNonceStore) Use(_ context.Context, nonce []byte, expiresAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastCleanup) > time.Minute {
+		for n, exp := range s.nonces {
+			if exp.Before(now) {
+				delete(s.nonces, n)
+			}
+		}
+		s.lastCleanup = now
+	}
+
+	key := string(nonce)
+	if exp, ok := s.nonces[key]; ok && !exp.Before(now) {
+		return false, nil
+	}
+	s.nonces[key] = expiresAt
+	return true, nil
+}
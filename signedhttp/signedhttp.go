@@ -2,6 +2,7 @@ package signedhttp
 
 import (
 	"bytes"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
@@ -9,9 +10,9 @@ import (
 	"fmt"
 	"github.com/tsukinoko-kun/pogo/auth"
 	"github.com/tsukinoko-kun/pogo/protos"
+	"hash"
 	"io"
 	"net/http"
-	"os"
 	"time"
 
 	"golang.org/x/crypto/ssh"
@@ -23,6 +24,23 @@ var (
 	ErrSignatureVerificationFailed = errors.New("signature verification failed")
 	ErrInvalidSignature            = errors.New("invalid signature format")
 	ErrMissingSignature            = errors.New("missing signature in trailing headers")
+	ErrReplayedRequest             = errors.New("request was already processed (nonce reused)")
+)
+
+// The signature is sent as trailing header after the body.
+// These headers announce the identity of the signer up front,
+// so the server can authorize the request before the body is read.
+// They are checked against the signature once the body is complete.
+const (
+	HeaderUsername  = "X-Signature-Username"
+	HeaderMachineID = "X-Signature-Machine-Id"
+	HeaderPublicKey = "X-Signature-Public-Key"
+)
+
+const (
+	// maxClockSkew is the maximum accepted difference between the signature timestamp and the server time.
+	maxClockSkew = 5 * time.Minute
+	nonceSize    = 16
 )
 
 // httpSignatureData represents the data structure that gets signed for HTTP requests
@@ -33,6 +51,7 @@ type httpSignatureData struct {
 	Username    string    `json:"username"`
 	MachineID   string    `json:"machine_id"`
 	PublicKey   []byte    `json:"public_key"`
+	Nonce       []byte    `json:"nonce"`
 }
 
 // Client handles sending signed HTTP requests
@@ -58,30 +77,20 @@ func NewClient(username, machineID string) (*Client, error) {
 	}, nil
 }
 
-// Post sends a signed POST request with chunked transfer encoding
+// Post sends a signed POST request with chunked transfer encoding.
+// The body is streamed. Its hash is calculated while it is sent
+// and the signature is sent as trailing header once the body is complete.
 func (c *Client) Post(url string, body io.Reader, headers map[string]string) (*http.Response, error) {
-	// Buffer the body to calculate hash and generate signature
-	var bodyBytes []byte
-	var err error
-
-	if body != nil {
-		bodyBytes, err = io.ReadAll(body)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read body: %w", err)
-		}
+	if body == nil {
+		body = bytes.NewReader(nil)
 	}
 
-	// Calculate body hash
-	bodyHash := sha256.Sum256(bodyBytes)
-
-	// Generate signature
-	signature, err := c.generateSignature(url, bodyHash[:])
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate signature: %w", err)
+	sr := &signingReader{
+		body:   body,
+		hasher: sha256.New(),
 	}
 
-	// Create request with buffered body
-	req, err := http.NewRequest("POST", url, bytes.NewReader(bodyBytes))
+	req, err := http.NewRequest("POST", url, sr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -95,18 +104,56 @@ func (c *Client) Post(url string, body io.Reader, headers map[string]string) (*h
 		req.Header.Set(key, value)
 	}
 
-	// Set up trailing headers
+	// Announce the identity of the signer
+	req.Header.Set(HeaderUsername, c.username)
+	req.Header.Set(HeaderMachineID, c.machineID)
+	req.Header.Set(HeaderPublicKey, base64.URLEncoding.EncodeToString(c.publicKey.Marshal()))
+
+	// Set up trailing headers, the value is set when the body is complete
 	req.Trailer = make(http.Header)
 	req.Header.Set("Trailer", "X-Signature")
-	req.Trailer.Set("X-Signature", signature)
+	req.Trailer.Set("X-Signature", "")
+	sr.onEOF = func(bodyHash []byte) error {
+		signature, err := c.generateSignature(url, bodyHash)
+		if err != nil {
+			return fmt.Errorf("failed to generate signature: %w", err)
+		}
+		req.Trailer.Set("X-Signature", signature)
+		return nil
+	}
 
 	return c.client.Do(req)
 }
 
+// signingReader hashes the body while it is read and calls onEOF with the hash once the body is complete.
+type signingReader struct {
+	body   io.Reader
+	hasher hash.Hash
+	onEOF  func(bodyHash []byte) error
+	done   bool
+}
+
+func (s *signingReader) Read(p []byte) (int, error) {
+	n, err := s.body.Read(p)
+	s.hasher.Write(p[:n])
+	if err == io.EOF && !s.done {
+		s.done = true
+		if err := s.onEOF(s.hasher.Sum(nil)); err != nil {
+			return n, err
+		}
+	}
+	return n, err
+}
+
 func (c *Client) generateSignature(requestURL string, bodyHash []byte) (string, error) {
 	now := time.Now().UTC()
 	publicKeyBytes := c.publicKey.Marshal()
 
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
 	// Extract path from URL
 	reqURL, err := http.NewRequest("GET", requestURL, nil)
 	if err != nil {
@@ -120,6 +167,7 @@ func (c *Client) generateSignature(requestURL string, bodyHash []byte) (string,
 		c.username,
 		c.machineID,
 		publicKeyBytes,
+		nonce,
 	}
 
 	dataBytes, err := json.Marshal(sigData)
@@ -139,6 +187,7 @@ func (c *Client) generateSignature(requestURL string, bodyHash []byte) (string,
 		Username:    sigData.Username,
 		MachineId:   sigData.MachineID,
 		PublicKey:   publicKeyBytes,
+		Nonce:       sigData.Nonce,
 		Format:      signature.Format,
 		Blob:        signature.Blob,
 		Rest:        signature.Rest,
@@ -152,58 +201,111 @@ func (c *Client) generateSignature(requestURL string, bodyHash []byte) (string,
 	return base64.URLEncoding.EncodeToString(sigBytes), nil
 }
 
-// Request wraps an HTTP request for signature verification
+// Request wraps an HTTP request for signature verification.
+// The signature is sent as trailing header, so it can only be verified after the body was read completely.
 type Request struct {
 	*http.Request
-	tempFile *os.File
-	bodyHash []byte
-	verified bool
-	httpSig  *protos.HTTPSignature
+	body      io.Reader
+	hasher    hash.Hash
+	nonces    NonceStore
+	username  string
+	machineID string
+	publicKey []byte
+	bodyHash  []byte
+	done      bool
+	verified  bool
+	verifyErr error
+	httpSig   *protos.HTTPSignature
 }
 
-// NewRequest creates a new signed request wrapper and verifies the signature
-func NewRequest(req *http.Request) (*Request, error) {
-	tempFile, err := os.CreateTemp("", "signedhttp-*")
+// NewRequest creates a new signed request wrapper.
+// Nothing is verified yet, the identity methods return the identity claimed in the request headers.
+// The signature is verified when the body was read to the end or when Verify or Buffer is called.
+// Call one of them before any effect of the request becomes permanent.
+// The nonce of the signature is recorded in nonces, so the same request can't be replayed.
+func NewRequest(req *http.Request, nonces NonceStore) (*Request, error) {
+	if req.Header.Get(HeaderUsername) == "" || req.Header.Get(HeaderPublicKey) == "" {
+		return nil, fmt.Errorf("%w: missing identity headers", ErrInvalidSignature)
+	}
+	publicKey, err := base64.URLEncoding.DecodeString(req.Header.Get(HeaderPublicKey))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create temp file: %w", err)
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
 	}
 
-	hasher := sha256.New()
-	multiWriter := io.MultiWriter(tempFile, hasher)
+	signedReq := &Request{
+		Request:   req,
+		hasher:    sha256.New(),
+		nonces:    nonces,
+		username:  req.Header.Get(HeaderUsername),
+		machineID: req.Header.Get(HeaderMachineID),
+		publicKey: publicKey,
+	}
+	signedReq.body = verifyingReader{signedReq}
 
-	// Copy body to temp file while calculating hash
-	_, err = io.Copy(multiWriter, req.Body)
-	if err != nil {
-		tempFile.Close()
-		os.Remove(tempFile.Name())
-		return nil, fmt.Errorf("failed to copy body: %w", err)
+	return signedReq, nil
+}
+
+// verifyingReader hashes the body while it is read.
+// At the end of the body, the signature is verified and a verification error is returned instead of io.EOF.
+type verifyingReader struct {
+	r *Request
+}
+
+func (v verifyingReader) Read(p []byte) (int, error) {
+	n, err := v.r.Request.Body.Read(p)
+	v.r.hasher.Write(p[:n])
+	if err == io.EOF {
+		if err := v.r.finish(); err != nil {
+			return n, err
+		}
 	}
+	return n, err
+}
 
-	bodyHash := hasher.Sum(nil)
+// finish verifies the signature after the body was read completely.
+func (r *Request) finish() error {
+	if r.done {
+		return r.verifyErr
+	}
+	r.done = true
+	r.bodyHash = r.hasher.Sum(nil)
 
-	// Reset temp file position to beginning
-	_, err = tempFile.Seek(0, 0)
-	if err != nil {
-		tempFile.Close()
-		os.Remove(tempFile.Name())
-		return nil, fmt.Errorf("failed to seek temp file: %w", err)
+	r.verifyErr = r.verifySignature()
+	if r.verifyErr == nil {
+		// Reject replayed requests
+		r.verifyErr = r.useNonce(r.nonces)
 	}
+	r.verified = r.verifyErr == nil
+	return r.verifyErr
+}
 
-	signedReq := &Request{
-		Request:  req,
-		tempFile: tempFile,
-		bodyHash: bodyHash,
+// Verify reads the rest of the body and verifies the signature.
+// Everything that was not read from Body yet is discarded.
+func (r *Request) Verify() error {
+	if _, err := io.Copy(io.Discard, r.body); err != nil {
+		return err
 	}
+	return r.finish()
+}
 
-	// Verify signature
-	err = signedReq.verifySignature()
+// Buffer reads the whole body into memory and verifies the signature.
+// Afterward, Body returns the buffered content.
+// Only use this for small bodies.
+func (r *Request) Buffer() error {
+	buf, err := io.ReadAll(r.body)
 	if err != nil {
-		signedReq.Close()
-		return nil, err
+		return err
 	}
+	if err := r.finish(); err != nil {
+		return err
+	}
+	r.body = bytes.NewReader(buf)
+	return nil
+}
 
-	signedReq.verified = true
-	return signedReq, nil
+// Verified reports whether the signature was verified successfully.
+func (r *Request) Verified() bool {
+	return r.verified
 }
 
 func (r *Request) verifySignature() error {
@@ -226,13 +328,24 @@ func (r *Request) verifySignature() error {
 	// Store for later access
 	r.httpSig = &httpSig
 
+	// The signature must be made by the identity the request claimed up front
+	if httpSig.Username != r.username ||
+		httpSig.MachineId != r.machineID ||
+		!bytes.Equal(httpSig.PublicKey, r.publicKey) {
+		return fmt.Errorf("%w: identity mismatch", ErrSignatureVerificationFailed)
+	}
+
 	sigTime := httpSig.Timestamp.AsTime()
 
 	now := time.Now().UTC()
-	if now.Sub(sigTime) > 5*time.Minute || sigTime.Sub(now) > 5*time.Minute {
+	if now.Sub(sigTime) > maxClockSkew || sigTime.Sub(now) > maxClockSkew {
 		return fmt.Errorf("%w: timestamp too old or in future", ErrSignatureVerificationFailed)
 	}
 
+	if len(httpSig.Nonce) < nonceSize {
+		return fmt.Errorf("%w: missing nonce", ErrInvalidSignature)
+	}
+
 	// Verify body hash matches
 	if !bytes.Equal(r.bodyHash, httpSig.BodyHash) {
 		return fmt.Errorf("%w: body hash mismatch", ErrSignatureVerificationFailed)
@@ -257,6 +370,7 @@ func (r *Request) verifySignature() error {
 		httpSig.Username,
 		httpSig.MachineId,
 		httpSig.PublicKey,
+		httpSig.Nonce,
 	}
 
 	dataBytes, err := json.Marshal(sigData)
@@ -279,33 +393,47 @@ func (r *Request) verifySignature() error {
 	return nil
 }
 
-// Body returns the request body as an io.Reader from the temp file
+func (r *Request) useNonce(nonces NonceStore) error {
+	if nonces == nil {
+		return errors.New("no nonce store configured")
+	}
+	// after this point in time, the timestamp check rejects the request anyway
+	expiresAt := r.httpSig.Timestamp.AsTime().Add(maxClockSkew)
+	fresh, err := nonces.Use(r.Context(), r.httpSig.Nonce, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to record nonce: %w", err)
+	}
+	if !fresh {
+		return ErrReplayedRequest
+	}
+	return nil
+}
+
+// Body returns the request body.
+// The signature is verified when the end of the body is reached.
 func (r *Request) Body() io.Reader {
-	return r.tempFile
+	return r.body
 }
 
-// Username returns the username from the verified signature
+// Username returns the username of the signer.
+// Until the signature is verified, this is the claimed username.
 func (r *Request) Username() string {
-	if !r.verified || r.httpSig == nil {
-		return ""
-	}
-	return r.httpSig.Username
+	return r.username
 }
 
-// MachineID returns the machine ID from the verified signature
+// MachineID returns the machine ID of the signer.
+// Until the signature is verified, this is the claimed machine ID.
 func (r *Request) MachineID() string {
-	if !r.verified || r.httpSig == nil {
-		return ""
-	}
-	return r.httpSig.MachineId
+	return r.machineID
 }
 
-// Close closes the temp file and removes it
+// PublicKey returns the wire format of the public key of the signer.
+// Until the signature is verified, this is the claimed public key.
+func (r *Request) PublicKey() []byte {
+	return r.publicKey
+}
+
+// Close closes the request body
 func (r *Request) Close() error {
-	if r.tempFile != nil {
-		r.tempFile.Close()
-		os.Remove(r.tempFile.Name())
-		r.tempFile = nil
-	}
-	return nil
+	return r.Request.Body.Close()
 }
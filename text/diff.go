@@ -0,0 +1,131 @@
+package text
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/devsisters/go-diff3"
+)
+
+// DiffStat counts the changed lines of a diff.
+type DiffStat struct {
+	Additions int
+	Deletions int
+}
+
+type diffLine struct {
+	op   byte // ' ', '-' or '+'
+	line string
+}
+
+// splitLines splits s into lines that keep their line ending.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines returns the edit script that turns a into b.
+func diffLines(a, b []string) []diffLine {
+	var script []diffLine
+	consumed := 0
+	for _, res := range diff3.DiffComm(a, b) {
+		for _, line := range res.Common {
+			script = append(script, diffLine{' ', line})
+		}
+		for _, line := range res.File1 {
+			script = append(script, diffLine{'-', line})
+		}
+		for _, line := range res.File2 {
+			script = append(script, diffLine{'+', line})
+		}
+		consumed += len(res.Common) + len(res.File1)
+	}
+	// DiffComm omits the common lines after the last difference
+	for _, line := range a[consumed:] {
+		script = append(script, diffLine{' ', line})
+	}
+	return script
+}
+
+// UnifiedDiff returns the difference between a and b in the unified format with context lines around each change.
+// The names are used for the --- and +++ header lines, use /dev/null for a missing file.
+// If a and b are equal, an empty string is returned.
+func UnifiedDiff(oldName, newName, a, b string, context int) (string, DiffStat) {
+	var stat DiffStat
+	if a == b {
+		return "", stat
+	}
+
+	script := diffLines(splitLines(a), splitLines(b))
+
+	// line numbers before each entry of the script
+	oldLine := make([]int, len(script)+1)
+	newLine := make([]int, len(script)+1)
+	var changes []int
+	for i, l := range script {
+		oldLine[i+1], newLine[i+1] = oldLine[i], newLine[i]
+		switch l.op {
+		case ' ':
+			oldLine[i+1]++
+			newLine[i+1]++
+		case '-':
+			oldLine[i+1]++
+			stat.Deletions++
+			changes = append(changes, i)
+		case '+':
+			newLine[i+1]++
+			stat.Additions++
+			changes = append(changes, i)
+		}
+	}
+	if len(changes) == 0 {
+		return "", stat
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+
+	for i := 0; i < len(changes); {
+		// changes closer than two contexts share a hunk
+		j := i
+		for j+1 < len(changes) && changes[j+1]-changes[j]-1 <= 2*context {
+			j++
+		}
+		start := max(0, changes[i]-context)
+		end := min(len(script), changes[j]+context+1)
+
+		sb.WriteString("@@ -")
+		writeRange(&sb, oldLine[start], oldLine[end]-oldLine[start])
+		sb.WriteString(" +")
+		writeRange(&sb, newLine[start], newLine[end]-newLine[start])
+		sb.WriteString(" @@\n")
+
+		for _, l := range script[start:end] {
+			sb.WriteByte(l.op)
+			sb.WriteString(l.line)
+			if !strings.HasSuffix(l.line, "\n") {
+				sb.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+
+		i = j + 1
+	}
+
+	return sb.String(), stat
+}
+
+// writeRange writes the range of a hunk header.
+// before is the number of lines before the hunk, count the number of lines in the hunk.
+func writeRange(sb *strings.Builder, before, count int) {
+	switch count {
+	case 0:
+		fmt.Fprintf(sb, "%d,0", before)
+	case 1:
+		fmt.Fprintf(sb, "%d", before+1)
+	default:
+		fmt.Fprintf(sb, "%d,%d", before+1, count)
+	}
+}
@@ -0,0 +1,68 @@
+package text
+
+import (
+	"testing"
+)
+
+func TestUnifiedDiffEqual(t *testing.T) {
+	patch, stat := UnifiedDiff("a/x", "b/x", "a\nb\n", "a\nb\n", 3)
+	if patch != "" {
+		t.Errorf("Expected empty patch, got %q", patch)
+	}
+	if stat != (DiffStat{}) {
+		t.Errorf("Expected empty stat, got %+v", stat)
+	}
+}
+
+func TestUnifiedDiffHunks(t *testing.T) {
+	a := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\n"
+	b := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\nn"
+	// generated with diff -u
+	expected := `--- a/x
++++ b/x
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -11,3 +11,4 @@
+ k
+ l
+ m
++n
+\ No newline at end of file
+`
+
+	patch, stat := UnifiedDiff("a/x", "b/x", a, b, 3)
+	if patch != expected {
+		t.Errorf("Expected patch\n%s\ngot\n%s", expected, patch)
+	}
+	if stat.Additions != 2 || stat.Deletions != 1 {
+		t.Errorf("Expected 2 additions and 1 deletion, got %+v", stat)
+	}
+}
+
+func TestUnifiedDiffAddedFile(t *testing.T) {
+	expected := "--- /dev/null\n+++ b/y\n@@ -0,0 +1,2 @@\n+one\n+two\n"
+
+	patch, stat := UnifiedDiff("/dev/null", "b/y", "", "one\ntwo\n", 3)
+	if patch != expected {
+		t.Errorf("Expected patch %q, got %q", expected, patch)
+	}
+	if stat.Additions != 2 || stat.Deletions != 0 {
+		t.Errorf("Expected 2 additions, got %+v", stat)
+	}
+}
+
+func TestUnifiedDiffMergedHunk(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n"
+	b := "1\nX\n3\n4\n5\n6\nY\n8\n"
+	expected := "--- a/z\n+++ b/z\n@@ -1,8 +1,8 @@\n 1\n-2\n+X\n 3\n 4\n 5\n 6\n-7\n+Y\n 8\n"
+
+	patch, _ := UnifiedDiff("a/z", "b/z", a, b, 3)
+	if patch != expected {
+		t.Errorf("Expected patch %q, got %q", expected, patch)
+	}
+}